@@ -0,0 +1,88 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nathfavour/auracrab/pkg/config"
+	"github.com/nathfavour/auracrab/pkg/core"
+	"github.com/nathfavour/auracrab/pkg/health"
+	"github.com/spf13/cobra"
+)
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check the health of the daemon and what it depends on",
+	Long: "Runs the health probes: vibe socket, model provider, channels, disk space, " +
+		"the history database, spine lag, stuck tasks and the vault. The running daemon's " +
+		"report is used when its /healthz endpoint is on (metrics.listen); otherwise the " +
+		"probes run in this process. Exits with status 1 when the daemon is down.",
+	Run: func(cmd *cobra.Command, args []string) {
+		asJSON, _ := cmd.Flags().GetBool("json")
+
+		cfg, err := config.LoadConfig()
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		rep, err := daemonHealth(cfg)
+		source := "daemon"
+		if err != nil {
+			source = "local"
+			if !asJSON {
+				fmt.Printf("(%s; checking from this process)\n", err)
+			}
+			rep = core.GetButler().Health(cmd.Context())
+		}
+
+		if asJSON {
+			data, _ := json.MarshalIndent(rep, "", "  ")
+			fmt.Println(string(data))
+		} else {
+			fmt.Print(rep.String())
+			if source == "daemon" {
+				fmt.Printf("(reported by the daemon at %s)\n", rep.Checked.Format(time.TimeOnly))
+			}
+		}
+		if rep.Status == health.StatusDown {
+			os.Exit(1)
+		}
+	},
+}
+
+// daemonHealth asks the running daemon for its health report.
+func daemonHealth(cfg *config.Config) (health.Report, error) {
+	var rep health.Report
+	pidData, err := os.ReadFile(config.PIDPath())
+	if err != nil {
+		return rep, fmt.Errorf("the daemon is not running")
+	}
+	if pid, _ := strconv.Atoi(strings.TrimSpace(string(pidData))); !isProcessRunning(pid) {
+		return rep, fmt.Errorf("the daemon is not running")
+	}
+	if cfg.Metrics.Listen == "" {
+		return rep, fmt.Errorf("the daemon does not serve /healthz; set metrics.listen")
+	}
+
+	client := &http.Client{Timeout: 15 * time.Second}
+	resp, err := client.Get("http://" + cfg.Metrics.Listen + "/healthz")
+	if err != nil {
+		return rep, fmt.Errorf("could not reach the daemon: %v", err)
+	}
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(&rep); err != nil {
+		return rep, fmt.Errorf("unreadable health report: %v", err)
+	}
+	return rep, nil
+}
+
+func init() {
+	doctorCmd.Flags().Bool("json", false, "print the report as JSON")
+	rootCmd.AddCommand(doctorCmd)
+}
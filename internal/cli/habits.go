@@ -0,0 +1,119 @@
+package cli
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/nathfavour/auracrab/pkg/memory"
+	"github.com/spf13/cobra"
+)
+
+var habitsCmd = &cobra.Command{
+	Use:   "habits",
+	Short: "Inspect and curate learned plan habits",
+}
+
+var habitsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List learned habits",
+	Run: func(cmd *cobra.Command, args []string) {
+		crab, _ := cmd.Flags().GetString("crab")
+		list := memory.GetHabitStore().List()
+
+		shown := 0
+		for _, h := range list {
+			if cmd.Flags().Changed("crab") && h.Crab != crab {
+				continue
+			}
+			scope := "global"
+			if h.Crab != "" {
+				scope = "@" + h.Crab
+			}
+			state := ""
+			if h.Invalidated {
+				state = " [invalidated]"
+			}
+			fmt.Printf("- %s v%d (%s) score=%.2f replays=%d steps=%d%s\n  %s\n",
+				h.ID, h.Version, scope, h.Score(), h.Replays, len(h.Steps), state, h.Template)
+			shown++
+		}
+
+		if shown == 0 {
+			fmt.Println("No habits learned yet.")
+		}
+	},
+}
+
+var habitsShowCmd = &cobra.Command{
+	Use:   "show <id>",
+	Short: "Show a habit with its slots and steps",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		h, err := memory.GetHabitStore().Get(args[0])
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return
+		}
+
+		scope := "global"
+		if h.Crab != "" {
+			scope = "@" + h.Crab
+		}
+		fmt.Printf("ID:        %s (v%d, %s)\n", h.ID, h.Version, scope)
+		fmt.Printf("Goal:      %s\n", h.Goal)
+		fmt.Printf("Template:  %s\n", h.Template)
+		fmt.Printf("Score:     %.2f (successes %.2f, failures %.2f, replays %d)\n", h.Score(), h.Successes, h.Failures, h.Replays)
+		fmt.Printf("Learned:   %s\n", h.LearnedAt.Format(time.RFC822))
+		fmt.Printf("Updated:   %s\n", h.UpdatedAt.Format(time.RFC822))
+		if h.Invalidated {
+			fmt.Printf("Status:    invalidated (%s)\n", h.Reason)
+		}
+		if len(h.Slots) > 0 {
+			fmt.Println("Slots:")
+			for _, s := range h.Slots {
+				fmt.Printf("  {%s} = %s\n", s.Name, s.Value)
+			}
+		}
+		fmt.Println("Steps:")
+		for i, s := range h.Steps {
+			fmt.Printf("  %d. %s\n", i+1, s)
+		}
+	},
+}
+
+var habitsForgetCmd = &cobra.Command{
+	Use:   "forget [id]",
+	Short: "Forget a habit, or all habits of a crab with --crab",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		store := memory.GetHabitStore()
+
+		if len(args) == 0 {
+			if !cmd.Flags().Changed("crab") {
+				fmt.Println("Error: pass a habit ID or --crab <id> (use --crab \"\" for global habits)")
+				return
+			}
+			crab, _ := cmd.Flags().GetString("crab")
+			n := store.ForgetCrab(crab)
+			fmt.Printf("Forgot %d habit(s).\n", n)
+			return
+		}
+
+		if err := store.Forget(args[0]); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return
+		}
+		fmt.Printf("Habit '%s' forgotten.\n", args[0])
+	},
+}
+
+func init() {
+	habitsListCmd.Flags().String("crab", "", "Only show habits of this crab")
+	habitsForgetCmd.Flags().String("crab", "", "Forget all habits of this crab")
+
+	habitsCmd.AddCommand(habitsListCmd)
+	habitsCmd.AddCommand(habitsShowCmd)
+	habitsCmd.AddCommand(habitsForgetCmd)
+
+	rootCmd.AddCommand(habitsCmd)
+}
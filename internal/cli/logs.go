@@ -0,0 +1,79 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/nathfavour/auracrab/pkg/config"
+	"github.com/nathfavour/auracrab/pkg/logging"
+	"github.com/nathfavour/auracrab/pkg/tracing"
+	"github.com/spf13/cobra"
+)
+
+var logsCmd = &cobra.Command{
+	Use:   "logs",
+	Short: "Show the daemon's log, optionally following it",
+	Run: func(cmd *cobra.Command, args []string) {
+		follow, _ := cmd.Flags().GetBool("follow")
+		lines, _ := cmd.Flags().GetInt("lines")
+		task, _ := cmd.Flags().GetString("task")
+		subsystem, _ := cmd.Flags().GetString("subsystem")
+		level, _ := cmd.Flags().GetString("level")
+		replica, _ := cmd.Flags().GetString("replica")
+
+		cfg, err := config.LoadConfig()
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		config.SetReplica(replica)
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		filter := logging.Filter{Task: task, Subsystem: subsystem, Level: logging.ParseLevel(level)}
+		if err := logging.Follow(ctx, logging.Path(cfg.Logging), filter, lines, follow, os.Stdout); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+// setupTelemetry sends the daemon's log to its rotated file, and to mirror
+// when it is not nil, and starts exporting trace spans when configured. The
+// returned function flushes both; call it before exiting.
+func setupTelemetry(mirror io.Writer) func() {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	if err := logging.Setup(cfg.Logging, mirror); err != nil {
+		fmt.Printf("Error: could not open the log: %v\n", err)
+		os.Exit(1)
+	}
+	if err := tracing.Setup(cfg.Tracing); err != nil {
+		fmt.Printf("Error: could not start tracing: %v\n", err)
+		os.Exit(1)
+	}
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = tracing.Shutdown(ctx)
+		_ = logging.Close()
+	}
+}
+
+func init() {
+	logsCmd.Flags().BoolP("follow", "f", false, "keep printing new records as they are written")
+	logsCmd.Flags().IntP("lines", "n", 50, "number of earlier records to show")
+	logsCmd.Flags().String("task", "", "only show records of this task")
+	logsCmd.Flags().String("subsystem", "", "only show records of this subsystem (e.g. spine, immune, nervous)")
+	logsCmd.Flags().String("level", "info", "minimum level: debug, info, warn or error")
+	logsCmd.Flags().String("replica", "", "read the log of a swarm worker replica instead")
+	rootCmd.AddCommand(logsCmd)
+}
@@ -0,0 +1,123 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/nathfavour/auracrab/pkg/memory"
+	"github.com/nathfavour/auracrab/pkg/vibe"
+	"github.com/spf13/cobra"
+)
+
+var memoryCmd = &cobra.Command{
+	Use:   "memory",
+	Short: "Manage the agent's long-term knowledge memory",
+}
+
+var memoryIngestCmd = &cobra.Command{
+	Use:   "ingest <path>",
+	Short: "Index a project into a knowledge collection (incremental)",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		root, err := filepath.Abs(args[0])
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return
+		}
+		collection, _ := cmd.Flags().GetString("collection")
+		if collection == "" {
+			collection = filepath.Base(root)
+		}
+		exclude, _ := cmd.Flags().GetStringSlice("exclude")
+		quiet, _ := cmd.Flags().GetBool("quiet")
+
+		embedder := memory.ProbeEmbedder(vibe.NewClient())
+		if _, ok := embedder.(memory.HashEmbedder); ok {
+			fmt.Println("⚠️  vibeauracle embeddings unavailable, using local hash embeddings.")
+		}
+
+		kb, err := memory.OpenKnowledge(collection, embedder)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+
+		start := time.Now()
+		stats, err := kb.Ingest(ctx, root, memory.IngestOptions{
+			Exclude: exclude,
+			Progress: func(path string) {
+				if !quiet {
+					fmt.Printf("  indexing %s\n", path)
+				}
+			},
+		})
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return
+		}
+
+		fmt.Printf("🧠 Collection '%s' updated in %v: %d files scanned, %d indexed (%d chunks), %d unchanged, %d removed.\n",
+			collection, time.Since(start).Round(time.Millisecond), stats.Scanned, stats.Indexed, stats.Chunks, stats.Unchanged, stats.Removed)
+	},
+}
+
+var memoryListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List ingested knowledge collections",
+	Run: func(cmd *cobra.Command, args []string) {
+		list := memory.ListKnowledge()
+		if len(list) == 0 {
+			fmt.Println("No knowledge collections ingested.")
+			return
+		}
+		for _, m := range list {
+			chunks := 0
+			for _, f := range m.Files {
+				chunks += len(f.Chunks)
+			}
+			embedder := m.Embedder
+			if embedder == "" {
+				embedder = "unknown, re-ingest to enable vector search"
+			}
+			fmt.Printf("- %s: %s (%d files, %d chunks, %s embeddings, updated %s)\n",
+				m.Collection, m.Root, len(m.Files), chunks, embedder, m.UpdatedAt.Format(time.RFC822))
+		}
+	},
+}
+
+var memoryQueryCmd = &cobra.Command{
+	Use:   "query <collection> <text>",
+	Short: "Show the chunks most relevant to a query",
+	Args:  cobra.MinimumNArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		limit, _ := cmd.Flags().GetInt("limit")
+		kb, err := memory.OpenKnowledge(args[0], memory.NewFallbackEmbedder(vibe.NewClient()))
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return
+		}
+		for _, c := range kb.Query(strings.Join(args[1:], " "), limit) {
+			fmt.Println(memory.FormatChunk(c))
+		}
+	},
+}
+
+func init() {
+	memoryIngestCmd.Flags().StringP("collection", "c", "", "Collection name (default: directory name)")
+	memoryIngestCmd.Flags().StringSlice("exclude", []string{}, "Extra globs to skip (e.g. 'vendor/**')")
+	memoryIngestCmd.Flags().BoolP("quiet", "q", false, "Do not print every indexed file")
+	memoryQueryCmd.Flags().IntP("limit", "n", 5, "Number of chunks to show")
+
+	memoryCmd.AddCommand(memoryIngestCmd)
+	memoryCmd.AddCommand(memoryListCmd)
+	memoryCmd.AddCommand(memoryQueryCmd)
+	rootCmd.AddCommand(memoryCmd)
+}
@@ -0,0 +1,83 @@
+package cli
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/nathfavour/auracrab/pkg/core"
+	"github.com/spf13/cobra"
+)
+
+var promptCmd = &cobra.Command{
+	Use:   "prompt",
+	Short: "Inspect the living prompts sent for tasks",
+}
+
+var promptInspectCmd = &cobra.Command{
+	Use:   "inspect <task-id>",
+	Short: "Show how a task's prompt was assembled within its token budget",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		list, err := core.LoadAssemblies(args[0])
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return
+		}
+		if len(list) == 0 {
+			fmt.Printf("no prompts recorded for task %s\n", args[0])
+			return
+		}
+		all, _ := cmd.Flags().GetBool("all")
+		raw, _ := cmd.Flags().GetBool("raw")
+
+		if !all {
+			list = list[len(list)-1:]
+		}
+
+		for i, a := range list {
+			if i > 0 {
+				fmt.Println()
+			}
+			provider := a.Provider
+			if provider == "" {
+				provider = "default"
+			}
+			fmt.Printf("📦 Packet built %s for provider %s: %d/%d tokens\n",
+				a.BuiltAt.Format(time.RFC822), provider, a.Total, a.Limit)
+
+			for _, s := range a.Sections {
+				var notes []string
+				if s.Required {
+					notes = append(notes, "required")
+				}
+				if s.Dropped > 0 {
+					notes = append(notes, fmt.Sprintf("dropped %d items", s.Dropped))
+				}
+				if s.Trimmed {
+					notes = append(notes, "trimmed")
+				}
+				if s.Omitted {
+					notes = append(notes, "omitted")
+				}
+				note := ""
+				if len(notes) > 0 {
+					note = fmt.Sprintf(" %v", notes)
+				}
+				fmt.Printf("  - %-32s prio=%-3d tokens=%d -> %d%s\n", s.Name, s.Priority, s.Original, s.Tokens, note)
+			}
+
+			if raw {
+				fmt.Println()
+				fmt.Println(a.String())
+			}
+		}
+	},
+}
+
+func init() {
+	promptInspectCmd.Flags().Bool("raw", false, "Print the assembled prompt text")
+	promptInspectCmd.Flags().Bool("all", false, "Show every packet recorded for the task, not just the latest")
+
+	promptCmd.AddCommand(promptInspectCmd)
+	rootCmd.AddCommand(promptCmd)
+}
@@ -0,0 +1,138 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/nathfavour/auracrab/pkg/core"
+	"github.com/nathfavour/auracrab/pkg/prompts"
+	"github.com/spf13/cobra"
+)
+
+var promptsCmd = &cobra.Command{
+	Use:   "prompts",
+	Short: "List, show and test the prompt templates",
+	Long: `Prompt templates are Go text/templates. Override one for the whole agent by
+writing <name>.tmpl into the prompts directory of the data dir, or for a single
+crab into prompts/crabs/<crab-id>/.`,
+}
+
+var promptsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List prompt templates and where each is loaded from",
+	Run: func(cmd *cobra.Command, args []string) {
+		crab, _ := cmd.Flags().GetString("crab")
+		for _, s := range prompts.Specs() {
+			_, origin, _ := prompts.Source(s.Name, crab)
+			fmt.Printf("- %-18s %s [%s]\n", s.Name, s.Description, origin)
+		}
+		fmt.Printf("\nOverrides: %s\n", prompts.Dir())
+	},
+}
+
+var promptsShowCmd = &cobra.Command{
+	Use:   "show <name>",
+	Short: "Show a template's variables and the text in effect",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		crab, _ := cmd.Flags().GetString("crab")
+		spec, ok := prompts.Lookup(args[0])
+		if !ok {
+			fmt.Printf("Error: unknown prompt template '%s'\n", args[0])
+			os.Exit(1)
+		}
+		text, origin, _ := prompts.Source(spec.Name, crab)
+
+		fmt.Printf("📝 %s: %s\nSource: %s\n", spec.Name, spec.Description, origin)
+		if len(spec.Vars) > 0 {
+			fmt.Println("Variables:")
+			for _, v := range spec.Vars {
+				fmt.Printf("  .%-13s %s\n", v.Name, v.Description)
+			}
+		}
+		fmt.Printf("\n%s\n", strings.TrimRight(text, "\n"))
+	},
+}
+
+var promptsTestCmd = &cobra.Command{
+	Use:   "test <name>",
+	Short: "Render a template against a sample task",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		crab, _ := cmd.Flags().GetString("crab")
+		file, _ := cmd.Flags().GetString("file")
+		taskID, _ := cmd.Flags().GetString("task")
+		sets, _ := cmd.Flags().GetStringArray("set")
+
+		spec, ok := prompts.Lookup(args[0])
+		if !ok {
+			fmt.Printf("Error: unknown prompt template '%s'\n", args[0])
+			os.Exit(1)
+		}
+		data := spec.Sample()
+
+		if taskID != "" {
+			var task *core.Task
+			for _, t := range core.GetButler().ListTasks() {
+				if t.ID == taskID {
+					task = t
+				}
+			}
+			if task == nil {
+				fmt.Printf("Error: task %s not found\n", taskID)
+				os.Exit(1)
+			}
+			for _, key := range []string{"Goal", "Task", "Prompt", "Text", "Command"} {
+				if _, ok := data[key]; ok {
+					data[key] = task.Content
+				}
+			}
+			if _, ok := data["Step"]; ok && task.Continuity != nil && len(task.Continuity.Plan) > 0 {
+				data["Step"] = task.Continuity.Plan[0].Description
+			}
+			if crab == "" && task.Metadata != nil {
+				crab = task.Metadata["crab_id"]
+			}
+		}
+
+		for _, kv := range sets {
+			k, v, ok := strings.Cut(kv, "=")
+			if !ok {
+				fmt.Printf("Error: invalid --set %q, expected key=value\n", kv)
+				os.Exit(1)
+			}
+			data[k] = v
+		}
+
+		var out string
+		var err error
+		if file != "" {
+			var text []byte
+			if text, err = os.ReadFile(file); err == nil {
+				out, err = prompts.RenderText(spec.Name, string(text), data)
+			}
+		} else {
+			out, err = prompts.Render(spec.Name, crab, data)
+		}
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println(out)
+	},
+}
+
+func init() {
+	promptsListCmd.Flags().String("crab", "", "Resolve overrides for this crab")
+	promptsShowCmd.Flags().String("crab", "", "Resolve overrides for this crab")
+	promptsTestCmd.Flags().String("crab", "", "Resolve overrides for this crab")
+	promptsTestCmd.Flags().String("task", "", "Fill the variables from an existing task")
+	promptsTestCmd.Flags().String("file", "", "Render this template file instead of the installed one")
+	promptsTestCmd.Flags().StringArray("set", nil, "Set a variable as key=value (repeatable)")
+
+	promptsCmd.AddCommand(promptsListCmd)
+	promptsCmd.AddCommand(promptsShowCmd)
+	promptsCmd.AddCommand(promptsTestCmd)
+	rootCmd.AddCommand(promptsCmd)
+}
@@ -2,13 +2,16 @@ package cli
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
+	"os/signal"
 	"path/filepath"
 	"strconv"
 	"syscall"
 
+	"github.com/nathfavour/auracrab/pkg/biology"
 	"github.com/nathfavour/auracrab/pkg/config"
 	"github.com/nathfavour/auracrab/pkg/core"
 	"github.com/spf13/cobra"
@@ -67,8 +70,11 @@ var rootCmd = &cobra.Command{
 		if !isDaemon && !verbose {
 			// Re-exec as daemon
 			cmd := exec.Command(os.Args[0], "--daemon")
-			// Redirect stdout/stderr to a log file if not verbose
-			logFile, _ := os.OpenFile(filepath.Join(config.DataDir(), "auracrab.log"), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
+			// The daemon logs to its own rotated file; stdout and stderr
+			// only catch what escapes it, such as a panic.
+			logDir := filepath.Join(config.DataDir(), "logs")
+			_ = os.MkdirAll(logDir, 0700)
+			logFile, _ := os.OpenFile(filepath.Join(logDir, "daemon.out"), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
 			cmd.Stdout = logFile
 			cmd.Stderr = logFile
 			
@@ -82,19 +88,34 @@ var rootCmd = &cobra.Command{
 		}
 
 		// 3. Main process logic
+		var closeTelemetry func()
 		if verbose {
 			fmt.Println("🦀 Auracrab is coming alive (verbose mode)...")
+			closeTelemetry = setupTelemetry(os.Stdout)
+		} else {
+			closeTelemetry = setupTelemetry(nil)
 		}
+		defer closeTelemetry()
 
 		// Save PID
 		_ = os.WriteFile(pidFile, []byte(strconv.Itoa(os.Getpid())), 0644)
 		defer os.Remove(pidFile)
 
-		// Run the butler in continuous mode
-		ctx := context.Background()
+		// Run the butler in continuous mode until stopped; SIGTERM drains
+		// running work before exiting.
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
 		butler := core.GetButler()
 
 		if err := butler.Serve(ctx); err != nil {
+			// os.Exit skips deferred calls
+			_ = os.Remove(pidFile)
+			closeTelemetry()
+			var death *core.ApoptosisError
+			if errors.As(err, &death) {
+				fmt.Printf("🦀 Auracrab retired itself: %s\n", death.Reason)
+				os.Exit(biology.ExitApoptosis)
+			}
 			if verbose {
 				fmt.Printf("Error: %v\n", err)
 			}
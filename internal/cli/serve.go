@@ -0,0 +1,54 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/nathfavour/auracrab/pkg/biology"
+	"github.com/nathfavour/auracrab/pkg/config"
+	"github.com/nathfavour/auracrab/pkg/core"
+	"github.com/spf13/cobra"
+)
+
+var serveCmd = &cobra.Command{
+	Use:    "serve",
+	Short:  "Run a swarm worker replica in the foreground",
+	Hidden: true,
+	Run: func(cmd *cobra.Command, args []string) {
+		child, _ := cmd.Flags().GetBool("child")
+		name, _ := cmd.Flags().GetString("replica")
+		if !child || name == "" {
+			fmt.Println("Error: serve only runs worker replicas started by the swarm; use 'auracrab' to start the daemon")
+			os.Exit(1)
+		}
+
+		// The replica keeps its own tasks before the butler loads them.
+		config.SetReplica(name)
+		closeTelemetry := setupTelemetry(nil)
+		defer closeTelemetry()
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		if err := core.GetButler().Serve(ctx); err != nil {
+			// os.Exit skips deferred calls
+			closeTelemetry()
+			var death *core.ApoptosisError
+			if errors.As(err, &death) {
+				fmt.Printf("🦀 Replica %s retired itself: %s\n", name, death.Reason)
+				os.Exit(biology.ExitApoptosis)
+			}
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	serveCmd.Flags().Bool("child", false, "run as a replica of the swarm leader")
+	serveCmd.Flags().String("replica", "", "replica name; its state lives under replicas/<name>")
+	rootCmd.AddCommand(serveCmd)
+}
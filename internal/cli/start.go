@@ -2,6 +2,7 @@ package cli
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/signal"
@@ -9,6 +10,7 @@ import (
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/nathfavour/auracrab/internal/tui"
+	"github.com/nathfavour/auracrab/pkg/biology"
 	"github.com/nathfavour/auracrab/pkg/core"
 	"github.com/spf13/cobra"
 )
@@ -32,24 +34,41 @@ var StartCmd = &cobra.Command{
 			cancel()
 		}()
 
+		// The TUI owns the terminal, so the log only goes to its file.
+		closeTelemetry := setupTelemetry(nil)
+		defer closeTelemetry()
+
 		butler := core.GetButler()
+		p := tea.NewProgram(tui.InitialModel())
+		served := make(chan error, 1)
 		go func() {
-			if err := butler.Serve(ctx); err != nil {
+			err := butler.Serve(ctx)
+			var death *core.ApoptosisError
+			if errors.As(err, &death) {
+				// The daemon retired itself; close the TUI with it.
+				p.Quit()
+			} else if err != nil && ctx.Err() == nil && verbose {
 				// Don't print error if it's just context cancellation
-				if ctx.Err() == nil {
-					if verbose {
-						fmt.Printf("Butler service error: %v\n", err)
-					}
-				}
+				fmt.Printf("Butler service error: %v\n", err)
 			}
+			served <- err
 		}()
 
-		p := tea.NewProgram(tui.InitialModel())
 		if _, err := p.Run(); err != nil {
 			if verbose {
 				fmt.Printf("Alas, there's been an error: %v\n", err)
 			}
+			closeTelemetry()
 			os.Exit(1)
 		}
+
+		// Let running steps drain before exiting.
+		cancel()
+		var death *core.ApoptosisError
+		if errors.As(<-served, &death) {
+			fmt.Printf("🦀 Auracrab retired itself: %s\n", death.Reason)
+			closeTelemetry()
+			os.Exit(biology.ExitApoptosis)
+		}
 	},
 }
@@ -0,0 +1,90 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nathfavour/auracrab/pkg/config"
+	"github.com/nathfavour/auracrab/pkg/core"
+	"github.com/nathfavour/auracrab/pkg/spine"
+	"github.com/spf13/cobra"
+)
+
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show whether the daemon is running and how its spine cells are doing",
+	Run: func(cmd *cobra.Command, args []string) {
+		running := false
+		if pidData, err := os.ReadFile(config.PIDPath()); err == nil {
+			pid, _ := strconv.Atoi(strings.TrimSpace(string(pidData)))
+			if isProcessRunning(pid) {
+				running = true
+				fmt.Printf("🦀 Auracrab daemon is running (PID: %d)\n", pid)
+			}
+		}
+		if !running {
+			fmt.Println("🦀 Auracrab daemon is not running.")
+		}
+
+		stats, err := spine.ReadStats(core.CellStatsPath())
+		if err != nil {
+			if os.IsNotExist(err) {
+				fmt.Println("No cell stats recorded yet.")
+				return
+			}
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		if cells, _ := cmd.Flags().GetBool("cells"); !cells {
+			var last time.Time
+			failing := 0
+			for _, s := range stats {
+				if s.LastPulse.After(last) {
+					last = s.LastPulse
+				}
+				if !s.BackoffUntil.IsZero() {
+					failing++
+				}
+			}
+			ago := "never"
+			if !last.IsZero() {
+				ago = time.Since(last).Round(time.Second).String() + " ago"
+			}
+			fmt.Printf("Spine: %d cells, last pulse %s", len(stats), ago)
+			if failing > 0 {
+				fmt.Printf(", %d backing off after a panic", failing)
+			}
+			fmt.Println(" (use --cells for details)")
+			return
+		}
+
+		fmt.Printf("\n%-18s %-8s %-10s %-10s %8s %7s %6s %8s %8s\n", "CELL", "STATE", "LAST", "TOOK", "PULSES", "ERRORS", "SKIPS", "TIMEOUTS", "RESTARTS")
+		for _, s := range stats {
+			state := "idle"
+			switch {
+			case s.Running:
+				state = "running"
+			case !s.BackoffUntil.IsZero():
+				state = "backoff"
+			}
+			last := "never"
+			if !s.LastPulse.IsZero() {
+				last = time.Since(s.LastPulse).Round(time.Second).String() + " ago"
+			}
+			fmt.Printf("%-18s %-8s %-10s %-10s %8d %7d %6d %8d %8d\n", s.Name, state, last,
+				s.LastDuration.Round(time.Millisecond), s.Pulses, s.Errors, s.Skipped, s.Timeouts, s.Restarts)
+			if s.LastError != "" {
+				fmt.Printf("%18s ⚠️  %s\n", "", oneLine(s.LastError, 80))
+			}
+		}
+	},
+}
+
+func init() {
+	statusCmd.Flags().Bool("cells", false, "Show per-cell pulse stats")
+	rootCmd.AddCommand(statusCmd)
+}
@@ -0,0 +1,174 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nathfavour/auracrab/pkg/config"
+	"github.com/nathfavour/auracrab/pkg/immune"
+	"github.com/spf13/cobra"
+)
+
+var swarmCmd = &cobra.Command{
+	Use:   "swarm",
+	Short: "Inspect and scale the agent's swarm of nodes",
+}
+
+var swarmStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the swarm's nodes and worker replicas",
+	Run: func(cmd *cobra.Command, args []string) {
+		dir := immune.SwarmDir()
+		nodes := immune.ReadNodes(dir)
+		if len(nodes) == 0 {
+			fmt.Println("No swarm nodes registered. Enable cells.immune to join the swarm.")
+			return
+		}
+
+		fmt.Printf("%-28s %-8s %-8s %-9s %-7s %s\n", "NODE", "PID", "ROLE", "STATUS", "LEADER", "LAST PING")
+		for _, n := range nodes {
+			role := n.Role
+			if role == "" {
+				role = immune.RoleIngress
+			}
+			status := string(n.Status)
+			if time.Since(n.LastPing) > immune.NodeTimeout {
+				status = "dead"
+			}
+			leader := ""
+			if n.Leader {
+				leader = "yes"
+			}
+			fmt.Printf("%-28s %-8d %-8s %-9s %-7s %s ago\n", n.ID, n.PID, role, status, leader,
+				time.Since(n.LastPing).Round(time.Second))
+		}
+
+		if peers, err := immune.ReadMeshPeers(dir); err == nil && len(peers) > 0 {
+			fmt.Printf("\n%-28s %-16s %-22s %-9s %6s %s\n", "PEER", "MACHINE", "ADDRESS", "STATUS", "ENERGY", "SKILLS")
+			for _, p := range peers {
+				fmt.Printf("%-28s %-16s %-22s %-9s %5.0f%% %s\n", p.ID, p.Machine, p.Addr, p.Status, p.Energy*100, strings.Join(p.Skills, ","))
+			}
+		}
+
+		replicas, _ := immune.ReadReplicas(dir)
+		scale := "auto"
+		if n, ok := immune.ReadScale(dir); ok {
+			scale = strconv.Itoa(n)
+		}
+		policy := "cloning disabled"
+		if cfg, err := config.LoadConfig(); err == nil && cfg.Swarm.MaxReplicas > 0 {
+			policy = fmt.Sprintf("%d-%d replicas, %s cooldown", cfg.Swarm.MinReplicas, cfg.Swarm.MaxReplicas, cfg.Swarm.Cooldown)
+		}
+		fmt.Printf("\nReplicas: %d running, scale %s (%s)\n", len(replicas), scale, policy)
+		for _, r := range replicas {
+			state := "running"
+			if r.Stopping {
+				state = "stopping"
+			}
+			fmt.Printf("- %s (PID %d) %s for %s, log %s\n", r.Name, r.PID, state,
+				time.Since(r.StartedAt).Round(time.Second), r.Log)
+		}
+	},
+}
+
+var swarmScaleCmd = &cobra.Command{
+	Use:   "scale <replicas|auto>",
+	Short: "Pin the number of worker replicas, or return to automatic scaling",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		n := -1
+		if args[0] != "auto" {
+			var err error
+			n, err = strconv.Atoi(args[0])
+			if err != nil || n < 0 {
+				fmt.Printf("Error: replicas must be a non-negative number or 'auto', got %q\n", args[0])
+				os.Exit(1)
+			}
+		}
+		if err := immune.WriteScale(immune.SwarmDir(), n); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		if n < 0 {
+			fmt.Println("🦀 Swarm returned to automatic scaling.")
+			return
+		}
+
+		fmt.Printf("🦀 Swarm scaled to %d worker replicas; the leader applies it one replica per cooldown.\n", n)
+		if cfg, err := config.LoadConfig(); err == nil && (n > cfg.Swarm.MaxReplicas || n < cfg.Swarm.MinReplicas) {
+			fmt.Printf("Note: swarm.min_replicas/max_replicas (%d-%d) still bound the count.\n", cfg.Swarm.MinReplicas, cfg.Swarm.MaxReplicas)
+		}
+	},
+}
+
+var swarmInitCmd = &cobra.Command{
+	Use:   "init",
+	Short: "Create the team CA and this machine's certificate for the network swarm",
+	Long: `Creates a team certificate authority and a certificate for this machine,
+so that swarms on other machines can connect over mutual TLS. Run it once on
+one machine, then give every other machine a bundle from 'auracrab swarm issue'
+and install it there with 'auracrab swarm init --import <dir>'.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		dir := immune.TLSDir(immune.SwarmDir())
+
+		if bundle, _ := cmd.Flags().GetString("import"); bundle != "" {
+			if err := immune.ImportBundle(bundle, dir); err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Printf("🦀 Installed the swarm certificate from %s.\n", bundle)
+			return
+		}
+
+		name, _ := cmd.Flags().GetString("name")
+		if name == "" {
+			name, _ = os.Hostname()
+		}
+		team, _ := cmd.Flags().GetString("team")
+		created, err := immune.InitCA(dir, team)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		if created {
+			fmt.Printf("🦀 Created the %s team CA in %s. Keep %s private.\n", team, dir, immune.CAKeyFile)
+		}
+		if err := immune.IssueNode(dir, name, dir); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("🦀 This machine joins the swarm as '%s'. Set swarm.listen (e.g. \":%d\") and restart the daemon.\n", name, immune.DefaultMeshPort)
+	},
+}
+
+var swarmIssueCmd = &cobra.Command{
+	Use:   "issue <machine>",
+	Short: "Issue a certificate bundle for another machine from the team CA",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		out, _ := cmd.Flags().GetString("out")
+		if out == "" {
+			out = "swarm-" + args[0]
+		}
+		if err := immune.IssueNode(immune.TLSDir(immune.SwarmDir()), args[0], out); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("🦀 Wrote the bundle for '%s' to %s. Copy it over and run 'auracrab swarm init --import %s' there.\n", args[0], out, out)
+	},
+}
+
+func init() {
+	swarmInitCmd.Flags().String("name", "", "this machine's name in the swarm (default: hostname)")
+	swarmInitCmd.Flags().String("team", "auracrab", "team name for a new CA")
+	swarmInitCmd.Flags().String("import", "", "install a bundle made by 'swarm issue' instead")
+	swarmIssueCmd.Flags().StringP("out", "o", "", "bundle directory (default: swarm-<machine>)")
+	swarmCmd.AddCommand(swarmInitCmd)
+	swarmCmd.AddCommand(swarmIssueCmd)
+	swarmCmd.AddCommand(swarmStatusCmd)
+	swarmCmd.AddCommand(swarmScaleCmd)
+	rootCmd.AddCommand(swarmCmd)
+}
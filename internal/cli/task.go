@@ -0,0 +1,122 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/nathfavour/auracrab/pkg/core"
+	"github.com/spf13/cobra"
+)
+
+var taskCmd = &cobra.Command{
+	Use:   "task",
+	Short: "Inspect delegated tasks",
+}
+
+var taskTraceCmd = &cobra.Command{
+	Use:   "trace <task-id>",
+	Short: "Show a task's event timeline, or replay its state at an event",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		events, err := core.LoadTrace(args[0])
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		if len(events) == 0 {
+			fmt.Println("Trace is empty.")
+			return
+		}
+
+		if at, _ := cmd.Flags().GetInt("at"); at > 0 {
+			printSnapshot(core.Replay(events, at), at, len(events))
+			return
+		}
+
+		full, _ := cmd.Flags().GetBool("prompts")
+		start := events[0].At
+		fmt.Printf("🧵 Trace of %s (%d events, %s)\n\n", args[0], len(events), events[len(events)-1].At.Sub(start).Round(time.Millisecond))
+		for i, e := range events {
+			took := ""
+			if e.Duration > 0 {
+				took = " (" + e.Duration.Round(time.Millisecond).String() + ")"
+			}
+			fmt.Printf("%3d  +%-9s %-8s %s%s\n", i+1, e.At.Sub(start).Round(time.Millisecond), e.Kind, describeEvent(e), took)
+			if e.Error != "" {
+				fmt.Printf("%17s ⚠️  %s\n", "", e.Error)
+			}
+			if full && e.Kind == core.TracePrompt {
+				fmt.Printf("%17s ── prompt ──\n%s\n%17s ── response ──\n%s\n", "", e.Prompt, "", e.Response)
+			}
+		}
+	},
+}
+
+func describeEvent(e core.TraceEvent) string {
+	switch e.Kind {
+	case core.TraceCreated:
+		return oneLine(e.Detail, 80)
+	case core.TraceStatus:
+		if e.Detail != "" {
+			return e.Status + ": " + oneLine(e.Detail, 60)
+		}
+		return e.Status
+	case core.TracePlan:
+		desc := fmt.Sprintf("%d steps", len(e.Plan))
+		if e.Detail != "" {
+			desc += " from " + e.Detail
+		}
+		return desc
+	case core.TraceStep:
+		desc := e.Step + " " + e.Status
+		if e.Attempt > 1 {
+			desc += fmt.Sprintf(" (attempt %d)", e.Attempt)
+		}
+		if e.Detail != "" && e.Status != "running" {
+			desc += ": " + oneLine(e.Detail, 50)
+		}
+		return desc
+	case core.TracePrompt:
+		return fmt.Sprintf("%s/%s %d chars → %d chars", e.Provider, e.Intent, len(e.Prompt), len(e.Response))
+	case core.TraceRetry:
+		return fmt.Sprintf("attempt %d", e.Attempt)
+	case core.TraceSkill:
+		return e.Skill + " " + oneLine(e.Detail, 60)
+	case core.TraceInput:
+		return e.Step + " " + e.Status + ": " + oneLine(e.Detail, 60)
+	}
+	return e.Detail
+}
+
+func printSnapshot(s core.TraceSnapshot, at, total int) {
+	fmt.Printf("⏪ State after event %d/%d (%s)\n", at, total, s.At.Format(time.RFC3339))
+	fmt.Printf("Goal:     %s\nStatus:   %s\n", s.Goal, s.Status)
+	if s.Result != "" {
+		fmt.Printf("Result:   %s\n", oneLine(s.Result, 100))
+	}
+	fmt.Printf("Prompts:  %d (last provider: %s), retries: %d\n", s.Prompts, s.Provider, s.Retries)
+	for _, step := range s.Steps {
+		fmt.Printf("  [%-9s] %s: %s\n", step.Status, step.ID, step.Description)
+		if step.Result != "" {
+			fmt.Printf("              → %s\n", oneLine(step.Result, 80))
+		}
+	}
+}
+
+func oneLine(s string, max int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if len(s) > max {
+		s = s[:max-3] + "..."
+	}
+	return s
+}
+
+func init() {
+	taskTraceCmd.Flags().Int("at", 0, "Replay the task state after this event number")
+	taskTraceCmd.Flags().Bool("prompts", false, "Print full prompts and responses")
+
+	taskCmd.AddCommand(taskTraceCmd)
+	rootCmd.AddCommand(taskCmd)
+}
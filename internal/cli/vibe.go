@@ -193,7 +193,7 @@ var executeCmd = &cobra.Command{
 			if len(args) > 1 {
 				argData = json.RawMessage(args[1])
 			}
-			res, err := s.Execute(context.Background(), argData)
+			res, err := skills.Execute(context.Background(), s, argData)
 			if err != nil {
 				fmt.Printf(`{"content": "Error: %v", "status": "error"}`+"\n", err)
 				return
@@ -225,7 +225,7 @@ var executeCmd = &cobra.Command{
 			data, _ := json.Marshal(tasks)
 			fmt.Printf(`{"content": %q, "status": "success"}`+"\n", string(data))
 		case toolName == "auracrab_watch_health":
-			health := butler.WatchHealth()
+			health := butler.Health(cmd.Context()).String()
 			fmt.Printf(`{"content": %q, "status": "success"}`+"\n", health)
 		case toolName == "auracrab_register_crab":
 			var crab crabs.Crab
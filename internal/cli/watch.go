@@ -0,0 +1,177 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/nathfavour/auracrab/pkg/config"
+	"github.com/nathfavour/auracrab/pkg/core"
+	"github.com/spf13/cobra"
+)
+
+const watchRestartHint = "Restart the daemon (auracrab stop && auracrab start) to apply."
+
+var watchCmd = &cobra.Command{
+	Use:   "watch",
+	Short: "Manage rules that act on file changes",
+	Long: "Watch rules watch a directory tree and, once changes settle, start a task " +
+		"with the changed files in focus, apply a workflow or send a notification. " +
+		"Rules live under watch.rules in config.yaml and run in the daemon.",
+}
+
+var watchListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the watch rules",
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg, err := config.LoadConfig()
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		if len(cfg.Watch.Rules) == 0 {
+			fmt.Println("No watch rules. Add one with `auracrab watch add`.")
+			return
+		}
+		for _, r := range cfg.Watch.Rules {
+			fmt.Printf("- %s: %s\n", r.Name, r.Dir)
+			if len(r.Include) > 0 {
+				fmt.Printf("    include:  %s\n", strings.Join(r.Include, ", "))
+			}
+			if len(r.Exclude) > 0 {
+				fmt.Printf("    exclude:  %s\n", strings.Join(r.Exclude, ", "))
+			}
+			if len(r.Events) > 0 {
+				fmt.Printf("    events:   %s\n", strings.Join(r.Events, ", "))
+			}
+			if r.Debounce > 0 {
+				fmt.Printf("    debounce: %s\n", r.Debounce)
+			}
+			switch {
+			case r.Task != "":
+				fmt.Printf("    task:     %s\n", r.Task)
+			case r.Workflow != "":
+				fmt.Printf("    workflow: %s\n", r.Workflow)
+			case r.Notify != "":
+				to := r.Chat
+				if to == "" {
+					to = "owners"
+				}
+				fmt.Printf("    notify:   %s (to %s)\n", r.Notify, to)
+			}
+			if err := core.ValidateWatchRule(r); err != nil {
+				fmt.Printf("    ⚠️  %v\n", err)
+			}
+		}
+	},
+}
+
+var watchAddCmd = &cobra.Command{
+	Use:   "add <name>",
+	Short: "Add a watch rule",
+	Example: `  auracrab watch add tests --dir ~/src/app --include '*.go' --task 'Run the tests covering {{ join .Files ", " }}'
+  auracrab watch add docs --dir ./docs --include '*.md' --notify 'Docs changed: {{ .Files }}' --chat telegram:12345
+  auracrab watch add deps --dir . --include go.mod --workflow dep-bump`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		flags := cmd.Flags()
+		rule := config.WatchRule{Name: args[0]}
+		rule.Dir, _ = flags.GetString("dir")
+		rule.Include, _ = flags.GetStringSlice("include")
+		rule.Exclude, _ = flags.GetStringSlice("exclude")
+		rule.Events, _ = flags.GetStringSlice("events")
+		rule.Debounce, _ = flags.GetDuration("debounce")
+		rule.Task, _ = flags.GetString("task")
+		rule.Workflow, _ = flags.GetString("workflow")
+		rule.Notify, _ = flags.GetString("notify")
+		rule.Chat, _ = flags.GetString("chat")
+
+		// The daemon runs elsewhere, so relative directories are resolved now.
+		if !strings.HasPrefix(rule.Dir, "~") {
+			abs, err := filepath.Abs(rule.Dir)
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+			rule.Dir = abs
+		}
+		dir, err := core.WatchDir(rule)
+		if err == nil {
+			var info os.FileInfo
+			if info, err = os.Stat(dir); err == nil && !info.IsDir() {
+				err = fmt.Errorf("%s is not a directory", dir)
+			}
+		}
+		if err == nil {
+			err = core.ValidateWatchRule(rule)
+		}
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		cfg, err := config.LoadConfig()
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		for _, r := range cfg.Watch.Rules {
+			if r.Name == rule.Name {
+				fmt.Printf("Error: watch rule '%s' already exists; remove it first\n", rule.Name)
+				os.Exit(1)
+			}
+		}
+		if err := config.SaveWatchRules(append(cfg.Watch.Rules, rule)); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("✅ Watch rule '%s' added for %s. %s\n", rule.Name, dir, watchRestartHint)
+	},
+}
+
+var watchRmCmd = &cobra.Command{
+	Use:   "rm <name>",
+	Short: "Remove a watch rule",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg, err := config.LoadConfig()
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		kept := []config.WatchRule{}
+		for _, r := range cfg.Watch.Rules {
+			if r.Name != args[0] {
+				kept = append(kept, r)
+			}
+		}
+		if len(kept) == len(cfg.Watch.Rules) {
+			fmt.Printf("Error: watch rule '%s' not found\n", args[0])
+			os.Exit(1)
+		}
+		if err := config.SaveWatchRules(kept); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("🗑️  Watch rule '%s' removed. %s\n", args[0], watchRestartHint)
+	},
+}
+
+func init() {
+	f := watchAddCmd.Flags()
+	f.String("dir", ".", "Directory to watch, including new subdirectories")
+	f.StringSlice("include", nil, "Only act on files matching these globs (e.g. '*.go', 'src/**/*.ts')")
+	f.StringSlice("exclude", nil, "Ignore files and directories matching these globs (.git always is)")
+	f.StringSlice("events", nil, "Event kinds: create, write, remove, rename, chmod (default all but chmod)")
+	f.Duration("debounce", 0, "Quiet period before acting on the collected changes (default 2s)")
+	f.String("task", "", "Start a task from this prompt template over .Files, .Dir and .Rule")
+	f.String("workflow", "", "Apply this workflow, passing files, dir and rule inputs it declares")
+	f.String("notify", "", "Send this message template")
+	f.String("chat", "", "Send notifications to platform:chat_id instead of the bot owners")
+
+	watchCmd.AddCommand(watchListCmd)
+	watchCmd.AddCommand(watchAddCmd)
+	watchCmd.AddCommand(watchRmCmd)
+	rootCmd.AddCommand(watchCmd)
+}
@@ -0,0 +1,155 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/nathfavour/auracrab/pkg/core"
+	"github.com/nathfavour/auracrab/pkg/workflow"
+	"github.com/spf13/cobra"
+)
+
+var workflowCmd = &cobra.Command{
+	Use:   "workflow",
+	Short: "Run declarative multi-step workflows",
+}
+
+var workflowRunCmd = &cobra.Command{
+	Use:   "run <name|file>",
+	Short: "Run a workflow ad hoc",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		wf, err := workflow.Load(args[0])
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		inputs, err := workflowInputs(cmd, wf)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("🧩 Running workflow '%s' (%d steps)\n", wf.Name, len(wf.Steps))
+		outputs, err := workflow.Run(cmd.Context(), wf, inputs, core.GetButler(), func(e workflow.Event) {
+			switch e.Status {
+			case "running":
+				fmt.Printf("  ▶ %s\n", e.Step)
+			case "retry":
+				fmt.Printf("  ↻ %s attempt %d (%v)\n", e.Step, e.Attempt, e.Err)
+			case "completed":
+				fmt.Printf("  ✔ %s\n", e.Step)
+			case "failed":
+				fmt.Printf("  ✖ %s: %v\n", e.Step, e.Err)
+			case "skipped":
+				fmt.Printf("  ⏭ %s (dependency failed)\n", e.Step)
+			}
+		})
+
+		for _, s := range wf.Steps {
+			if out, ok := outputs[s.ID]; ok {
+				fmt.Printf("\n== %s ==\n%s\n", s.Title(), out)
+			}
+		}
+		if err != nil {
+			fmt.Printf("\nError: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var workflowListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List stored workflows",
+	Run: func(cmd *cobra.Command, args []string) {
+		names := workflow.List()
+		if len(names) == 0 {
+			fmt.Println("No workflows stored. Use 'auracrab mission apply -f <file>' to add one.")
+			return
+		}
+		for _, n := range names {
+			if wf, err := workflow.Load(n); err == nil {
+				fmt.Printf("- %s: %s (%d steps)\n", n, wf.Description, len(wf.Steps))
+			} else {
+				fmt.Printf("- %s: %v\n", n, err)
+			}
+		}
+	},
+}
+
+var missionApplyCmd = &cobra.Command{
+	Use:   "apply -f <workflow.yaml>",
+	Short: "Validate a workflow and load it as a mission with prebuilt sub-tasks",
+	Run: func(cmd *cobra.Command, args []string) {
+		file, _ := cmd.Flags().GetString("file")
+		if file == "" {
+			fmt.Println("Error: -f <workflow.yaml> is required")
+			os.Exit(1)
+		}
+		data, err := os.ReadFile(file)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		wf, err := workflow.Parse(data)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		inputs, err := workflowInputs(cmd, wf)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		if dry, _ := cmd.Flags().GetBool("dry-run"); dry {
+			fmt.Printf("✅ Workflow '%s' is valid (%d steps).\n", wf.Name, len(wf.Steps))
+			return
+		}
+
+		force, _ := cmd.Flags().GetBool("force")
+		path, err := wf.Save(force)
+		if errors.Is(err, workflow.ErrStored) {
+			fmt.Printf("Error: %v; use --force to replace it\n", err)
+			os.Exit(1)
+		}
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		m, err := wf.ToMission(core.GetButler().Missions, inputs)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Mission created from workflow '%s': %s (%d sub-tasks)\nWorkflow stored at %s\n", wf.Name, m.ID, len(m.Tasks), path)
+	},
+}
+
+// workflowInputs parses repeated --input k=v flags and applies defaults.
+func workflowInputs(cmd *cobra.Command, wf *workflow.Workflow) (map[string]string, error) {
+	raw, _ := cmd.Flags().GetStringArray("input")
+	given := map[string]string{}
+	for _, kv := range raw {
+		k, v, ok := strings.Cut(kv, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid --input %q, expected key=value", kv)
+		}
+		given[k] = v
+	}
+	return wf.ResolveInputs(given)
+}
+
+func init() {
+	workflowRunCmd.Flags().StringArray("input", nil, "Workflow input as key=value (repeatable)")
+	missionApplyCmd.Flags().StringP("file", "f", "", "Workflow YAML file")
+	missionApplyCmd.Flags().StringArray("input", nil, "Workflow input as key=value (repeatable)")
+	missionApplyCmd.Flags().Bool("dry-run", false, "Only validate the workflow")
+	missionApplyCmd.Flags().Bool("force", false, "Replace a different stored workflow of the same name")
+
+	workflowCmd.AddCommand(workflowRunCmd)
+	workflowCmd.AddCommand(workflowListCmd)
+	missionCmd.AddCommand(missionApplyCmd)
+	rootCmd.AddCommand(workflowCmd)
+}
@@ -7,8 +7,16 @@ import (
 	"time"
 
 	"github.com/nathfavour/auracrab/pkg/cortensor"
+	"github.com/nathfavour/auracrab/pkg/logging"
+	"github.com/nathfavour/auracrab/pkg/metrics"
+	"github.com/nathfavour/auracrab/pkg/tracing"
 )
 
+var logger = logging.For("provider")
+
+var fallbacks = metrics.NewCounter("auracrab_provider_fallbacks_total",
+	"Queries a provider handed to its fallback provider.", "from", "to")
+
 // CortensorProvider implements the InferenceProvider interface for the Cortensor Protocol
 type CortensorProvider struct {
 	client             *cortensor.Client
@@ -32,12 +40,23 @@ func (p *CortensorProvider) Name() string {
 	return "cortensor"
 }
 
-func (p *CortensorProvider) GetCompletion(ctx context.Context, req CompletionRequest) (CompletionResponse, error) {
+func (p *CortensorProvider) GetCompletion(ctx context.Context, req CompletionRequest) (resp CompletionResponse, err error) {
+	ctx, span := tracing.Start(ctx, "GetCompletion", "provider", p.Name(), "intent", req.Intent, "prompt", req.Content)
+	defer func() {
+		span.SetAttrs("completion", resp.Content, "miner_id", resp.MinerID)
+		span.RecordError(err)
+		span.End()
+	}()
+	return p.getCompletion(ctx, req)
+}
+
+func (p *CortensorProvider) getCompletion(ctx context.Context, req CompletionRequest) (CompletionResponse, error) {
 	threshold := p.consensusThreshold
 	if threshold <= 1 {
 		resp, err := p.client.Query(ctx, req.Content, req.Intent)
 		if err != nil {
-			fmt.Printf("CortensorProvider: Router error, falling back to %s: %v\n", p.fallback.Name(), err)
+			logger.WarnContext(ctx, "router error, falling back", "fallback", p.fallback.Name(), "err", err)
+			fallbacks.Inc(p.Name(), p.fallback.Name())
 			return p.fallback.GetCompletion(ctx, req)
 		}
 
@@ -58,7 +77,13 @@ func (p *CortensorProvider) GetCompletion(ctx context.Context, req CompletionReq
 
 	for i := 0; i < threshold; i++ {
 		go func() {
+			_, span := tracing.Start(ctx, "cortensor.Query", "provider", p.Name(), "replica", i+1, "of", threshold)
 			r, err := p.client.Query(ctx, req.Content, req.Intent)
+			if err == nil {
+				span.SetAttrs("miner_id", r.MinerID)
+			}
+			span.RecordError(err)
+			span.End()
 			resChan <- result{r, err}
 		}()
 	}
@@ -72,6 +97,8 @@ func (p *CortensorProvider) GetCompletion(ctx context.Context, req CompletionReq
 	}
 
 	if len(responses) == 0 {
+		logger.WarnContext(ctx, "no miner answered, falling back", "fallback", p.fallback.Name(), "asked", threshold)
+		fallbacks.Inc(p.Name(), p.fallback.Name())
 		return p.fallback.GetCompletion(ctx, req)
 	}
 
@@ -101,6 +128,11 @@ func (p *CortensorProvider) majorityVote(resps []*cortensor.RouterCompletionResp
 	}
 }
 
+// Ping checks that the router is reachable.
+func (p *CortensorProvider) Ping(ctx context.Context) error {
+	return p.client.Ping(ctx)
+}
+
 func (p *CortensorProvider) VerifyProof(ctx context.Context, proof string) (bool, error) {
 	if proof == "" {
 		return false, nil
@@ -116,7 +148,7 @@ func (p *CortensorProvider) ManageSession(ctx context.Context) error {
 		p.mu.Unlock()
 		// Only use it if it's not expired
 		if time.Now().Before(meta.Expiry) {
-			fmt.Printf("CortensorProvider: Loaded existing session for node %s\n", meta.NodeID)
+			logger.Info("loaded existing session", "node", meta.NodeID)
 			return nil
 		}
 	}
@@ -128,7 +160,7 @@ func (p *CortensorProvider) ManageSession(ctx context.Context) error {
 	p.mu.Lock()
 	p.activeNode = meta.NodeID
 	p.mu.Unlock()
-	fmt.Printf("CortensorProvider: Session active on node %s (Balance: %.2f $COR)\n", meta.NodeID, meta.CORBalance)
+	logger.Info("session active", "node", meta.NodeID, "balance_cor", meta.CORBalance)
 	return nil
 }
 
@@ -2,6 +2,8 @@ package provider
 
 import (
 	"context"
+
+	"github.com/nathfavour/auracrab/pkg/schema"
 )
 
 // CompletionRequest defines the input for an inference query
@@ -16,6 +18,9 @@ type CompletionResponse struct {
 	Reasoning string `json:"reasoning,omitempty"`
 	Proof     string `json:"proof,omitempty"`    // For Cortensor cryptographic proofs
 	MinerID   string `json:"miner_id,omitempty"` // For Cortensor miner attribution
+
+	// Packet is the parsed reply when a structured prompt format was used.
+	Packet *schema.ResponsePacket `json:"packet,omitempty"`
 }
 
 // InferenceProvider is the core interface for interacting with LLM backends
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/nathfavour/auracrab/pkg/tracing"
 	"github.com/nathfavour/auracrab/pkg/vibe"
 )
 
@@ -23,7 +24,11 @@ func (p *VibeProvider) Name() string {
 }
 
 func (p *VibeProvider) GetCompletion(ctx context.Context, req CompletionRequest) (CompletionResponse, error) {
+	_, span := tracing.Start(ctx, "GetCompletion", "provider", p.Name(), "intent", req.Intent, "prompt", req.Content)
+	defer span.End()
 	content, err := p.client.Query(req.Content, req.Intent)
+	span.SetAttrs("completion", content)
+	span.RecordError(err)
 	if err != nil {
 		return CompletionResponse{}, fmt.Errorf("vibe provider error: %w", err)
 	}
@@ -33,6 +38,11 @@ func (p *VibeProvider) GetCompletion(ctx context.Context, req CompletionRequest)
 	}, nil
 }
 
+// Ping checks that the vibeauracle socket answers.
+func (p *VibeProvider) Ping(ctx context.Context) error {
+	return p.client.Ping()
+}
+
 func (p *VibeProvider) VerifyProof(ctx context.Context, proof string) (bool, error) {
 	// Local Vibe provider doesn't use cryptographic proofs.
 	return true, nil
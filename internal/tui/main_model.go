@@ -84,7 +84,7 @@ var (
 			MarginLeft(2)
 
 	allCommands = []string{
-		"/config", "/setup", "/bot", "/shot", "/exit", "/quit", "/help", "/version", "/update", "/clear", "/status", "/restart",
+		"/config", "/setup", "/bot", "/shot", "/exit", "/quit", "/help", "/version", "/update", "/clear", "/status", "/restart", "/trace", "/resume",
 	}
 
 	subCommands = map[string][]string{
@@ -123,6 +123,11 @@ type Model struct {
 	currentStep    int
 	configValues   map[string]string
 	configuringFor string
+	// Trace replay fields
+	isTracing   bool
+	traceTask   string
+	traceEvents []core.TraceEvent
+	tracePos    int
 }
 
 type configStep struct {
@@ -161,7 +166,7 @@ func InitialModel() Model {
 	return Model{
 		tasks:          butler.ListTasks(),
 		statusMsg:      butler.GetStatus(),
-		healthMsg:      butler.WatchHealth(),
+		healthMsg:      butler.LastHealth().Summary(),
 		skillsList:     skillNames,
 		input:          ti,
 		viewport:       vp,
@@ -260,7 +265,7 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		butler := core.GetButler()
 		m.tasks = butler.ListTasks()
 		m.statusMsg = butler.GetStatus()
-		m.healthMsg = butler.WatchHealth()
+		m.healthMsg = butler.LastHealth().Summary()
 
 		var skillNames []string
 		v := vault.GetVault()
@@ -287,6 +292,11 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, tick()
 
 	case tea.KeyMsg:
+		if m.isTracing {
+			if handled, next := m.handleTraceKey(msg.String()); handled {
+				return next, nil
+			}
+		}
 		switch msg.String() {
 		case "ctrl+c":
 			return m, tea.Quit
@@ -487,11 +497,30 @@ func (m Model) handleCommand(input string) (tea.Model, tea.Cmd) {
 	case "/exit", "/quit":
 		return m, tea.Quit
 	case "/help":
-		m.lastResponse = "Commands: /shot, /config, /setup, /version, /update, /clear, /status, /exit"
+		m.lastResponse = "Commands: /shot, /config, /setup, /version, /update, /clear, /status, /trace [task-id], /resume <task-id>, /exit"
 	case "/clear":
 		m.lastResponse = ""
 	case "/status":
-		m.lastResponse = core.GetButler().GetStatus()
+		butler := core.GetButler()
+		m.lastResponse = butler.GetStatus() + "\n\n" + butler.LastHealth().String()
+	case "/trace":
+		id := ""
+		if len(parts) > 1 {
+			id = parts[1]
+		} else if m.cursor < len(m.tasks) {
+			id = m.tasks[m.cursor].ID
+		}
+		return m.startTrace(id)
+	case "/resume":
+		if len(parts) < 2 {
+			m.lastResponse = "Usage: /resume <task-id>"
+			return m, nil
+		}
+		if err := core.GetButler().ResumeTask(parts[1]); err != nil {
+			m.lastResponse = "Error: " + err.Error()
+		} else {
+			m.lastResponse = "Resumed " + parts[1]
+		}
 	default:
 		m.lastResponse = "Unknown command: " + cmd
 	}
@@ -715,6 +744,8 @@ func (m Model) View() string {
 				statusIcon = "✅"
 			} else if task.Status == core.TaskStatusFailed {
 				statusIcon = "❌"
+			} else if task.Status == core.TaskStatusPaused {
+				statusIcon = "⏸️"
 			}
 
 			content := task.Content
@@ -737,7 +768,7 @@ func (m Model) View() string {
 	sidebar.WriteString(styleSectionTitle.Render("SYSTEM VIBES") + "\n")
 
 	healthStyle := styleHealthOk
-	if strings.Contains(strings.ToLower(m.healthMsg), "warning") || strings.Contains(strings.ToLower(m.healthMsg), "anomaly") {
+	if strings.HasPrefix(m.healthMsg, "System Health: degraded") || strings.HasPrefix(m.healthMsg, "System Health: down") {
 		healthStyle = styleHealthWarn
 	}
 
@@ -760,8 +791,13 @@ func (m Model) View() string {
 	)
 	view.WriteString(mainContent)
 
-	// Logs Section (for selected task)
-	if len(m.tasks) > 0 && m.cursor < len(m.tasks) {
+	// Logs Section (for selected task), or the replayed trace
+	if m.isTracing {
+		view.WriteString("\n" + styleSectionTitle.Render(fmt.Sprintf("TRACE REPLAY: %s (%d/%d)", m.traceTask, m.tracePos, len(m.traceEvents))) + "\n")
+		m.viewport.SetContent(m.renderTrace())
+		m.viewport.GotoTop()
+		view.WriteString(m.viewport.View())
+	} else if len(m.tasks) > 0 && m.cursor < len(m.tasks) {
 		selectedTask := m.tasks[m.cursor]
 		view.WriteString("\n" + styleSectionTitle.Render("TASK LOGS: "+selectedTask.ID) + "\n")
 
@@ -789,6 +825,8 @@ func (m Model) View() string {
 
 	if m.isConfiguring {
 		view.WriteString(styleFooter.Render("\n[Enter] Confirm Step • [Ctrl+C] Cancel Setup"))
+	} else if m.isTracing {
+		view.WriteString(styleFooter.Render("\n[←/→] Step Event • [Home/End] Jump • [Esc] Close Replay"))
 	} else {
 		view.WriteString(styleFooter.Render("\n[↑/↓] Navigate • [Enter] Submit • [/shot] Screenshot • [/config] Config • [/setup] Setup • [Ctrl+C] Quit"))
 	}
@@ -0,0 +1,91 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/nathfavour/auracrab/pkg/core"
+)
+
+// startTrace loads a task's event log and opens the replay at its end.
+func (m Model) startTrace(taskID string) (tea.Model, tea.Cmd) {
+	if taskID == "" {
+		m.lastResponse = "Usage: /trace <task-id> (or select a task)"
+		return m, nil
+	}
+	events, err := core.LoadTrace(taskID)
+	if err != nil {
+		m.lastResponse = err.Error()
+		return m, nil
+	}
+	m.isTracing = true
+	m.traceTask = taskID
+	m.traceEvents = events
+	m.tracePos = len(events)
+	m.lastResponse = fmt.Sprintf("Replaying %d events of %s", len(events), taskID)
+	return m, nil
+}
+
+// handleTraceKey moves through the replay. It reports whether the key was
+// consumed.
+func (m Model) handleTraceKey(key string) (bool, Model) {
+	switch key {
+	case "left":
+		if m.tracePos > 1 {
+			m.tracePos--
+		}
+	case "right":
+		if m.tracePos < len(m.traceEvents) {
+			m.tracePos++
+		}
+	case "home":
+		m.tracePos = 1
+	case "end":
+		m.tracePos = len(m.traceEvents)
+	case "esc":
+		m.isTracing = false
+		m.traceEvents = nil
+		m.lastResponse = ""
+	default:
+		return false, m
+	}
+	return true, m
+}
+
+// renderTrace shows the task state as of the current replay position.
+func (m Model) renderTrace() string {
+	if len(m.traceEvents) == 0 {
+		return styleFooter.Render("  Trace is empty.")
+	}
+	s := core.Replay(m.traceEvents, m.tracePos)
+	e := m.traceEvents[m.tracePos-1]
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s  +%s  %s %s\n", s.At.Format("15:04:05"), e.At.Sub(m.traceEvents[0].At).Round(time.Millisecond), e.Kind, e.Status)
+	if e.Error != "" {
+		fmt.Fprintf(&b, "⚠️  %s\n", e.Error)
+	}
+	fmt.Fprintf(&b, "Status: %s • prompts: %d • retries: %d\n", s.Status, s.Prompts, s.Retries)
+	for _, step := range s.Steps {
+		icon := "⏳"
+		switch step.Status {
+		case "running":
+			icon = "▶"
+		case "completed":
+			icon = "✅"
+		case "failed":
+			icon = "❌"
+		case "skipped":
+			icon = "⏭"
+		case "waiting":
+			icon = "🙋"
+		}
+		fmt.Fprintf(&b, "%s %s\n", icon, step.Description)
+	}
+	if s.Result != "" {
+		fmt.Fprintf(&b, "\nResult: %s", s.Result)
+	}
+	return b.String()
+}
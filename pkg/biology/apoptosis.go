@@ -0,0 +1,78 @@
+package biology
+
+import (
+	"sync"
+	"time"
+
+	"github.com/nathfavour/auracrab/pkg/logging"
+)
+
+var logger = logging.For("biology")
+
+// ApoptosisWindow is how long energy must stay below ApoptosisTreshold
+// before the process retires, so a short spike cannot kill it.
+const ApoptosisWindow = time.Minute
+
+// ExitApoptosis is the exit code of a daemon that retired itself, distinct
+// from crashes so a supervisor knows to restart it (EX_TEMPFAIL).
+const ExitApoptosis = 75
+
+// starvation tracks how long energy has been critically low.
+type starvation struct {
+	mu    sync.Mutex
+	since time.Time
+}
+
+var starving starvation
+
+// observe records a reading and reports whether energy has been below the
+// threshold for the whole window.
+func (s *starvation) observe(level float64, now time.Time, window time.Duration) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if level >= ApoptosisTreshold {
+		s.since = time.Time{}
+		return false
+	}
+	if s.since.IsZero() {
+		s.since = now
+	}
+	return now.Sub(s.since) >= window
+}
+
+// ShouldApoptose checks if the process is "diseased": resource-starved for
+// longer than ApoptosisWindow.
+func ShouldApoptose() bool {
+	e, err := CheckThermodynamics()
+	if err != nil {
+		return false
+	}
+	return starving.observe(e.EnergyLevel, e.SampledAt, ApoptosisWindow)
+}
+
+var (
+	apoptosisOnce sync.Once
+	dying         = make(chan struct{})
+	deathReason   string
+)
+
+// Apoptosis asks the process to retire. It only signals Dying; the daemon
+// drains its work, checkpoints and exits with ExitApoptosis.
+func Apoptosis(reason string) {
+	apoptosisOnce.Do(func() {
+		logger.Warn("apoptosis, draining work before retiring", "reason", reason)
+		deathReason = reason
+		close(dying)
+	})
+}
+
+// Dying is closed once Apoptosis has been triggered.
+func Dying() <-chan struct{} {
+	return dying
+}
+
+// DeathReason is the reason given to Apoptosis, valid once Dying is closed.
+func DeathReason() string {
+	<-dying
+	return deathReason
+}
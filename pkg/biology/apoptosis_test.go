@@ -0,0 +1,27 @@
+package biology
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStarvationNeedsTheWholeWindow(t *testing.T) {
+	var s starvation
+	start := time.Now()
+	window := time.Minute
+
+	if s.observe(0.01, start, window) {
+		t.Fatal("a single low sample triggered apoptosis")
+	}
+	if s.observe(0.01, start.Add(30*time.Second), window) {
+		t.Fatal("triggered before the window elapsed")
+	}
+	// A recovery resets the window.
+	s.observe(0.5, start.Add(40*time.Second), window)
+	if s.observe(0.01, start.Add(70*time.Second), window) {
+		t.Fatal("the window did not restart after recovery")
+	}
+	if !s.observe(0.02, start.Add(130*time.Second), window) {
+		t.Fatal("did not trigger after a full window of starvation")
+	}
+}
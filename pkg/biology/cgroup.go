@@ -0,0 +1,84 @@
+package biology
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Paths of the cgroup v2 hierarchy, variables so tests can fake them.
+var (
+	cgroupRoot     = "/sys/fs/cgroup"
+	procSelfCgroup = "/proc/self/cgroup"
+)
+
+// cgroupStats is a reading of the process's cgroup v2 controllers.
+type cgroupStats struct {
+	CPULimit    float64 // Cores allowed by cpu.max, 0 when unlimited
+	CPUUsageUS  uint64  // usage_usec from cpu.stat
+	MemoryLimit uint64  // memory.max in bytes, 0 when unlimited
+	MemoryUsed  uint64  // memory.current in bytes
+}
+
+// cgroupDir finds the directory of the process's own cgroup, falling back
+// to the hierarchy root, which is the container's own cgroup under a
+// cgroup namespace. It reports false on hosts without cgroup v2.
+func cgroupDir() (string, bool) {
+	if _, err := os.Stat(filepath.Join(cgroupRoot, "cgroup.controllers")); err != nil {
+		return "", false
+	}
+	f, err := os.Open(procSelfCgroup)
+	if err != nil {
+		return cgroupRoot, true
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// The v2 entry reads "0::/path".
+		if rel, ok := strings.CutPrefix(scanner.Text(), "0::"); ok {
+			dir := filepath.Join(cgroupRoot, rel)
+			if _, err := os.Stat(filepath.Join(dir, "cpu.stat")); err == nil {
+				return dir, true
+			}
+		}
+	}
+	return cgroupRoot, true
+}
+
+// readCgroup reads the limits and usage of the process's cgroup.
+func readCgroup() (cgroupStats, bool) {
+	dir, ok := cgroupDir()
+	if !ok {
+		return cgroupStats{}, false
+	}
+
+	var s cgroupStats
+	if fields := strings.Fields(readCgroupFile(dir, "cpu.max")); len(fields) == 2 && fields[0] != "max" {
+		quota, err1 := strconv.ParseFloat(fields[0], 64)
+		period, err2 := strconv.ParseFloat(fields[1], 64)
+		if err1 == nil && err2 == nil && period > 0 {
+			s.CPULimit = quota / period
+		}
+	}
+	for _, line := range strings.Split(readCgroupFile(dir, "cpu.stat"), "\n") {
+		if v, ok := strings.CutPrefix(line, "usage_usec "); ok {
+			s.CPUUsageUS, _ = strconv.ParseUint(v, 10, 64)
+		}
+	}
+	if v := readCgroupFile(dir, "memory.max"); v != "" && v != "max" {
+		s.MemoryLimit, _ = strconv.ParseUint(v, 10, 64)
+	}
+	s.MemoryUsed, _ = strconv.ParseUint(readCgroupFile(dir, "memory.current"), 10, 64)
+	return s, true
+}
+
+func readCgroupFile(dir, name string) string {
+	data, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
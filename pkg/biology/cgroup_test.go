@@ -0,0 +1,70 @@
+package biology
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func fakeCgroup(t *testing.T, files map[string]string) {
+	t.Helper()
+	root := t.TempDir()
+	dir := filepath.Join(root, "app.slice")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	_ = os.WriteFile(filepath.Join(root, "cgroup.controllers"), []byte("cpu memory"), 0644)
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	self := filepath.Join(root, "self_cgroup")
+	_ = os.WriteFile(self, []byte("0::/app.slice\n"), 0644)
+
+	oldRoot, oldSelf := cgroupRoot, procSelfCgroup
+	cgroupRoot, procSelfCgroup = root, self
+	t.Cleanup(func() { cgroupRoot, procSelfCgroup = oldRoot, oldSelf })
+}
+
+func TestReadCgroupLimits(t *testing.T) {
+	fakeCgroup(t, map[string]string{
+		"cpu.max":        "150000 100000\n",
+		"cpu.stat":       "usage_usec 4200\nuser_usec 4000\nsystem_usec 200\n",
+		"memory.max":     "536870912\n",
+		"memory.current": "134217728\n",
+	})
+
+	cg, ok := readCgroup()
+	if !ok {
+		t.Fatal("cgroup v2 not detected")
+	}
+	if cg.CPULimit != 1.5 || cg.CPUUsageUS != 4200 || cg.MemoryLimit != 512<<20 || cg.MemoryUsed != 128<<20 {
+		t.Fatalf("unexpected reading: %+v", cg)
+	}
+}
+
+func TestReadCgroupUnlimited(t *testing.T) {
+	fakeCgroup(t, map[string]string{
+		"cpu.max":    "max 100000\n",
+		"cpu.stat":   "usage_usec 1\n",
+		"memory.max": "max\n",
+	})
+
+	cg, ok := readCgroup()
+	if !ok || cg.CPULimit != 0 || cg.MemoryLimit != 0 {
+		t.Fatalf("unexpected reading: %+v (ok=%v)", cg, ok)
+	}
+}
+
+func TestCurrentDoesNotBlock(t *testing.T) {
+	Current() // start the sampler
+	start := time.Now()
+	for i := 0; i < 100; i++ {
+		Current()
+	}
+	if d := time.Since(start); d > 100*time.Millisecond {
+		t.Fatalf("100 reads took %s", d)
+	}
+}
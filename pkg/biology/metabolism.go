@@ -37,12 +37,34 @@ func GetMetabolism() *Metabolism {
 	return metabolismInstance
 }
 
+// String names the kind of action that costs rate.
+func (rate MetabolicRate) String() string {
+	switch rate {
+	case CostAPIQuery:
+		return "api_query"
+	case CostDiskWrite:
+		return "disk_write"
+	case CostComputeLow:
+		return "compute_low"
+	case CostComputeHigh:
+		return "compute_high"
+	}
+	return "other"
+}
+
 // Burn consumes energy for an action.
 func (m *Metabolism) Burn(rate MetabolicRate) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	m.TotalBurned += float64(rate)
 	m.LastActivity = time.Now()
+	burns.Inc(rate.String())
+}
+
+func (m *Metabolism) lastActivity() time.Time {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.LastActivity
 }
 
 // GetStats returns the metabolic summary.
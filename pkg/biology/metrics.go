@@ -0,0 +1,42 @@
+package biology
+
+import (
+	"time"
+
+	"github.com/nathfavour/auracrab/pkg/metrics"
+)
+
+var burns = metrics.NewCounter("auracrab_metabolism_burns_total",
+	"Actions that burned energy, by kind of action.", "action")
+
+func init() {
+	metrics.NewGaugeFunc("auracrab_energy_level",
+		"Energy level from 0 (exhausted) to 1 (full health).", nil,
+		func(emit metrics.Emit) { emit(Current().EnergyLevel) })
+	metrics.NewGaugeFunc("auracrab_cpu_usage_percent",
+		"CPU usage, of the cgroup limit when one is set.", nil,
+		func(emit metrics.Emit) { emit(Current().CPUUsage) })
+	metrics.NewGaugeFunc("auracrab_memory_usage_percent",
+		"Memory usage, of the cgroup limit when one is set.", nil,
+		func(emit metrics.Emit) { emit(Current().MemoryUsage) })
+
+	metrics.NewCounterFunc("auracrab_metabolism_energy_burned_total",
+		"Energy burned by actions since the daemon started.", nil,
+		func(emit metrics.Emit) {
+			total, _ := GetMetabolism().GetStats()
+			emit(total)
+		})
+	metrics.NewGaugeFunc("auracrab_metabolism_uptime_seconds",
+		"Time since the metabolism started.", nil,
+		func(emit metrics.Emit) {
+			_, uptime := GetMetabolism().GetStats()
+			emit(uptime.Seconds())
+		})
+	metrics.NewGaugeFunc("auracrab_metabolism_last_activity_timestamp_seconds",
+		"Unix time of the last action that burned energy.", nil,
+		func(emit metrics.Emit) {
+			if last := GetMetabolism().lastActivity(); !last.IsZero() {
+				emit(float64(last.UnixNano()) / float64(time.Second))
+			}
+		})
+}
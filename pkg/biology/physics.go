@@ -1,20 +1,23 @@
 package biology
 
 import (
-	"fmt"
 	"os"
 	"time"
-
-	"github.com/shirou/gopsutil/cpu"
-	"github.com/shirou/gopsutil/mem"
-	"github.com/shirou/gopsutil/process"
 )
 
 // Energy represents the available resources for the agent system.
 type Energy struct {
-	CPUUsage    float64 // Percentage
-	MemoryUsage float64 // Percentage
+	CPUUsage    float64 // Percentage, of the cgroup CPU limit when one is set
+	MemoryUsage float64 // Percentage, of the cgroup memory limit when one is set
 	EnergyLevel float64 // 0.0 to 1.0 (1.0 is full health)
+
+	Host    Usage // The whole machine
+	Process Usage // This process, relative to the capacity available to it
+
+	Limited     bool    // Running under cgroup CPU or memory limits
+	CPULimit    float64 // Cores allowed, 0 when unlimited
+	MemoryLimit uint64  // Bytes allowed, 0 when unlimited
+	SampledAt   time.Time
 }
 
 const (
@@ -23,32 +26,13 @@ const (
 	ApoptosisTreshold = 0.05 // Level at which the process should self-terminate
 )
 
-// CheckThermodynamics evaluates the system's energy state.
+// CheckThermodynamics returns the latest sampled energy state without
+// blocking, or an error when the system could not be sampled yet.
 func CheckThermodynamics() (Energy, error) {
-	c, err := cpu.Percent(time.Second, false)
-	if err != nil {
-		return Energy{}, err
-	}
-
-	v, err := mem.VirtualMemory()
-	if err != nil {
-		return Energy{}, err
-	}
-
-	cpuUsed := c[0]
-	memUsed := v.UsedPercent
-
-	// Simple heuristic: Energy decreases as usage increases
-	cpuFactor := (100.0 - cpuUsed) / 100.0
-	memFactor := (100.0 - memUsed) / 100.0
-	
-	level := (cpuFactor + memFactor) / 2.0
-
-	return Energy{
-		CPUUsage:    cpuUsed,
-		MemoryUsage: memUsed,
-		EnergyLevel: level,
-	}, nil
+	s := getSampler()
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.current, s.err
 }
 
 // CanClone checks if the system has enough energy to spawn a replica.
@@ -61,71 +45,17 @@ func CanClone() bool {
 	return e.CPUUsage < MaxCPUTreshold && e.MemoryUsage < MaxMemoryTreshold
 }
 
-// ShouldApoptose checks if the process is "diseased" (corrupted or resource-starved).
-func ShouldApoptose() bool {
-	e, err := CheckThermodynamics()
-	if err != nil {
-		return false
-	}
-
-	// If energy is critically low for too long, apoptosis is triggered.
-	if e.EnergyLevel < ApoptosisTreshold {
-		return true
-	}
-
-	return false
-}
-
-// Apoptosis gracefully or forcibly retires the process.
-func Apoptosis(reason string) {
-	fmt.Printf("APOPTOSIS: %s. Releasing resources and exiting.\n", reason)
-	// Log the death
-	// In a real system, we might want to notify the swarm before dying.
-	os.Exit(1)
-}
-
 // DNA returns the current process's executable path, representing its "genetic code".
 func DNA() (string, error) {
 	return os.Executable()
 }
 
-// Clone creates a new instance of the same binary.
-func Clone() error {
-	if !CanClone() {
-		return fmt.Errorf("thermodynamic failure: insufficient energy to clone")
-	}
-
-	exe, err := DNA()
-	if err != nil {
-		return err
-	}
-
-	// Spawn a new process with same DNA
-	attr := &os.ProcAttr{
-		Dir:   ".",
-		Env:   os.Environ(),
-		Files: []*os.File{os.Stdin, os.Stdout, os.Stderr},
-	}
-
-	fmt.Println("CLONING: Spawning a new cell...")
-	p, err := os.StartProcess(exe, []string{exe, "serve", "--child"}, attr)
-	if err != nil {
-		return err
-	}
-
-	fmt.Printf("CLONING: New cell spawned with PID %d\n", p.Pid)
-	return nil
-}
-
-// GetProcessStats returns stats for the current process.
+// GetProcessStats returns the CPU percentage and resident memory of the
+// current process from the latest sample.
 func GetProcessStats() (float64, uint64, error) {
-	p, err := process.NewProcess(int32(os.Getpid()))
+	e, err := CheckThermodynamics()
 	if err != nil {
 		return 0, 0, err
 	}
-
-	cpuPercent, _ := p.CPUPercent()
-	memInfo, _ := p.MemoryInfo()
-	
-	return cpuPercent, memInfo.RSS, nil
+	return e.Process.CPUPercent, e.Process.MemoryBytes, nil
 }
@@ -0,0 +1,179 @@
+package biology
+
+import (
+	"context"
+	"os"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/shirou/gopsutil/cpu"
+	"github.com/shirou/gopsutil/mem"
+	"github.com/shirou/gopsutil/process"
+)
+
+// SampleInterval is how often the background sampler refreshes the energy
+// snapshot.
+const SampleInterval = 5 * time.Second
+
+// Usage is a CPU and memory reading. CPUPercent is relative to the CPU
+// capacity available to the reading's scope.
+type Usage struct {
+	CPUPercent    float64 `json:"cpu_percent"`
+	MemoryBytes   uint64  `json:"memory_bytes"`
+	MemoryPercent float64 `json:"memory_percent"`
+}
+
+// sampler keeps the latest Energy snapshot. CPU figures are deltas between
+// consecutive samples, so a sample never waits on a measurement window.
+type sampler struct {
+	mu      sync.RWMutex
+	current Energy
+	err     error
+
+	proc     *process.Process
+	prevAt   time.Time
+	prevHost cpu.TimesStat
+	prevProc float64 // process CPU seconds
+	prevCg   uint64  // cgroup usage_usec
+}
+
+var (
+	energySampler *sampler
+	samplerOnce   sync.Once
+)
+
+func getSampler() *sampler {
+	samplerOnce.Do(func() {
+		energySampler = &sampler{}
+		energySampler.proc, _ = process.NewProcess(int32(os.Getpid()))
+		energySampler.sample()
+		go energySampler.run(context.Background())
+	})
+	return energySampler
+}
+
+// Current returns the latest energy snapshot without blocking. The first
+// call starts the background sampler.
+func Current() Energy {
+	s := getSampler()
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.current
+}
+
+func (s *sampler) run(ctx context.Context) {
+	ticker := time.NewTicker(SampleInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.sample()
+		}
+	}
+}
+
+func (s *sampler) sample() {
+	now := time.Now()
+	elapsed := now.Sub(s.prevAt).Seconds()
+	first := s.prevAt.IsZero()
+	cores := float64(runtime.NumCPU())
+
+	e := Energy{SampledAt: now}
+	var err error
+
+	// Host
+	if times, terr := cpu.Times(false); terr == nil && len(times) > 0 {
+		t := times[0]
+		busy := t.Total() - t.Idle - t.Iowait
+		prevBusy := s.prevHost.Total() - s.prevHost.Idle - s.prevHost.Iowait
+		if total := t.Total() - s.prevHost.Total(); total > 0 {
+			// The first sample averages since boot.
+			e.Host.CPUPercent = clampPercent((busy - prevBusy) / total * 100)
+		}
+		s.prevHost = t
+	} else {
+		err = terr
+	}
+	if v, verr := mem.VirtualMemory(); verr == nil {
+		e.Host.MemoryBytes = v.Used
+		e.Host.MemoryPercent = v.UsedPercent
+	} else {
+		err = verr
+	}
+
+	// Container limits
+	cg, inCgroup := readCgroup()
+	capacity := cores
+	if inCgroup && cg.CPULimit > 0 && cg.CPULimit < cores {
+		capacity = cg.CPULimit
+		e.CPULimit = cg.CPULimit
+	}
+	if inCgroup && cg.MemoryLimit > 0 {
+		e.MemoryLimit = cg.MemoryLimit
+	}
+	e.Limited = e.CPULimit > 0 || e.MemoryLimit > 0
+
+	// Process
+	memTotal := float64(e.Host.MemoryBytes)
+	if e.Host.MemoryPercent > 0 {
+		memTotal = float64(e.Host.MemoryBytes) / e.Host.MemoryPercent * 100
+	}
+	if e.MemoryLimit > 0 {
+		memTotal = float64(e.MemoryLimit)
+	}
+	if s.proc != nil {
+		if t, perr := s.proc.Times(); perr == nil {
+			used := t.User + t.System
+			if !first && elapsed > 0 {
+				e.Process.CPUPercent = clampPercent((used - s.prevProc) / elapsed / capacity * 100)
+			}
+			s.prevProc = used
+		}
+		if m, perr := s.proc.MemoryInfo(); perr == nil {
+			e.Process.MemoryBytes = m.RSS
+			if memTotal > 0 {
+				e.Process.MemoryPercent = float64(m.RSS) / memTotal * 100
+			}
+		}
+	}
+
+	// Effective figures: the cgroup's when it is limited, the host's otherwise.
+	e.CPUUsage = e.Host.CPUPercent
+	e.MemoryUsage = e.Host.MemoryPercent
+	if e.CPULimit > 0 {
+		if !first && elapsed > 0 {
+			e.CPUUsage = clampPercent(float64(cg.CPUUsageUS-s.prevCg) / 1e6 / elapsed / capacity * 100)
+		} else {
+			e.CPUUsage = 0
+		}
+	}
+	if e.MemoryLimit > 0 {
+		e.MemoryUsage = clampPercent(float64(cg.MemoryUsed) / float64(e.MemoryLimit) * 100)
+	}
+	s.prevCg = cg.CPUUsageUS
+	s.prevAt = now
+
+	e.EnergyLevel = ((100.0-e.CPUUsage)/100.0 + (100.0-e.MemoryUsage)/100.0) / 2.0
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err != nil && s.current.SampledAt.IsZero() {
+		s.err = err
+		return
+	}
+	s.current = e
+	s.err = nil
+}
+
+func clampPercent(p float64) float64 {
+	if p < 0 {
+		return 0
+	}
+	if p > 100 {
+		return 100
+	}
+	return p
+}
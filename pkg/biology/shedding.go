@@ -0,0 +1,80 @@
+package biology
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Priority orders work when energy runs low.
+type Priority string
+
+const (
+	PriorityBackground Priority = "background" // Reflexes, social posts, scheduled audits
+	PriorityLow        Priority = "low"
+	PriorityNormal     Priority = "normal" // Mission work
+	PriorityOwner      Priority = "owner"  // Asked for by the owner
+)
+
+// Rank orders priorities from background (0) to owner (3). Unknown
+// priorities rank as normal.
+func (p Priority) Rank() int {
+	switch p {
+	case PriorityBackground:
+		return 0
+	case PriorityLow:
+		return 1
+	case PriorityOwner:
+		return 3
+	}
+	return 2
+}
+
+// ShedPolicy decides which work is deferred at a given energy level.
+type ShedPolicy struct {
+	// Below ShedBelow background and low-priority work is deferred.
+	ShedBelow float64
+	// Below CriticalBelow only owner-initiated work runs.
+	CriticalBelow float64
+}
+
+// DefaultShedPolicy applies until SetShedPolicy is called.
+var DefaultShedPolicy = ShedPolicy{ShedBelow: 0.3, CriticalBelow: 0.15}
+
+var (
+	policyMu sync.RWMutex
+	policy   = DefaultShedPolicy
+)
+
+// SetShedPolicy replaces the load shedding thresholds.
+func SetShedPolicy(p ShedPolicy) {
+	policyMu.Lock()
+	defer policyMu.Unlock()
+	policy = p
+}
+
+// Defers reports whether work of priority p waits at the given energy level.
+func (sp ShedPolicy) Defers(p Priority, level float64) bool {
+	switch {
+	case p.Rank() >= PriorityOwner.Rank():
+		return false
+	case level < sp.CriticalBelow:
+		return true
+	case level < sp.ShedBelow:
+		return p.Rank() < PriorityNormal.Rank()
+	}
+	return false
+}
+
+// ShouldDefer reports whether work of priority p should wait for energy to
+// recover, with the reason when it should.
+func ShouldDefer(p Priority) (bool, string) {
+	policyMu.RLock()
+	sp := policy
+	policyMu.RUnlock()
+
+	level := Current().EnergyLevel
+	if !sp.Defers(p, level) {
+		return false, ""
+	}
+	return true, fmt.Sprintf("energy at %.2f, %s work waits until it recovers", level, p)
+}
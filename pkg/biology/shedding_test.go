@@ -0,0 +1,25 @@
+package biology
+
+import "testing"
+
+func TestShedPolicyDefers(t *testing.T) {
+	sp := ShedPolicy{ShedBelow: 0.3, CriticalBelow: 0.15}
+	cases := []struct {
+		p     Priority
+		level float64
+		want  bool
+	}{
+		{PriorityBackground, 0.8, false},
+		{PriorityBackground, 0.2, true},
+		{PriorityLow, 0.2, true},
+		{PriorityNormal, 0.2, false},
+		{PriorityNormal, 0.1, true},
+		{"", 0.1, true}, // unknown ranks as normal
+		{PriorityOwner, 0.01, false},
+	}
+	for _, c := range cases {
+		if got := sp.Defers(c.p, c.level); got != c.want {
+			t.Errorf("Defers(%q, %.2f) = %v, want %v", c.p, c.level, got, c.want)
+		}
+	}
+}
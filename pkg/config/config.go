@@ -16,6 +16,7 @@ var (
 	Commit       = "none"
 	BuildDate    = "unknown"
 	currentAgent = "auracrab"
+	replica      string
 )
 
 // SetCurrentAgent sets the agent handle for the current execution context
@@ -53,9 +54,31 @@ func SecretsPath() string {
 	return filepath.Join(DataDir(), "secrets.json")
 }
 
+// SetReplica marks this process as the swarm replica name. A replica
+// shares the agent's data dir but keeps its own state under StateDir.
+func SetReplica(name string) {
+	replica = name
+}
+
+// Replica returns the swarm replica name, or "" for the main process.
+func Replica() string {
+	return replica
+}
+
+// StateDir returns the directory for state only this process writes: the
+// data dir itself, or replicas/{name} inside it for a swarm replica.
+func StateDir() string {
+	if replica == "" {
+		return DataDir()
+	}
+	path := filepath.Join(DataDir(), "replicas", replica)
+	_ = os.MkdirAll(path, 0755)
+	return path
+}
+
 // TasksPath returns the path to the tasks persistence file
 func TasksPath() string {
-	return filepath.Join(DataDir(), "tasks.json")
+	return filepath.Join(StateDir(), "tasks.json")
 }
 
 // CronPath returns the path to the cron persistence file
@@ -5,6 +5,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/spf13/viper"
 	"github.com/nathfavour/auracrab/pkg/vault"
@@ -19,10 +20,154 @@ type CortensorConfig struct {
 type InferenceConfig struct {
 	ActiveProvider string          `mapstructure:"active_provider"`
 	Cortensor      CortensorConfig `mapstructure:"cortensor"`
+
+	// ContextLimits caps the prompt size in tokens per provider name.
+	ContextLimits map[string]int `mapstructure:"context_limits"`
+	// ResponseReserve is the number of tokens kept free for the reply.
+	ResponseReserve int `mapstructure:"response_reserve"`
+	// PromptFormats selects the wire format per provider: "text" (default),
+	// or a structured "hjson" / "json" PromptPacket.
+	PromptFormats map[string]string `mapstructure:"prompt_formats"`
+	// CharsPerToken tunes the token estimate per provider to its
+	// tokenizer's average, 4 when unset.
+	CharsPerToken map[string]float64 `mapstructure:"chars_per_token"`
+}
+
+// DefaultContextLimit applies to providers without a configured limit.
+const DefaultContextLimit = 32000
+
+// ContextLimit returns the prompt token budget for a provider, with the
+// response reserve already subtracted.
+func (c *InferenceConfig) ContextLimit(provider string) int {
+	limit := c.ContextLimits[provider]
+	if limit <= 0 {
+		limit = DefaultContextLimit
+	}
+	if c.ResponseReserve > 0 && c.ResponseReserve < limit {
+		limit -= c.ResponseReserve
+	}
+	return limit
+}
+
+// PromptFormat returns the wire format for a provider, "text" unless a
+// structured format was opted into.
+func (c *InferenceConfig) PromptFormat(provider string) string {
+	if f := c.PromptFormats[provider]; f != "" {
+		return strings.ToLower(f)
+	}
+	return "text"
+}
+
+// EventsConfig controls the spine event bus.
+type EventsConfig struct {
+	// Persist appends events on the listed topic patterns to
+	// StateDir()/events/events.jsonl, one log per replica.
+	Persist   bool     `mapstructure:"persist"`
+	Topics    []string `mapstructure:"topics"`
+	MaxSizeMB int      `mapstructure:"max_size_mb"`
+	// RulesFile holds the event rules, DataDir()/rules.yaml by default.
+	RulesFile string `mapstructure:"rules_file"`
+}
+
+// CellConfig controls how the spine supervises one cell.
+type CellConfig struct {
+	// Enabled attaches an opt-in cell; core cells are always attached.
+	Enabled bool `mapstructure:"enabled"`
+	// Interval is the cell's own cadence, every spine beat when zero.
+	Interval time.Duration `mapstructure:"interval"`
+	// Timeout cancels a pulse that runs longer, no limit when zero.
+	Timeout time.Duration `mapstructure:"timeout"`
+}
+
+// SchedulerConfig controls load shedding and task budgets.
+type SchedulerConfig struct {
+	// Below ShedBelow energy, background and low-priority work is deferred;
+	// below CriticalBelow only owner-initiated tasks run.
+	ShedBelow     float64 `mapstructure:"shed_below"`
+	CriticalBelow float64 `mapstructure:"critical_below"`
+	// EnergyBudget and TokenBudget cap what one task may spend before it is
+	// paused, unlimited when zero.
+	EnergyBudget float64 `mapstructure:"energy_budget"`
+	TokenBudget  int     `mapstructure:"token_budget"`
+}
+
+// SwarmConfig bounds the worker replicas the swarm leader starts.
+type SwarmConfig struct {
+	MinReplicas int `mapstructure:"min_replicas"`
+	// MaxReplicas caps the workers; zero disables cloning.
+	MaxReplicas int `mapstructure:"max_replicas"`
+	// Cooldown spaces out starting and stopping replicas.
+	Cooldown time.Duration `mapstructure:"cooldown"`
+	// IdleAfter is how long the swarm must be idle before a replica above
+	// the minimum is retired.
+	IdleAfter time.Duration `mapstructure:"idle_after"`
+
+	// Listen is the TCP address other machines reach this swarm on; the
+	// network swarm is off when it is empty.
+	Listen string `mapstructure:"listen"`
+	// Peers are addresses of other machines to gossip with.
+	Peers []string `mapstructure:"peers"`
+	// Discovery finds peers on the LAN by multicast.
+	Discovery bool `mapstructure:"discovery"`
+}
+
+// LoggingConfig controls the daemon's structured log.
+type LoggingConfig struct {
+	// Level applies to subsystems without their own entry in Levels, for
+	// example {"spine": "warn", "immune": "debug"}.
+	Level  string            `mapstructure:"level"`
+	Levels map[string]string `mapstructure:"levels"`
+	// Format is "text" or "json".
+	Format string `mapstructure:"format"`
+	// File is DataDir()/logs/auracrab.log by default.
+	File string `mapstructure:"file"`
+	// The file is rotated past MaxSizeMB or after RotateEvery, keeping
+	// MaxBackups rotated files, gzipped when Compress is set.
+	MaxSizeMB   int           `mapstructure:"max_size_mb"`
+	RotateEvery time.Duration `mapstructure:"rotate_every"`
+	MaxBackups  int           `mapstructure:"max_backups"`
+	Compress    bool          `mapstructure:"compress"`
+}
+
+// MetricsConfig controls the HTTP endpoint Prometheus scrapes, which also
+// serves the health report on /healthz.
+type MetricsConfig struct {
+	// Listen is a loopback address such as "127.0.0.1:9464"; the
+	// endpoint is off when it is empty.
+	Listen string `mapstructure:"listen"`
+}
+
+// TracingConfig controls where spans of tasks, steps, model queries and
+// skill calls are exported.
+type TracingConfig struct {
+	// Exporter is "file", "otlp" or empty to turn tracing off.
+	Exporter string `mapstructure:"exporter"`
+	// File is DataDir()/logs/spans.jsonl by default.
+	File string `mapstructure:"file"`
+	// Endpoint is the base URL of an OTLP/HTTP collector; spans are
+	// posted to Endpoint/v1/traces with Headers.
+	Endpoint string            `mapstructure:"endpoint"`
+	Headers  map[string]string `mapstructure:"headers"`
+	// Attribute values longer than MaxAttrBytes, such as prompts, are
+	// recorded by size and hash only.
+	MaxAttrBytes int `mapstructure:"max_attr_bytes"`
 }
 
 type Config struct {
-	Inference InferenceConfig `mapstructure:"inference"`
+	Inference InferenceConfig       `mapstructure:"inference"`
+	Events    EventsConfig          `mapstructure:"events"`
+	Cells     map[string]CellConfig `mapstructure:"cells"`
+	Scheduler SchedulerConfig       `mapstructure:"scheduler"`
+	Swarm     SwarmConfig           `mapstructure:"swarm"`
+	Logging   LoggingConfig         `mapstructure:"logging"`
+	Metrics   MetricsConfig         `mapstructure:"metrics"`
+	Tracing   TracingConfig         `mapstructure:"tracing"`
+	Watch     WatchConfig           `mapstructure:"watch"`
+}
+
+// Cell returns the supervision settings of a spine cell.
+func (c *Config) Cell(name string) CellConfig {
+	return c.Cells[name]
 }
 
 func LoadConfig() (*Config, error) {
@@ -32,6 +177,33 @@ func LoadConfig() (*Config, error) {
 	v.SetDefault("inference.active_provider", "vibe")
 	v.SetDefault("inference.cortensor.router_endpoint", "https://router.cortensor.io")
 	v.SetDefault("inference.cortensor.consensus_threshold", 1)
+	v.SetDefault("inference.context_limits", map[string]int{"vibe": DefaultContextLimit, "cortensor": DefaultContextLimit})
+	v.SetDefault("inference.response_reserve", 2048)
+	v.SetDefault("events.persist", true)
+	v.SetDefault("events.topics", []string{"task.created", "task.status", "step.*", "mission.*", "channel.*", "watcher.*", "health.*", "cron.*"})
+	v.SetDefault("events.max_size_mb", 16)
+	v.SetDefault("scheduler.shed_below", 0.3)
+	v.SetDefault("scheduler.critical_below", 0.15)
+	v.SetDefault("scheduler.energy_budget", 2.0)
+	v.SetDefault("scheduler.token_budget", 200000)
+	v.SetDefault("cells.immune.enabled", false)
+	v.SetDefault("cells.immune.interval", "10s")
+	v.SetDefault("cells.immune.timeout", "30s")
+	v.SetDefault("cells.reflex.enabled", false)
+	v.SetDefault("cells.reflex.interval", "1m")
+	v.SetDefault("cells.reflex.timeout", "5m")
+	v.SetDefault("swarm.min_replicas", 0)
+	v.SetDefault("swarm.max_replicas", 2)
+	v.SetDefault("swarm.cooldown", "2m")
+	v.SetDefault("swarm.idle_after", "10m")
+	v.SetDefault("logging.level", "info")
+	v.SetDefault("logging.format", "text")
+	v.SetDefault("logging.max_size_mb", 50)
+	v.SetDefault("logging.rotate_every", "24h")
+	v.SetDefault("logging.max_backups", 7)
+	v.SetDefault("logging.compress", true)
+	v.SetDefault("tracing.endpoint", "http://localhost:4318")
+	v.SetDefault("tracing.max_attr_bytes", 512)
 
 	// Config file locations
 	v.SetConfigName("config")
@@ -58,6 +230,9 @@ func LoadConfig() (*Config, error) {
 
 	// Expand environment variables in string fields (e.g., ${CORTENSOR_SESSION_ID})
 	cfg.Inference.Cortensor.SessionID = os.ExpandEnv(cfg.Inference.Cortensor.SessionID)
+	if cfg.Events.RulesFile == "" {
+		cfg.Events.RulesFile = filepath.Join(DataDir(), "rules.yaml")
+	}
 
 	// If SessionID is still empty, try to get it from the Vault
 	if cfg.Inference.ActiveProvider == "cortensor" && cfg.Inference.Cortensor.SessionID == "" {
@@ -0,0 +1,159 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// WatchConfig lists the filesystem watch rules of the daemon.
+type WatchConfig struct {
+	Rules []WatchRule `mapstructure:"rules"`
+}
+
+// WatchRule acts on a batch of files changed under Dir, e.g.
+//
+//	watch:
+//	  rules:
+//	    - name: tests
+//	      dir: ~/src/app
+//	      include: ["*.go"]
+//	      exclude: ["vendor/**"]
+//	      events: [write, create]
+//	      debounce: 5s
+//	      task: "Run the tests affected by {{ join .Files \", \" }}"
+//
+// Exactly one of Task, Workflow and Notify is set.
+type WatchRule struct {
+	Name string `mapstructure:"name" yaml:"name"`
+	Dir  string `mapstructure:"dir" yaml:"dir"`
+	// Include and Exclude are globs relative to Dir. A glob without "/"
+	// matches base names and "**" any number of directories; excluded
+	// directories are not watched at all. .git is always excluded.
+	Include []string `mapstructure:"include" yaml:"include,omitempty"`
+	Exclude []string `mapstructure:"exclude" yaml:"exclude,omitempty"`
+	// Events are create, write, remove, rename and chmod; all but chmod
+	// when empty.
+	Events []string `mapstructure:"events" yaml:"events,omitempty"`
+	// Debounce is how long Dir must be quiet before the changes collected
+	// so far are acted on, 2s when zero.
+	Debounce time.Duration `mapstructure:"debounce" yaml:"debounce,omitempty"`
+
+	// Task is a prompt template over .Rule, .Dir and .Files; the task
+	// starts with the changed files in its fovea.
+	Task string `mapstructure:"task" yaml:"task,omitempty"`
+	// Workflow is a stored workflow name or file, applied with the inputs
+	// files, dir and rule when it declares them.
+	Workflow string `mapstructure:"workflow" yaml:"workflow,omitempty"`
+	// Notify is a message template sent to Chat ("platform:chat_id"), or
+	// to the bot owners when Chat is empty.
+	Notify string `mapstructure:"notify" yaml:"notify,omitempty"`
+	Chat   string `mapstructure:"chat" yaml:"chat,omitempty"`
+}
+
+// watchRuleName restricts rule names, which become part of task IDs and
+// thus of trace and packet file names.
+var watchRuleName = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// Validate reports every problem with the rule at once.
+func (r WatchRule) Validate() error {
+	var errs []string
+	if r.Name == "" {
+		errs = append(errs, "missing name")
+	} else if !watchRuleName.MatchString(r.Name) {
+		errs = append(errs, "name may only contain letters, digits, '_' and '-'")
+	}
+	if r.Dir == "" {
+		errs = append(errs, "missing dir")
+	}
+	actions := 0
+	for _, a := range []string{r.Task, r.Workflow, r.Notify} {
+		if a != "" {
+			actions++
+		}
+	}
+	if actions != 1 {
+		errs = append(errs, "needs exactly one of task, workflow and notify")
+	}
+	if r.Chat != "" {
+		if r.Notify == "" {
+			errs = append(errs, "chat only applies to notify")
+		}
+		if platform, id, ok := strings.Cut(r.Chat, ":"); !ok || platform == "" || id == "" {
+			errs = append(errs, fmt.Sprintf("chat %q is not platform:chat_id", r.Chat))
+		}
+	}
+	if r.Debounce < 0 {
+		errs = append(errs, "negative debounce")
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("watch rule '%s': %s", r.Name, strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// SaveWatchRules replaces watch.rules in the config file, keeping the rest
+// of the file and its comments.
+func SaveWatchRules(rules []WatchRule) error {
+	path := ConfigPath()
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	var doc yaml.Node
+	if len(bytes.TrimSpace(data)) > 0 {
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return fmt.Errorf("could not parse %s: %w", path, err)
+		}
+	}
+	if len(doc.Content) == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}}
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return fmt.Errorf("%s is not a YAML mapping", path)
+	}
+
+	if rules == nil {
+		rules = []WatchRule{}
+	}
+	var list yaml.Node
+	if err := list.Encode(rules); err != nil {
+		return err
+	}
+	watch := mappingEntry(root, "watch")
+	if watch.Kind != yaml.MappingNode {
+		*watch = yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	}
+	*mappingEntry(watch, "rules") = list
+
+	var out bytes.Buffer
+	enc := yaml.NewEncoder(&out)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return err
+	}
+	if err := enc.Close(); err != nil {
+		return err
+	}
+	return os.WriteFile(path, out.Bytes(), 0600)
+}
+
+// mappingEntry returns the value node of key in a mapping, adding an empty
+// one when the key is missing.
+func mappingEntry(m *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			return m.Content[i+1]
+		}
+	}
+	value := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null"}
+	m.Content = append(m.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, value)
+	return value
+}
@@ -0,0 +1,70 @@
+package config
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSaveWatchRules_KeepsTheRestOfTheFile(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	original := "# my settings\nlogging:\n  level: debug # chatty\n"
+	if err := os.WriteFile(ConfigPath(), []byte(original), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	rule := WatchRule{Name: "docs", Dir: "/tmp/docs", Include: []string{"*.md"}, Debounce: 5 * time.Second, Notify: "{{ .Files }}"}
+	if err := SaveWatchRules([]WatchRule{rule}); err != nil {
+		t.Fatal(err)
+	}
+
+	data, _ := os.ReadFile(ConfigPath())
+	for _, want := range []string{"# my settings", "# chatty", "level: debug"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("expected %q to survive, got:\n%s", want, data)
+		}
+	}
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.Watch.Rules) != 1 {
+		t.Fatalf("expected the rule to load back, got %+v", cfg.Watch.Rules)
+	}
+	got := cfg.Watch.Rules[0]
+	if got.Name != "docs" || got.Debounce != 5*time.Second || len(got.Include) != 1 || got.Notify != rule.Notify {
+		t.Fatalf("rule changed on the way back: %+v", got)
+	}
+
+	if err := SaveWatchRules(nil); err != nil {
+		t.Fatal(err)
+	}
+	cfg, _ = LoadConfig()
+	if len(cfg.Watch.Rules) != 0 || cfg.Logging.Level != "debug" {
+		t.Fatalf("expected no rules and the logging level kept, got %+v", cfg)
+	}
+}
+
+func TestWatchRule_Validate(t *testing.T) {
+	bad := WatchRule{Name: "x", Dir: ".", Task: "a", Notify: "b", Chat: "telegram"}
+	err := bad.Validate()
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	for _, want := range []string{"exactly one", "platform:chat_id"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected %q in %v", want, err)
+		}
+	}
+	for _, name := range []string{"docs/api", "../x", "two words"} {
+		if err := (WatchRule{Name: name, Dir: ".", Notify: "b"}).Validate(); err == nil || !strings.Contains(err.Error(), "name may only") {
+			t.Errorf("name %q: expected it to be rejected, got %v", name, err)
+		}
+	}
+	ok := WatchRule{Name: "api-docs_2", Dir: ".", Notify: "b", Chat: "telegram:42"}
+	if err := ok.Validate(); err != nil {
+		t.Fatal(err)
+	}
+}
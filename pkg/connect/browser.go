@@ -80,7 +80,7 @@ func (c *BrowserChannel) Start(ctx context.Context, onMessage func(platform stri
 		server.Close()
 	}()
 
-	fmt.Printf("Browser: WebSocket server starting on %s\n", server.Addr)
+	logger.Info("browser WebSocket server starting", "addr", server.Addr)
 	// This will block, so we run it in a goroutine if we want to return from Start
 	// But Start is usually called in a goroutine anyway in butler.go
 	return server.ListenAndServe()
@@ -89,7 +89,7 @@ func (c *BrowserChannel) Start(ctx context.Context, onMessage func(platform stri
 func (c *BrowserChannel) handleWebSocket(w http.ResponseWriter, r *http.Request) {
 	conn, err := c.upgrader.Upgrade(w, r, nil)
 	if err != nil {
-		fmt.Printf("Browser: Upgrade error: %v\n", err)
+		logger.Warn("browser WebSocket upgrade failed", "err", err)
 		return
 	}
 	defer conn.Close()
@@ -136,7 +136,7 @@ func (c *BrowserChannel) handleWebSocket(w http.ResponseWriter, r *http.Request)
 			client.WindowID = msg.WindowID
 			client.Tabs = msg.Tabs
 			c.mu.Unlock()
-			fmt.Printf("Browser: Registered client [%s] window %s (Profile: %s, Tabs: %d)\n", msg.InstanceID, msg.WindowID, msg.Profile, len(msg.Tabs))
+			logger.Info("browser client registered", "instance", msg.InstanceID, "window", msg.WindowID, "profile", msg.Profile, "tabs", len(msg.Tabs))
 			continue
 		}
 
@@ -215,7 +215,7 @@ func (c *BrowserChannel) Send(to string, text string) error {
 	for conn := range c.clients {
 		err := conn.WriteMessage(websocket.TextMessage, msg)
 		if err != nil {
-			fmt.Printf("Browser: Write error: %v\n", err)
+			logger.Warn("browser write failed", "err", err)
 		}
 	}
 	return nil
@@ -2,8 +2,12 @@ package connect
 
 import (
 	"context"
+
+	"github.com/nathfavour/auracrab/pkg/logging"
 )
 
+var logger = logging.For("connect")
+
 // Channel defines an integration surface like Telegram, Discord, or Signal.
 type Channel interface {
 	Name() string
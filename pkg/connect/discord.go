@@ -3,10 +3,10 @@ package connect
 import (
 	"context"
 	"fmt"
-	"log"
 	"strings"
 
 	"github.com/bwmarrin/discordgo"
+	"github.com/nathfavour/auracrab/pkg/logging"
 	"github.com/nathfavour/auracrab/pkg/memory"
 	"github.com/nathfavour/auracrab/pkg/vault"
 )
@@ -26,7 +26,7 @@ func (d *DiscordChannel) Start(ctx context.Context, onMessage func(platform stri
 	var err error
 	d.history, err = memory.NewHistoryStore()
 	if err != nil {
-		log.Printf("Warning: Discord could not initialize history store: %v", err)
+		logger.Warn("could not open history store", logging.KeyPlatform, "discord", "err", err)
 	}
 
 	dg, err := discordgo.New("Bot " + d.Token)
@@ -77,7 +77,7 @@ func (d *DiscordChannel) Start(ctx context.Context, onMessage func(platform stri
 			}
 		}
 
-		log.Printf("[Discord][%s] (Channel: %s) %s", from, m.ChannelID, text)
+		logger.Debug("message received", logging.KeyPlatform, "discord", logging.KeyChat, m.ChannelID, "from", from, "text", text)
 
 		// Handle internal bot commands
 		if strings.HasPrefix(text, "!") || strings.HasPrefix(text, "/") {
@@ -97,7 +97,7 @@ func (d *DiscordChannel) Start(ctx context.Context, onMessage func(platform stri
 		}
 
 		if !isAllowed {
-			log.Printf("Ignored Discord message from unauthorized channel: %s", m.ChannelID)
+			logger.Warn("ignored message from unauthorized channel", logging.KeyPlatform, "discord", logging.KeyChat, m.ChannelID)
 			return
 		}
 
@@ -110,7 +110,7 @@ func (d *DiscordChannel) Start(ctx context.Context, onMessage func(platform stri
 		// Send reply
 		_, err := s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("<@%s> %s", m.Author.ID, reply))
 		if err != nil {
-			log.Printf("Error sending Discord message: %v", err)
+			logger.Error("could not send message", logging.KeyPlatform, "discord", logging.KeyChat, m.ChannelID, "err", err)
 		}
 	})
 
@@ -120,7 +120,7 @@ func (d *DiscordChannel) Start(ctx context.Context, onMessage func(platform stri
 		return fmt.Errorf("error opening Discord connection: %v", err)
 	}
 
-	log.Printf("Auracrab: Discord bot is now running.")
+	logger.Info("bot running", logging.KeyPlatform, "discord")
 
 	go func() {
 		<-ctx.Done()
@@ -132,7 +132,7 @@ func (d *DiscordChannel) Start(ctx context.Context, onMessage func(platform stri
 
 func (d *DiscordChannel) Stop() error {
 	if d.session != nil {
-		log.Printf("Auracrab: Closing Discord connection.")
+		logger.Info("closing connection", logging.KeyPlatform, "discord")
 		return d.session.Close()
 	}
 	return nil
@@ -4,12 +4,12 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"log"
 	"os"
 	"path/filepath"
 	"strings"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/nathfavour/auracrab/pkg/logging"
 	"github.com/nathfavour/auracrab/pkg/memory"
 	"github.com/nathfavour/auracrab/pkg/vault"
 )
@@ -38,7 +38,7 @@ func (t *TelegramChannel) Start(ctx context.Context, onMessage func(platform str
 	var err error
 	t.history, err = memory.NewHistoryStore()
 	if err != nil {
-		log.Printf("Warning: Telegram could not initialize history store: %v", err)
+		logger.Warn("could not open history store", logging.KeyPlatform, "telegram", "err", err)
 	}
 
 	bot, err := tgbotapi.NewBotAPI(t.Token)
@@ -47,7 +47,7 @@ func (t *TelegramChannel) Start(ctx context.Context, onMessage func(platform str
 	}
 	t.bot = bot
 
-	log.Printf("Auracrab: Authorized on account %s", bot.Self.UserName)
+	logger.Info("authorized", logging.KeyPlatform, "telegram", "account", bot.Self.UserName)
 
 	u := tgbotapi.NewUpdate(t.offset + 1)
 	u.Timeout = 60
@@ -112,7 +112,7 @@ func (t *TelegramChannel) Start(ctx context.Context, onMessage func(platform str
 					}
 				}
 
-				log.Printf("[%s] (Chat: %d) %s", from, chatID, text)
+				logger.Debug("message received", logging.KeyPlatform, "telegram", logging.KeyChat, chatID, "from", from, "text", text)
 
 				// Handle internal bot commands first
 				if strings.HasPrefix(text, "/") {
@@ -136,8 +136,8 @@ func (t *TelegramChannel) Start(ctx context.Context, onMessage func(platform str
 							continue
 						}
 						reply := onMessage("telegram", chatIDStr, from, "get_status_internal")
-						msg := tgbotapi.NewMessage(chatID, "📊 *System Status*\n"+reply)
-						msg.ParseMode = "Markdown"
+						// Probe reasons hold paths and IDs that Markdown would mangle.
+						msg := tgbotapi.NewMessage(chatID, "📊 System Status\n"+reply)
 						bot.Send(msg)
 						continue
 					case "/help":
@@ -149,7 +149,7 @@ func (t *TelegramChannel) Start(ctx context.Context, onMessage func(platform str
 				}
 
 				if !isAllowed {
-					log.Printf("Ignored message from unauthorized chat: %d", chatID)
+					logger.Warn("ignored message from unauthorized chat", logging.KeyPlatform, "telegram", logging.KeyChat, chatID)
 					msg := tgbotapi.NewMessage(chatID, "🚫 *Access Denied*\n\nThis chat is not authorized to interact with this Auracrab instance. Please contact the owner to authorize this Chat ID.")
 					msg.ParseMode = "Markdown"
 					_, _ = bot.Send(msg)
@@ -170,7 +170,7 @@ func (t *TelegramChannel) Start(ctx context.Context, onMessage func(platform str
 				msg.ReplyToMessageID = update.Message.MessageID
 
 				if _, err := bot.Send(msg); err != nil {
-					log.Printf("Error sending telegram message: %v", err)
+					logger.Error("could not send message", logging.KeyPlatform, "telegram", logging.KeyChat, chatID, "err", err)
 				}
 			}
 		}
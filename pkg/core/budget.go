@@ -0,0 +1,229 @@
+package core
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/nathfavour/auracrab/pkg/config"
+)
+
+// TokenEstimator approximates how many tokens a provider will count for text.
+type TokenEstimator interface {
+	Estimate(text string) int
+}
+
+// HeuristicEstimator assumes ~4 characters per token, or CharsPerToken
+// when set, but never fewer tokens than whitespace-separated words. It is
+// close enough for budgeting without shipping a tokenizer per provider.
+type HeuristicEstimator struct {
+	CharsPerToken float64
+}
+
+func (h HeuristicEstimator) Estimate(text string) int {
+	perToken := h.CharsPerToken
+	if perToken <= 0 {
+		perToken = 4
+	}
+	byChars := int(math.Ceil(float64(utf8.RuneCountInString(text)) / perToken))
+	words := len(strings.Fields(text))
+	if words > byChars {
+		return words
+	}
+	return byChars
+}
+
+const trimMarker = "\n... [TRIMMED FOR TOKEN BUDGET] ..."
+
+// PromptSection is one "== NAME ==" block of the living prompt. Sections are
+// shrunk in ascending Priority order until the packet fits the budget.
+type PromptSection struct {
+	Name     string   `json:"name"`
+	Priority int      `json:"priority"`
+	Prefix   string   `json:"-"`
+	Items    []string `json:"-"`
+	Required bool     `json:"required,omitempty"` // Never shrunk
+
+	// DropFromStart drops the oldest items first (e.g. anomalies);
+	// otherwise the last, least relevant items go first.
+	DropFromStart bool `json:"-"`
+
+	Tokens   int    `json:"tokens"`
+	Original int    `json:"original_tokens"`
+	Dropped  int    `json:"dropped_items,omitempty"`
+	Trimmed  bool   `json:"trimmed,omitempty"`
+	Omitted  bool   `json:"omitted,omitempty"`
+	Content  string `json:"content"`
+}
+
+func (s *PromptSection) render() string {
+	if s.Omitted {
+		return "(omitted to fit the token budget)"
+	}
+	return s.Prefix + strings.Join(s.Items, "\n")
+}
+
+// PromptAssembly is a fully budgeted living prompt.
+type PromptAssembly struct {
+	TaskID   string          `json:"task_id,omitempty"`
+	Provider string          `json:"provider"`
+	Limit    int             `json:"limit"`
+	Total    int             `json:"total_tokens"`
+	Sections []PromptSection `json:"sections"`
+	BuiltAt  time.Time       `json:"built_at"`
+}
+
+func (a *PromptAssembly) String() string {
+	var b strings.Builder
+	b.WriteString("AURACRAB_LIVING_PROMPT\n\n")
+	for i, s := range a.Sections {
+		b.WriteString("== " + s.Name + " ==\n")
+		b.WriteString(s.Content)
+		if i < len(a.Sections)-1 {
+			b.WriteString("\n\n")
+		}
+	}
+	return b.String()
+}
+
+// assemble fits sections into limit tokens, shrinking the lowest-priority
+// sections first: whole items are dropped before the last remaining item is
+// truncated, and a section is omitted entirely only as a last resort.
+// Required sections are only shrunk when everything else is gone and the
+// packet still does not fit; they are truncated but never omitted, so the
+// total may still exceed the limit when even that is not enough.
+func assemble(sections []PromptSection, limit int, est TokenEstimator) *PromptAssembly {
+	header := est.Estimate("AURACRAB_LIVING_PROMPT")
+	measure := func(s *PromptSection) int {
+		return est.Estimate("== "+s.Name+" ==\n") + est.Estimate(s.render())
+	}
+
+	total := header
+	for i := range sections {
+		sections[i].Tokens = measure(&sections[i])
+		sections[i].Original = sections[i].Tokens
+		total += sections[i].Tokens
+	}
+
+	order := make([]int, len(sections))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return sections[order[a]].Priority < sections[order[b]].Priority
+	})
+
+	shrink := func(s *PromptSection, omit bool) {
+		for total > limit && len(s.Items) > 1 {
+			if s.DropFromStart {
+				s.Items = s.Items[1:]
+			} else {
+				s.Items = s.Items[:len(s.Items)-1]
+			}
+			s.Dropped++
+			before := s.Tokens
+			s.Tokens = measure(s)
+			total += s.Tokens - before
+		}
+
+		if total > limit && len(s.Items) == 1 {
+			over := total - limit
+			keep := est.Estimate(s.Items[0]) - over - est.Estimate(trimMarker)
+			switch {
+			case keep > 32:
+				// Convert the token target back to characters proportionally.
+				runes := []rune(s.Items[0])
+				n := len(runes) * keep / max(est.Estimate(s.Items[0]), 1)
+				s.Items[0] = string(runes[:n]) + trimMarker
+				s.Trimmed = true
+			case omit:
+				s.Omitted = true
+			default:
+				return
+			}
+			before := s.Tokens
+			s.Tokens = measure(s)
+			total += s.Tokens - before
+		}
+	}
+
+	for _, idx := range order {
+		if total <= limit {
+			break
+		}
+		if !sections[idx].Required {
+			shrink(&sections[idx], true)
+		}
+	}
+	if total > limit {
+		logger.Warn("required prompt sections exceed the token budget, truncating them", "tokens", total, "limit", limit)
+		for _, idx := range order {
+			if total <= limit {
+				break
+			}
+			if sections[idx].Required && !sections[idx].Omitted {
+				shrink(&sections[idx], false)
+			}
+		}
+	}
+
+	for i := range sections {
+		sections[i].Content = sections[i].render()
+	}
+	return &PromptAssembly{
+		Limit:    limit,
+		Total:    total,
+		Sections: sections,
+		BuiltAt:  time.Now(),
+	}
+}
+
+// maxPacketLogBytes caps a task's packet log; the oldest packets go first.
+const maxPacketLogBytes = 4 << 20
+
+func packetLogPath(taskID string) string {
+	return filepath.Join(config.DataDir(), "packets", taskID+".jsonl")
+}
+
+// recordAssembly appends the assembly to the task's packet log so that it
+// can be inspected later with `auracrab prompt inspect`.
+func recordAssembly(a *PromptAssembly) {
+	if a.TaskID == "" {
+		return
+	}
+	data, err := json.Marshal(a)
+	if err != nil {
+		return
+	}
+	_ = appendCapped(packetLogPath(a.TaskID), data, maxPacketLogBytes, keepNewest(maxPacketLogBytes))
+}
+
+// LoadAssemblies returns every recorded prompt packet of a task, oldest first.
+func LoadAssemblies(taskID string) ([]PromptAssembly, error) {
+	f, err := os.Open(packetLogPath(taskID))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("no prompt packets recorded for task '%s'", taskID)
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	var list []PromptAssembly
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var a PromptAssembly
+		if err := json.Unmarshal(scanner.Bytes(), &a); err == nil {
+			list = append(list, a)
+		}
+	}
+	return list, scanner.Err()
+}
@@ -0,0 +1,172 @@
+package core
+
+import (
+	"os"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/nathfavour/auracrab/pkg/config"
+)
+
+func TestAssemble_ShrinksLowPriorityFirst(t *testing.T) {
+	sections := []PromptSection{
+		{Name: "LOW", Priority: 10, Items: []string{strings.Repeat("a ", 200), strings.Repeat("b ", 200)}},
+		{Name: "HIGH", Priority: 90, Items: []string{strings.Repeat("c ", 200)}},
+		{Name: "USER", Priority: 100, Items: []string{"do it"}, Required: true},
+	}
+
+	a := assemble(sections, 300, HeuristicEstimator{})
+	if a.Total > a.Limit {
+		t.Fatalf("expected packet within budget, got %d/%d", a.Total, a.Limit)
+	}
+	if a.Sections[0].Dropped == 0 {
+		t.Error("expected low priority section to drop items")
+	}
+	if a.Sections[1].Dropped != 0 || a.Sections[1].Trimmed || a.Sections[1].Omitted {
+		t.Error("expected high priority section to be untouched")
+	}
+	if !strings.Contains(a.String(), "do it") {
+		t.Error("expected required section to survive")
+	}
+}
+
+func TestAssemble_LastResorts(t *testing.T) {
+	long := strings.Repeat("word ", 400)
+	cases := []struct {
+		name     string
+		sections []PromptSection
+		limit    int
+		check    func(t *testing.T, a *PromptAssembly)
+	}{
+		{
+			name: "oldest anomalies drop first",
+			sections: []PromptSection{
+				{Name: "ANOMALIES", Priority: 30, Items: []string{"old " + long, "new"}, DropFromStart: true},
+				{Name: "USER", Priority: 100, Items: []string{"go"}, Required: true},
+			},
+			limit: 50,
+			check: func(t *testing.T, a *PromptAssembly) {
+				if got := a.Sections[0].Items; len(got) != 1 || got[0] != "new" {
+					t.Fatalf("kept %q", got)
+				}
+			},
+		},
+		{
+			name: "single item is trimmed, then omitted",
+			sections: []PromptSection{
+				{Name: "LOW", Priority: 10, Items: []string{long}},
+				{Name: "MID", Priority: 50, Items: []string{long}},
+				{Name: "USER", Priority: 100, Items: []string{"go"}, Required: true},
+			},
+			limit: 300,
+			check: func(t *testing.T, a *PromptAssembly) {
+				if !a.Sections[0].Omitted || !a.Sections[1].Trimmed || a.Sections[1].Omitted {
+					t.Fatalf("LOW omitted=%v, MID trimmed=%v", a.Sections[0].Omitted, a.Sections[1].Trimmed)
+				}
+				if !strings.HasSuffix(a.Sections[1].Content, trimMarker) {
+					t.Error("a trimmed section must say so")
+				}
+			},
+		},
+		{
+			name: "oversized required section is truncated",
+			sections: []PromptSection{
+				{Name: "CONTEXT", Priority: 10, Items: []string{long}},
+				{Name: "USER", Priority: 100, Items: []string{long + long}, Required: true},
+			},
+			limit: 200,
+			check: func(t *testing.T, a *PromptAssembly) {
+				user := a.Sections[1]
+				if !user.Trimmed || user.Omitted {
+					t.Fatalf("required section trimmed=%v omitted=%v", user.Trimmed, user.Omitted)
+				}
+			},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			a := assemble(c.sections, c.limit, HeuristicEstimator{})
+			if a.Total > a.Limit {
+				t.Fatalf("packet of %d tokens exceeds the limit of %d", a.Total, a.Limit)
+			}
+			if got := (HeuristicEstimator{}).Estimate(a.String()); got > a.Limit+len(a.Sections) {
+				t.Fatalf("rendered packet measures %d tokens, limit %d", got, a.Limit)
+			}
+			c.check(t, a)
+		})
+	}
+}
+
+func TestHeuristicEstimator(t *testing.T) {
+	cases := []struct {
+		est  HeuristicEstimator
+		text string
+		want int
+	}{
+		{HeuristicEstimator{}, "abcdefgh", 2},
+		{HeuristicEstimator{}, "a b c d e", 5},
+		{HeuristicEstimator{CharsPerToken: 2}, "abcdefgh", 4},
+		{HeuristicEstimator{CharsPerToken: 3.5}, strings.Repeat("x", 70), 20},
+	}
+	for _, c := range cases {
+		if got := c.est.Estimate(c.text); got != c.want {
+			t.Errorf("%+v.Estimate(%q) = %d, want %d", c.est, c.text, got, c.want)
+		}
+	}
+}
+
+func TestNewMetabolizerUsesProviderEstimator(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Inference.ActiveProvider = "cortensor"
+	cfg.Inference.CharsPerToken = map[string]float64{"cortensor": 2}
+	est, _ := NewMetabolizer(&Butler{Config: cfg}).budget()
+	if got := est.Estimate("abcdefgh"); got != 4 {
+		t.Fatalf("estimate = %d, want the configured 2 chars per token", got)
+	}
+}
+
+func TestFitVibePrompt(t *testing.T) {
+	est := HeuristicEstimator{}
+	listing := strings.Repeat("some/file.go\n", 400)
+
+	wire, err := fitVibePrompt("fix the build", listing, est, 100000)
+	if err != nil || !strings.Contains(wire, listing) {
+		t.Fatalf("a prompt within budget must be sent whole: %v", err)
+	}
+
+	wire, err = fitVibePrompt("fix the build", listing, est, 800)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := est.Estimate(wire); n > 800 || !strings.Contains(wire, "fix the build") || !strings.Contains(wire, trimMarker) {
+		t.Fatalf("expected the listing trimmed to fit, got %d tokens", n)
+	}
+
+	if _, err := fitVibePrompt(strings.Repeat("too long ", 1000), listing, est, 800); err == nil {
+		t.Fatal("expected an error when the prompt alone exceeds the limit")
+	}
+}
+
+func TestRecordAssemblyIsPrivateAndCapped(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	big := strings.Repeat("x", maxPacketLogBytes/8)
+	for i := 0; i < 12; i++ {
+		recordAssembly(&PromptAssembly{TaskID: "t1", Sections: []PromptSection{{Name: "S", Content: big}}})
+	}
+
+	info, err := os.Stat(packetLogPath("t1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if runtime.GOOS != "windows" && info.Mode().Perm() != 0600 {
+		t.Errorf("packet log mode = %v, want 0600", info.Mode().Perm())
+	}
+	if info.Size() > maxPacketLogBytes {
+		t.Errorf("packet log grew to %d bytes", info.Size())
+	}
+	list, err := LoadAssemblies("t1")
+	if err != nil || len(list) == 0 || len(list) >= 12 {
+		t.Fatalf("kept %d packets, %v", len(list), err)
+	}
+}
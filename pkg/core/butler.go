@@ -6,24 +6,33 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/nathfavour/auracrab/internal/provider"
+	"github.com/nathfavour/auracrab/pkg/biology"
 	"github.com/nathfavour/auracrab/pkg/config"
 	"github.com/nathfavour/auracrab/pkg/connect"
 	"github.com/nathfavour/auracrab/pkg/crabs"
 	"github.com/nathfavour/auracrab/pkg/cron"
 	"github.com/nathfavour/auracrab/pkg/ego"
+	"github.com/nathfavour/auracrab/pkg/health"
+	"github.com/nathfavour/auracrab/pkg/immune"
+	"github.com/nathfavour/auracrab/pkg/logging"
 	"github.com/nathfavour/auracrab/pkg/memory"
 	"github.com/nathfavour/auracrab/pkg/mission"
+	"github.com/nathfavour/auracrab/pkg/prompts"
 	"github.com/nathfavour/auracrab/pkg/schema"
 	"github.com/nathfavour/auracrab/pkg/social"
 	"github.com/nathfavour/auracrab/pkg/spine"
+	"github.com/nathfavour/auracrab/pkg/tracing"
 	"github.com/nathfavour/auracrab/pkg/vibe"
 )
 
+var logger = logging.For("butler")
+
 type TaskStatus string
 
 const (
@@ -31,6 +40,7 @@ const (
 	TaskStatusRunning   TaskStatus = "running"
 	TaskStatusCompleted TaskStatus = "completed"
 	TaskStatusFailed    TaskStatus = "failed"
+	TaskStatusPaused    TaskStatus = "paused" // Over budget, waiting for /resume
 )
 
 type Task struct {
@@ -47,6 +57,13 @@ type Task struct {
 	Platform   string                 `json:"platform,omitempty"`
 	ChatID     string                 `json:"chat_id,omitempty"`
 	Metadata   map[string]string      `json:"metadata,omitempty"`
+
+	// Priority decides which tasks wait when energy runs low.
+	Priority biology.Priority `json:"priority,omitempty"`
+
+	// Traceparent ties the task's planning, steps and queries to the span
+	// StartTask opened, across pulses and restarts.
+	Traceparent string `json:"traceparent,omitempty"`
 }
 
 type Butler struct {
@@ -61,6 +78,20 @@ type Butler struct {
 	Missions  *mission.Manager
 	Ego       *ego.Ego
 	Spine     *spine.Spine
+	Config    *config.Config
+
+	knowledgeMu sync.Mutex
+	knowledge   map[string]*memory.KnowledgeBase
+
+	foveaMu sync.Mutex
+	foveas  map[string]*foveaScan // Working tree scans of the current pulse, by task
+
+	probes   *health.Registry
+	channels map[string]error // Connection state of each messaging channel
+	failing  map[string]bool  // Probes that were not ok at the last health watch
+	deferred map[string]bool  // Tasks currently held back by load shedding
+	nervous  *NervousSystem
+	swarm    *immune.ImmuneSystem // nil unless the immune cell is enabled
 }
 
 var (
@@ -70,13 +101,17 @@ var (
 
 func GetButler() *Butler {
 	once.Do(func() {
-		stateDir := config.DataDir()
+		stateDir := config.StateDir()
 
 		reg, _ := crabs.NewRegistry()
 		mem, _ := memory.NewStore("global")
 		hist, _ := memory.NewHistoryStore()
 		miss, _ := mission.NewManager()
 		eg, _ := ego.NewEgo()
+		cfg, err := config.LoadConfig()
+		if err != nil {
+			cfg = &config.Config{}
+		}
 
 		instance = &Butler{
 			tasks:     make(map[string]*Task),
@@ -88,8 +123,20 @@ func GetButler() *Butler {
 			Missions:  miss,
 			Ego:       eg,
 			Spine:     spine.NewSpine(time.Second),
+			Config:    cfg,
+			knowledge: make(map[string]*memory.KnowledgeBase),
+			probes:    health.NewRegistry(),
+			channels:  make(map[string]error),
+			failing:   make(map[string]bool),
+			deferred:  make(map[string]bool),
 		}
+		// Habits are matched by embedding; fall back to local hashing when
+		// vibeauracle cannot embed.
+		memory.GetHabitStore().SetEmbedder(memory.NewFallbackEmbedder(vibe.NewClient()))
+
 		instance.load()
+		instance.registerProbes()
+		instance.setupScheduler()
 		instance.setupSpine()
 		instance.setupCron()
 	})
@@ -97,11 +144,8 @@ func GetButler() *Butler {
 }
 
 func (b *Butler) setupSpine() {
-	ns := NewNervousSystem(b)
-	b.Spine.Attach(ns)
-
-	// Attach other cells as they are implemented
-	// b.Spine.Attach(immune.GetImmuneSystem())
+	b.attachCells()
+	b.setupEvents()
 }
 
 func (b *Butler) Serve(ctx context.Context) error {
@@ -113,58 +157,184 @@ func (b *Butler) Serve(ctx context.Context) error {
 	b.running = true
 	b.mu.Unlock()
 
-	// Start integrations
+	// Start integrations. A swarm replica only runs tasks handed to it;
+	// the main process owns the channels and the schedule.
+	worker := config.Replica() != ""
+	if worker {
+		logger.Info("running as swarm replica", "replica", config.Replica())
+	} else {
+		b.startIngress(ctx)
+	}
+
+	if !worker {
+		b.serveMetrics(ctx)
+	}
+
+	// Start scheduler
+	if !worker {
+		go b.scheduler.Start(ctx)
+	}
+
+	// Pick up tasks interrupted by the last shutdown before the spine runs
+	b.resumeInterrupted()
+
+	// Start Spine. Its pulses outlive ctx so that shutdown can drain them.
+	b.Spine.StatsFile = CellStatsPath()
+	go b.Spine.Breathes(context.WithoutCancel(ctx))
+
+	// Event and watch rules only act while the daemon runs, and only once
+	// per swarm
+	if !worker {
+		if engine := b.startRules(); engine != nil {
+			defer engine.Stop()
+		}
+		b.startWatchers(ctx)
+	}
+
+	var err error
+	select {
+	case <-ctx.Done():
+	case <-biology.Dying():
+		err = &ApoptosisError{Reason: biology.DeathReason()}
+	}
+	b.shutdown(err)
+
+	b.mu.Lock()
+	b.running = false
+	b.mu.Unlock()
+	return err
+}
+
+// startIngress connects the messaging channels and social bots.
+func (b *Butler) startIngress(ctx context.Context) {
 	channels := connect.GetChannels()
 	if len(channels) == 0 {
-		fmt.Println("Butler: No messaging channels (Telegram/Discord) configured.")
+		logger.Info("no messaging channels (Telegram/Discord) configured")
 	}
 
 	for _, ch := range channels {
 		go func(c connect.Channel) {
+			started := b.watchChannel(c.Name())
 			err := c.Start(ctx, b.handleChannelMessage)
+			started(err)
 			if err != nil {
-				fmt.Printf("Error starting channel %s: %v\n", c.Name(), err)
+				logger.Error("could not start channel", logging.KeyPlatform, c.Name(), "err", err)
 			}
 		}(ch)
 	}
 
 	// Start Social Bots (POC Migration)
+	b.watchBots()
 	social.GetBotManager().StartBots(ctx, b.History, b, b.handleChannelMessage)
 
 	// Start continuous social daemon loop
 	go social.GetManager().Start(ctx, b)
-
-	// Initial health check
-	fmt.Println(b.WatchHealth())
-
-	// Start scheduler
-	go b.scheduler.Start(ctx)
-
-	// Start Spine
-	go b.Spine.Breathes(ctx)
-
-	<-ctx.Done()
-	b.mu.Lock()
-	b.running = false
-	b.mu.Unlock()
-	return nil
 }
 
 func (b *Butler) setupCron() {
 	// Periodic system sanity Check
 	b.scheduler.Schedule("security_audit", 24*time.Hour, func(ctx context.Context) {
+		// One node audits for the whole swarm.
+		if !b.isLeader() {
+			return
+		}
 		_, _ = b.StartTask(ctx, "run security audit and log results to ~/.auracrab/audits.log", "system", "internal", "")
 	})
 
+	// Publish health transitions for event rules
+	b.scheduler.Schedule("health_watch", time.Minute, b.watchHealthEvents)
+	b.scheduler.OnFire = func(id string, interval time.Duration) {
+		b.Spine.Broadcast(spine.Event{Type: spine.TopicCronFired, Payload: spine.CronFired{Job: id, Interval: interval}})
+	}
+
 	// Memory sync or cleanup can happen here
 }
 
 func (b *Butler) QueryWithContext(ctx context.Context, prompt string, intent string) (provider.CompletionResponse, error) {
+	return b.queryVibe(ctx, prompt, intent, b.projectSnapshot(prompt))
+}
+
+func (b *Butler) queryVibe(ctx context.Context, prompt string, intent string, snapshot string) (provider.CompletionResponse, error) {
 	if intent == "" {
 		intent = "vibe"
 	}
 
+	est, limit := NewMetabolizer(b).budget()
+	customPrompt, err := fitVibePrompt(prompt, snapshot, est, limit)
+	if err != nil {
+		return provider.CompletionResponse{}, err
+	}
+
+	client := vibe.NewClient()
+	_, span := tracing.Start(ctx, "GetCompletion", "provider", "vibe", "intent", intent, "prompt", customPrompt)
+	start := time.Now()
+	reply, err := client.Query(customPrompt, intent)
+	observeQuery("vibe", customPrompt, start, provider.CompletionResponse{Content: reply}, err)
+	span.SetAttrs("completion", reply)
+	span.RecordError(err)
+	span.End()
+	if err != nil {
+		return provider.CompletionResponse{}, err
+	}
+	reply = strings.TrimSpace(reply)
+	if reply == "" {
+		return provider.CompletionResponse{}, fmt.Errorf("empty response from vibeauracle")
+	}
+	return provider.CompletionResponse{Content: reply}, nil
+}
+
+// vibePrompt wraps prompt in the "vibe" template with the project snapshot.
+func vibePrompt(prompt, snapshot string) string {
 	cwd, _ := os.Getwd()
+	return prompts.Text("vibe", "", map[string]string{
+		"WorkingDir": cwd,
+		"Snapshot":   snapshot,
+		"Prompt":     prompt,
+	})
+}
+
+// fitVibePrompt renders the prompt sent to vibeauracle within limit tokens.
+// The snapshot is context and is trimmed, then omitted, to make room; the
+// prompt itself is never cut.
+func fitVibePrompt(prompt, snapshot string, est TokenEstimator, limit int) (string, error) {
+	wire := vibePrompt(prompt, snapshot)
+	over := est.Estimate(wire) - limit
+	if over <= 0 {
+		return wire, nil
+	}
+	keep := est.Estimate(snapshot) - over - est.Estimate(trimMarker)
+	if keep > 32 {
+		runes := []rune(snapshot)
+		snapshot = string(runes[:len(runes)*keep/max(est.Estimate(snapshot), 1)]) + trimMarker
+	} else {
+		snapshot = "(omitted to fit the token budget)"
+	}
+	wire = vibePrompt(prompt, snapshot)
+	if n := est.Estimate(wire); n > limit {
+		return "", fmt.Errorf("prompt needs %d tokens, over the limit of %d", n, limit)
+	}
+	return wire, nil
+}
+
+// projectSnapshot returns the repository chunks most relevant to prompt when
+// the working directory has been ingested, or a plain file listing otherwise.
+func (b *Butler) projectSnapshot(prompt string) string {
+	cwd, _ := os.Getwd()
+	if name, ok := memory.FindKnowledgeForPath(cwd); ok {
+		if kb := b.Knowledge(name); kb != nil {
+			var chunks []string
+			for _, c := range kb.Query(prompt, 5) {
+				chunks = append(chunks, memory.FormatChunk(c))
+			}
+			if len(chunks) > 0 {
+				return fmt.Sprintf("(relevant chunks from knowledge collection '%s')\n%s", name, strings.Join(chunks, "\n"))
+			}
+		}
+	}
+	return fileListing()
+}
+
+func fileListing() string {
 	files, _ := filepath.Glob("*")
 	if len(files) > 25 {
 		files = files[:25]
@@ -173,48 +343,241 @@ func (b *Butler) QueryWithContext(ctx context.Context, prompt string, intent str
 	if dirSnapshot == "" {
 		dirSnapshot = "(no files discovered)"
 	}
+	return dirSnapshot
+}
 
-	customPrompt := fmt.Sprintf(
-		"AURACRAB_CUSTOM_PROMPT_TEMPLATE\nWORKING_DIRECTORY:\n%s\n\nPROJECT_FILES_SNAPSHOT:\n%s\n\nUSER_PROMPT:\n%s\n\nOUTPUT_RULES:\n- Return the final actionable answer only.\n- Do not include chain-of-thought or hidden reasoning.\n- Be concrete, execution-oriented, and directly useful.",
-		cwd,
-		dirSnapshot,
-		prompt,
-	)
-
-	client := vibe.NewClient()
-	reply, err := client.Query(customPrompt, intent)
+// Knowledge returns the cached knowledge collection with the given name,
+// reopening it when its manifest changed on disk since it was cached.
+func (b *Butler) Knowledge(collection string) *memory.KnowledgeBase {
+	b.knowledgeMu.Lock()
+	defer b.knowledgeMu.Unlock()
+	if kb, ok := b.knowledge[collection]; ok && !kb.Stale() {
+		return kb
+	}
+	kb, err := memory.OpenKnowledge(collection, memory.NewFallbackEmbedder(vibe.NewClient()))
 	if err != nil {
-		return provider.CompletionResponse{}, err
+		return nil
 	}
-	reply = strings.TrimSpace(reply)
-	if reply == "" {
-		return provider.CompletionResponse{}, fmt.Errorf("empty response from vibeauracle")
+	b.knowledge[collection] = kb
+	return kb
+}
+
+// collectionFor picks the knowledge collection for a task: an explicit
+// "collection" metadata entry, or the one covering its working directory.
+func (b *Butler) collectionFor(task *Task) string {
+	if task.Metadata == nil {
+		return ""
 	}
-	return provider.CompletionResponse{Content: reply}, nil
+	if task.Metadata["collection"] != "" {
+		return task.Metadata["collection"]
+	}
+	if task.Metadata["workdir"] == "" {
+		return ""
+	}
+	name, _ := memory.FindKnowledgeForPath(task.Metadata["workdir"])
+	return name
+}
+
+// foveaFor focuses fovea on the task's working directory (metadata
+// "workdir") using git state and the given texts. Files listed one per line
+// in metadata "fovea" are pinned first. StartTask records the directory a
+// task was started from; a task without one only gets its pinned files.
+func (b *Butler) foveaFor(ctx context.Context, task *Task, fovea *Fovea, texts ...string) *Fovea {
+	if fovea == nil {
+		fovea = &Fovea{}
+	}
+	if task.Metadata == nil {
+		return fovea
+	}
+	for _, f := range strings.Split(task.Metadata["fovea"], "\n") {
+		if f == "" || len(fovea.Files) >= maxFoveaFiles {
+			continue
+		}
+		if info, err := os.Stat(f); err == nil && !info.IsDir() {
+			fovea.Files = append(fovea.Files, f)
+		}
+	}
+	dir := task.Metadata["workdir"]
+	if dir == "" {
+		return fovea
+	}
+	return b.foveaBuilder(ctx, task.ID, dir).Build(ctx, fovea, texts...)
+}
+
+// foveaBuilder returns the scan of the task's working tree for the current
+// pulse, so that its planning and steps do not each walk the tree and run
+// git.
+func (b *Butler) foveaBuilder(ctx context.Context, taskID, dir string) *FoveaBuilder {
+	b.foveaMu.Lock()
+	if b.foveas == nil {
+		b.foveas = make(map[string]*foveaScan)
+	}
+	key := taskID + "\x00" + dir
+	scan, ok := b.foveas[key]
+	if !ok {
+		scan = &foveaScan{}
+		b.foveas[key] = scan
+	}
+	b.foveaMu.Unlock()
+	scan.once.Do(func() { scan.fb = NewFoveaBuilder(ctx, dir) })
+	return scan.fb
+}
+
+// forgetFoveas drops the previous pulse's scans; the tree may have changed.
+func (b *Butler) forgetFoveas() {
+	b.foveaMu.Lock()
+	b.foveas = nil
+	b.foveaMu.Unlock()
+}
+
+// waitingTasks returns the tasks of the chat with a step waiting for human
+// input, oldest first.
+func (b *Butler) waitingTasks(platform, chatID string) []*Task {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	var found []*Task
+	for _, t := range b.tasks {
+		if t.Platform != platform || t.ChatID != chatID || t.Continuity == nil {
+			continue
+		}
+		for _, step := range t.Continuity.Plan {
+			if step.Status == string(StepWaiting) {
+				found = append(found, t)
+				break
+			}
+		}
+	}
+	sort.Slice(found, func(i, j int) bool { return found[i].StartedAt.Before(found[j].StartedAt) })
+	return found
+}
+
+// answer handles "/answer [task-id] <text>". The task ID may be left out
+// while a single task of the chat waits for input.
+func (b *Butler) answer(platform, chatID, args string) string {
+	waiting := b.waitingTasks(platform, chatID)
+	if len(waiting) == 0 {
+		return "No task in this chat is waiting for input."
+	}
+
+	var task *Task
+	if id, rest, _ := strings.Cut(args, " "); id != "" {
+		for _, t := range waiting {
+			if t.ID == id {
+				task, args = t, strings.TrimSpace(rest)
+			}
+		}
+	}
+	if task == nil {
+		if len(waiting) > 1 {
+			var ids []string
+			for _, t := range waiting {
+				ids = append(ids, fmt.Sprintf("- %s: %s", t.ID, t.Content))
+			}
+			return "Several tasks are waiting for input; reply with /answer <task-id> <answer>:\n" + strings.Join(ids, "\n")
+		}
+		task = waiting[0]
+	}
+	if args == "" {
+		return "Usage: /answer [task-id] <answer>"
+	}
+	if err := b.ProvideInput(task.ID, args); err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+	return fmt.Sprintf("👍 Thanks, continuing '%s' (%s).", task.Content, task.ID)
+}
+
+// ProvideInput answers the first step of a task that waits for human input.
+func (b *Butler) ProvideInput(taskID, input string) error {
+	b.mu.Lock()
+	task, ok := b.tasks[taskID]
+	if !ok || task.Continuity == nil {
+		b.mu.Unlock()
+		return fmt.Errorf("task '%s' not found", taskID)
+	}
+	var step *schema.ContinuityStep
+	for i := range task.Continuity.Plan {
+		if task.Continuity.Plan[i].Status == string(StepWaiting) {
+			step = &task.Continuity.Plan[i]
+			break
+		}
+	}
+	if step == nil {
+		b.mu.Unlock()
+		return fmt.Errorf("task '%s' is not waiting for input", taskID)
+	}
+	step.Status = string(StepCompleted)
+	step.Result = input
+	task.Continuity.LastCheckpoint = time.Now().Unix()
+	task.Continuity.Sync()
+	b.emit(taskID, TraceEvent{Kind: TraceInput, Step: step.ID, Status: "provided", Detail: input})
+	b.traceStep(taskID, step, time.Since(time.Unix(step.StartedAt, 0)))
+	b.mu.Unlock()
+	b.save()
+	return nil
 }
 
-func (b *Butler) handleChannelMessage(platform string, chatID string, from string, text string) string {
+func (b *Butler) handleChannelMessage(platform string, chatID string, from string, text string) (reply string) {
+	channelMessages.Inc(platform, "in")
+	defer func() {
+		// The channel sends the reply back.
+		if reply != "" {
+			channelMessages.Inc(platform, "out")
+		}
+	}()
 	if text == "get_status_internal" {
-		return fmt.Sprintf("%s\n%s", b.GetStatus(), b.WatchHealth())
+		return fmt.Sprintf("%s\n\n%s", b.GetStatus(), b.Health(context.Background()))
+	}
+
+	if id, ok := strings.CutPrefix(text, "/resume"); ok {
+		id = strings.TrimSpace(id)
+		if id == "" {
+			return "Usage: /resume <task-id>"
+		}
+		if err := b.ResumeTask(id); err != nil {
+			return fmt.Sprintf("Error: %v", err)
+		}
+		return fmt.Sprintf("▶️ Resumed %s with a new budget.", id)
 	}
 
+	ctx := logging.With(context.Background(), logging.KeyPlatform, platform, logging.KeyChat, chatID)
+	logger.DebugContext(ctx, "channel message", "from", from)
+
 	// Record incoming message in history
 	convID, err := b.History.GetOrCreateConversationForPlatform(platform, from)
 	if err == nil {
 		_ = b.History.AddMessage(convID, "user", text)
 	}
 
+	b.Spine.Broadcast(spine.Event{Type: spine.TopicChannelMessage, Payload: spine.ChannelMessage{Platform: platform, ChatID: chatID, From: from, Text: text}})
+
+	// Only an explicit /answer feeds a step waiting for human input, so that
+	// unrelated chatter in the chat is not taken as the answer.
+	if args, ok := strings.CutPrefix(text, "/answer"); ok && (args == "" || args[0] == ' ') {
+		return b.answer(platform, chatID, strings.TrimSpace(args))
+	}
+
 	if strings.HasPrefix(text, "@") {
 		parts := strings.SplitN(text, " ", 2)
 		if len(parts) > 1 {
 			crabID := strings.TrimPrefix(parts[0], "@")
 			if c, err := b.registry.Get(crabID); err == nil {
 				// Start task with crab's specialized instructions
-				augmentedTask := fmt.Sprintf("CRAB AGENT: %s\nINSTRUCTIONS: %s\n\nUSER TASK: %s", c.Name, c.Instructions, parts[1])
+				augmentedTask := prompts.Text("crab.delegate", c.ID, map[string]string{
+					"Name":         c.Name,
+					"Instructions": c.Instructions,
+					"Task":         parts[1],
+				})
 				task, err := b.StartTask(context.Background(), augmentedTask, platform, chatID, convID)
 				if err != nil {
 					return fmt.Sprintf("Error starting delegated task: %v", err)
 				}
+				b.mu.Lock()
+				if task.Metadata == nil {
+					task.Metadata = make(map[string]string)
+				}
+				task.Metadata["crab_id"] = c.ID
+				b.mu.Unlock()
+				b.save()
 				reply := fmt.Sprintf("Delegated to agent '%s' (Task ID: %s)", c.Name, task.ID)
 				if err == nil {
 					_ = b.History.AddMessage(convID, "assistant", reply)
@@ -224,47 +587,90 @@ func (b *Butler) handleChannelMessage(platform string, chatID string, from strin
 		}
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 90*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 90*time.Second)
 	defer cancel()
 
 	resp, err := b.QueryWithContext(ctx, text, "vibe")
 	if err != nil {
 		return fmt.Sprintf("Error processing prompt: %v", err)
 	}
-	reply := resp.Content
+	reply = resp.Content
 	if convID != "" {
 		_ = b.History.AddMessage(convID, "assistant", reply)
 	}
 	return reply
 }
 
-func (b *Butler) QueryMetabolic(ctx context.Context, prompt string, intent string, signature *ThoughtSignature, fovea *Fovea) (provider.CompletionResponse, error) {
+func (b *Butler) QueryMetabolic(ctx context.Context, prompt string, intent string, signature *ThoughtSignature, fovea *Fovea) (resp provider.CompletionResponse, err error) {
 	metabolizer := NewMetabolizer(b)
+	taskID := ""
+	if signature != nil {
+		taskID = signature.TaskID
+	}
+	ctx, span := tracing.Start(ctx, "QueryMetabolic", "task_id", taskID, "intent", intent)
+	defer func() {
+		span.RecordError(err)
+		span.End()
+	}()
+
+	if b.Config != nil {
+		format := b.Config.Inference.PromptFormat(b.Config.Inference.ActiveProvider)
+		if format == schema.FormatHJSON || format == schema.FormatJSON {
+			packet, err := metabolizer.BuildPacket(intent, prompt, signature, fovea)
+			if err != nil {
+				return provider.CompletionResponse{}, err
+			}
+			out, _ := replyShape(intent)
+			return b.queryPacket(ctx, b.inferenceProvider(), taskID, packet, format, intent, out)
+		}
+	}
+
+	// The living prompt is budgeted to leave room for the vibe template and
+	// the file listing around it; queryVibe checks the final prompt.
+	est, limit := metabolizer.budget()
+	listing := fileListing()
+	metabolizer.SetContextLimit(max(limit-est.Estimate(vibePrompt("", listing)), 1))
 	livingPrompt := metabolizer.Build(prompt, signature, fovea)
 
-	return b.QueryWithContext(ctx, livingPrompt, intent)
+	// Relevant chunks are already part of the living prompt.
+	start := time.Now()
+	resp, err = b.queryVibe(ctx, livingPrompt, intent, listing)
+	b.tracePrompt(taskID, "vibeauracle", intent, livingPrompt, start, resp, err)
+	return resp, err
 }
 
 func (b *Butler) SendUpdate(platform, chatID, text string) {
 	if platform == "" || chatID == "" {
-		fmt.Printf("BUTLER UPDATE: %s\n", text)
+		logger.Info("update", "text", text)
+		return
+	}
+	if b.relay(platform, chatID, text) {
 		return
 	}
 
-	social.GetBotManager().SendMessage(platform, chatID, text)
+	if social.GetBotManager().SendMessage(platform, chatID, text) == nil {
+		channelMessages.Inc(platform, "out")
+	}
 }
 
 func (b *Butler) SendUpdateExt(platform, chatID, text string, lazy bool) {
 	if platform == "" || chatID == "" {
-		fmt.Printf("BUTLER UPDATE (lazy=%v): %s\n", lazy, text)
+		logger.Info("update", "lazy", lazy, "text", text)
+		return
+	}
+	if b.relay(platform, chatID, text) {
 		return
 	}
 
+	var err error
 	if lazy {
 		// Throttled/Lazy I/O logic could go here
-		social.GetBotManager().SendMessage(platform, chatID, text)
+		err = social.GetBotManager().SendMessage(platform, chatID, text)
 	} else {
-		social.GetBotManager().SendMessage(platform, chatID, text)
+		err = social.GetBotManager().SendMessage(platform, chatID, text)
+	}
+	if err == nil {
+		channelMessages.Inc(platform, "out")
 	}
 }
 
@@ -274,9 +680,94 @@ func (b *Butler) load() {
 	if err != nil {
 		return
 	}
+	// Decode tasks one by one so that a continuity that cannot be migrated
+	// only loses that task.
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		logger.Error("could not read tasks", "err", err)
+		return
+	}
 	b.mu.Lock()
 	defer b.mu.Unlock()
-	_ = json.Unmarshal(data, &b.tasks)
+	for id, msg := range raw {
+		var task Task
+		if err := json.Unmarshal(msg, &task); err != nil {
+			logger.Warn("skipping task", logging.KeyTask, id, "err", err)
+			continue
+		}
+		b.tasks[id] = &task
+	}
+}
+
+// resumeInterrupted rehydrates tasks that were in flight when the daemon
+// stopped. Steps caught running are reset to pending, the interruption is
+// recorded as an anomaly, and execution resumes from the last checkpoint.
+func (b *Butler) resumeInterrupted() {
+	b.mu.Lock()
+	var resumed []*Task
+	for id, task := range b.tasks {
+		if task.Status != TaskStatusRunning && task.Status != TaskStatusPending {
+			continue
+		}
+		// An interrupted workflow step is dropped so that its mission
+		// dispatches it again.
+		if task.Metadata != nil && task.Metadata["workflow"] != "" {
+			delete(b.tasks, id)
+			continue
+		}
+		tc := task.Continuity
+		if tc == nil {
+			continue
+		}
+		if err := tc.Validate(); err != nil {
+			task.Status = TaskStatusFailed
+			task.Result = err.Error()
+			task.EndedAt = time.Now()
+			b.emit(id, TraceEvent{Kind: TraceStatus, Status: string(task.Status), Detail: task.Result})
+			continue
+		}
+
+		interrupted := false
+		for i := range tc.Plan {
+			step := &tc.Plan[i]
+			if step.Status != string(StepRunning) {
+				continue
+			}
+			interrupted = true
+			step.Status = string(StepPending)
+			checkpoint := "never"
+			if tc.LastCheckpoint > 0 {
+				checkpoint = time.Unix(tc.LastCheckpoint, 0).Format(time.RFC3339)
+			}
+			tc.Anomalies = append(tc.Anomalies, fmt.Sprintf(
+				"step %s (%s) interrupted by daemon restart; resuming from checkpoint %s", step.ID, step.Description, checkpoint))
+			b.traceStep(id, step, 0)
+		}
+
+		// Resume from the first step that has not finished.
+		tc.Sync()
+
+		if interrupted {
+			tc.Meta.RetryCount++
+			b.emit(id, TraceEvent{Kind: TraceRetry, Attempt: tc.Meta.RetryCount, Error: "interrupted by daemon restart"})
+			if tc.Meta.MaxRetries > 0 && tc.Meta.RetryCount > tc.Meta.MaxRetries {
+				task.Status = TaskStatusFailed
+				task.Result = fmt.Sprintf("interrupted %d times, giving up", tc.Meta.RetryCount)
+				task.EndedAt = time.Now()
+				b.emit(id, TraceEvent{Kind: TraceStatus, Status: string(task.Status), Detail: task.Result})
+				continue
+			}
+			task.Status = TaskStatusPending
+			b.emit(id, TraceEvent{Kind: TraceStatus, Status: string(task.Status)})
+			resumed = append(resumed, task)
+		}
+	}
+	b.mu.Unlock()
+	b.save()
+
+	for _, task := range resumed {
+		b.SendUpdate(task.Platform, task.ChatID, fmt.Sprintf("♻️ Resuming '%s' after restart.", task.Content))
+	}
 }
 
 func (b *Butler) save() {
@@ -284,36 +775,74 @@ func (b *Butler) save() {
 	defer b.mu.RUnlock()
 	path := config.TasksPath()
 	data, _ := json.MarshalIndent(b.tasks, "", "  ")
-	_ = os.WriteFile(path, data, 0644)
+	// Write then rename, so a crash never leaves tasks.json half written.
+	if err := os.WriteFile(path+".tmp", data, 0644); err == nil {
+		_ = os.Rename(path+".tmp", path)
+	}
 }
 
 func (b *Butler) StartTask(ctx context.Context, content string, platform string, chatID string, convID string) (*Task, error) {
-	b.mu.Lock()
 	id := fmt.Sprintf("task_%d", time.Now().Unix())
+	ctx, span := tracing.Start(ctx, "StartTask", "task_id", id, "platform", platform, "content", content)
+	defer span.End()
+
+	b.mu.Lock()
 	task := &Task{
-		ID:        id,
-		Content:   content,
-		Status:    TaskStatusPending,
-		StartedAt: time.Now(),
-		Platform:  platform,
-		ChatID:    chatID,
+		ID:          id,
+		Content:     content,
+		Status:      TaskStatusPending,
+		StartedAt:   time.Now(),
+		Platform:    platform,
+		ChatID:      chatID,
+		Priority:    priorityFor(platform),
+		Traceparent: span.Traceparent(),
+		Metadata:    taskMetadata(),
 	}
 	b.tasks[id] = task
 	b.mu.Unlock()
 	b.save()
+	b.emit(id, TraceEvent{Kind: TraceCreated, Detail: content})
+	ctx = logging.With(ctx, logging.KeyTask, id, logging.KeyPlatform, platform, logging.KeyChat, chatID)
+	logger.InfoContext(ctx, "task started")
 
 	go b.executeTask(id, content, convID)
 
 	return task, nil
 }
 
+// taskMetadata starts a task's metadata with the directory it was started
+// from: the caller's for the CLI and TUI, the daemon's for channels, missions
+// and rules. It focuses the task's fovea and knowledge recall.
+func taskMetadata() map[string]string {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil
+	}
+	return map[string]string{"workdir": cwd}
+}
+
 func (b *Butler) executeTask(id, content string, convID string) {
+	b.mu.RLock()
+	task := b.tasks[id]
+	b.mu.RUnlock()
+	// A deferred task stays pending for the nervous system to pick up once
+	// energy recovers.
+	if task == nil || b.shouldDefer(task) || !b.leaseTask(id) {
+		return
+	}
+
 	b.updateStatus(id, TaskStatusRunning, "")
-	ctx, cancel := context.WithTimeout(context.Background(), 90*time.Second)
+	ctx, span := b.startSpan(logging.With(context.Background(), logging.KeyTask, id), task, "executeTask")
+	defer span.End()
+	ctx, cancel := context.WithTimeout(ctx, 90*time.Second)
 	defer cancel()
 
+	start := time.Now()
 	resp, err := b.QueryWithContext(ctx, content, "vibe")
+	b.tracePrompt(id, "vibeauracle", "vibe", content, start, resp, err)
+	span.RecordError(err)
 	if err != nil {
+		logger.ErrorContext(ctx, "task failed", "err", err)
 		b.updateStatus(id, TaskStatusFailed, fmt.Sprintf("Error querying vibeauracle: %v", err))
 		return
 	}
@@ -341,14 +870,33 @@ func (b *Butler) executeTask(id, content string, convID string) {
 	}
 }
 
+// startSpan starts a span in the trace of task. Tasks created before
+// tracing was on get a trace of their own here.
+func (b *Butler) startSpan(ctx context.Context, task *Task, name string, args ...any) (context.Context, *tracing.Span) {
+	b.mu.RLock()
+	tp := task.Traceparent
+	b.mu.RUnlock()
+	ctx, span := tracing.Start(tracing.Resume(ctx, tp), name, append([]any{"task_id", task.ID}, args...)...)
+	if tp == "" && span != nil {
+		b.mu.Lock()
+		task.Traceparent = span.Traceparent()
+		b.mu.Unlock()
+	}
+	return ctx, span
+}
+
 func (b *Butler) updateStatus(id string, status TaskStatus, result string) {
 	b.mu.Lock()
 	if t, ok := b.tasks[id]; ok {
 		t.Status = status
 		t.Result = result
+		var took time.Duration
 		if status == TaskStatusCompleted || status == TaskStatusFailed {
 			t.EndedAt = time.Now()
+			took = t.EndedAt.Sub(t.StartedAt)
+			defer b.releaseTask(id)
 		}
+		b.emit(id, TraceEvent{Kind: TraceStatus, Status: string(status), Detail: result, Duration: took})
 	}
 	b.mu.Unlock()
 	b.save()
@@ -370,34 +918,6 @@ func (b *Butler) GetStatus() string {
 	return fmt.Sprintf("Auracrab: %d active, %d tasks done. System: Stable.", running, completed)
 }
 
-func (b *Butler) WatchHealth() string {
-	home, _ := os.UserHomeDir()
-	logPath := filepath.Join(home, ".vibeauracle", "vibeauracle.log")
-
-	data, err := os.ReadFile(logPath)
-	if err != nil {
-		return "System Health: OK (Vibeauracle logs not found, assuming fresh start)"
-	}
-
-	lines := strings.Split(string(data), "\n")
-	var errCount int
-	start := len(lines) - 50
-	if start < 0 {
-		start = 0
-	}
-
-	for _, line := range lines[start:] {
-		if strings.Contains(line, "error") || strings.Contains(line, "panic") {
-			errCount++
-		}
-	}
-
-	if errCount == 0 {
-		return "System Health: Excellent."
-	}
-	return fmt.Sprintf("System Health: Warning (%d anomalies detected). Recommend 'vibeaura doctor'.", errCount)
-}
-
 func (b *Butler) ListTasks() []*Task {
 	b.mu.RLock()
 	defer b.mu.RUnlock()
@@ -0,0 +1,84 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/nathfavour/auracrab/pkg/config"
+	"github.com/nathfavour/auracrab/pkg/immune"
+	"github.com/nathfavour/auracrab/pkg/spine"
+)
+
+// CellStatsPath is where the daemon keeps the spine's cell stats.
+func CellStatsPath() string {
+	return filepath.Join(config.StateDir(), "cells.json")
+}
+
+// cellOptions turns a cell's config into spine supervision options.
+func (b *Butler) cellOptions(name string) (config.CellConfig, []spine.CellOption) {
+	if b.Config == nil {
+		return config.CellConfig{}, nil
+	}
+	cc := b.Config.Cell(name)
+	var opts []spine.CellOption
+	if cc.Interval > 0 {
+		opts = append(opts, spine.Every(cc.Interval))
+	}
+	if cc.Timeout > 0 {
+		opts = append(opts, spine.Timeout(cc.Timeout))
+	}
+	return cc, opts
+}
+
+// attachCells attaches the nervous system and the opt-in cells.
+func (b *Butler) attachCells() {
+	// The nervous system hands work to its own goroutines, so a pulse
+	// timeout would cancel them; only its cadence is configurable.
+	nervous, _ := b.cellOptions("nervous")
+	var opts []spine.CellOption
+	if nervous.Interval > 0 {
+		opts = append(opts, spine.Every(nervous.Interval))
+	}
+	b.nervous = NewNervousSystem(b)
+	b.Spine.Attach(b.nervous, opts...)
+
+	if cc, opts := b.cellOptions("immune"); cc.Enabled {
+		nodeID, _ := os.Hostname()
+		is := immune.NewImmuneSystem(nodeID)
+		if config.Replica() != "" {
+			is.SetRole(immune.RoleWorker)
+		} else if b.Config != nil {
+			sc := b.Config.Swarm
+			is.SetReplicaPolicy(immune.ReplicaPolicy{
+				Min: sc.MinReplicas, Max: sc.MaxReplicas, Cooldown: sc.Cooldown, IdleAfter: sc.IdleAfter,
+			})
+			if sc.Listen != "" {
+				b.joinMesh(is, sc)
+			}
+		}
+		if err := is.Register(); err != nil {
+			logger.Error("immune system registration failed", "err", err)
+		}
+		is.SetHost(swarmHost{b})
+		b.swarm = is
+		b.Spine.Attach(is, opts...)
+	}
+
+	if cc, opts := b.cellOptions("reflex"); cc.Enabled {
+		b.Spine.Attach(NewReflexCell(b), opts...)
+	}
+}
+
+// joinMesh connects the immune system to other machines with this
+// machine's certificate from "auracrab swarm init".
+func (b *Butler) joinMesh(is *immune.ImmuneSystem, sc config.SwarmConfig) {
+	creds, err := immune.LoadCredentials(immune.TLSDir(immune.SwarmDir()))
+	if err != nil {
+		logger.Warn("network swarm disabled, run 'auracrab swarm init' first", "err", err)
+		return
+	}
+	cfg := immune.MeshConfig{Listen: sc.Listen, Peers: sc.Peers, Discovery: sc.Discovery}
+	if err := is.JoinMesh(cfg, creds); err != nil {
+		logger.Error("network swarm disabled", "err", err)
+	}
+}
@@ -0,0 +1,120 @@
+package core
+
+import (
+	"context"
+	"path/filepath"
+
+	"github.com/nathfavour/auracrab/pkg/config"
+	"github.com/nathfavour/auracrab/pkg/health"
+	"github.com/nathfavour/auracrab/pkg/rules"
+	"github.com/nathfavour/auracrab/pkg/social"
+	"github.com/nathfavour/auracrab/pkg/spine"
+)
+
+func init() {
+	spine.RegisterPayload("task.*", TraceEvent{})
+	spine.RegisterPayload("step.*", TraceEvent{})
+}
+
+// EventLogPath is where persisted bus events are appended. Each replica
+// keeps its own log, so that their rotations do not interleave.
+func EventLogPath() string {
+	return filepath.Join(config.StateDir(), "events", "events.jsonl")
+}
+
+// setupEvents enables event persistence and subscribes the task trace
+// recorder.
+func (b *Butler) setupEvents() {
+	if b.Config != nil && b.Config.Events.Persist {
+		b.Spine.Bus.Persist(EventLogPath(), int64(b.Config.Events.MaxSizeMB)<<20, b.Config.Events.Topics...)
+	}
+
+	// Traces must be complete, so the recorder pushes back instead of
+	// dropping events.
+	recorder := &traceRecorder{}
+	b.Spine.Bus.Handle("task.*", recorder.Handle, spine.Blocking())
+	b.Spine.Bus.Handle("step.*", recorder.Handle, spine.Blocking())
+}
+
+// startRules loads the configured event rules and starts evaluating them.
+func (b *Butler) startRules() *rules.Engine {
+	if b.Config == nil {
+		return nil
+	}
+	list, err := rules.Load(b.Config.Events.RulesFile)
+	if err != nil {
+		logger.Error("could not load event rules", "err", err)
+		return nil
+	}
+	if len(list) == 0 {
+		return nil
+	}
+	engine := rules.NewEngine(b.Spine.Bus, ruleActions{b}, list)
+	engine.Start()
+	logger.Info("event rules active", "rules", len(list))
+	return engine
+}
+
+// ruleActions carries out fired rules.
+type ruleActions struct {
+	b *Butler
+}
+
+func (a ruleActions) StartTask(goal string) error {
+	_, err := a.b.StartTask(context.Background(), goal, "rules", "internal", "")
+	return err
+}
+
+func (a ruleActions) Notify(text string) {
+	a.b.NotifyOwners(text)
+}
+
+// NotifyOwners messages the owner of every configured bot, or prints the
+// text when no owner is known.
+func (b *Butler) NotifyOwners(text string) {
+	sent := false
+	for _, bot := range social.GetBotManager().ListBots() {
+		if bot.OwnerID != "" {
+			b.SendUpdate(bot.Platform, bot.OwnerID, text)
+			sent = true
+		}
+	}
+	if !sent {
+		b.SendUpdate("", "", text)
+	}
+}
+
+// watchHealthEvents runs the health probes and publishes health.degraded
+// when a probe stops being ok and health.recovered when it is ok again.
+func (b *Butler) watchHealthEvents(ctx context.Context) {
+	rep := b.probes.Run(ctx)
+
+	var changes []spine.Event
+	b.mu.Lock()
+	for _, res := range rep.Results {
+		was := b.failing[res.Name]
+		now := res.Status != health.StatusOK
+		b.failing[res.Name] = now
+		switch {
+		case now && !was:
+			changes = append(changes, spine.Event{Type: spine.TopicHealthDegraded, Payload: spine.HealthChange{
+				Subsystem: res.Name, Reason: res.Reason,
+			}})
+		case !now && was:
+			changes = append(changes, spine.Event{Type: spine.TopicHealthRecovered, Payload: spine.HealthChange{
+				Subsystem: res.Name, Reason: res.Reason,
+			}})
+		}
+	}
+	b.mu.Unlock()
+
+	for _, e := range changes {
+		c := e.Payload.(spine.HealthChange)
+		if e.Type == spine.TopicHealthDegraded {
+			logger.WarnContext(ctx, "health check failing", "probe", c.Subsystem, "reason", c.Reason, "status", rep.Status)
+		} else {
+			logger.InfoContext(ctx, "health check recovered", "probe", c.Subsystem, "status", rep.Status)
+		}
+		b.Spine.Broadcast(e)
+	}
+}
@@ -0,0 +1,18 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/nathfavour/auracrab/pkg/config"
+)
+
+func TestEventLogPathPerReplica(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	main := EventLogPath()
+
+	config.SetReplica("worker-1")
+	defer config.SetReplica("")
+	if replica := EventLogPath(); replica == main {
+		t.Fatalf("replica and main process share the event log %s", main)
+	}
+}
@@ -0,0 +1,408 @@
+package core
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nathfavour/auracrab/pkg/memory"
+	"github.com/nathfavour/auracrab/pkg/schema"
+)
+
+const (
+	maxFoveaFiles    = 5
+	maxTopologyFiles = 40
+	maxScanFiles     = 5000
+	maxDeltaBytes    = 4096
+	recentWindow     = 48 * time.Hour
+)
+
+// Candidate weights. A file named in the goal almost always matters; a dirty
+// file in git is likely what the user is working on.
+const (
+	weightMentioned = 10.0
+	weightGitDirty  = 5.0
+	weightRecent    = 2.0
+	weightManifest  = 1.0
+	weightRelevance = 1.5
+)
+
+// dependencyManifests are read to describe the project's dependencies.
+var dependencyManifests = []string{
+	"go.mod", "package.json", "Cargo.toml", "requirements.txt", "pyproject.toml",
+}
+
+var skipDirs = map[string]bool{
+	".git": true, "node_modules": true, "vendor": true, "dist": true,
+	"build": true, "target": true, "__pycache__": true, ".venv": true,
+}
+
+var mentionPattern = regexp.MustCompile(`[\w./-]*[\w-]+\.[A-Za-z0-9]{1,6}\b|[\w.-]+(?:/[\w.-]+)+`)
+
+// FoveaCandidate is a file considered for the fovea with the reasons it scored.
+type FoveaCandidate struct {
+	Path    string
+	Score   float64
+	Reasons []string
+}
+
+// FoveaBuilder selects the files a task is most likely talking about from
+// the working tree, git state and the task's own text.
+type FoveaBuilder struct {
+	Root string
+
+	files    []string // Relative, slash-separated
+	inGit    bool
+	dirty    []string
+	recent   []string
+	manifest []string
+
+	// The diff and dependencies are read once, so a builder can be reused.
+	described sync.Once
+	deltas    string
+	deps      []string
+}
+
+// NewFoveaBuilder scans dir (or the enclosing git work tree) for candidates.
+func NewFoveaBuilder(ctx context.Context, dir string) *FoveaBuilder {
+	if dir == "" {
+		dir, _ = os.Getwd()
+	}
+	fb := &FoveaBuilder{Root: dir}
+	if top, err := git(ctx, dir, "rev-parse", "--show-toplevel"); err == nil && top != "" {
+		fb.Root = strings.TrimSpace(top)
+		fb.inGit = true
+	}
+	fb.scan(ctx)
+	return fb
+}
+
+func git(ctx context.Context, dir string, args ...string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "git", append([]string{"-C", dir}, args...)...)
+	out, err := cmd.Output()
+	return string(out), err
+}
+
+func (fb *FoveaBuilder) scan(ctx context.Context) {
+	if fb.inGit {
+		if out, err := git(ctx, fb.Root, "ls-files", "--cached", "--others", "--exclude-standard"); err == nil {
+			for _, l := range strings.Split(out, "\n") {
+				if l = strings.TrimSpace(l); l != "" {
+					fb.files = append(fb.files, l)
+				}
+				if len(fb.files) >= maxScanFiles {
+					break
+				}
+			}
+		}
+		if out, err := git(ctx, fb.Root, "status", "--porcelain"); err == nil {
+			for _, l := range strings.Split(out, "\n") {
+				if len(l) < 4 {
+					continue
+				}
+				p := strings.TrimSpace(l[3:])
+				// Renames are reported as "old -> new".
+				if i := strings.Index(p, " -> "); i >= 0 {
+					p = p[i+4:]
+				}
+				if l[:2] != " D" && l[:2] != "D " {
+					fb.dirty = append(fb.dirty, strings.Trim(p, `"`))
+				}
+			}
+		}
+	} else {
+		_ = filepath.WalkDir(fb.Root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return nil
+			}
+			if d.IsDir() {
+				if path != fb.Root && (skipDirs[d.Name()] || strings.HasPrefix(d.Name(), ".")) {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if len(fb.files) >= maxScanFiles {
+				return filepath.SkipAll
+			}
+			if rel, err := filepath.Rel(fb.Root, path); err == nil {
+				fb.files = append(fb.files, filepath.ToSlash(rel))
+			}
+			return nil
+		})
+	}
+
+	type modified struct {
+		path string
+		at   time.Time
+	}
+	var recent []modified
+	cutoff := time.Now().Add(-recentWindow)
+	for _, f := range fb.files {
+		base := filepath.Base(f)
+		for _, m := range dependencyManifests {
+			if base == m && !strings.Contains(f, "/") {
+				fb.manifest = append(fb.manifest, f)
+			}
+		}
+		if info, err := os.Stat(filepath.Join(fb.Root, f)); err == nil && info.ModTime().After(cutoff) {
+			recent = append(recent, modified{f, info.ModTime()})
+		}
+	}
+	sort.Slice(recent, func(i, j int) bool { return recent[i].at.After(recent[j].at) })
+	for i, r := range recent {
+		if i == maxTopologyFiles {
+			break
+		}
+		fb.recent = append(fb.recent, r.path)
+	}
+}
+
+// Rank scores every known file against the task text. texts are the goal
+// and any previous step results.
+func (fb *FoveaBuilder) Rank(texts ...string) []FoveaCandidate {
+	scores := map[string]*FoveaCandidate{}
+	add := func(p string, w float64, reason string) {
+		c, ok := scores[p]
+		if !ok {
+			c = &FoveaCandidate{Path: p}
+			scores[p] = c
+		}
+		c.Score += w
+		c.Reasons = append(c.Reasons, reason)
+	}
+
+	byBase := map[string][]string{}
+	known := map[string]bool{}
+	for _, f := range fb.files {
+		known[f] = true
+		byBase[filepath.Base(f)] = append(byBase[filepath.Base(f)], f)
+	}
+
+	for _, text := range texts {
+		for _, m := range mentionPattern.FindAllString(text, -1) {
+			m = strings.TrimPrefix(m, fb.Root+"/")
+			m = strings.TrimRight(strings.TrimPrefix(m, "./"), ".")
+			switch {
+			case known[m]:
+				add(m, weightMentioned, "mentioned")
+			case len(byBase[m]) > 0 && len(byBase[m]) <= 3:
+				for _, f := range byBase[m] {
+					add(f, weightMentioned/float64(len(byBase[m])), "mentioned")
+				}
+			}
+		}
+	}
+
+	for _, f := range fb.dirty {
+		add(f, weightGitDirty, "git")
+	}
+	for i, f := range fb.recent {
+		// Newer files weigh more.
+		add(f, weightRecent*(1-float64(i)/float64(len(fb.recent)+1)), "recent")
+	}
+	for _, f := range fb.manifest {
+		add(f, weightManifest, "manifest")
+	}
+
+	words := map[string]bool{}
+	for _, text := range texts {
+		for _, w := range memory.Tokenize(text) {
+			if len(w) > 2 {
+				words[w] = true
+			}
+		}
+	}
+	// Path relevance only refines candidates that already have a signal,
+	// otherwise every file sharing a common word would be pulled in.
+	for p, c := range scores {
+		hits := 0
+		for _, w := range memory.Tokenize(strings.TrimSuffix(p, filepath.Ext(p))) {
+			if words[w] {
+				hits++
+			}
+		}
+		if hits > 0 {
+			c.Score += weightRelevance * float64(hits)
+			c.Reasons = append(c.Reasons, "relevant")
+		}
+	}
+
+	list := make([]FoveaCandidate, 0, len(scores))
+	for _, c := range scores {
+		list = append(list, *c)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Score != list[j].Score {
+			return list[i].Score > list[j].Score
+		}
+		return list[i].Path < list[j].Path
+	})
+	return list
+}
+
+// Topology describes the project around the fovea.
+func (fb *FoveaBuilder) Topology(ctx context.Context, ranked []FoveaCandidate) schema.ProjectTopology {
+	fb.described.Do(func() { fb.describe(ctx) })
+	topo := schema.ProjectTopology{
+		ModifiedRecently: append(append([]string{}, fb.dirty...), fb.recent...),
+		Dependencies:     append([]string{}, fb.deps...),
+		Deltas:           fb.deltas,
+	}
+	topo.ModifiedRecently = dedupe(topo.ModifiedRecently)
+	if len(topo.ModifiedRecently) > maxTopologyFiles {
+		topo.ModifiedRecently = topo.ModifiedRecently[:maxTopologyFiles]
+	}
+	for i, c := range ranked {
+		if i == maxTopologyFiles {
+			break
+		}
+		topo.Files = append(topo.Files, c.Path)
+	}
+	return topo
+}
+
+// describe reads the uncommitted diff and the dependencies.
+func (fb *FoveaBuilder) describe(ctx context.Context) {
+	fb.deps = fb.dependencies()
+	if !fb.inGit {
+		return
+	}
+	if diff, err := git(ctx, fb.Root, "diff", "HEAD", "--stat"); err == nil {
+		fb.deltas = diff
+	}
+	if diff, err := git(ctx, fb.Root, "diff", "HEAD", "-U1"); err == nil && diff != "" {
+		fb.deltas += "\n" + diff
+	}
+	if len(fb.deltas) > maxDeltaBytes {
+		fb.deltas = fb.deltas[:maxDeltaBytes] + "\n... [DIFF TRUNCATED] ..."
+	}
+}
+
+// foveaScan is a task's scanned working tree, shared by the queries of one
+// pulse.
+type foveaScan struct {
+	once sync.Once
+	fb   *FoveaBuilder
+}
+
+func (fb *FoveaBuilder) dependencies() []string {
+	var deps []string
+	for _, m := range fb.manifest {
+		data, err := os.ReadFile(filepath.Join(fb.Root, m))
+		if err != nil {
+			continue
+		}
+		switch filepath.Base(m) {
+		case "go.mod":
+			deps = append(deps, goModDeps(string(data))...)
+		case "package.json":
+			var pkg struct {
+				Dependencies    map[string]string `json:"dependencies"`
+				DevDependencies map[string]string `json:"devDependencies"`
+			}
+			if json.Unmarshal(data, &pkg) == nil {
+				for name, v := range pkg.Dependencies {
+					deps = append(deps, name+"@"+v)
+				}
+				for name, v := range pkg.DevDependencies {
+					deps = append(deps, name+"@"+v+" (dev)")
+				}
+			}
+		case "requirements.txt":
+			for _, l := range strings.Split(string(data), "\n") {
+				if l = strings.TrimSpace(l); l != "" && !strings.HasPrefix(l, "#") {
+					deps = append(deps, l)
+				}
+			}
+		default:
+			deps = append(deps, m)
+		}
+	}
+	sort.Strings(deps)
+	return deps
+}
+
+func goModDeps(content string) []string {
+	var deps []string
+	inBlock := false
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case strings.HasPrefix(line, "require ("):
+			inBlock = true
+			continue
+		case inBlock && line == ")":
+			inBlock = false
+			continue
+		case strings.HasPrefix(line, "require "):
+			line = strings.TrimPrefix(line, "require ")
+		case !inBlock:
+			continue
+		}
+		// Indirect dependencies are noise for the model.
+		if strings.Contains(line, "// indirect") {
+			continue
+		}
+		if f := strings.Fields(line); len(f) >= 2 {
+			deps = append(deps, f[0]+"@"+f[1])
+		}
+	}
+	return deps
+}
+
+func dedupe(list []string) []string {
+	seen := map[string]bool{}
+	out := list[:0]
+	for _, s := range list {
+		if !seen[s] {
+			seen[s] = true
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// Build fills a Fovea with the best ranked files and the project topology.
+// Existing Files on the fovea are kept and take precedence.
+func (fb *FoveaBuilder) Build(ctx context.Context, fovea *Fovea, texts ...string) *Fovea {
+	if fovea == nil {
+		fovea = &Fovea{}
+	}
+	ranked := fb.Rank(texts...)
+	fovea.WorkingDir = fb.Root
+
+	have := map[string]bool{}
+	for _, f := range fovea.Files {
+		have[f] = true
+	}
+	for _, c := range ranked {
+		if len(fovea.Files) >= maxFoveaFiles {
+			break
+		}
+		abs := filepath.Join(fb.Root, filepath.FromSlash(c.Path))
+		if have[abs] {
+			continue
+		}
+		if info, err := os.Stat(abs); err != nil || info.IsDir() {
+			continue
+		}
+		fovea.Files = append(fovea.Files, abs)
+		have[abs] = true
+	}
+
+	topo := fb.Topology(ctx, ranked)
+	fovea.Topology = &topo
+	return fovea
+}
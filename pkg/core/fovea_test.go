@@ -0,0 +1,64 @@
+package core
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFoveaBuilder_MentionedFilesRankFirst(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod":             "module example.com/x\n\nrequire (\n\tgithub.com/spf13/cobra v1.8.0\n\tgolang.org/x/sys v0.1.0 // indirect\n)\n",
+		"pkg/server/http.go": "package server\n",
+		"pkg/client/api.go":  "package client\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		_ = os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctx := context.Background()
+	fovea := NewFoveaBuilder(ctx, dir).Build(ctx, nil, "fix the timeout in http.go")
+	if len(fovea.Files) == 0 || fovea.Files[0] != filepath.Join(fovea.WorkingDir, "pkg/server/http.go") {
+		t.Fatalf("expected http.go to lead the fovea, got %v", fovea.Files)
+	}
+	if fovea.Topology == nil || len(fovea.Topology.Dependencies) != 1 || fovea.Topology.Dependencies[0] != "github.com/spf13/cobra@v1.8.0" {
+		t.Errorf("unexpected dependencies: %+v", fovea.Topology)
+	}
+}
+
+func TestFoveaFor_FocusesTheStartingDirOncePerPulse(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("ship it\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	b := &Butler{}
+
+	// StartTask records the directory a task was started from.
+	t.Chdir(dir)
+	task := &Task{ID: "t1", Metadata: taskMetadata()}
+	workdir := task.Metadata["workdir"]
+	if workdir == "" {
+		t.Fatal("a started task must record its working directory")
+	}
+	fovea := b.foveaFor(ctx, task, nil, "update notes.txt")
+	if fovea.Topology == nil || len(fovea.Files) == 0 || filepath.Base(fovea.Files[0]) != "notes.txt" {
+		t.Fatalf("expected the starting directory to be focused, got %+v", fovea)
+	}
+
+	first := b.foveaBuilder(ctx, task.ID, workdir)
+	b.foveaFor(ctx, task, nil, "notes.txt")
+	if b.foveaBuilder(ctx, task.ID, workdir) != first || len(b.foveas) != 1 {
+		t.Error("the scan must be reused within a pulse")
+	}
+	b.forgetFoveas()
+	if b.foveaBuilder(ctx, task.ID, workdir) == first {
+		t.Error("a new pulse must rescan the tree")
+	}
+}
@@ -0,0 +1,212 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/nathfavour/auracrab/pkg/config"
+	"github.com/nathfavour/auracrab/pkg/health"
+	"github.com/nathfavour/auracrab/pkg/social"
+	"github.com/nathfavour/auracrab/pkg/spine"
+	"github.com/nathfavour/auracrab/pkg/vault"
+	"github.com/nathfavour/auracrab/pkg/vibe"
+)
+
+// healthMaxAge is how long a health report is served before the probes
+// run again.
+const healthMaxAge = 10 * time.Second
+
+// Health thresholds.
+const (
+	diskDegradedBelow = 1 << 30   // 1 GiB
+	diskDownBelow     = 100 << 20 // 100 MiB
+	// The spine slows to one beat every 100s when idle, so a longer gap
+	// means it has stalled.
+	spineStalledAfter = 3 * time.Minute
+	spineLagDegraded  = 5 * time.Second
+	// A running task that has not checkpointed for this long is stuck.
+	taskStuckAfter = 30 * time.Minute
+)
+
+// errChannelConnecting is the state of a channel whose Start has not
+// returned yet.
+var errChannelConnecting = errors.New("connecting")
+
+// pinger is implemented by providers that can check they are reachable
+// without running a query.
+type pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// registerProbes adds the checks that apply to every butler. Channels
+// add their own when they start.
+func (b *Butler) registerProbes() {
+	vibeCritical := b.Config == nil || b.Config.Inference.ActiveProvider == "" || b.Config.Inference.ActiveProvider == "vibe"
+	b.probes.Register(health.Probe{Name: "vibe", Critical: vibeCritical, Check: probeVibe})
+	b.probes.Register(health.Probe{Name: "provider", Check: b.probeProvider})
+	b.probes.Register(health.Probe{Name: "disk", Critical: true,
+		Check: health.Disk(config.DataDir(), diskDegradedBelow, diskDownBelow)})
+	b.probes.Register(health.Probe{Name: "sqlite", Critical: true, Check: b.probeSQLite})
+	b.probes.Register(health.Probe{Name: "spine", Critical: true, Check: b.probeSpine})
+	b.probes.Register(health.Probe{Name: "tasks", Check: b.probeTasks})
+	b.probes.Register(health.Probe{Name: "vault", Check: probeVault})
+}
+
+// Health runs the probes, or returns their report when it is recent.
+func (b *Butler) Health(ctx context.Context) health.Report {
+	return b.probes.Report(ctx, healthMaxAge)
+}
+
+// LastHealth returns the latest report without waiting on the probes,
+// refreshing it in the background when it is stale. Interactive views use
+// it so a hung probe cannot freeze them.
+func (b *Butler) LastHealth() health.Report {
+	return b.probes.Last(healthMaxAge)
+}
+
+func probeVibe(ctx context.Context) (health.Status, string) {
+	start := time.Now()
+	if err := vibe.NewClient().Ping(); err != nil {
+		return health.StatusDown, err.Error()
+	}
+	return health.StatusOK, fmt.Sprintf("socket answered in %s", time.Since(start).Round(time.Millisecond))
+}
+
+func (b *Butler) probeProvider(ctx context.Context) (health.Status, string) {
+	p := b.inferenceProvider()
+	if p.Name() == "vibe" {
+		return health.StatusOK, "vibe is the active provider"
+	}
+	pp, ok := p.(pinger)
+	if !ok {
+		return health.StatusOK, p.Name() + " cannot be checked without a query"
+	}
+	if err := pp.Ping(ctx); err != nil {
+		// Queries fall back to vibe, so the daemon keeps working.
+		return health.StatusDown, fmt.Sprintf("%s: %v; queries fall back to vibe", p.Name(), err)
+	}
+	return health.StatusOK, p.Name() + " is reachable"
+}
+
+func (b *Butler) probeSQLite(ctx context.Context) (health.Status, string) {
+	if b.History == nil {
+		return health.StatusDown, "the history database could not be opened"
+	}
+	if err := b.History.Integrity(ctx); err != nil {
+		return health.StatusDown, err.Error()
+	}
+	return health.StatusOK, "history database passed its integrity check"
+}
+
+// probeSpine checks the heartbeat of this process's spine, or the cell
+// stats the daemon writes when the spine runs elsewhere.
+func (b *Butler) probeSpine(ctx context.Context) (health.Status, string) {
+	last := b.Spine.LastBeat()
+	lag := b.Spine.PulseLag()
+	if last.IsZero() {
+		stats, err := spine.ReadStats(CellStatsPath())
+		if err != nil {
+			return health.StatusDown, "the spine has never pulsed; is the daemon running?"
+		}
+		for _, s := range stats {
+			if s.LastPulse.After(last) {
+				last = s.LastPulse
+			}
+		}
+		if last.IsZero() {
+			return health.StatusDown, "no cell has pulsed yet"
+		}
+	}
+	since := time.Since(last)
+	switch {
+	case since > spineStalledAfter:
+		return health.StatusDown, fmt.Sprintf("last pulse %s ago", since.Round(time.Second))
+	case lag > spineLagDegraded:
+		return health.StatusDegraded, fmt.Sprintf("pulses run %s late", lag.Round(time.Millisecond))
+	}
+	return health.StatusOK, fmt.Sprintf("last pulse %s ago", since.Round(time.Second))
+}
+
+func (b *Butler) probeTasks(ctx context.Context) (health.Status, string) {
+	var stuck []string
+	running := 0
+	b.mu.RLock()
+	for _, t := range b.tasks {
+		if t.Status != TaskStatusRunning {
+			continue
+		}
+		running++
+		progress := t.StartedAt
+		if t.Continuity != nil && t.Continuity.LastCheckpoint > 0 {
+			if cp := time.Unix(t.Continuity.LastCheckpoint, 0); cp.After(progress) {
+				progress = cp
+			}
+		}
+		if time.Since(progress) > taskStuckAfter {
+			stuck = append(stuck, t.ID)
+		}
+	}
+	b.mu.RUnlock()
+	if len(stuck) > 0 {
+		sort.Strings(stuck)
+		return health.StatusDegraded, fmt.Sprintf("no progress for over %s: %s", taskStuckAfter, strings.Join(stuck, ", "))
+	}
+	return health.StatusOK, fmt.Sprintf("%d running", running)
+}
+
+func probeVault(ctx context.Context) (health.Status, string) {
+	keys, err := vault.GetVault().List()
+	if err != nil {
+		return health.StatusDown, err.Error()
+	}
+	return health.StatusOK, fmt.Sprintf("%d secrets readable", len(keys))
+}
+
+// watchChannel adds a probe for a messaging channel being started.
+func (b *Butler) watchChannel(name string) func(error) {
+	b.setChannelState(name, errChannelConnecting)
+	b.probes.Register(health.Probe{Name: "channel:" + name, Check: func(ctx context.Context) (health.Status, string) {
+		b.mu.RLock()
+		err := b.channels[name]
+		b.mu.RUnlock()
+		return connectionStatus(err)
+	}})
+	return func(err error) { b.setChannelState(name, err) }
+}
+
+func (b *Butler) setChannelState(name string, err error) {
+	b.mu.Lock()
+	b.channels[name] = err
+	b.mu.Unlock()
+}
+
+// watchBots adds a probe for each configured social bot.
+func (b *Butler) watchBots() {
+	for _, bot := range social.GetBotManager().ListBots() {
+		name := bot.Name
+		if name == "" {
+			name = bot.Platform
+		}
+		b.probes.Register(health.Probe{Name: "bot:" + name, Check: func(ctx context.Context) (health.Status, string) {
+			err, ok := social.GetBotManager().Connections()[name]
+			if !ok {
+				err = social.ErrConnecting
+			}
+			return connectionStatus(err)
+		}})
+	}
+}
+
+func connectionStatus(err error) (health.Status, string) {
+	switch {
+	case err == nil:
+		return health.StatusOK, "connected"
+	case errors.Is(err, errChannelConnecting), errors.Is(err, social.ErrConnecting):
+		return health.StatusDegraded, "still connecting"
+	}
+	return health.StatusDown, err.Error()
+}
@@ -0,0 +1,39 @@
+package core
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nathfavour/auracrab/pkg/health"
+	"github.com/nathfavour/auracrab/pkg/schema"
+)
+
+func TestProbeTasksReportsStuckTasks(t *testing.T) {
+	old := time.Now().Add(-2 * taskStuckAfter)
+	progressing := schema.NewTaskContinuity("t2", "build", string(TaskStatusRunning))
+	progressing.LastCheckpoint = time.Now().Unix()
+
+	b := &Butler{tasks: map[string]*Task{
+		"t1": {ID: "t1", Status: TaskStatusRunning, StartedAt: old},
+		"t2": {ID: "t2", Status: TaskStatusRunning, StartedAt: old, Continuity: progressing},
+		"t3": {ID: "t3", Status: TaskStatusCompleted, StartedAt: old},
+	}}
+	status, reason := b.probeTasks(context.Background())
+	if status != health.StatusDegraded || !strings.HasSuffix(reason, ": t1") {
+		t.Fatalf("probeTasks() = %s, %q; want degraded naming only t1", status, reason)
+	}
+}
+
+func TestConnectionStatus(t *testing.T) {
+	if s, _ := connectionStatus(nil); s != health.StatusOK {
+		t.Errorf("connected channel is %s", s)
+	}
+	if s, _ := connectionStatus(errChannelConnecting); s != health.StatusDegraded {
+		t.Errorf("connecting channel is %s", s)
+	}
+	if s, r := connectionStatus(context.DeadlineExceeded); s != health.StatusDown || r == "" {
+		t.Errorf("failed channel is %s, %q", s, r)
+	}
+}
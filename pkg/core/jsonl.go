@@ -0,0 +1,62 @@
+package core
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+)
+
+// appendCapped appends line to the JSONL log at path. Logs hold prompts and
+// replies, so they are readable by the owner only. Once the file grows past
+// maxBytes, shrink gets its lines and returns the ones to keep, and the
+// file is rewritten.
+func appendCapped(path string, line []byte, maxBytes int64, shrink func(lines [][]byte) [][]byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	// Logs written by earlier versions were world-readable.
+	_ = f.Chmod(0600)
+	_, err = f.Write(append(line, '\n'))
+	info, statErr := f.Stat()
+	f.Close()
+	if err != nil || statErr != nil || info.Size() <= maxBytes {
+		return err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	lines := bytes.Split(bytes.TrimRight(data, "\n"), []byte("\n"))
+	kept := shrink(lines)
+	out := bytes.Join(kept, []byte("\n"))
+	if len(kept) > 0 {
+		out = append(out, '\n')
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, out, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// keepNewest is a shrink function keeping the newest lines that fit in
+// half of maxBytes, and at least the last one.
+func keepNewest(maxBytes int64) func(lines [][]byte) [][]byte {
+	return func(lines [][]byte) [][]byte {
+		size := int64(0)
+		i := len(lines)
+		for i > 0 {
+			size += int64(len(lines[i-1])) + 1
+			if size > maxBytes/2 && i < len(lines) {
+				break
+			}
+			i--
+		}
+		return lines[i:]
+	}
+}
@@ -0,0 +1,119 @@
+package core
+
+import (
+	"context"
+	"time"
+
+	"github.com/nathfavour/auracrab/internal/provider"
+	"github.com/nathfavour/auracrab/pkg/health"
+	"github.com/nathfavour/auracrab/pkg/metrics"
+)
+
+var (
+	stepDuration = metrics.NewHistogram("auracrab_step_duration_seconds",
+		"How long plan steps ran, by the status they ended in.", nil, "status")
+	providerLatency = metrics.NewHistogram("auracrab_provider_request_duration_seconds",
+		"Latency of model queries by provider.", nil, "provider")
+	providerErrors = metrics.NewCounter("auracrab_provider_errors_total",
+		"Model queries that failed, by provider.", "provider")
+	providerTokens = metrics.NewCounter("auracrab_provider_tokens_total",
+		"Estimated tokens sent to (prompt) and received from (completion) each provider.", "provider", "kind")
+	channelMessages = metrics.NewCounter("auracrab_channel_messages_total",
+		"Chat messages received (in) and sent (out), by platform.", "platform", "direction")
+	watchBatches = metrics.NewCounter("auracrab_watch_batches_total",
+		"Batches of changed files acted on, by watch rule.", "rule")
+)
+
+func init() {
+	metrics.NewGaugeFunc("auracrab_tasks", "Tasks by status.", []string{"status"}, func(emit metrics.Emit) {
+		b := instance
+		if b == nil {
+			return
+		}
+		counts := map[TaskStatus]int{}
+		b.mu.RLock()
+		for _, t := range b.tasks {
+			counts[t.Status]++
+		}
+		b.mu.RUnlock()
+		for _, s := range []TaskStatus{TaskStatusPending, TaskStatusRunning, TaskStatusPaused, TaskStatusCompleted, TaskStatusFailed} {
+			emit(float64(counts[s]), string(s))
+		}
+	})
+	metrics.NewGaugeFunc("auracrab_queue_depth",
+		"Work waiting to run: pending tasks, and pending steps of unfinished tasks.", []string{"kind"},
+		func(emit metrics.Emit) {
+			b := instance
+			if b == nil {
+				return
+			}
+			tasks, steps, _ := b.queue()
+			emit(float64(tasks), "tasks")
+			emit(float64(steps), "steps")
+		})
+	metrics.NewGaugeFunc("auracrab_approvals_pending",
+		"Steps waiting for a human reply.", nil,
+		func(emit metrics.Emit) {
+			b := instance
+			if b == nil {
+				return
+			}
+			_, _, waiting := b.queue()
+			emit(float64(waiting))
+		})
+}
+
+// queue counts pending tasks, pending steps of unfinished tasks and steps
+// waiting for a human reply.
+func (b *Butler) queue() (tasks, steps, waiting int) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for _, t := range b.tasks {
+		if t.Status == TaskStatusPending {
+			tasks++
+		}
+		if t.Continuity == nil || t.Status == TaskStatusCompleted || t.Status == TaskStatusFailed {
+			continue
+		}
+		for _, s := range t.Continuity.Plan {
+			switch StepStatus(s.Status) {
+			case StepPending:
+				steps++
+			case StepWaiting:
+				waiting++
+			}
+		}
+	}
+	return tasks, steps, waiting
+}
+
+// observeQuery records a model query for the provider metrics.
+func observeQuery(providerName, prompt string, start time.Time, resp provider.CompletionResponse, err error) {
+	providerLatency.Observe(time.Since(start).Seconds(), providerName)
+	if err != nil {
+		providerErrors.Inc(providerName)
+	}
+	est := HeuristicEstimator{}
+	providerTokens.Add(float64(est.Estimate(prompt)), providerName, "prompt")
+	providerTokens.Add(float64(est.Estimate(resp.Content)), providerName, "completion")
+}
+
+// serveMetrics serves /metrics and /healthz until ctx is done, when the
+// config turns the endpoint on.
+func (b *Butler) serveMetrics(ctx context.Context) {
+	if b.Config == nil || b.Config.Metrics.Listen == "" {
+		return
+	}
+	srv, err := metrics.Listen(b.Config.Metrics.Listen)
+	if err != nil {
+		logger.Error("metrics endpoint disabled", "err", err)
+		return
+	}
+	srv.Handle("/healthz", health.Handler(b.probes, healthMaxAge))
+	logger.Info("serving metrics", "url", "http://"+srv.Addr()+"/metrics", "health", "http://"+srv.Addr()+"/healthz")
+	go func() {
+		if err := srv.Serve(ctx); err != nil {
+			logger.Error("metrics endpoint stopped", "err", err)
+		}
+	}()
+}
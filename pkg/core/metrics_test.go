@@ -0,0 +1,50 @@
+package core
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/nathfavour/auracrab/pkg/metrics"
+	"github.com/nathfavour/auracrab/pkg/schema"
+)
+
+func TestQueueCountsPendingWork(t *testing.T) {
+	waiting := schema.NewTaskContinuity("t2", "deploy", string(TaskStatusRunning))
+	waiting.Plan = []schema.ContinuityStep{
+		{ID: "s1", Status: string(StepCompleted)},
+		{ID: "s2", Status: string(StepWaiting)},
+		{ID: "s3", Status: string(StepPending)},
+	}
+	done := schema.NewTaskContinuity("t3", "old", string(TaskStatusFailed))
+	done.Plan = []schema.ContinuityStep{{ID: "s1", Status: string(StepPending)}}
+
+	b := &Butler{tasks: map[string]*Task{
+		"t1": {ID: "t1", Status: TaskStatusPending},
+		"t2": {ID: "t2", Status: TaskStatusRunning, Continuity: waiting},
+		"t3": {ID: "t3", Status: TaskStatusFailed, Continuity: done},
+	}}
+	tasks, steps, approvals := b.queue()
+	if tasks != 1 || steps != 1 || approvals != 1 {
+		t.Fatalf("queue() = %d tasks, %d steps, %d approvals; want 1, 1, 1", tasks, steps, approvals)
+	}
+}
+
+func TestMetricsCoverTheDaemon(t *testing.T) {
+	var b strings.Builder
+	if err := metrics.Default.Write(&b); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{
+		"auracrab_tasks", "auracrab_step_duration_seconds", "auracrab_queue_depth",
+		"auracrab_provider_request_duration_seconds", "auracrab_provider_errors_total",
+		"auracrab_provider_tokens_total", "auracrab_provider_fallbacks_total",
+		"auracrab_channel_messages_total", "auracrab_approvals_pending",
+		"auracrab_spine_pulse_lag_seconds", "auracrab_energy_level",
+		"auracrab_vector_store_chunks", "auracrab_cron_runs_total",
+		"auracrab_metabolism_energy_burned_total",
+	} {
+		if !strings.Contains(b.String(), "# TYPE "+name+" ") {
+			t.Errorf("%s is not exported", name)
+		}
+	}
+}
@@ -3,33 +3,50 @@ package core
 import (
 	"context"
 	"fmt"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/nathfavour/auracrab/pkg/biology"
+	"github.com/nathfavour/auracrab/pkg/logging"
 	"github.com/nathfavour/auracrab/pkg/memory"
 	"github.com/nathfavour/auracrab/pkg/mission"
+	"github.com/nathfavour/auracrab/pkg/prompts"
 	"github.com/nathfavour/auracrab/pkg/schema"
+	"github.com/nathfavour/auracrab/pkg/spine"
 )
 
+// nervousLog is the nervous system's own subsystem, so planning can be
+// traced at debug level without the rest of the butler.
+var nervousLog = logging.For("nervous")
+
 type StepStatus string
 
 const (
 	StepPending   StepStatus = "pending"
 	StepRunning   StepStatus = "running"
+	StepWaiting   StepStatus = "waiting" // Waiting for a human reply
 	StepCompleted StepStatus = "completed"
+	StepSkipped   StepStatus = "skipped"
 	StepFailed    StepStatus = "failed"
 )
 
+// DefaultStepWorkers caps how many steps run concurrently across all tasks.
+const DefaultStepWorkers = 4
+
 type NervousSystem struct {
-	mu     sync.RWMutex
-	butler *Butler
+	mu       sync.RWMutex
+	butler   *Butler
+	workers  chan struct{}
+	planning map[string]bool
 }
 
 func NewNervousSystem(b *Butler) *NervousSystem {
 	return &NervousSystem{
-		butler: b,
+		butler:   b,
+		workers:  make(chan struct{}, DefaultStepWorkers),
+		planning: make(map[string]bool),
 	}
 }
 
@@ -39,8 +56,12 @@ func (ns *NervousSystem) Name() string {
 
 // Pulse implements spine.Cell
 func (ns *NervousSystem) Pulse(ctx context.Context) error {
-	// 1. Process Missions into Tasks
-	ns.processMissions(ctx)
+	ns.butler.forgetFoveas()
+
+	// 1. Process Missions into Tasks; in a swarm only the leader dispatches
+	if ns.butler.isLeader() {
+		ns.processMissions(ctx)
+	}
 
 	// 2. Process all Tasks
 	tasks := ns.butler.ListTasks()
@@ -49,33 +70,133 @@ func (ns *NervousSystem) Pulse(ctx context.Context) error {
 		if task.Status != TaskStatusRunning && task.Status != TaskStatusPending {
 			continue
 		}
-
-		if task.Continuity == nil {
-			task.Continuity = &schema.TaskContinuity{
-				Version: "1.0",
-				TaskID:  task.ID,
-				Goal:    task.Content,
-				Status:  string(task.Status),
-				Plan:    []schema.ContinuityStep{},
-			}
+		// Workflow steps run on their own, see startWorkflowStep.
+		if task.Metadata != nil && task.Metadata["workflow"] != "" {
+			continue
 		}
+		// Under low energy only work important enough starts new steps;
+		// steps already running finish.
+		if ns.butler.shouldDefer(task) {
+			continue
+		}
+		// In a swarm each task runs on exactly one node.
+		if !ns.butler.leaseTask(task.ID) {
+			continue
+		}
+
+		ns.butler.mu.Lock()
+		ns.butler.continuityLocked(task)
+		needsPlan := len(task.Continuity.Plan) == 0
+		ns.butler.mu.Unlock()
 
 		// 1. If task has no steps, it needs "Initial Planning"
-		if len(task.Continuity.Plan) == 0 {
-			go ns.initialPlanning(ctx, task)
+		if needsPlan {
+			ns.mu.Lock()
+			busy := ns.planning[task.ID]
+			ns.planning[task.ID] = true
+			ns.mu.Unlock()
+			if !busy {
+				go func(t *Task) {
+					defer func() {
+						ns.mu.Lock()
+						delete(ns.planning, t.ID)
+						ns.mu.Unlock()
+					}()
+					ns.initialPlanning(ctx, t)
+				}(task)
+			}
 			continue
 		}
 
-		// 2. Execute the current step if it's pending
-		if task.Continuity.Cursor < len(task.Continuity.Plan) {
-			step := &task.Continuity.Plan[task.Continuity.Cursor]
-			if step.Status == string(StepPending) {
-				go ns.executeStep(ctx, task, step)
+		// 2. Dispatch every ready step of the graph while workers are free
+		ns.dispatch(ctx, task)
+	}
+
+	return nil
+}
+
+// dispatch starts the task's ready steps. Steps are claimed under the butler
+// lock so that a later pulse cannot start them twice.
+func (ns *NervousSystem) dispatch(ctx context.Context, task *Task) {
+	ctx = logging.With(ctx, logging.KeyTask, task.ID)
+	if id := task.Metadata["mission_id"]; id != "" {
+		ctx = logging.With(ctx, logging.KeyMission, id)
+	}
+	ns.butler.mu.Lock()
+	tc := task.Continuity
+	changed := false
+	for _, step := range tc.Ready() {
+		// Conditional guards decide before the step takes a worker.
+		if g := step.Condition; g != nil {
+			ref := step
+			if g.Step != "" {
+				ref = tc.Step(g.Step)
+			}
+			if ref == nil || !g.Match(ref.Result) {
+				step.Status = string(StepSkipped)
+				step.Result = "skipped: condition not met"
+				ns.butler.traceStep(task.ID, step, 0)
+				changed = true
+				continue
 			}
 		}
+
+		if step.HumanInput {
+			step.Status = string(StepWaiting)
+			step.StartedAt = time.Now().Unix()
+			ns.butler.emit(task.ID, TraceEvent{Kind: TraceInput, Step: step.ID, Status: "requested", Detail: step.Description})
+			ns.butler.traceStep(task.ID, step, 0)
+			changed = true
+			go ns.butler.SendUpdate(task.Platform, task.ChatID, fmt.Sprintf("🙋 Input needed for '%s': %s\n(Reply with /answer %s <your answer> to continue.)", task.Content, step.Description, task.ID))
+			continue
+		}
+
+		select {
+		case ns.workers <- struct{}{}:
+		default:
+			// All workers busy; the next pulse picks the step up.
+			continue
+		}
+		step.Status = string(StepRunning)
+		step.Attempts++
+		step.StartedAt = time.Now().Unix()
+		ns.butler.traceStep(task.ID, step, 0)
+		if task.Status != TaskStatusRunning {
+			task.Status = TaskStatusRunning
+			ns.butler.emit(task.ID, TraceEvent{Kind: TraceStatus, Status: string(task.Status)})
+		}
+		changed = true
+		go func(step *schema.ContinuityStep) {
+			defer func() { <-ns.workers }()
+			ns.executeStep(logging.With(ctx, logging.KeyStep, step.ID), task, step)
+		}(step)
 	}
 
-	return nil
+	// Skipped steps or a human reply can finish the graph without a step
+	// running to completion here.
+	finished := tc.Finished()
+	if finished {
+		ns.finishLocked(task)
+		changed = true
+	}
+	if changed {
+		tc.Sync()
+	}
+	ns.butler.mu.Unlock()
+
+	if changed {
+		ns.butler.save()
+	}
+	if finished {
+		ns.reportFinished(task, "")
+	}
+}
+
+// missionEvent publishes a mission.* event for a mission and sub-task.
+func (ns *NervousSystem) missionEvent(topic string, m *mission.Mission, st mission.SubTask, status mission.Status, result string) {
+	ns.butler.Spine.Broadcast(spine.Event{Type: topic, Payload: spine.MissionEvent{
+		MissionID: m.ID, Title: m.Title, SubTaskID: st.ID, SubTask: st.Title, Status: string(status), Result: result,
+	}})
 }
 
 func (ns *NervousSystem) processMissions(ctx context.Context) {
@@ -84,6 +205,10 @@ func (ns *NervousSystem) processMissions(ctx context.Context) {
 		return
 	}
 
+	// New mission work waits while energy is critical.
+	shed, _ := biology.ShouldDefer(biology.PriorityNormal)
+
+	ctx = logging.With(ctx, logging.KeyMission, activeMission.ID)
 	executableTasks := activeMission.GetExecutableTasks()
 	for _, subTask := range executableTasks {
 		// Check if a task already exists for this subtask
@@ -97,14 +222,32 @@ func (ns *NervousSystem) processMissions(ctx context.Context) {
 				// Reconcile status if needed
 				if t.Status == TaskStatusCompleted && subTask.Status != mission.StatusCompleted {
 					_ = activeMission.UpdateSubTaskStatus(subTask.ID, mission.StatusCompleted, t.Result)
+					_ = ns.butler.Missions.Save()
+					ns.missionEvent(spine.TopicMissionSubtask, activeMission, subTask, mission.StatusCompleted, t.Result)
 					ns.butler.SendUpdate("", "", fmt.Sprintf("🎯 Mission Subtask Completed: %s", subTask.Title))
 				} else if t.Status == TaskStatusFailed && subTask.Status != mission.StatusFailed {
-					_ = activeMission.UpdateSubTaskStatus(subTask.ID, mission.StatusFailed, t.Result)
+					ns.failSubTask(activeMission, subTask, t.Result)
 				}
 				break
 			}
 		}
 
+		if !exists && shed {
+			continue
+		}
+
+		if !exists && len(subTask.Spec) > 0 {
+			// Workflow steps are prebuilt and skip planning.
+			if _, err := ns.butler.startWorkflowStep(ctx, activeMission, subTask, subTaskTag); err != nil {
+				nervousLog.WarnContext(ctx, "could not dispatch workflow step", "subtask", subTask.ID, "err", err)
+				ns.failSubTask(activeMission, subTask, err.Error())
+			} else {
+				ns.missionEvent(spine.TopicMissionDispatched, activeMission, subTask, "", "")
+				ns.butler.SendUpdate("", "", fmt.Sprintf("🚀 Workflow Step Dispatched: %s", subTask.Title))
+			}
+			continue
+		}
+
 		if !exists {
 			// Create a new Butler task for this mission subtask
 			content := fmt.Sprintf("MISSION: %s\nSUBTASK: %s\nGOAL: %s", activeMission.Title, subTask.Title, subTask.Description)
@@ -119,6 +262,7 @@ func (ns *NervousSystem) processMissions(ctx context.Context) {
 				task.Metadata["subtask_id"] = subTask.ID
 				ns.butler.mu.Unlock()
 				ns.butler.save()
+				ns.missionEvent(spine.TopicMissionDispatched, activeMission, subTask, "", "")
 				ns.butler.SendUpdate("", "", fmt.Sprintf("🚀 Mission Task Dispatched: %s", subTask.Title))
 			}
 		}
@@ -138,130 +282,249 @@ func (ns *NervousSystem) processMissions(ctx context.Context) {
 
 			if newProgress >= 1.0 {
 				_ = ns.butler.Missions.CompleteMission(activeMission.ID)
+				ns.missionEvent(spine.TopicMissionCompleted, activeMission, mission.SubTask{}, mission.StatusCompleted, "")
 				ns.butler.SendUpdate("", "", fmt.Sprintf("🏆 MISSION ACCOMPLISHED: %s", activeMission.Title))
+				return
 			}
 		}
+		// Some sub-task failed and nothing is left to run: the mission ends
+		// so that the next one can start.
+		if activeMission.Settled() {
+			_ = ns.butler.Missions.FailMission(activeMission.ID)
+			ns.missionEvent(spine.TopicMissionFailed, activeMission, mission.SubTask{}, mission.StatusFailed, "")
+			ns.butler.SendUpdate("", "", fmt.Sprintf("💥 Mission failed: %s (%d of %d sub-tasks completed)", activeMission.Title, completedCount, len(activeMission.Tasks)))
+		}
+	}
+}
+
+// failSubTask records a failed sub-task and abandons the sub-tasks that
+// depend on it, which can no longer run.
+func (ns *NervousSystem) failSubTask(m *mission.Mission, st mission.SubTask, result string) {
+	_ = m.UpdateSubTaskStatus(st.ID, mission.StatusFailed, result)
+	abandoned := m.AbandonDependents(st.ID)
+	_ = ns.butler.Missions.Save()
+	ns.missionEvent(spine.TopicMissionSubtask, m, st, mission.StatusFailed, result)
+	for _, a := range abandoned {
+		ns.missionEvent(spine.TopicMissionSubtask, m, a, mission.StatusAbandoned, a.Result)
 	}
 }
 
 func (ns *NervousSystem) initialPlanning(ctx context.Context, task *Task) {
+	ctx, span := ns.butler.startSpan(ctx, task, "initialPlanning")
+	defer span.End()
+
 	// 1. Semantic Habituation: Check for cached plan
-	if cachedSteps, ok := memory.GetHabitStore().Recall(task.Content); ok {
-		fmt.Printf("NERVOUS: Habitual memory hit for '%s'! Reusing cached plan.\n", task.Content)
+	if habit, ok := memory.GetHabitStore().Recall(task.Metadata["crab_id"], task.Content); ok {
+		nervousLog.InfoContext(ctx, "habitual memory hit, reusing cached plan", "habit", habit.ID, "version", habit.Version, "similarity", habit.Similarity)
 		ns.butler.mu.Lock()
-		for i, desc := range cachedSteps {
-			task.Continuity.Plan = append(task.Continuity.Plan, schema.ContinuityStep{
-				ID:          fmt.Sprintf("%s_s%d", task.ID, i),
-				Description: desc,
-				Status:      string(StepPending),
-			})
-		}
-		task.Continuity.RemainingSteps = cachedSteps
+		task.Continuity.Memory.HabituationKey = habit.ID
+		task.Continuity.Plan = chainSteps(task.ID, habit.Steps)
+		task.Continuity.Sync()
+		ns.butler.emit(task.ID, TraceEvent{Kind: TracePlan, Detail: "habit " + habit.ID, Plan: append([]schema.ContinuityStep{}, task.Continuity.Plan...)})
 		ns.butler.mu.Unlock()
 		ns.butler.save()
 		ns.butler.SendUpdate(task.Platform, task.ChatID, fmt.Sprintf("🧠 Habitual memory triggered for '%s'. Pulse Plan recalled from experience.", task.Content))
 		return
 	}
 
-	prompt := fmt.Sprintf("TASK_PLANNING: Goal: '%s'. Break this into 2-5 atomic, executable steps. Return a simple bulleted list of descriptions.", task.Content)
+	prompt := prompts.Text("planning", task.Metadata["crab_id"], map[string]string{
+		"Goal":      task.Content,
+		"Blueprint": stepGraphBlueprint,
+	})
 
 	// Update ThoughtSignature for planning
-	ts := &ThoughtSignature{Goal: task.Content, PulseCount: task.Continuity.PulseCount}
+	ts := &ThoughtSignature{TaskID: task.ID, Goal: task.Content, PulseCount: task.Continuity.PulseCount}
 
 	// Use metabolic query for planning
-	resp, err := ns.butler.QueryMetabolic(ctx, prompt, "plan", ts, &Fovea{ActiveSkills: []string{"system"}})
+	fovea := ns.butler.foveaFor(ctx, task, &Fovea{
+		ActiveSkills: []string{"system"},
+		Collection:   ns.butler.collectionFor(task),
+	}, task.Content)
+	resp, err := ns.butler.QueryMetabolic(ctx, prompt, "plan", ts, fovea)
 	if err != nil {
+		span.RecordError(err)
 		return
 	}
 
-	// Improved parser for bullet points
-	lines := []string{}
-	for _, line := range strings.Split(resp.Content, "\n") {
-		line = strings.TrimSpace(line)
-		if line == "" {
-			continue
-		}
-		// Remove common bullet prefixes
-		line = strings.TrimPrefix(line, "- ")
-		line = strings.TrimPrefix(line, "* ")
-		lines = append(lines, line)
+	validate := func(plan []schema.ContinuityStep) error {
+		ns.butler.mu.RLock()
+		check := *task.Continuity
+		ns.butler.mu.RUnlock()
+		check.Plan = plan
+		return check.Validate()
 	}
-
-	ns.butler.mu.Lock()
-	for i, desc := range lines {
-		task.Continuity.Plan = append(task.Continuity.Plan, schema.ContinuityStep{
-			ID:          fmt.Sprintf("%s_s%d", task.ID, i),
-			Description: desc,
-			Status:      string(StepPending),
+	repair := func(previous, problems string) (string, error) {
+		nervousLog.WarnContext(ctx, "plan graph rejected, asking for a repair", "problems", problems)
+		prompt := prompts.Text("planning.repair", task.Metadata["crab_id"], map[string]string{
+			"Goal":      task.Content,
+			"Previous":  previous,
+			"Problems":  problems,
+			"Blueprint": stepGraphBlueprint,
 		})
+		resp, err := ns.butler.QueryMetabolic(ctx, prompt, "plan", ts, fovea)
+		return resp.Content, err
+	}
+	plan, err := checkedPlan(task.ID, resp.Content, validate, repair)
+	if err != nil {
+		span.RecordError(err)
+		ns.butler.mu.Lock()
+		task.Continuity.Anomalies = append(task.Continuity.Anomalies, err.Error())
+		ns.butler.mu.Unlock()
+		ns.butler.updateStatus(task.ID, TaskStatusFailed, err.Error())
+		ns.reportFinished(task, "")
+		return
 	}
-	task.Continuity.RemainingSteps = lines
+	span.SetAttrs("steps", len(plan))
+
+	ns.butler.mu.Lock()
+	task.Continuity.Plan = plan
+	task.Continuity.Sync()
+	ns.butler.emit(task.ID, TraceEvent{Kind: TracePlan, Plan: append([]schema.ContinuityStep{}, task.Continuity.Plan...)})
 	ns.butler.mu.Unlock()
 	ns.butler.save()
 
 	ns.butler.SendUpdate(task.Platform, task.ChatID, fmt.Sprintf("🧬 Pulse Plan for '%s' initialized with %d stages.", task.Content, len(task.Continuity.Plan)))
 }
 
+// executeStep runs a claimed step. The caller has already marked it running.
 func (ns *NervousSystem) executeStep(ctx context.Context, task *Task, step *schema.ContinuityStep) {
-	ns.butler.mu.Lock()
-	step.Status = string(StepRunning)
-	task.Status = TaskStatusRunning
-	ns.butler.mu.Unlock()
-
 	biology.GetMetabolism().Burn(biology.CostComputeLow)
 
-	// Foveated Sensing: Only activate skills relevant to the task if possible.
-	fovea := &Fovea{
-		ActiveSkills: []string{"system", "browser"},
+	ctx, span := ns.butler.startSpan(ctx, task, "executeStep",
+		"step_id", step.ID, "attempt", step.Attempts, "iteration", step.Iterations, "description", step.Description)
+	defer span.End()
+
+	ns.butler.mu.RLock()
+	// Files mentioned by the goal, this step or earlier results are focused.
+	texts := []string{task.Content, step.Description}
+	var depResults []string
+	for _, s := range task.Continuity.Plan {
+		if s.Result != "" {
+			texts = append(texts, s.Result)
+		}
+	}
+	for _, id := range step.DependsOn {
+		if dep := task.Continuity.Step(id); dep != nil && dep.Result != "" {
+			depResults = append(depResults, fmt.Sprintf("- %s: %s", dep.Description, dep.Result))
+		}
 	}
-
 	ts := &ThoughtSignature{
+		TaskID:         task.ID,
 		Goal:           task.Content,
 		PulseCount:     task.Continuity.PulseCount,
-		RemainingSteps: task.Continuity.RemainingSteps,
-		Anomalies:      task.Continuity.Anomalies,
+		RemainingSteps: append([]string{}, task.Continuity.RemainingSteps...),
+		Anomalies:      append([]string{}, task.Continuity.Anomalies...),
 	}
+	previous := step.Result
+	ns.butler.mu.RUnlock()
+
+	// Foveated Sensing: Only activate skills relevant to the task if possible.
+	fovea := ns.butler.foveaFor(ctx, task, &Fovea{
+		ActiveSkills: []string{"system", "browser"},
+		Collection:   ns.butler.collectionFor(task),
+	}, texts...)
 
-	prompt := fmt.Sprintf("TASK_EXECUTION: Goal: '%s'. Current Step: '%s'. Perform this step and return the result.", task.Content, step.Description)
+	if step.Until == nil {
+		previous = ""
+	}
+	prompt := prompts.Text("execution", task.Metadata["crab_id"], map[string]string{
+		"Goal":         task.Content,
+		"Step":         step.Description,
+		"Dependencies": strings.Join(depResults, "\n"),
+		"Iteration":    strconv.Itoa(step.Iterations),
+		"Previous":     previous,
+	})
 
 	resp, err := ns.butler.QueryMetabolic(ctx, prompt, "agent", ts, fovea)
+	span.RecordError(err)
 
 	ns.butler.mu.Lock()
 	task.Continuity.PulseCount++
 	task.Continuity.LastCheckpoint = time.Now().Unix()
 	if err != nil {
+		nervousLog.WarnContext(ctx, "step failed", "attempt", step.Attempts, "err", err)
 		step.Status = string(StepFailed)
 		step.Result = err.Error()
 		task.Continuity.Anomalies = append(task.Continuity.Anomalies, err.Error())
+		if key := task.Continuity.Memory.HabituationKey; key != "" {
+			// A replayed habit that fails is no longer trusted.
+			memory.GetHabitStore().RecordOutcome(key, false, err.Error())
+		}
 	} else {
-		step.Status = string(StepCompleted)
 		step.Result = resp.Content
-		task.Continuity.Cursor++
-		if len(task.Continuity.RemainingSteps) > 0 {
-			task.Continuity.RemainingSteps = task.Continuity.RemainingSteps[1:]
+		step.Status = string(StepCompleted)
+		if step.Until != nil && !step.Until.Match(resp.Content) {
+			step.Iterations++
+			limit := step.MaxIterations
+			if limit <= 0 {
+				limit = schema.DefaultMaxIterations
+			}
+			if step.Iterations < limit {
+				// Loop: run the step again on a later pulse.
+				step.Status = string(StepPending)
+			} else {
+				step.Status = string(StepFailed)
+				task.Continuity.Anomalies = append(task.Continuity.Anomalies,
+					fmt.Sprintf("step %s did not satisfy its exit check after %d iterations", step.ID, step.Iterations))
+			}
 		}
 	}
-	isDone := task.Continuity.Cursor >= len(task.Continuity.Plan)
+	ns.butler.traceStep(task.ID, step, time.Since(time.Unix(step.StartedAt, 0)))
+	span.SetAttrs("status", step.Status)
+	task.Continuity.Sync()
+	isDone := task.Continuity.Finished()
 	if isDone {
-		task.Status = TaskStatusCompleted
-		task.EndedAt = time.Now()
+		ns.finishLocked(task)
 	}
+	progress := task.Continuity.Progress()
 	ns.butler.mu.Unlock()
 	ns.butler.save()
 
 	// "Lazy I/O" Progress update
 	if isDone {
-		// Semantic Habituation: Record successful plan
+		ns.reportFinished(task, resp.Content)
+	} else if step.Status == string(StepCompleted) {
+		update := fmt.Sprintf("⚡ Step Complete: %s (%.0f%%)", step.Description, progress*100)
+		ns.butler.SendUpdateExt(task.Platform, task.ChatID, update, true) // Lazy I/O for progress
+	}
+}
+
+// finishLocked settles a task whose step graph can make no more progress.
+// The caller holds the butler lock.
+func (ns *NervousSystem) finishLocked(task *Task) {
+	task.EndedAt = time.Now()
+	go ns.butler.releaseTask(task.ID)
+	defer func() {
+		ns.butler.emit(task.ID, TraceEvent{Kind: TraceStatus, Status: string(task.Status), Detail: task.Result, Duration: task.EndedAt.Sub(task.StartedAt)})
+	}()
+	if task.Continuity.Failed() {
+		task.Status = TaskStatusFailed
+		task.Result = "one or more steps failed"
+		if n := len(task.Continuity.Anomalies); n > 0 {
+			task.Result = task.Continuity.Anomalies[n-1]
+		}
+		return
+	}
+	task.Status = TaskStatusCompleted
+}
+
+func (ns *NervousSystem) reportFinished(task *Task, outcome string) {
+	if task.Status == TaskStatusFailed {
+		ns.butler.SendUpdateExt(task.Platform, task.ChatID, fmt.Sprintf("❌ Goal Failed: %s\n\nReason: %s", task.Content, task.Result), false)
+		return
+	}
+
+	// Semantic Habituation: Reinforce a replayed habit or learn the new plan
+	if key := task.Continuity.Memory.HabituationKey; key != "" {
+		memory.GetHabitStore().RecordOutcome(key, true, "")
+	} else {
 		allSteps := []string{}
 		for _, s := range task.Continuity.Plan {
 			allSteps = append(allSteps, s.Description)
 		}
-		memory.GetHabitStore().Learn(task.Content, allSteps)
-
-		update := fmt.Sprintf("✅ Goal Reached: %s\n\nFinal Outcome: %s", task.Content, resp.Content)
-		ns.butler.SendUpdateExt(task.Platform, task.ChatID, update, false) // Fast I/O for completion
-	} else {
-		update := fmt.Sprintf("⚡ Step Complete: %s", step.Description)
-		ns.butler.SendUpdateExt(task.Platform, task.ChatID, update, true) // Lazy I/O for progress
+		memory.GetHabitStore().Learn(task.Metadata["crab_id"], task.Content, allSteps)
 	}
+
+	update := fmt.Sprintf("✅ Goal Reached: %s\n\nFinal Outcome: %s", task.Content, outcome)
+	ns.butler.SendUpdateExt(task.Platform, task.ChatID, update, false) // Fast I/O for completion
 }
@@ -0,0 +1,44 @@
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/nathfavour/auracrab/pkg/mission"
+	"github.com/nathfavour/auracrab/pkg/spine"
+)
+
+func TestFailedSubTaskEndsTheMission(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	missions, err := mission.NewManager()
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := missions.CreateMission("release", "", "ship it", time.Time{})
+	build := m.AddSubTask("build", "", nil)
+	ship := m.AddSubTask("ship", "", []string{build})
+	announce := m.AddSubTask("announce", "", []string{ship})
+	docs := m.AddSubTask("docs", "", nil)
+	_ = m.UpdateSubTaskStatus(docs, mission.StatusCompleted, "written")
+	_ = missions.Save()
+
+	b := &Butler{tasks: map[string]*Task{}, Missions: missions, Spine: spine.NewSpine(time.Second)}
+	b.tasks["t1"] = &Task{ID: "t1", Status: TaskStatusFailed, Result: "compiler crashed", Metadata: map[string]string{
+		"subtask_tag": "mission:" + m.ID + ":task:" + build,
+	}}
+	NewNervousSystem(b).processMissions(context.Background())
+
+	want := map[string]mission.Status{build: mission.StatusFailed, ship: mission.StatusAbandoned, announce: mission.StatusAbandoned, docs: mission.StatusCompleted}
+	for _, st := range m.Tasks {
+		if st.Status != want[st.ID] {
+			t.Errorf("sub-task %s is %s, want %s", st.Title, st.Status, want[st.ID])
+		}
+	}
+	if m.Status != mission.StatusFailed {
+		t.Fatalf("mission is %s, want failed", m.Status)
+	}
+	if active := missions.GetActiveMission(); active != nil {
+		t.Fatalf("the failed mission still blocks the queue: %+v", active)
+	}
+}
@@ -0,0 +1,325 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/nathfavour/auracrab/internal/provider"
+	"github.com/nathfavour/auracrab/pkg/biology"
+	"github.com/nathfavour/auracrab/pkg/memory"
+	"github.com/nathfavour/auracrab/pkg/mission"
+	"github.com/nathfavour/auracrab/pkg/schema"
+	"github.com/nathfavour/auracrab/pkg/skills"
+)
+
+// Facts sent with a packet are capped in number and length.
+const (
+	maxPacketFacts    = 20
+	maxPacketFactSize = 256
+)
+
+// privateFactKeys mark remembered facts that never leave the machine.
+var privateFactKeys = []string{"token", "secret", "password", "passwd", "key", "apikey", "authorization", "cookie", "credential", "credentials"}
+
+// BuildPacket fills a structured PromptPacket from live state: biology, the
+// ego, the active mission, remembered facts, the files in focus, recalled
+// knowledge and the expressed skills. The parts that grow with the task are
+// budgeted like the sections of the living prompt, so that the encoded
+// packet fits the provider's token limit.
+func (m *Metabolizer) BuildPacket(
+	intent string,
+	userPrompt string,
+	signature *ThoughtSignature,
+	fovea *Fovea,
+) (*schema.PromptPacket, error) {
+	bio := biology.Current()
+
+	packet := &schema.PromptPacket{
+		Mode:    "casual",
+		Request: userPrompt,
+		System: schema.SystemTelemetry{
+			OS:          runtime.GOOS,
+			CPUUsage:    bio.CPUUsage,
+			MemoryUsage: bio.MemoryUsage,
+			EnergyLevel: bio.EnergyLevel,
+		},
+	}
+	_, packet.Blueprint = replyShape(intent)
+	if intent == "plan" || intent == "agent" {
+		packet.Mode = "analytical"
+	}
+	var failures []string
+	if signature != nil {
+		packet.Signature = signature.String()
+		failures = signature.Anomalies
+	}
+
+	var focus, recalled []string
+	var tools []schema.ToolManifest
+	deltas := ""
+	if fovea != nil {
+		if fovea.Topology != nil {
+			packet.Project = *fovea.Topology
+			deltas = packet.Project.Deltas
+			packet.Project.Deltas = ""
+		}
+		kb := m.knowledge(fovea)
+		// Placeholders of the living prompt come without a prefix.
+		if prefix, items := m.metabolizeFovea(fovea, kb, userPrompt); prefix != "" {
+			focus = items
+		}
+		if prefix, items := m.metabolizeKnowledge(fovea, kb, userPrompt); prefix != "" {
+			recalled = items
+		}
+		for _, name := range fovea.ActiveSkills {
+			if s, ok := skills.GetRegistry().Get(name); ok {
+				tools = append(tools, schema.ToolManifest{
+					Name:        s.Name(),
+					Description: s.Description(),
+					Parameters:  string(s.Manifest()),
+				})
+			}
+		}
+	}
+
+	var factKeys, factValues []string
+	if b := m.butler; b != nil {
+		if b.Ego != nil {
+			packet.Memory.EgoState = b.Ego.GetIdentity().Vibe
+			packet.Memory.RecentActions = b.Ego.RecentThoughts(5)
+		}
+		if b.Missions != nil {
+			packet.Memory.Mission = missionInfo(b, b.Missions.GetActiveMission())
+		}
+		if b.Memory != nil {
+			factKeys, factValues = packetFacts(b.Memory.All(), userPrompt, m.provider != "" && m.provider != "vibe")
+		}
+	}
+
+	est, limit := m.budget()
+	budget := limit - packetTokens(packet, est)
+	// Encoding escapes the budgeted text, so the budget is tightened until
+	// the whole packet fits.
+	for attempt := 0; attempt < 3; attempt++ {
+		var factItems, toolItems []string
+		for i, k := range factKeys {
+			factItems = append(factItems, k+": "+factValues[i])
+		}
+		for _, t := range tools {
+			data, _ := json.Marshal(t)
+			toolItems = append(toolItems, string(data))
+		}
+		var deltaItems []string
+		if deltas != "" {
+			deltaItems = []string{deltas}
+		}
+		sections := []PromptSection{
+			{Name: "FOCUS", Priority: priorityFovea, Items: append([]string(nil), focus...)},
+			{Name: "DELTAS", Priority: priorityTopology, Items: deltaItems},
+			{Name: "KNOWLEDGE", Priority: priorityKnowledge, Items: append([]string(nil), recalled...)},
+			{Name: "FACTS", Priority: priorityFacts, Items: factItems},
+			{Name: "LAST_FAILURES", Priority: priorityAnomalies, Items: append([]string(nil), failures...), DropFromStart: true},
+			{Name: "TOOLS", Priority: prioritySkills, Items: toolItems},
+		}
+		assemble(sections, max(budget, 0), est)
+
+		packet.Focus = textItems(sections[0])
+		packet.Project.Deltas = strings.Join(textItems(sections[1]), "")
+		packet.Knowledge = textItems(sections[2])
+		packet.Memory.Facts = nil
+		for i := range keptItems(sections[3]) {
+			if packet.Memory.Facts == nil {
+				packet.Memory.Facts = map[string]string{}
+			}
+			packet.Memory.Facts[factKeys[i]] = factValues[i]
+		}
+		packet.Memory.LastFailures = failures[len(failures)-keptItems(sections[4]):]
+		packet.Tools = tools[:keptItems(sections[5])]
+
+		over := packetTokens(packet, est) - limit
+		if over <= 0 {
+			return packet, nil
+		}
+		budget -= over
+	}
+	return nil, fmt.Errorf("prompt packet needs %d tokens, over the limit of %d for %s", packetTokens(packet, est), limit, m.provider)
+}
+
+// packetTokens estimates the encoded size of a packet, measured as
+// indented JSON, the larger of the wire formats.
+func packetTokens(p *schema.PromptPacket, est TokenEstimator) int {
+	data, _ := json.MarshalIndent(p, "", "  ")
+	return est.Estimate(string(data))
+}
+
+// textItems returns what is left of a budgeted text section.
+func textItems(s PromptSection) []string {
+	if s.Omitted {
+		return nil
+	}
+	return s.Items
+}
+
+// keptItems counts the items of a budgeted section that survived whole;
+// a truncated item is of no use for structured values.
+func keptItems(s PromptSection) int {
+	if s.Omitted {
+		return 0
+	}
+	if s.Trimmed {
+		return len(s.Items) - 1
+	}
+	return len(s.Items)
+}
+
+func missionInfo(b *Butler, m *mission.Mission) *schema.MissionInfo {
+	if m == nil {
+		return nil
+	}
+	info := &schema.MissionInfo{
+		Title:    m.Title,
+		Goal:     m.Goal,
+		Progress: m.Progress,
+		TTC:      m.EstimatedTTC.String(),
+	}
+	if remaining, err := b.Missions.TimeRemaining(m.ID); err == nil {
+		info.TimeRemaining = remaining.Round(time.Minute).String()
+	}
+	for _, t := range m.Tasks {
+		info.SubTasks = append(info.SubTasks, schema.SubTaskInfo{
+			ID:           t.ID,
+			Title:        t.Title,
+			Status:       string(t.Status),
+			Dependencies: t.Dependencies,
+		})
+	}
+	return info
+}
+
+// packetFacts picks the remembered facts to send with a packet, most
+// relevant to the request first. Facts whose key looks like a credential
+// are never sent, and a remote provider only gets the facts that share a
+// word with the request.
+func packetFacts(all map[string]interface{}, request string, remote bool) (keys, values []string) {
+	words := map[string]bool{}
+	for _, w := range memory.Tokenize(request) {
+		words[w] = true
+	}
+	score := map[string]int{}
+	for k, v := range all {
+		if privateFact(k) {
+			continue
+		}
+		n := 0
+		for _, w := range memory.Tokenize(k + " " + fmt.Sprint(v)) {
+			if words[w] {
+				n++
+			}
+		}
+		if remote && n == 0 {
+			continue
+		}
+		score[k] = n
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if score[keys[i]] != score[keys[j]] {
+			return score[keys[i]] > score[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	if len(keys) > maxPacketFacts {
+		keys = keys[:maxPacketFacts]
+	}
+	for _, k := range keys {
+		v := fmt.Sprint(all[k])
+		if len(v) > maxPacketFactSize {
+			v = v[:maxPacketFactSize] + "..."
+		}
+		values = append(values, v)
+	}
+	return keys, values
+}
+
+func privateFact(key string) bool {
+	k := strings.ToLower(key)
+	for _, s := range privateFactKeys {
+		if strings.HasSuffix(k, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// inferenceProvider returns the configured provider; Cortensor falls back to
+// the local vibe provider.
+func (b *Butler) inferenceProvider() provider.InferenceProvider {
+	local := provider.NewVibeProvider()
+	if b.Config != nil && b.Config.Inference.ActiveProvider == "cortensor" {
+		c := b.Config.Inference.Cortensor
+		return provider.NewCortensorProvider(c.RouterEndpoint, c.SessionID, c.ConsensusThreshold, local)
+	}
+	return local
+}
+
+// replyShape returns what a structured reply for intent must validate
+// against, and the blueprint that describes it to the model. Plans are step
+// graphs; every other intent answers with a ResponsePacket.
+func replyShape(intent string) (interface{}, string) {
+	if intent == "plan" {
+		return &plannedGraph{}, stepGraphBlueprint
+	}
+	return &schema.ResponsePacket{}, schema.ResponseBlueprint
+}
+
+// queryPacket sends a structured PromptPacket in the given wire format and
+// extracts the reply into out, asking once for a repair when it does not
+// validate. A ResponsePacket reply is rendered as text and attached to the
+// response; any other shape is returned as its normalised JSON. Replies
+// that still do not validate are returned as plain text.
+func (b *Butler) queryPacket(ctx context.Context, p provider.InferenceProvider, taskID string, packet *schema.PromptPacket, format string, intent string, out interface{}) (provider.CompletionResponse, error) {
+	encoded, err := packet.Encode(format)
+	if err != nil {
+		return provider.CompletionResponse{}, err
+	}
+
+	content := fmt.Sprintf("AURACRAB_PROMPT_PACKET (%s)\n%s", format, encoded)
+	start := time.Now()
+	resp, err := p.GetCompletion(ctx, provider.CompletionRequest{
+		Content: content,
+		Intent:  intent,
+	})
+	observeQuery(p.Name(), content, start, resp, err)
+	b.tracePrompt(taskID, p.Name(), intent, content, start, resp, err)
+	if err != nil {
+		return resp, err
+	}
+	resp.Content = strings.TrimSpace(resp.Content)
+	if resp.Content == "" {
+		return resp, fmt.Errorf("empty response from %s", p.Name())
+	}
+
+	err = schema.ExtractWithRepair(resp.Content, out, func(repair string) (string, error) {
+		b.emit(taskID, TraceEvent{Kind: TraceRetry, Attempt: 2, Error: "reply did not match the response schema"})
+		start := time.Now()
+		r, err := p.GetCompletion(ctx, provider.CompletionRequest{Content: repair, Intent: intent})
+		observeQuery(p.Name(), repair, start, r, err)
+		b.tracePrompt(taskID, p.Name(), intent, repair, start, r, err)
+		return r.Content, err
+	})
+	if err != nil {
+		return resp, nil
+	}
+	if parsed, ok := out.(*schema.ResponsePacket); ok {
+		resp.Packet = parsed
+		resp.Content = parsed.Text()
+	} else if data, err := json.Marshal(out); err == nil {
+		resp.Content = string(data)
+	}
+	return resp, nil
+}
@@ -0,0 +1,127 @@
+package core
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/nathfavour/auracrab/internal/provider"
+	"github.com/nathfavour/auracrab/pkg/memory"
+	"github.com/nathfavour/auracrab/pkg/schema"
+)
+
+// scriptedProvider answers queries with canned replies, in order.
+type scriptedProvider struct {
+	replies []string
+	prompts []string
+}
+
+func (p *scriptedProvider) Name() string { return "scripted" }
+
+func (p *scriptedProvider) GetCompletion(ctx context.Context, req provider.CompletionRequest) (provider.CompletionResponse, error) {
+	p.prompts = append(p.prompts, req.Content)
+	reply := p.replies[0]
+	p.replies = p.replies[1:]
+	return provider.CompletionResponse{Content: reply}, nil
+}
+
+func (p *scriptedProvider) VerifyProof(ctx context.Context, proof string) (bool, error) {
+	return true, nil
+}
+
+func (p *scriptedProvider) ManageSession(ctx context.Context) error { return nil }
+
+func (p *scriptedProvider) GetInfo() string { return "" }
+
+func TestQueryPacket_PlanningReplyKeepsTheGraph(t *testing.T) {
+	b := &Butler{tasks: map[string]*Task{}}
+	p := &scriptedProvider{replies: []string{"Here is the plan:\n" + `{"steps": [
+		{"id": "build", "description": "Build the binary"},
+		{"id": "ask", "description": "Ship to prod?", "human_input": true, "depends_on": ["build"]},
+		{"id": "ship", "description": "Deploy", "depends_on": ["ask"], "condition": {"step": "ask", "contains": "yes"}}
+	]}`}}
+
+	out, blueprint := replyShape("plan")
+	if blueprint != stepGraphBlueprint {
+		t.Fatal("planning packets must ask for a step graph")
+	}
+	packet := &schema.PromptPacket{Request: "deploy", Blueprint: blueprint}
+	resp, err := b.queryPacket(context.Background(), p, "", packet, schema.FormatJSON, "plan", out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(p.prompts) != 1 {
+		t.Fatalf("a valid plan must not trigger a repair query, got %d queries", len(p.prompts))
+	}
+	if resp.Packet != nil {
+		t.Error("a plan is not a response packet")
+	}
+
+	plan := parseStepGraph("t1", resp.Content)
+	if len(plan) != 3 || !plan[1].HumanInput || plan[2].Condition == nil || plan[2].Condition.Step != "t1_ask" {
+		t.Fatalf("the step graph did not survive: %+v", plan)
+	}
+}
+
+func TestQueryPacket_ChatReplyIsAResponsePacket(t *testing.T) {
+	b := &Butler{tasks: map[string]*Task{}}
+	p := &scriptedProvider{replies: []string{`{"intent": "answer", "casual_message": "done"}`}}
+
+	out, _ := replyShape("agent")
+	resp, err := b.queryPacket(context.Background(), p, "", &schema.PromptPacket{}, schema.FormatJSON, "agent", out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Packet == nil || resp.Content != "done" {
+		t.Fatalf("expected a parsed response packet, got %+v", resp)
+	}
+}
+
+func TestBuildPacket_CarriesFocusWithinBudget(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	dir := t.TempDir()
+	small := filepath.Join(dir, "small.go")
+	big := filepath.Join(dir, "big.go")
+	if err := os.WriteFile(small, []byte("package small\n\nfunc Deploy() {}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(big, []byte(strings.Repeat("// filler line\n", 2000)), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	store, err := memory.NewStore("facts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = store.Set("github_token", "ghp_secret")
+	_ = store.Set("deploy_target", "the staging cluster")
+	_ = store.Set("favourite_colour", "green")
+
+	m := &Metabolizer{butler: &Butler{Memory: store}, provider: "cortensor", limit: 1500}
+	packet, err := m.BuildPacket("agent", "deploy the service", nil, &Fovea{Files: []string{small, big}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(packet.Focus) == 0 || !strings.Contains(packet.Focus[0], "func Deploy") {
+		t.Fatalf("expected the focused file contents, got %v", packet.Focus)
+	}
+	if got := packetTokens(packet, HeuristicEstimator{}); got > 1500 {
+		t.Fatalf("packet of %d tokens exceeds the limit", got)
+	}
+	if _, ok := packet.Memory.Facts["github_token"]; ok {
+		t.Error("a credential was sent to a remote provider")
+	}
+	if _, ok := packet.Memory.Facts["favourite_colour"]; ok {
+		t.Error("an unrelated fact was sent to a remote provider")
+	}
+	if packet.Memory.Facts["deploy_target"] == "" {
+		t.Errorf("expected the relevant fact, got %v", packet.Memory.Facts)
+	}
+
+	m.limit = 10
+	if _, err := m.BuildPacket("agent", "deploy the service", nil, nil); err == nil {
+		t.Error("expected an error when even the request does not fit")
+	}
+}
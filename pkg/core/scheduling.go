@@ -0,0 +1,151 @@
+package core
+
+import (
+	"fmt"
+
+	"github.com/nathfavour/auracrab/pkg/biology"
+	"github.com/nathfavour/auracrab/pkg/logging"
+	"github.com/nathfavour/auracrab/pkg/schema"
+)
+
+// priorityFor ranks a new task by where it came from: the owner's own
+// requests always run, missions yield under critical load and automated
+// work is the first to wait.
+func priorityFor(platform string) biology.Priority {
+	switch platform {
+	case "system":
+		return biology.PriorityBackground
+	case "rules", "watch":
+		return biology.PriorityLow
+	case "mission":
+		return biology.PriorityNormal
+	}
+	return biology.PriorityOwner
+}
+
+// setupScheduler applies the configured load shedding thresholds.
+func (b *Butler) setupScheduler() {
+	if b.Config == nil {
+		return
+	}
+	sc := b.Config.Scheduler
+	if sc.ShedBelow > 0 || sc.CriticalBelow > 0 {
+		biology.SetShedPolicy(biology.ShedPolicy{ShedBelow: sc.ShedBelow, CriticalBelow: sc.CriticalBelow})
+	}
+}
+
+// shouldDefer reports whether the task waits for energy to recover. The
+// first deferral of a stretch is traced so the wait shows in the timeline.
+func (b *Butler) shouldDefer(task *Task) bool {
+	wait, reason := biology.ShouldDefer(task.Priority)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !wait {
+		delete(b.deferred, task.ID)
+		return false
+	}
+	if !b.deferred[task.ID] {
+		b.deferred[task.ID] = true
+		logger.Info("deferring task", logging.KeyTask, task.ID, "reason", reason)
+		b.emit(task.ID, TraceEvent{Kind: TraceStatus, Status: "deferred", Detail: reason})
+	}
+	return true
+}
+
+// continuityLocked returns the task's continuity, creating it with the
+// configured budgets. The caller holds the butler lock.
+func (b *Butler) continuityLocked(task *Task) *schema.TaskContinuity {
+	if task.Continuity == nil {
+		task.Continuity = schema.NewTaskContinuity(task.ID, task.Content, string(task.Status))
+	}
+	meta := &task.Continuity.Meta
+	if b.Config != nil && meta.EnergyBudget == 0 && meta.TokenBudget == 0 {
+		meta.EnergyBudget = b.Config.Scheduler.EnergyBudget
+		meta.TokenBudget = b.Config.Scheduler.TokenBudget
+	}
+	return task.Continuity
+}
+
+// overBudget explains how a task overran its budget, or returns "".
+func overBudget(meta schema.ContinuityMeta) string {
+	switch {
+	case meta.EnergyBudget > 0 && meta.EnergyBurned > meta.EnergyBudget:
+		return fmt.Sprintf("it burned %.2f of its %.2f energy budget", meta.EnergyBurned, meta.EnergyBudget)
+	case meta.TokenBudget > 0 && meta.TokensUsed > meta.TokenBudget:
+		return fmt.Sprintf("it used %d of its %d token budget", meta.TokensUsed, meta.TokenBudget)
+	}
+	return ""
+}
+
+// charge bills a prompt and its response to the task and pauses the task
+// once it overruns its budget.
+func (b *Butler) charge(taskID, prompt, response string) {
+	biology.GetMetabolism().Burn(biology.CostAPIQuery)
+	if taskID == "" {
+		return
+	}
+	tokens := HeuristicEstimator{}.Estimate(prompt) + HeuristicEstimator{}.Estimate(response)
+
+	b.mu.Lock()
+	task, ok := b.tasks[taskID]
+	if !ok {
+		b.mu.Unlock()
+		return
+	}
+	meta := &b.continuityLocked(task).Meta
+	meta.EnergyBurned += float64(biology.CostAPIQuery)
+	meta.TokensUsed += tokens
+
+	reason := ""
+	if task.Status == TaskStatusRunning || task.Status == TaskStatusPending {
+		reason = overBudget(*meta)
+	}
+	if reason != "" {
+		task.Status = TaskStatusPaused
+		task.Result = "paused: " + reason
+		b.emit(taskID, TraceEvent{Kind: TraceStatus, Status: string(task.Status), Detail: task.Result})
+	}
+	b.mu.Unlock()
+
+	if reason != "" {
+		b.save()
+		b.SendUpdate(task.Platform, task.ChatID, fmt.Sprintf(
+			"⏸️ Paused '%s': %s. Steps already running will finish; reply /resume %s to grant it another budget.",
+			task.Content, reason, taskID))
+	}
+}
+
+// ResumeTask restarts a paused task with its budget extended by another
+// configured allowance.
+func (b *Butler) ResumeTask(taskID string) error {
+	b.mu.Lock()
+	task, ok := b.tasks[taskID]
+	if !ok {
+		b.mu.Unlock()
+		return fmt.Errorf("task '%s' not found", taskID)
+	}
+	if task.Status != TaskStatusPaused {
+		b.mu.Unlock()
+		return fmt.Errorf("task '%s' is %s, not paused", taskID, task.Status)
+	}
+	meta := &b.continuityLocked(task).Meta
+	if b.Config != nil {
+		if meta.EnergyBudget > 0 {
+			meta.EnergyBudget = meta.EnergyBurned + b.Config.Scheduler.EnergyBudget
+		}
+		if meta.TokenBudget > 0 {
+			meta.TokenBudget = meta.TokensUsed + b.Config.Scheduler.TokenBudget
+		}
+	}
+	// Without a configured allowance the budget is lifted.
+	if overBudget(*meta) != "" {
+		meta.EnergyBudget, meta.TokenBudget = 0, 0
+	}
+	task.Status = TaskStatusPending
+	task.Result = ""
+	b.emit(taskID, TraceEvent{Kind: TraceStatus, Status: string(task.Status), Detail: "resumed with a new budget"})
+	b.mu.Unlock()
+	b.save()
+	return nil
+}
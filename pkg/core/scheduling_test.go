@@ -0,0 +1,46 @@
+package core
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nathfavour/auracrab/pkg/config"
+	"github.com/nathfavour/auracrab/pkg/spine"
+)
+
+func TestChargePausesAndResumes(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	b := &Butler{
+		tasks:    map[string]*Task{},
+		deferred: map[string]bool{},
+		Spine:    spine.NewSpine(time.Second),
+		Config:   &config.Config{Scheduler: config.SchedulerConfig{EnergyBudget: 0.1}},
+	}
+	task := &Task{ID: "t1", Content: "index the repo", Status: TaskStatusRunning, Priority: priorityFor("cli")}
+	b.tasks["t1"] = task
+
+	b.charge("t1", "plan", "ok")
+	b.charge("t1", "step one", "ok")
+	if task.Status != TaskStatusRunning {
+		t.Fatalf("paused at the budget: %s", task.Result)
+	}
+	b.charge("t1", "step two", "ok")
+	if task.Status != TaskStatusPaused || !strings.Contains(task.Result, "energy budget") {
+		t.Fatalf("status %s (%s), want paused over the energy budget", task.Status, task.Result)
+	}
+	if meta := task.Continuity.Meta; meta.TokensUsed == 0 {
+		t.Error("tokens were not counted")
+	}
+
+	if err := b.ResumeTask("t1"); err != nil {
+		t.Fatal(err)
+	}
+	if task.Status != TaskStatusPending || task.Continuity.Meta.EnergyBudget <= task.Continuity.Meta.EnergyBurned {
+		t.Fatalf("resume did not extend the budget: %+v", task.Continuity.Meta)
+	}
+	if err := b.ResumeTask("t1"); err == nil {
+		t.Error("resumed a task that is not paused")
+	}
+}
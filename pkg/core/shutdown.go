@@ -0,0 +1,95 @@
+package core
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/nathfavour/auracrab/pkg/biology"
+	"github.com/nathfavour/auracrab/pkg/config"
+)
+
+// DrainTimeout bounds how long shutdown waits for running steps.
+const DrainTimeout = 30 * time.Second
+
+// ApoptosisError is returned by Serve when the daemon retired itself. The
+// process should exit with biology.ExitApoptosis.
+type ApoptosisError struct {
+	Reason string
+}
+
+func (e *ApoptosisError) Error() string {
+	return "apoptosis: " + e.Reason
+}
+
+// Idle reports whether no step is running and no plan is being made.
+func (ns *NervousSystem) Idle() bool {
+	ns.mu.RLock()
+	defer ns.mu.RUnlock()
+	return len(ns.workers) == 0 && len(ns.planning) == 0
+}
+
+// shutdown stops the spine, lets running steps finish for up to
+// DrainTimeout and checkpoints every store. On apoptosis the owners are told
+// why the daemon is going down.
+func (b *Butler) shutdown(cause error) {
+	active := b.activeTasks()
+	if cause != nil {
+		b.NotifyOwners(fmt.Sprintf("🪦 Auracrab is going down: %s. Running steps get %s to finish, then %d active tasks are checkpointed and resume on restart.",
+			biology.DeathReason(), DrainTimeout, active))
+	}
+	logger.Info("shutting down, draining active tasks", "active", active)
+
+	b.Spine.Stop()
+	if b.swarm != nil {
+		b.swarm.Retire()
+	}
+	deadline := time.Now().Add(DrainTimeout)
+	for !b.drained() && time.Now().Before(deadline) {
+		time.Sleep(100 * time.Millisecond)
+	}
+	if !b.drained() {
+		logger.Warn("drain timed out; unfinished steps resume from the checkpoint")
+	}
+
+	b.checkpoint()
+	// Missions belong to the main process; a replica only reads them.
+	if b.Missions != nil && config.Replica() == "" {
+		if err := b.Missions.Save(); err != nil {
+			logger.Error("could not save missions", "err", err)
+		}
+	}
+	if b.Spine.StatsFile != "" {
+		_ = b.Spine.WriteStats(b.Spine.StatsFile)
+	}
+	logger.Info("state checkpointed")
+}
+
+func (b *Butler) drained() bool {
+	return b.Spine.Idle() && (b.nervous == nil || b.nervous.Idle())
+}
+
+func (b *Butler) activeTasks() int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	n := 0
+	for _, t := range b.tasks {
+		if t.Status == TaskStatusRunning || t.Status == TaskStatusPending {
+			n++
+		}
+	}
+	return n
+}
+
+// checkpoint stamps active tasks and saves them. Steps still running are
+// picked up by resumeInterrupted on the next start.
+func (b *Butler) checkpoint() {
+	now := time.Now().Unix()
+	b.mu.Lock()
+	for _, t := range b.tasks {
+		if t.Continuity != nil && (t.Status == TaskStatusRunning || t.Status == TaskStatusPending) {
+			t.Continuity.LastCheckpoint = now
+		}
+	}
+	b.mu.Unlock()
+	b.save()
+}
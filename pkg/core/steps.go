@@ -0,0 +1,125 @@
+package core
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/nathfavour/auracrab/pkg/schema"
+)
+
+// stepGraphBlueprint asks the planner for a step graph. Plain bullet lists
+// are still accepted and run in order.
+const stepGraphBlueprint = `Return JSON only: {"steps": [{"id": "s1", "description": "...", "depends_on": [], "weight": 1}]}.
+Steps without a dependency between them run in parallel. Optional fields: "condition": {"step": "s1", "contains": "text"} runs the step only if that step's result matches; "until": {"contains": "text"} with "max_iterations" repeats the step until its result matches; "human_input": true asks the user the description and waits for the reply. Weight is the relative effort used for progress.`
+
+// plannedGraph is the planner's reply shape.
+type plannedGraph struct {
+	Steps []plannedStep `json:"steps" schema:"required"`
+}
+
+type plannedStep struct {
+	ID            string            `json:"id"`
+	Description   string            `json:"description" schema:"required"`
+	DependsOn     []string          `json:"depends_on"`
+	Weight        int               `json:"weight" schema:"min=0"`
+	Condition     *schema.StepGuard `json:"condition,omitempty"`
+	Until         *schema.StepGuard `json:"until,omitempty"`
+	MaxIterations int               `json:"max_iterations" schema:"min=0"`
+	HumanInput    bool              `json:"human_input,omitempty"`
+}
+
+var bulletPrefix = regexp.MustCompile(`^(?:[-*•]|\d+[.)])\s+`)
+
+// parseStepGraph turns the planner reply into plan steps. Step IDs are
+// namespaced by the task so they stay unique across tasks.
+func parseStepGraph(taskID, reply string) []schema.ContinuityStep {
+	var graph plannedGraph
+	if err := schema.Extract(reply, &graph); err != nil || len(graph.Steps) == 0 {
+		var lines []string
+		for _, line := range strings.Split(reply, "\n") {
+			line = strings.TrimSpace(bulletPrefix.ReplaceAllString(strings.TrimSpace(line), ""))
+			if line != "" {
+				lines = append(lines, line)
+			}
+		}
+		return chainSteps(taskID, lines)
+	}
+
+	ids := map[string]string{}
+	for i, p := range graph.Steps {
+		local := p.ID
+		if local == "" {
+			local = fmt.Sprintf("s%d", i)
+		}
+		ids[local] = fmt.Sprintf("%s_%s", taskID, local)
+		graph.Steps[i].ID = local
+	}
+	resolve := func(local string) string {
+		if id, ok := ids[local]; ok {
+			return id
+		}
+		// Unknown references are kept so that validation reports them.
+		return local
+	}
+
+	plan := make([]schema.ContinuityStep, 0, len(graph.Steps))
+	for _, p := range graph.Steps {
+		step := schema.ContinuityStep{
+			ID:            ids[p.ID],
+			Description:   p.Description,
+			Status:        string(StepPending),
+			Weight:        p.Weight,
+			Condition:     p.Condition,
+			Until:         p.Until,
+			MaxIterations: p.MaxIterations,
+			HumanInput:    p.HumanInput,
+		}
+		for _, d := range p.DependsOn {
+			step.DependsOn = append(step.DependsOn, resolve(d))
+		}
+		if step.Condition != nil && step.Condition.Step != "" {
+			step.Condition.Step = resolve(step.Condition.Step)
+		}
+		plan = append(plan, step)
+	}
+	return plan
+}
+
+// checkedPlan parses a planning reply into a step graph that passes
+// validate. A broken graph goes back once through repair with the problems
+// found; a graph still broken after that is rejected rather than run
+// without its dependencies and guards.
+func checkedPlan(taskID, reply string, validate func([]schema.ContinuityStep) error, repair func(previous, problems string) (string, error)) ([]schema.ContinuityStep, error) {
+	plan := parseStepGraph(taskID, reply)
+	err := validate(plan)
+	if err == nil {
+		return plan, nil
+	}
+	fixed, rerr := repair(reply, err.Error())
+	if rerr != nil {
+		return nil, fmt.Errorf("plan graph rejected: %v (repair failed: %v)", err, rerr)
+	}
+	plan = parseStepGraph(taskID, fixed)
+	if err := validate(plan); err != nil {
+		return nil, fmt.Errorf("plan graph rejected after repair: %v", err)
+	}
+	return plan, nil
+}
+
+// chainSteps builds a linear plan where each step depends on the previous.
+func chainSteps(taskID string, descriptions []string) []schema.ContinuityStep {
+	plan := make([]schema.ContinuityStep, 0, len(descriptions))
+	for i, desc := range descriptions {
+		step := schema.ContinuityStep{
+			ID:          fmt.Sprintf("%s_s%d", taskID, i),
+			Description: desc,
+			Status:      string(StepPending),
+		}
+		if i > 0 {
+			step.DependsOn = []string{plan[i-1].ID}
+		}
+		plan = append(plan, step)
+	}
+	return plan
+}
@@ -0,0 +1,133 @@
+package core
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nathfavour/auracrab/pkg/memory"
+	"github.com/nathfavour/auracrab/pkg/schema"
+	"github.com/nathfavour/auracrab/pkg/spine"
+)
+
+func TestParseStepGraph(t *testing.T) {
+	reply := "```json\n" + `{"steps": [
+		{"id": "s1", "description": "run tests"},
+		{"id": "s2", "description": "fix failures", "depends_on": ["s1"], "condition": {"step": "s1", "contains": "fail"}},
+		{"id": "s3", "description": "update docs"}
+	]}` + "\n```"
+
+	plan := parseStepGraph("task_1", reply)
+	if len(plan) != 3 {
+		t.Fatalf("expected 3 steps, got %d", len(plan))
+	}
+	if plan[1].DependsOn[0] != "task_1_s1" || plan[1].Condition.Step != "task_1_s1" {
+		t.Errorf("references not namespaced: %+v", plan[1])
+	}
+	if len(plan[2].DependsOn) != 0 {
+		t.Errorf("expected s3 to be independent, got %v", plan[2].DependsOn)
+	}
+
+	plan = parseStepGraph("task_2", "1. clone repo\n2. build it\n- ship")
+	if len(plan) != 3 || plan[0].Description != "clone repo" || plan[2].DependsOn[0] != plan[1].ID {
+		t.Errorf("bullet list not chained: %+v", plan)
+	}
+}
+
+func TestCheckedPlan(t *testing.T) {
+	valid := `{"steps": [
+		{"id": "build", "description": "Build"},
+		{"id": "ship", "description": "Deploy", "depends_on": ["build"], "condition": {"step": "build", "contains": "ok"}}
+	]}`
+	broken := `{"steps": [
+		{"id": "build", "description": "Build"},
+		{"id": "ship", "description": "Deploy", "depends_on": ["test"]}
+	]}`
+	validate := func(plan []schema.ContinuityStep) error {
+		tc := &schema.TaskContinuity{Version: schema.ContinuityVersion, TaskID: "t1", Goal: "ship", Plan: plan}
+		return tc.Validate()
+	}
+
+	cases := []struct {
+		name    string
+		reply   string
+		repair  string
+		err     error
+		repairs int
+		ok      bool
+	}{
+		{"valid plan", valid, "", nil, 0, true},
+		{"repaired", broken, valid, nil, 1, true},
+		{"still broken", broken, broken, nil, 1, false},
+		{"repair fails", broken, "", errors.New("offline"), 1, false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			repairs := 0
+			plan, err := checkedPlan("t1", c.reply, validate, func(previous, problems string) (string, error) {
+				repairs++
+				if previous != c.reply || !strings.Contains(problems, `unknown step "test"`) {
+					t.Errorf("repair got previous %q, problems %q", previous, problems)
+				}
+				return c.repair, c.err
+			})
+			if repairs != c.repairs {
+				t.Fatalf("repairs = %d, want %d", repairs, c.repairs)
+			}
+			if (err == nil) != c.ok {
+				t.Fatalf("err = %v, want ok %v", err, c.ok)
+			}
+			if c.ok && (len(plan) != 2 || plan[1].Condition == nil || plan[1].DependsOn[0] != "t1_build") {
+				t.Fatalf("the guarded graph was not kept: %+v", plan)
+			}
+		})
+	}
+}
+
+func TestAnswerNeedsTheCommand(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	waiting := func(id, chatID string, started time.Time) *Task {
+		return &Task{ID: id, Content: "deploy " + id, Platform: "telegram", ChatID: chatID, StartedAt: started,
+			Continuity: &schema.TaskContinuity{Plan: []schema.ContinuityStep{{ID: id + "_ask", Status: string(StepWaiting)}}}}
+	}
+	now := time.Now()
+
+	cases := []struct {
+		name   string
+		tasks  []*Task
+		text   string
+		reply  string
+		answer string // the task that got the input
+	}{
+		{"nothing waiting", nil, "/answer yes", "No task in this chat", ""},
+		{"single task", []*Task{waiting("a", "c1", now)}, "/answer yes", "continuing 'deploy a'", "a"},
+		{"missing answer", []*Task{waiting("a", "c1", now)}, "/answer", "Usage: /answer", ""},
+		{"several need an id", []*Task{waiting("a", "c1", now), waiting("b", "c1", now.Add(time.Second))}, "/answer yes", "- a: deploy a\n- b: deploy b", ""},
+		{"picked by id", []*Task{waiting("a", "c1", now), waiting("b", "c1", now.Add(time.Second))}, "/answer b yes", "continuing 'deploy b'", "b"},
+		{"other chat", []*Task{waiting("a", "c2", now)}, "/answer a yes", "No task in this chat", ""},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			history, err := memory.NewHistoryStore()
+			if err != nil {
+				t.Fatal(err)
+			}
+			b := &Butler{tasks: map[string]*Task{}, History: history, Spine: spine.NewSpine(time.Second)}
+			for _, task := range c.tasks {
+				b.tasks[task.ID] = task
+			}
+			if reply := b.handleChannelMessage("telegram", "c1", "owner", c.text); !strings.Contains(reply, c.reply) {
+				t.Fatalf("reply = %q, want it to contain %q", reply, c.reply)
+			}
+			for _, task := range c.tasks {
+				step := task.Continuity.Plan[0]
+				if answered := step.Status == string(StepCompleted); answered != (task.ID == c.answer) {
+					t.Errorf("task %s answered = %v", task.ID, answered)
+				} else if answered && step.Result != "yes" {
+					t.Errorf("task %s got input %q", task.ID, step.Result)
+				}
+			}
+		})
+	}
+}
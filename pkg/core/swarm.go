@@ -0,0 +1,159 @@
+package core
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/nathfavour/auracrab/pkg/biology"
+	"github.com/nathfavour/auracrab/pkg/immune"
+	"github.com/nathfavour/auracrab/pkg/logging"
+	"github.com/nathfavour/auracrab/pkg/skills"
+)
+
+// isLeader reports whether this node runs the swarm-wide duties: mission
+// dispatch and scheduled jobs. A node outside a swarm always leads.
+func (b *Butler) isLeader() bool {
+	return b.swarm == nil || b.swarm.IsLeader()
+}
+
+// leaseTask claims a task for this node so no peer runs it too.
+func (b *Butler) leaseTask(id string) bool {
+	if b.swarm == nil {
+		return true
+	}
+	ok, err := b.swarm.Leases().Acquire(id, b.swarm.NodeID())
+	if err != nil {
+		logger.Warn("could not take task lease", logging.KeyTask, id, "err", err)
+		return false
+	}
+	return ok
+}
+
+// releaseTask gives up a finished task's lease.
+func (b *Butler) releaseTask(id string) {
+	if b.swarm != nil {
+		_ = b.swarm.Leases().Release(id, b.swarm.NodeID())
+	}
+}
+
+// swarmHost lets the immune system move queued tasks between nodes.
+type swarmHost struct {
+	b *Butler
+}
+
+func (h swarmHost) QueuedTasks() []immune.HandoffTask {
+	h.b.mu.RLock()
+	defer h.b.mu.RUnlock()
+	var queued []immune.HandoffTask
+	for _, t := range h.b.tasks {
+		// Work that has started stays where its state is.
+		if t.Status != TaskStatusPending || (t.Continuity != nil && t.Continuity.PulseCount > 0) {
+			continue
+		}
+		if t.Metadata != nil && t.Metadata["workflow"] != "" {
+			continue
+		}
+		queued = append(queued, immune.HandoffTask{
+			ID: t.ID, Content: t.Content, Platform: t.Platform, ChatID: t.ChatID,
+			Priority: string(t.Priority), Metadata: t.Metadata, Skills: h.b.skillsFor(t),
+		})
+	}
+	return queued
+}
+
+func (h swarmHost) AdoptTask(ht immune.HandoffTask) error {
+	have := map[string]bool{}
+	for _, s := range h.Skills() {
+		have[s] = true
+	}
+	for _, s := range ht.Skills {
+		if !have[s] {
+			return fmt.Errorf("task %s needs %s, which this node lacks", ht.ID, s)
+		}
+	}
+
+	h.b.mu.Lock()
+	if _, ok := h.b.tasks[ht.ID]; ok {
+		h.b.mu.Unlock()
+		return fmt.Errorf("task %s already exists here", ht.ID)
+	}
+	h.b.tasks[ht.ID] = &Task{
+		ID:        ht.ID,
+		Content:   ht.Content,
+		Status:    TaskStatusPending,
+		StartedAt: time.Now(),
+		Platform:  ht.Platform,
+		ChatID:    ht.ChatID,
+		Metadata:  ht.Metadata,
+		Priority:  biology.Priority(ht.Priority),
+	}
+	h.b.mu.Unlock()
+	h.b.save()
+	h.b.emit(ht.ID, TraceEvent{Kind: TraceStatus, Status: string(TaskStatusPending), Detail: "adopted by node " + h.b.swarm.NodeID()})
+	return nil
+}
+
+// HoldTask leaves an offered task queued here; its lease belongs to the
+// peer, so this node does not start it meanwhile.
+func (h swarmHost) HoldTask(id, peer string) {
+	h.b.emit(id, TraceEvent{Kind: TraceStatus, Status: "handoff_pending", Detail: "offered to node " + peer})
+}
+
+func (h swarmHost) ReclaimTask(id, peer string) {
+	h.b.emit(id, TraceEvent{Kind: TraceStatus, Status: string(TaskStatusPending), Detail: "node " + peer + " did not adopt the task, running it here"})
+}
+
+func (h swarmHost) DropTask(id, peer string) {
+	h.b.mu.Lock()
+	delete(h.b.tasks, id)
+	h.b.mu.Unlock()
+	h.b.save()
+	h.b.emit(id, TraceEvent{Kind: TraceStatus, Status: "handed_off", Detail: "moved to node " + peer})
+}
+
+func (h swarmHost) Skills() []string {
+	var names []string
+	for _, s := range skills.GetRegistry().List() {
+		names = append(names, s.Name())
+	}
+	if h.b.registry != nil {
+		if list, err := h.b.registry.List(); err == nil {
+			for _, c := range list {
+				names = append(names, crabSkill(c.ID))
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// crabSkill names an installed crab in a node's skill list.
+func crabSkill(id string) string {
+	return "crab:" + id
+}
+
+// skillsFor lists what a node needs to run the task: the crab it was
+// routed to and that crab's skills. The built-in skills are everywhere.
+// The caller holds the butler lock.
+func (b *Butler) skillsFor(t *Task) []string {
+	id := t.Metadata["crab_id"]
+	if id == "" || b.registry == nil {
+		return nil
+	}
+	need := []string{crabSkill(id)}
+	if c, err := b.registry.Get(id); err == nil {
+		need = append(need, c.Skills...)
+	}
+	return need
+}
+
+func (h swarmHost) Deliver(u immune.Update) {
+	h.b.SendUpdate(u.Platform, u.ChatID, u.Text)
+}
+
+// relay hands a chat message to the ingress node when this process is a
+// worker replica without channels of its own.
+func (b *Butler) relay(platform, chatID, text string) bool {
+	return b.swarm != nil && b.swarm.Relay(immune.Update{Platform: platform, ChatID: chatID, Text: text})
+}
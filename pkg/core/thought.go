@@ -9,11 +9,16 @@ import (
 	"time"
 
 	"github.com/nathfavour/auracrab/pkg/biology"
+	"github.com/nathfavour/auracrab/pkg/config"
+	"github.com/nathfavour/auracrab/pkg/memory"
+	"github.com/nathfavour/auracrab/pkg/prompts"
+	"github.com/nathfavour/auracrab/pkg/schema"
 	"github.com/nathfavour/auracrab/pkg/skills"
 )
 
 // ThoughtSignature represents a distilled state of a multi-pulse goal.
 type ThoughtSignature struct {
+	TaskID         string
 	PulseCount     int
 	Goal           string
 	LastResult     string
@@ -33,11 +38,43 @@ func (ts *ThoughtSignature) String() string {
 
 // Metabolizer assembles the dynamic, foveated prompt.
 type Metabolizer struct {
-	butler *Butler
+	butler    *Butler
+	estimator TokenEstimator
+	provider  string
+	limit     int
 }
 
 func NewMetabolizer(b *Butler) *Metabolizer {
-	return &Metabolizer{butler: b}
+	m := &Metabolizer{butler: b}
+	if b != nil && b.Config != nil {
+		m.provider = b.Config.Inference.ActiveProvider
+		m.limit = b.Config.Inference.ContextLimit(m.provider)
+		m.SetTokenEstimator(HeuristicEstimator{CharsPerToken: b.Config.Inference.CharsPerToken[m.provider]})
+	}
+	return m
+}
+
+// SetTokenEstimator plugs in a provider-specific tokenizer estimate.
+func (m *Metabolizer) SetTokenEstimator(e TokenEstimator) {
+	m.estimator = e
+}
+
+// SetContextLimit overrides the token budget of the assembled prompt.
+func (m *Metabolizer) SetContextLimit(limit int) {
+	m.limit = limit
+}
+
+// budget returns the token estimator and the prompt limit in tokens.
+func (m *Metabolizer) budget() (TokenEstimator, int) {
+	estimator := m.estimator
+	if estimator == nil {
+		estimator = HeuristicEstimator{}
+	}
+	limit := m.limit
+	if limit <= 0 {
+		limit = config.DefaultContextLimit
+	}
+	return estimator, limit
 }
 
 // Fovea defines the high-detail focus area for the current pulse.
@@ -45,15 +82,43 @@ type Fovea struct {
 	Files        []string // Detailed contents of these files
 	WorkingDir   string
 	ActiveSkills []string // Only these skills will be expressed
+	Collection   string   // Knowledge collection to recall relevant chunks from
+	Topology     *schema.ProjectTopology
 }
 
+// Section priorities: lower values shrink first when over budget.
+const (
+	priorityBiology    = 10
+	prioritySkills     = 20
+	priorityAnomalies  = 30
+	priorityFacts      = 35 // Packets only
+	priorityKnowledge  = 40
+	priorityTemporal   = 50
+	priorityTopology   = 60
+	priorityFovea      = 70
+	prioritySignature  = 80
+	priorityDirective  = 90
+	priorityUserPrompt = 100
+)
+
 func (m *Metabolizer) Build(
 	userPrompt string,
 	signature *ThoughtSignature,
 	fovea *Fovea,
 ) string {
+	return m.Assemble(userPrompt, signature, fovea).String()
+}
+
+// Assemble builds the living prompt as prioritised sections fitted into the
+// provider's token budget. Packets built for a task are recorded for
+// `auracrab prompt inspect`.
+func (m *Metabolizer) Assemble(
+	userPrompt string,
+	signature *ThoughtSignature,
+	fovea *Fovea,
+) *PromptAssembly {
 	// 1. Biological Proprioception
-	bio, _ := biology.CheckThermodynamics()
+	bio := biology.Current()
 	met := biology.GetMetabolism()
 	burn, _ := met.GetStats()
 
@@ -62,51 +127,74 @@ func (m *Metabolizer) Build(
 	if fovea != nil {
 		activeSkills = fovea.ActiveSkills
 	}
-	skillDNA := m.metabolizeSkills(activeSkills)
+	skillPrefix, skillDNA := m.metabolizeSkills(activeSkills)
 
-	// 3. Foveated Sensing (Focus Area)
-	contextDNA := m.metabolizeFovea(fovea)
+	// 3. Foveated Sensing (Focus Area) and recalled repository knowledge
+	kb := m.knowledge(fovea)
+	foveaPrefix, contextDNA := m.metabolizeFovea(fovea, kb, userPrompt)
+	knowledgePrefix, knowledgeDNA := m.metabolizeKnowledge(fovea, kb, userPrompt)
 
 	// 4. Temporal Pulse Framing
 	pulseCount := 0
+	var anomalies []string
 	if signature != nil {
 		pulseCount = signature.PulseCount
+		anomalies = signature.Anomalies
 	}
 	pulseFrame := fmt.Sprintf(
-		"CURRENT_PULSE: #%d (Metabolic Rate: %.2fHz)\nENERGY: %.2f/1.00 (Burned: %.3f)\n",
+		"CURRENT_PULSE: #%d (Metabolic Rate: %.2fHz)\nENERGY: %.2f/1.00 (Burned: %.3f)",
 		pulseCount+1, 1.0, bio.EnergyLevel, burn,
 	)
 
-	return fmt.Sprintf(
-		"AURACRAB_LIVING_PROMPT\n\n"+
-			"== TEMPORAL_FRAME ==\n%s\n"+
-			"== BIOLOGICAL_STATE ==\n"+
-			"- CPU: %.1f%% | MEM: %.1f%%\n"+
-			"- THERMODYNAMIC_LIMIT: 0.15\n\n"+
-			"== THOUGHT_SIGNATURE ==\n%s\n\n"+
-			"== FOVEATED_SENSING (HIGH_DETAIL) ==\n%s\n\n"+
-			"== SKILL_EXPRESSION (DNA) ==\n%s\n\n"+
-			"== USER_PULSE_REQUEST ==\n%s\n\n"+
-			"== METABOLIC_DIRECTIVE ==\n"+
-			"- Act within the current temporal window.\n"+
-			"- Distill the result for the next pulse signature.\n"+
-			"- Prioritize energy efficiency. Abort if energy < 0.15.\n"+
+	// Anomalies get their own section so that older ones can be dropped
+	// without losing the rest of the signature.
+	sigText := "SIGNATURE: (New metabolic goal initialized)"
+	if signature != nil {
+		core := *signature
+		core.Anomalies = nil
+		sigText = strings.TrimSuffix(core.String(), "\nAnomalies: []")
+	}
+	if len(anomalies) == 0 {
+		anomalies = []string{"(none)"}
+	}
+
+	sections := []PromptSection{
+		{Name: "TEMPORAL_FRAME", Priority: priorityTemporal, Items: []string{pulseFrame}},
+		{Name: "BIOLOGICAL_STATE", Priority: priorityBiology, Items: []string{
+			fmt.Sprintf("- CPU: %.1f%% | MEM: %.1f%%", bio.CPUUsage, bio.MemoryUsage),
+			"- THERMODYNAMIC_LIMIT: 0.15",
+		}},
+		{Name: "THOUGHT_SIGNATURE", Priority: prioritySignature, Items: []string{sigText}},
+		{Name: "ANOMALIES", Priority: priorityAnomalies, Items: anomalies, DropFromStart: true},
+		{Name: "FOVEATED_SENSING (HIGH_DETAIL)", Priority: priorityFovea, Prefix: foveaPrefix, Items: contextDNA},
+		{Name: "PROJECT_TOPOLOGY", Priority: priorityTopology, Items: m.metabolizeTopology(fovea)},
+		{Name: "KNOWLEDGE_RECALL", Priority: priorityKnowledge, Prefix: knowledgePrefix, Items: knowledgeDNA},
+		{Name: "SKILL_EXPRESSION (DNA)", Priority: prioritySkills, Prefix: skillPrefix, Items: skillDNA},
+		{Name: "USER_PULSE_REQUEST", Priority: priorityUserPrompt, Items: []string{userPrompt}, Required: true},
+		{Name: "METABOLIC_DIRECTIVE", Priority: priorityDirective, Required: true, Items: []string{
+			"- Act within the current temporal window.",
+			"- Distill the result for the next pulse signature.",
+			"- Prioritize energy efficiency. Abort if energy < 0.15.",
 			"- Output final result or next required action only.",
-		pulseFrame,
-		bio.CPUUsage, bio.MemoryUsage,
-		signature.String(),
-		contextDNA,
-		skillDNA,
-		userPrompt,
-	)
+		}},
+	}
+
+	estimator, limit := m.budget()
+	assembly := assemble(sections, limit, estimator)
+	assembly.Provider = m.provider
+	if signature != nil {
+		assembly.TaskID = signature.TaskID
+	}
+	recordAssembly(assembly)
+	return assembly
 }
 
-func (m *Metabolizer) metabolizeSkills(active []string) string {
+func (m *Metabolizer) metabolizeSkills(active []string) (string, []string) {
 	reg := skills.GetRegistry()
 	var expressed []string
 
 	if len(active) == 0 {
-		return "ACTIVE_DNA: (General reasoning, no specialized skills expressed)"
+		return "", []string{"ACTIVE_DNA: (General reasoning, no specialized skills expressed)"}
 	}
 
 	for _, name := range active {
@@ -115,12 +203,12 @@ func (m *Metabolizer) metabolizeSkills(active []string) string {
 		}
 	}
 
-	return "ACTIVE_DNA:\n" + strings.Join(expressed, "\n")
+	return "ACTIVE_DNA:\n", expressed
 }
 
-func (m *Metabolizer) metabolizeFovea(fovea *Fovea) string {
+func (m *Metabolizer) metabolizeFovea(fovea *Fovea, kb *memory.KnowledgeBase, query string) (string, []string) {
 	if fovea == nil || len(fovea.Files) == 0 {
-		return "FOVEA: (General context, no specific file focus)"
+		return "", []string{"FOVEA: (General context, no specific file focus)"}
 	}
 
 	var focusedContent []string
@@ -132,16 +220,87 @@ func (m *Metabolizer) metabolizeFovea(fovea *Fovea) string {
 		}
 
 		content := string(data)
-		// Pruning logic: If file is too large (> 2KB), take only first and last 1KB
-		if len(content) > 2048 {
-			pruned := content[:1024] + "\n... [PRUNED FOR METABOLIC EFFICIENCY] ...\n" + content[len(content)-1024:]
-			focusedContent = append(focusedContent, fmt.Sprintf("- %s (PRUNED):\n```\n%s\n```", path, pruned))
-		} else {
-			focusedContent = append(focusedContent, fmt.Sprintf("- %s:\n```\n%s\n```", path, content))
+		if len(content) <= 2048 {
+			focusedContent = append(focusedContent, fmt.Sprintf("- %s:\n```\n%s\n```\n", path, content))
+			continue
+		}
+
+		// Large indexed files contribute their most relevant chunks.
+		if kb != nil && kb.Covers(path) {
+			if chunks := kb.Query(query, 3, path); len(chunks) > 0 {
+				var parts []string
+				for _, c := range chunks {
+					parts = append(parts, memory.FormatChunk(c))
+				}
+				focusedContent = append(focusedContent, strings.Join(parts, "\n")+"\n")
+				continue
+			}
+		}
+
+		// Pruning logic: take only first and last 1KB of unindexed large files
+		pruned := content[:1024] + "\n... [PRUNED FOR METABOLIC EFFICIENCY] ...\n" + content[len(content)-1024:]
+		focusedContent = append(focusedContent, fmt.Sprintf("- %s (PRUNED):\n```\n%s\n```\n", path, pruned))
+	}
+
+	return "FOVEA (HIGH_DETAIL_CONTEXT):\n", focusedContent
+}
+
+// metabolizeTopology describes the project around the fovea: recently
+// touched files, dependencies and the uncommitted diff, least useful last.
+func (m *Metabolizer) metabolizeTopology(fovea *Fovea) []string {
+	if fovea == nil || fovea.Topology == nil {
+		return []string{"TOPOLOGY: (Unknown project layout)"}
+	}
+	t := fovea.Topology
+	items := []string{"ROOT: " + fovea.WorkingDir}
+	if len(t.ModifiedRecently) > 0 {
+		items = append(items, "MODIFIED_RECENTLY: "+strings.Join(t.ModifiedRecently, ", "))
+	}
+	if t.Deltas != "" {
+		items = append(items, "DELTAS:\n```diff\n"+t.Deltas+"\n```")
+	}
+	if len(t.Dependencies) > 0 {
+		items = append(items, "DEPENDENCIES: "+strings.Join(t.Dependencies, ", "))
+	}
+	if len(t.Files) > 0 {
+		items = append(items, "RELATED_FILES: "+strings.Join(t.Files, ", "))
+	}
+	return items
+}
+
+// metabolizeKnowledge recalls the repository chunks most relevant to the
+// current request, skipping files already inlined by the fovea.
+func (m *Metabolizer) metabolizeKnowledge(fovea *Fovea, kb *memory.KnowledgeBase, query string) (string, []string) {
+	if kb == nil {
+		return "", []string{"KNOWLEDGE: (No repository knowledge ingested)"}
+	}
+
+	inFovea := map[string]bool{}
+	for _, f := range fovea.Files {
+		inFovea[kb.RelPath(f)] = true
+	}
+
+	var recalled []string
+	for _, c := range kb.Query(query, 8) {
+		if p, _ := c.Metadata["path"].(string); inFovea[p] {
+			continue
+		}
+		recalled = append(recalled, memory.FormatChunk(c))
+		if len(recalled) == 5 {
+			break
 		}
 	}
+	if len(recalled) == 0 {
+		return "", []string{"KNOWLEDGE: (No relevant chunks found)"}
+	}
+	return fmt.Sprintf("KNOWLEDGE (%s):\n", fovea.Collection), recalled
+}
 
-	return "FOVEA (HIGH_DETAIL_CONTEXT):\n" + strings.Join(focusedContent, "\n\n")
+func (m *Metabolizer) knowledge(fovea *Fovea) *memory.KnowledgeBase {
+	if m.butler == nil || fovea == nil || fovea.Collection == "" {
+		return nil
+	}
+	return m.butler.Knowledge(fovea.Collection)
 }
 
 // ReflexCell generates autonomous reflections and actions.
@@ -161,8 +320,8 @@ func (rc *ReflexCell) Name() string {
 }
 
 func (rc *ReflexCell) Pulse(ctx context.Context) error {
-	energy, _ := biology.CheckThermodynamics()
-	if energy.EnergyLevel < 0.6 {
+	// Reflexes are background work, the first to go under load.
+	if wait, _ := biology.ShouldDefer(biology.PriorityBackground); wait {
 		return nil
 	}
 
@@ -172,15 +331,15 @@ func (rc *ReflexCell) Pulse(ctx context.Context) error {
 	}
 
 	rc.lastThought = time.Now()
-	go rc.reflect(ctx)
+	rc.reflect(ctx)
 	return nil
 }
 
 func (rc *ReflexCell) reflect(ctx context.Context) {
-	prompt := "SYSTEM_REFLEX: You are idling. Generate a brief, punchy autonomous reflection on your current environment. Keep it under 140 characters."
+	prompt := prompts.Text("reflex", "", nil)
 	resp, err := rc.butler.QueryWithContext(ctx, prompt, "vibe")
 	if err != nil {
 		return
 	}
-	fmt.Printf("REFLEX: %s\n", resp.Content)
+	logger.Info("reflex", "thought", resp.Content)
 }
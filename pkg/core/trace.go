@@ -0,0 +1,268 @@
+package core
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+
+	"github.com/nathfavour/auracrab/internal/provider"
+	"github.com/nathfavour/auracrab/pkg/config"
+	"github.com/nathfavour/auracrab/pkg/schema"
+	"github.com/nathfavour/auracrab/pkg/spine"
+)
+
+// TraceKind classifies task trace events.
+type TraceKind string
+
+const (
+	TraceCreated TraceKind = "created" // Detail: goal
+	TraceStatus  TraceKind = "status"  // Status: new task status, Detail: result
+	TracePlan    TraceKind = "plan"    // Plan: the step graph
+	TraceStep    TraceKind = "step"    // Step, Status, Detail: result
+	TracePrompt  TraceKind = "prompt"  // Provider, Intent, Prompt, Response, Duration
+	TraceRetry   TraceKind = "retry"   // Attempt, Error
+	TraceSkill   TraceKind = "skill"   // Skill, Detail: args, Response, Duration
+	TraceInput   TraceKind = "input"   // Step, Status: requested|provided, Detail
+)
+
+// TraceEvent is one entry of a task's append-only event log. Events are
+// published on the spine bus as "task.<kind>", or "step.<status>" for step
+// events, and persisted by the trace recorder.
+type TraceEvent struct {
+	Seq      uint64                  `json:"seq"`
+	TaskID   string                  `json:"task_id"`
+	Kind     TraceKind               `json:"kind"`
+	At       time.Time               `json:"at"`
+	Status   string                  `json:"status,omitempty"`
+	Step     string                  `json:"step,omitempty"`
+	Provider string                  `json:"provider,omitempty"`
+	Intent   string                  `json:"intent,omitempty"`
+	Prompt   string                  `json:"prompt,omitempty"`
+	Response string                  `json:"response,omitempty"`
+	Skill    string                  `json:"skill,omitempty"`
+	Attempt  int                     `json:"attempt,omitempty"`
+	Duration time.Duration           `json:"duration,omitempty"`
+	Error    string                  `json:"error,omitempty"`
+	Detail   string                  `json:"detail,omitempty"`
+	Plan     []schema.ContinuityStep `json:"plan,omitempty"`
+}
+
+var traceSeq atomic.Uint64
+
+// emit stamps and broadcasts a trace event for a task.
+func (b *Butler) emit(taskID string, e TraceEvent) {
+	if taskID == "" {
+		return
+	}
+	e.TaskID = taskID
+	e.Seq = traceSeq.Add(1)
+	if e.At.IsZero() {
+		e.At = time.Now()
+	}
+	topic := "task." + string(e.Kind)
+	if e.Kind == TraceStep {
+		topic = "step." + e.Status
+	}
+	b.Spine.Broadcast(spine.Event{Type: topic, Payload: e, Timestamp: e.At})
+}
+
+// traceStep records a step's current status and result.
+func (b *Butler) traceStep(taskID string, step *schema.ContinuityStep, d time.Duration) {
+	if d > 0 {
+		stepDuration.Observe(d.Seconds(), step.Status)
+	}
+	b.emit(taskID, TraceEvent{Kind: TraceStep, Step: step.ID, Status: step.Status, Detail: step.Result, Attempt: step.Attempts, Duration: d})
+}
+
+// tracePrompt records a model query and its outcome.
+func (b *Butler) tracePrompt(taskID, providerName, intent, prompt string, start time.Time, resp provider.CompletionResponse, err error) {
+	e := TraceEvent{Kind: TracePrompt, Provider: providerName, Intent: intent, Prompt: prompt, Response: resp.Content, Duration: time.Since(start)}
+	if err != nil {
+		e.Error = err.Error()
+	}
+	b.emit(taskID, e)
+	// Every prompt of a task passes through here, so it is billed here too.
+	b.charge(taskID, prompt, resp.Content)
+}
+
+func traceDir() string {
+	return filepath.Join(config.DataDir(), "traces")
+}
+
+const (
+	// maxTraceBytes caps a task's trace file.
+	maxTraceBytes = 8 << 20
+	// traceKeepFull is how many of the newest events keep their full text
+	// when a trace is compacted.
+	traceKeepFull = 20
+	// traceClipRunes is what remains of older prompts, replies and results.
+	traceClipRunes = 512
+)
+
+// compactTrace shrinks a trace that outgrew maxTraceBytes. Older events
+// lose the bulk of their prompt, reply and result text first, which keeps
+// Replay intact; if that is not enough, the oldest prompt and skill events
+// are dropped, and Replay undercounts prompts. The newest events are clipped
+// last.
+func compactTrace(lines [][]byte) [][]byte {
+	events := make([]TraceEvent, 0, len(lines))
+	for _, line := range lines {
+		var e TraceEvent
+		if json.Unmarshal(line, &e) == nil {
+			events = append(events, e)
+		}
+	}
+	clip := func(e *TraceEvent) {
+		e.Prompt = clipRunes(e.Prompt, traceClipRunes)
+		e.Response = clipRunes(e.Response, traceClipRunes)
+		e.Detail = clipRunes(e.Detail, traceClipRunes)
+	}
+	for i := 0; i < len(events)-traceKeepFull; i++ {
+		clip(&events[i])
+	}
+
+	encode := func() ([][]byte, int64) {
+		out := make([][]byte, 0, len(events))
+		size := int64(0)
+		for _, e := range events {
+			if data, err := json.Marshal(e); err == nil {
+				out = append(out, data)
+				size += int64(len(data)) + 1
+			}
+		}
+		return out, size
+	}
+	out, size := encode()
+	if size <= maxTraceBytes/2 {
+		return out
+	}
+	kept := events[:0]
+	for i, e := range events {
+		droppable := e.Kind == TracePrompt || e.Kind == TraceSkill
+		if droppable && i < len(events)-traceKeepFull && size > maxTraceBytes/2 {
+			size -= int64(len(out[i])) + 1
+			continue
+		}
+		kept = append(kept, e)
+	}
+	events = kept
+	if out, size = encode(); size <= maxTraceBytes/2 {
+		return out
+	}
+	// Even the newest events are too large; clip them as well so that the
+	// next appends do not rewrite the file again.
+	for i := range events {
+		clip(&events[i])
+	}
+	out, _ = encode()
+	return out
+}
+
+func clipRunes(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n]) + " ... [pruned]"
+}
+
+// traceRecorder appends trace events to DataDir()/traces/<task>.jsonl,
+// compacting a file that grows past maxTraceBytes.
+type traceRecorder struct {
+	mu sync.Mutex
+}
+
+func (r *traceRecorder) Handle(e spine.Event) {
+	ev, ok := e.Payload.(TraceEvent)
+	if !ok {
+		return
+	}
+	data, err := json.Marshal(ev)
+	if err != nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_ = appendCapped(filepath.Join(traceDir(), ev.TaskID+".jsonl"), data, maxTraceBytes, compactTrace)
+}
+
+// LoadTrace reads a task's event log in the order the events happened.
+func LoadTrace(taskID string) ([]TraceEvent, error) {
+	f, err := os.Open(filepath.Join(traceDir(), taskID+".jsonl"))
+	if err != nil {
+		return nil, fmt.Errorf("no trace recorded for task %s", taskID)
+	}
+	defer f.Close()
+
+	var events []TraceEvent
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var e TraceEvent
+		if err := json.Unmarshal(scanner.Bytes(), &e); err == nil {
+			events = append(events, e)
+		}
+	}
+	// Handlers run concurrently, so lines may land slightly out of order.
+	sort.SliceStable(events, func(i, j int) bool {
+		if !events[i].At.Equal(events[j].At) {
+			return events[i].At.Before(events[j].At)
+		}
+		return events[i].Seq < events[j].Seq
+	})
+	return events, scanner.Err()
+}
+
+// TraceSnapshot is a task's state reconstructed from its trace.
+type TraceSnapshot struct {
+	At       time.Time
+	Goal     string
+	Status   string
+	Result   string
+	Provider string
+	Prompts  int
+	Retries  int
+	Steps    []schema.ContinuityStep
+}
+
+// Replay reconstructs the task state after the first n events.
+func Replay(events []TraceEvent, n int) TraceSnapshot {
+	var s TraceSnapshot
+	if n > len(events) {
+		n = len(events)
+	}
+	for _, e := range events[:n] {
+		s.At = e.At
+		switch e.Kind {
+		case TraceCreated:
+			s.Goal = e.Detail
+			s.Status = string(TaskStatusPending)
+		case TraceStatus:
+			s.Status = e.Status
+			s.Result = e.Detail
+		case TracePlan:
+			s.Steps = append([]schema.ContinuityStep{}, e.Plan...)
+		case TraceStep:
+			for i := range s.Steps {
+				if s.Steps[i].ID == e.Step {
+					s.Steps[i].Status = e.Status
+					s.Steps[i].Result = e.Detail
+					s.Steps[i].Attempts = e.Attempt
+				}
+			}
+		case TracePrompt:
+			s.Prompts++
+			s.Provider = e.Provider
+		case TraceRetry:
+			s.Retries++
+		}
+	}
+	return s
+}
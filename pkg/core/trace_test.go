@@ -0,0 +1,91 @@
+package core
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nathfavour/auracrab/pkg/spine"
+)
+
+func TestTraceRecordAndReplay(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	base := time.Now()
+	plan := chainSteps("t1", []string{"build", "test"})
+	events := []TraceEvent{
+		{Kind: TraceCreated, Detail: "ship it"},
+		{Kind: TracePlan, Plan: plan},
+		{Kind: TraceStatus, Status: "running"},
+		{Kind: TraceStep, Step: plan[0].ID, Status: "running", Attempt: 1},
+		{Kind: TracePrompt, Provider: "vibeauracle", Prompt: "build", Response: "ok", Duration: time.Second},
+		{Kind: TraceStep, Step: plan[0].ID, Status: "completed", Detail: "built"},
+		{Kind: TraceStatus, Status: "completed"},
+	}
+
+	// Write out of order, as concurrent handlers may.
+	r := &traceRecorder{}
+	for i := len(events) - 1; i >= 0; i-- {
+		e := events[i]
+		e.TaskID, e.Seq, e.At = "t1", uint64(i+1), base.Add(time.Duration(i)*time.Millisecond)
+		r.Handle(spine.Event{Payload: e})
+	}
+
+	loaded, err := LoadTrace("t1")
+	if err != nil || len(loaded) != len(events) {
+		t.Fatalf("LoadTrace: %d events, %v", len(loaded), err)
+	}
+	if loaded[0].Kind != TraceCreated || loaded[len(loaded)-1].Kind != TraceStatus {
+		t.Errorf("events not in order: %v ... %v", loaded[0].Kind, loaded[len(loaded)-1].Kind)
+	}
+
+	mid := Replay(loaded, 5)
+	if mid.Status != "running" || mid.Prompts != 1 || mid.Steps[0].Status != "running" || mid.Steps[1].Status != string(StepPending) {
+		t.Errorf("unexpected mid-replay state: %+v", mid)
+	}
+	end := Replay(loaded, len(loaded))
+	if end.Status != "completed" || end.Steps[0].Result != "built" || end.Goal != "ship it" {
+		t.Errorf("unexpected final state: %+v", end)
+	}
+	if _, err := LoadTrace("missing"); err == nil {
+		t.Error("expected error for a task without trace")
+	}
+}
+
+func TestCompactTraceKeepsReplay(t *testing.T) {
+	huge := strings.Repeat("p", maxTraceBytes/40)
+	var lines [][]byte
+	add := func(e TraceEvent) {
+		data, _ := json.Marshal(e)
+		lines = append(lines, data)
+	}
+	add(TraceEvent{Kind: TraceCreated, Detail: "ship it"})
+	add(TraceEvent{Kind: TracePlan, Plan: chainSteps("t1", []string{"build"})})
+	for i := 0; i < 60; i++ {
+		add(TraceEvent{Kind: TracePrompt, Provider: "vibeauracle", Prompt: huge, Response: "ok"})
+	}
+	add(TraceEvent{Kind: TraceStatus, Status: "completed", Detail: "done"})
+
+	kept := compactTrace(lines)
+	size := 0
+	var events []TraceEvent
+	for _, line := range kept {
+		size += len(line) + 1
+		var e TraceEvent
+		if err := json.Unmarshal(line, &e); err != nil {
+			t.Fatal(err)
+		}
+		events = append(events, e)
+	}
+	if size > maxTraceBytes/2 {
+		t.Fatalf("compacted trace is %d bytes", size)
+	}
+	s := Replay(events, len(events))
+	if s.Goal != "ship it" || s.Status != "completed" || len(s.Steps) != 1 {
+		t.Fatalf("replay lost state: %+v", s)
+	}
+	if events[2].Prompt == huge || len(events[len(events)-2].Prompt) != len(huge) {
+		t.Error("expected older prompts clipped and the newest kept whole")
+	}
+}
@@ -0,0 +1,354 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/nathfavour/auracrab/pkg/config"
+	"github.com/nathfavour/auracrab/pkg/logging"
+	"github.com/nathfavour/auracrab/pkg/mission"
+	"github.com/nathfavour/auracrab/pkg/spine"
+	"github.com/nathfavour/auracrab/pkg/tracing"
+	"github.com/nathfavour/auracrab/pkg/watcher"
+	"github.com/nathfavour/auracrab/pkg/workflow"
+)
+
+// watchFuncs are available to task and notify templates of watch rules.
+var watchFuncs = template.FuncMap{"join": strings.Join}
+
+// watchData is what task and notify templates of a watch rule see. Files
+// are relative to Dir and slash-separated.
+type watchData struct {
+	Rule  string
+	Dir   string
+	Files []string
+}
+
+// ValidateWatchRule checks a rule, its globs, events and template.
+func ValidateWatchRule(rule config.WatchRule) error {
+	if err := rule.Validate(); err != nil {
+		return err
+	}
+	if _, err := watchOptions(rule); err != nil {
+		return fmt.Errorf("watch rule '%s': %w", rule.Name, err)
+	}
+	if _, err := watchTemplate(rule); err != nil {
+		return fmt.Errorf("watch rule '%s': %w", rule.Name, err)
+	}
+	return nil
+}
+
+// defaultWatchExclude is never watched: git rewrites .git/index on every
+// `git status`, which would retrigger rules endlessly.
+var defaultWatchExclude = []string{".git", ".git/**"}
+
+func watchOptions(rule config.WatchRule) (watcher.Options, error) {
+	ops, err := watcher.ParseOps(rule.Events)
+	if err != nil {
+		return watcher.Options{}, err
+	}
+	exclude := append(append([]string{}, defaultWatchExclude...), rule.Exclude...)
+	opts := watcher.Options{Include: rule.Include, Exclude: exclude, Ops: ops, Debounce: rule.Debounce}
+	return opts, opts.Validate()
+}
+
+// watchTemplate parses the task or notify text of a rule, nil for a
+// workflow rule.
+func watchTemplate(rule config.WatchRule) (*template.Template, error) {
+	text := rule.Task
+	if text == "" {
+		text = rule.Notify
+	}
+	if text == "" {
+		return nil, nil
+	}
+	return template.New(rule.Name).Funcs(watchFuncs).Option("missingkey=error").Parse(text)
+}
+
+// WatchDir resolves the directory of a rule, expanding a leading "~".
+func WatchDir(rule config.WatchRule) (string, error) {
+	dir := rule.Dir
+	if dir == "~" || strings.HasPrefix(dir, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		dir = filepath.Join(home, dir[1:])
+	}
+	return filepath.Abs(dir)
+}
+
+// startWatchers starts a filesystem watcher for every configured watch
+// rule. They stop with ctx.
+func (b *Butler) startWatchers(ctx context.Context) {
+	if b.Config == nil {
+		return
+	}
+	for _, rule := range b.Config.Watch.Rules {
+		if err := b.startWatcher(ctx, rule); err != nil {
+			logger.Error("could not start watch rule", "rule", rule.Name, "err", err)
+		}
+	}
+}
+
+func (b *Butler) startWatcher(ctx context.Context, rule config.WatchRule) error {
+	if err := ValidateWatchRule(rule); err != nil {
+		return err
+	}
+	opts, _ := watchOptions(rule)
+	tmpl, _ := watchTemplate(rule)
+	dir, err := WatchDir(rule)
+	if err != nil {
+		return err
+	}
+
+	w, err := watcher.NewWatcher(opts, func(changes []watcher.Change) {
+		b.onWatch(ctx, rule, tmpl, dir, changes)
+	})
+	if err != nil {
+		return err
+	}
+	if err := w.Start(ctx, dir); err != nil {
+		w.Close()
+		return err
+	}
+	go func() {
+		<-ctx.Done()
+		w.Close()
+	}()
+	logger.Info("watching", "rule", rule.Name, "dir", dir)
+	return nil
+}
+
+// onWatch publishes a batch of changes and carries out the rule's action.
+func (b *Butler) onWatch(ctx context.Context, rule config.WatchRule, tmpl *template.Template, dir string, changes []watcher.Change) {
+	ctx = logging.With(ctx, "rule", rule.Name)
+	watchBatches.Inc(rule.Name)
+
+	data := watchData{Rule: rule.Name, Dir: dir}
+	var paths []string
+	for _, c := range changes {
+		b.Spine.Broadcast(spine.Event{Type: spine.TopicWatcherFileChanged, Payload: spine.FileChanged{
+			Watcher: rule.Name, Path: c.Path, Op: c.Op.String(),
+		}})
+		paths = append(paths, c.Path)
+		rel, err := filepath.Rel(dir, c.Path)
+		if err != nil {
+			rel = c.Path
+		}
+		data.Files = append(data.Files, filepath.ToSlash(rel))
+	}
+	logger.DebugContext(ctx, "files changed", "files", len(changes))
+
+	if rule.Workflow != "" {
+		if b.coalesceWatchMission(ctx, rule, data) {
+			return
+		}
+		if err := b.applyWatchWorkflow(rule, data); err != nil {
+			logger.ErrorContext(ctx, "could not apply workflow", "workflow", rule.Workflow, "err", err)
+		}
+		return
+	}
+
+	if rule.Task != "" && b.coalesceWatch(ctx, rule, tmpl, dir, &data, paths) {
+		return
+	}
+
+	var text strings.Builder
+	if err := tmpl.Execute(&text, data); err != nil {
+		logger.ErrorContext(ctx, "could not render watch rule", "err", err)
+		return
+	}
+	if rule.Task != "" {
+		if _, err := b.startWatchTask(ctx, rule, dir, paths, text.String()); err != nil {
+			logger.ErrorContext(ctx, "could not start task", "err", err)
+		}
+		return
+	}
+	if platform, chatID, ok := strings.Cut(rule.Chat, ":"); ok {
+		b.SendUpdate(platform, chatID, text.String())
+	} else {
+		b.NotifyOwners(text.String())
+	}
+}
+
+// coalesceWatch keeps a rule to one task at a time, so that a task writing
+// into the watched directory does not trigger itself. While the rule's
+// last task is still queued, the changes join it: the files are merged
+// into its fovea and its goal is rendered again. While it runs, or waits
+// for input or a resume, the changes are skipped. It reports whether the
+// batch was handled.
+func (b *Butler) coalesceWatch(ctx context.Context, rule config.WatchRule, tmpl *template.Template, dir string, data *watchData, paths []string) bool {
+	b.mu.Lock()
+	var active *Task
+	for _, t := range b.tasks {
+		if t.Metadata == nil || t.Metadata["watch"] != rule.Name {
+			continue
+		}
+		if t.Status == TaskStatusPending || t.Status == TaskStatusRunning || t.Status == TaskStatusPaused {
+			active = t
+			break
+		}
+	}
+	if active == nil {
+		b.mu.Unlock()
+		return false
+	}
+	id := active.ID
+	if active.Status != TaskStatusPending || active.Continuity != nil {
+		b.mu.Unlock()
+		logger.InfoContext(ctx, "skipping changes while the rule's task is active", logging.KeyTask, id, "files", len(paths))
+		return true
+	}
+
+	seen := map[string]bool{}
+	var merged []string
+	for _, p := range append(strings.Split(active.Metadata["fovea"], "\n"), paths...) {
+		if p != "" && !seen[p] {
+			seen[p] = true
+			merged = append(merged, p)
+		}
+	}
+	data.Files = nil
+	for _, p := range merged {
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			rel = p
+		}
+		data.Files = append(data.Files, filepath.ToSlash(rel))
+	}
+	var text strings.Builder
+	if err := tmpl.Execute(&text, *data); err != nil {
+		b.mu.Unlock()
+		logger.ErrorContext(ctx, "could not render watch rule", "err", err)
+		return true
+	}
+	active.Content = text.String()
+	active.Metadata["fovea"] = strings.Join(merged, "\n")
+	b.mu.Unlock()
+	b.save()
+	logger.InfoContext(ctx, "merged changes into the queued task", logging.KeyTask, id, "files", len(merged))
+	return true
+}
+
+// startWatchTask creates a task working in dir with the changed files
+// pinned in its fovea, and leaves it to the nervous system to plan.
+func (b *Butler) startWatchTask(ctx context.Context, rule config.WatchRule, dir string, files []string, goal string) (*Task, error) {
+	now := time.Now()
+	id := fmt.Sprintf("task_%d_%s", now.Unix(), rule.Name)
+	ctx, span := tracing.Start(ctx, "StartTask", "task_id", id, "platform", "watch", "content", goal)
+	defer span.End()
+
+	b.mu.Lock()
+	if _, taken := b.tasks[id]; taken {
+		id = fmt.Sprintf("task_%d_%s", now.UnixNano(), rule.Name)
+	}
+	task := &Task{
+		ID:          id,
+		Content:     goal,
+		Status:      TaskStatusPending,
+		StartedAt:   now,
+		Platform:    "watch",
+		ChatID:      "internal",
+		Priority:    priorityFor("watch"),
+		Traceparent: span.Traceparent(),
+		Metadata: map[string]string{
+			"watch":   rule.Name,
+			"workdir": dir,
+			"fovea":   strings.Join(files, "\n"),
+		},
+	}
+	b.tasks[id] = task
+	b.mu.Unlock()
+	b.save()
+	b.emit(id, TraceEvent{Kind: TraceCreated, Detail: goal})
+	logger.InfoContext(logging.With(ctx, logging.KeyTask, id), "task started", "files", len(files))
+	return task, nil
+}
+
+// coalesceWatchMission keeps a workflow rule to one mission at a time, as
+// coalesceWatch does for tasks. While none of the rule's mission steps has
+// been dispatched, the changed files join its "files" input; after that,
+// the changes are skipped. It reports whether the batch was handled.
+func (b *Butler) coalesceWatchMission(ctx context.Context, rule config.WatchRule, data watchData) bool {
+	var active *mission.Mission
+	for _, m := range b.Missions.ActiveMissions() {
+		if m.Watch == rule.Name {
+			active = m
+			break
+		}
+	}
+	if active == nil {
+		return false
+	}
+	ctx = logging.With(ctx, logging.KeyMission, active.ID)
+
+	started := false
+	b.mu.RLock()
+	for _, t := range b.tasks {
+		if t.Metadata != nil && t.Metadata["mission_id"] == active.ID {
+			started = true
+			break
+		}
+	}
+	b.mu.RUnlock()
+	files, declared := active.Inputs["files"]
+	if started || !declared {
+		logger.InfoContext(ctx, "skipping changes while the rule's mission is active", "files", len(data.Files))
+		return true
+	}
+
+	seen := map[string]bool{}
+	var merged []string
+	for _, f := range append(strings.Split(files, "\n"), data.Files...) {
+		if f != "" && !seen[f] {
+			seen[f] = true
+			merged = append(merged, f)
+		}
+	}
+	inputs := make(map[string]string, len(active.Inputs))
+	for k, v := range active.Inputs {
+		inputs[k] = v
+	}
+	inputs["files"] = strings.Join(merged, "\n")
+	if err := b.Missions.SetInputs(active.ID, inputs); err != nil {
+		logger.ErrorContext(ctx, "could not merge changes into the mission", "err", err)
+		return true
+	}
+	logger.InfoContext(ctx, "merged changes into the queued mission", "files", len(merged))
+	return true
+}
+
+// applyWatchWorkflow turns the rule's workflow into a mission, passing the
+// changed files, directory and rule name to the inputs it declares.
+func (b *Butler) applyWatchWorkflow(rule config.WatchRule, data watchData) error {
+	wf, err := workflow.Load(rule.Workflow)
+	if err != nil {
+		return err
+	}
+	given := map[string]string{}
+	for k, v := range map[string]string{"files": strings.Join(data.Files, "\n"), "dir": data.Dir, "rule": data.Rule} {
+		if _, ok := wf.Inputs[k]; ok {
+			given[k] = v
+		}
+	}
+	inputs, err := wf.ResolveInputs(given)
+	if err != nil {
+		return err
+	}
+	m, err := wf.ToMission(b.Missions, inputs)
+	if err != nil {
+		return err
+	}
+	m.Watch = rule.Name
+	if err := b.Missions.Save(); err != nil {
+		return err
+	}
+	logger.Info("watch rule applied workflow", "rule", rule.Name, "workflow", wf.Name, logging.KeyMission, m.ID)
+	return nil
+}
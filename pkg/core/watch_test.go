@@ -0,0 +1,173 @@
+package core
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/nathfavour/auracrab/pkg/config"
+	"github.com/nathfavour/auracrab/pkg/mission"
+	"github.com/nathfavour/auracrab/pkg/spine"
+	"github.com/nathfavour/auracrab/pkg/watcher"
+	"github.com/nathfavour/auracrab/pkg/workflow"
+)
+
+func TestWatchTaskPinsChangedFiles(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	dir := t.TempDir()
+	for _, name := range []string{"a.go", "b.go"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("package a"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	b := &Butler{tasks: map[string]*Task{}, Spine: spine.NewSpine(time.Second)}
+	rule := config.WatchRule{Name: "review", Dir: dir, Task: `Review {{ join .Files ", " }}`}
+	tmpl, err := watchTemplate(rule)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.onWatch(context.Background(), rule, tmpl, dir, []watcher.Change{
+		{Path: filepath.Join(dir, "a.go"), Op: fsnotify.Write},
+		{Path: filepath.Join(dir, "b.go"), Op: fsnotify.Create},
+		{Path: filepath.Join(dir, "gone.go"), Op: fsnotify.Remove},
+	})
+
+	tasks := b.ListTasks()
+	if len(tasks) != 1 {
+		t.Fatalf("expected one task, got %d", len(tasks))
+	}
+	task := tasks[0]
+	if task.Content != "Review a.go, b.go, gone.go" || task.Status != TaskStatusPending {
+		t.Fatalf("unexpected task %q (%s)", task.Content, task.Status)
+	}
+
+	fovea := b.foveaFor(context.Background(), task, &Fovea{})
+	if len(fovea.Files) < 2 || fovea.Files[0] != filepath.Join(dir, "a.go") || fovea.Files[1] != filepath.Join(dir, "b.go") {
+		t.Fatalf("expected the changed files to lead the fovea, got %v", fovea.Files)
+	}
+	for _, f := range fovea.Files {
+		if filepath.Base(f) == "gone.go" {
+			t.Fatal("a removed file was pinned")
+		}
+	}
+}
+
+func TestValidateWatchRule(t *testing.T) {
+	bad := []config.WatchRule{
+		{Name: "x", Dir: ".", Task: "{{ .Nope", Events: []string{"write"}},
+		{Name: "x", Dir: ".", Notify: "hi", Events: []string{"touch"}},
+		{Name: "x", Dir: ".", Notify: "hi", Include: []string{"[a-"}},
+	}
+	for _, rule := range bad {
+		if err := ValidateWatchRule(rule); err == nil {
+			t.Errorf("expected %+v to be rejected", rule)
+		}
+	}
+	if err := ValidateWatchRule(config.WatchRule{Name: "x", Dir: ".", Workflow: "lint"}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWatchRuleRunsOneTaskAtATime(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	dir := t.TempDir()
+	b := &Butler{tasks: map[string]*Task{}, Spine: spine.NewSpine(time.Second)}
+	rule := config.WatchRule{Name: "review", Dir: dir, Task: `Review {{ join .Files ", " }}`}
+	tmpl, _ := watchTemplate(rule)
+	change := func(name string) {
+		b.onWatch(context.Background(), rule, tmpl, dir, []watcher.Change{{Path: filepath.Join(dir, name), Op: fsnotify.Write}})
+	}
+
+	steps := []struct {
+		name    string
+		file    string
+		before  TaskStatus
+		tasks   int
+		content string
+	}{
+		{"first batch starts a task", "a.go", "", 1, "Review a.go"},
+		{"queued task absorbs the batch", "b.go", TaskStatusPending, 1, "Review a.go, b.go"},
+		{"running task skips the batch", "c.go", TaskStatusRunning, 1, "Review a.go, b.go"},
+		{"finished task lets a new one start", "d.go", TaskStatusCompleted, 2, "Review d.go"},
+	}
+	var first *Task
+	for _, s := range steps {
+		if first != nil {
+			first.Status = s.before
+		}
+		change(s.file)
+		tasks := b.ListTasks()
+		if len(tasks) != s.tasks {
+			t.Fatalf("%s: %d tasks, want %d", s.name, len(tasks), s.tasks)
+		}
+		if first == nil {
+			first = b.tasks[tasks[0].ID]
+		}
+		found := false
+		for _, task := range tasks {
+			found = found || task.Content == s.content
+		}
+		if !found {
+			t.Fatalf("%s: no task reads %q", s.name, s.content)
+		}
+	}
+	if got := first.Metadata["fovea"]; got != filepath.Join(dir, "a.go")+"\n"+filepath.Join(dir, "b.go") {
+		t.Errorf("merged fovea = %q", got)
+	}
+}
+
+func TestWatchOptionsAlwaysExcludeGit(t *testing.T) {
+	opts, err := watchOptions(config.WatchRule{Name: "all", Dir: ".", Task: "x", Exclude: []string{"vendor/**"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, rel := range []string{".git", ".git/index", "vendor/x.go"} {
+		excluded := false
+		for _, p := range opts.Exclude {
+			excluded = excluded || watcher.Match(p, rel)
+		}
+		if !excluded {
+			t.Errorf("%s is watched", rel)
+		}
+	}
+}
+
+func TestWatchWorkflowOneMissionAtATime(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	dir := t.TempDir()
+	wf, err := workflow.Parse([]byte("name: lint\ninputs:\n  files: {}\nsteps:\n  - id: lint\n    prompt: \"lint {{ .inputs.files }}\"\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wf.Save(false); err != nil {
+		t.Fatal(err)
+	}
+	missions, err := mission.NewManager()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := &Butler{tasks: map[string]*Task{}, Missions: missions, Spine: spine.NewSpine(time.Second)}
+	rule := config.WatchRule{Name: "lint", Dir: dir, Workflow: "lint"}
+	batch := func(name string) {
+		b.onWatch(context.Background(), rule, nil, dir, []watcher.Change{{Path: filepath.Join(dir, name), Op: fsnotify.Write}})
+	}
+
+	batch("a.go")
+	batch("b.go")
+	active := missions.ActiveMissions()
+	if len(active) != 1 || active[0].Watch != "lint" || active[0].Inputs["files"] != "a.go\nb.go" {
+		t.Fatalf("expected one mission with both files, got %+v", active)
+	}
+
+	// Once a step is dispatched, later changes are skipped.
+	b.tasks["t1"] = &Task{ID: "t1", Status: TaskStatusRunning, Metadata: map[string]string{"mission_id": active[0].ID}}
+	batch("c.go")
+	if active := missions.ActiveMissions(); len(active) != 1 || active[0].Inputs["files"] != "a.go\nb.go" {
+		t.Fatalf("changes reached a running mission: %+v", active)
+	}
+}
@@ -0,0 +1,90 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/nathfavour/auracrab/internal/provider"
+	"github.com/nathfavour/auracrab/pkg/biology"
+	"github.com/nathfavour/auracrab/pkg/mission"
+	"github.com/nathfavour/auracrab/pkg/workflow"
+)
+
+// startWorkflowStep runs a prebuilt workflow sub-task directly, without
+// planning. Outputs of completed sub-tasks feed its templates.
+func (b *Butler) startWorkflowStep(ctx context.Context, m *mission.Mission, st mission.SubTask, tag string) (*Task, error) {
+	var step workflow.Step
+	if err := json.Unmarshal(st.Spec, &step); err != nil {
+		return nil, fmt.Errorf("invalid workflow step %s: %w", st.ID, err)
+	}
+
+	outputs := map[string]string{}
+	for _, t := range m.Tasks {
+		if t.Status == mission.StatusCompleted {
+			outputs[t.ID] = t.Result
+		}
+	}
+	vars := workflow.Vars(m.Inputs, outputs)
+
+	b.mu.Lock()
+	id := fmt.Sprintf("task_%d_%s", time.Now().Unix(), st.ID)
+	task := &Task{
+		ID:        id,
+		Content:   fmt.Sprintf("WORKFLOW %s: %s", m.Workflow, step.Title()),
+		Status:    TaskStatusRunning,
+		StartedAt: time.Now(),
+		Platform:  "mission",
+		ChatID:    "internal",
+		Priority:  biology.PriorityNormal,
+		Metadata: map[string]string{
+			"subtask_tag": tag,
+			"mission_id":  m.ID,
+			"subtask_id":  st.ID,
+			"workflow":    m.Workflow,
+		},
+	}
+	b.tasks[id] = task
+	b.mu.Unlock()
+	b.save()
+	b.emit(id, TraceEvent{Kind: TraceCreated, Detail: task.Content})
+	b.emit(id, TraceEvent{Kind: TraceStatus, Status: string(TaskStatusRunning)})
+
+	go func() {
+		start := time.Now()
+		out, err := workflow.RunStep(ctx, step, vars, tracingQuerier{b, id}, func(attempt int, err error) {
+			b.mu.Lock()
+			task.Logs = append(task.Logs, fmt.Sprintf("attempt %d after error: %v", attempt+1, err))
+			b.mu.Unlock()
+			b.emit(id, TraceEvent{Kind: TraceRetry, Attempt: attempt + 1, Error: err.Error()})
+		})
+		if step.Skill != "" {
+			args, _ := json.Marshal(step.Args)
+			e := TraceEvent{Kind: TraceSkill, Skill: step.Skill, Detail: string(args), Response: out, Duration: time.Since(start)}
+			if err != nil {
+				e.Error = err.Error()
+			}
+			b.emit(id, e)
+		}
+		if err != nil {
+			b.updateStatus(id, TaskStatusFailed, err.Error())
+			return
+		}
+		b.updateStatus(id, TaskStatusCompleted, out)
+	}()
+	return task, nil
+}
+
+// tracingQuerier records the prompts of a workflow step in its task trace.
+type tracingQuerier struct {
+	b      *Butler
+	taskID string
+}
+
+func (q tracingQuerier) QueryWithContext(ctx context.Context, prompt, intent string) (provider.CompletionResponse, error) {
+	start := time.Now()
+	resp, err := q.b.QueryWithContext(ctx, prompt, intent)
+	q.b.tracePrompt(q.taskID, "vibeauracle", intent, prompt, start, resp, err)
+	return resp, err
+}
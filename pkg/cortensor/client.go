@@ -13,8 +13,11 @@ import (
 	"time"
 
 	"github.com/nathfavour/auracrab/pkg/config"
+	"github.com/nathfavour/auracrab/pkg/logging"
 )
 
+var logger = logging.For("cortensor")
+
 // SessionMetadata stores Cortensor-specific session state
 type SessionMetadata struct {
 	SessionID     string    `json:"session_id"`
@@ -117,6 +120,27 @@ func (c *Client) LoadState() (*SessionMetadata, error) {
 	return &meta, nil
 }
 
+// Ping checks that the router answers HTTP at all; any reply short of a
+// server error counts.
+func (c *Client) Ping(ctx context.Context) error {
+	if c.routerEndpoint == "" {
+		return fmt.Errorf("cortensor router endpoint not configured")
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", c.routerEndpoint, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("cortensor router unreachable: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 500 {
+		return fmt.Errorf("cortensor router returned status: %d", resp.StatusCode)
+	}
+	return nil
+}
+
 // Query sends an inference request to the Cortensor network
 func (c *Client) Query(ctx context.Context, content, intent string) (*RouterCompletionResponse, error) {
 	url := fmt.Sprintf("%s/v1/inference/completion", c.routerEndpoint)
@@ -142,7 +166,7 @@ func (c *Client) Query(ctx context.Context, content, intent string) (*RouterComp
 			routerReq.Content = compressed
 			routerReq.Compressed = true
 			routerReq.Encoding = "gzip/base64"
-			fmt.Printf("CortensorClient: Large context compressed (original: %d bytes, compressed: %d bytes)\n", len(content), len(compressed))
+			logger.Debug("compressed large context", "bytes", len(content), "compressed", len(compressed))
 		}
 	}
 
@@ -2,9 +2,20 @@ package cron
 
 import (
 	"context"
-	"log"
 	"sync"
 	"time"
+
+	"github.com/nathfavour/auracrab/pkg/logging"
+	"github.com/nathfavour/auracrab/pkg/metrics"
+)
+
+var logger = logging.For("cron")
+
+var (
+	runs = metrics.NewCounter("auracrab_cron_runs_total",
+		"Scheduled job runs by job and outcome (ok or panic).", "job", "outcome")
+	runDuration = metrics.NewHistogram("auracrab_cron_run_duration_seconds",
+		"How long scheduled jobs ran.", nil, "job")
 )
 
 // ScheduledTask represents a task to be run on a schedule.
@@ -20,6 +31,9 @@ type Scheduler struct {
 	tasks map[string]*ScheduledTask
 	mu    sync.RWMutex
 	stop  chan struct{}
+
+	// OnFire, if set, is called whenever a task runs.
+	OnFire func(id string, interval time.Duration)
 }
 
 func NewScheduler() *Scheduler {
@@ -66,11 +80,28 @@ func (s *Scheduler) checkTasks(ctx context.Context) {
 	now := time.Now()
 	for _, t := range s.tasks {
 		if now.Sub(t.lastRun) >= t.Interval {
-			log.Printf("Cron: Running scheduled task '%s'", t.ID)
-			go func(task *ScheduledTask) {
-				task.Action(ctx)
-				task.lastRun = time.Now()
-			}(t)
+			logger.Info("running scheduled task", "cron_id", t.ID)
+			if s.OnFire != nil {
+				s.OnFire(t.ID, t.Interval)
+			}
+			go run(ctx, t)
 		}
 	}
 }
+
+// run runs a job once and records its outcome. A panicking job is logged
+// rather than taking the daemon down.
+func run(ctx context.Context, task *ScheduledTask) {
+	start := time.Now()
+	defer func() {
+		outcome := "ok"
+		if r := recover(); r != nil {
+			outcome = "panic"
+			logger.Error("scheduled task panicked", "cron_id", task.ID, "panic", r)
+		}
+		runs.Inc(task.ID, outcome)
+		runDuration.Observe(time.Since(start).Seconds(), task.ID)
+		task.lastRun = time.Now()
+	}()
+	task.Action(ctx)
+}
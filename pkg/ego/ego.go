@@ -149,6 +149,17 @@ func (e *Ego) GetIdentity() Identity {
 	return e.Identity
 }
 
+// RecentThoughts returns up to n of the latest narrative entries, oldest first.
+func (e *Ego) RecentThoughts(n int) []string {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	start := len(e.Narrative) - n
+	if start < 0 {
+		start = 0
+	}
+	return append([]string{}, e.Narrative[start:]...)
+}
+
 func (e *Ego) SetOpinion(subject string, sentiment float64, reason string) {
 	e.mu.Lock()
 	defer e.mu.Unlock()
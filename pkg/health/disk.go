@@ -0,0 +1,38 @@
+package health
+
+import (
+	"context"
+	"fmt"
+)
+
+// Disk checks the free space of the filesystem holding dir: degraded
+// below degradedBelow bytes and down below downBelow.
+func Disk(dir string, degradedBelow, downBelow uint64) Check {
+	return func(ctx context.Context) (Status, string) {
+		free, total, err := diskSpace(dir)
+		if err != nil {
+			return StatusDown, err.Error()
+		}
+		reason := fmt.Sprintf("%s free of %s in %s", formatBytes(free), formatBytes(total), dir)
+		switch {
+		case free < downBelow:
+			return StatusDown, reason
+		case free < degradedBelow:
+			return StatusDegraded, reason
+		}
+		return StatusOK, reason
+	}
+}
+
+func formatBytes(n uint64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := uint64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,272 @@
+// Package health runs probes against the parts of the daemon it depends on
+// (the vibe socket, the model provider, channels, disk, databases, the
+// spine) and folds their results into one ok, degraded or down verdict.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Status is the state of one probe or of the whole daemon.
+type Status string
+
+const (
+	StatusOK       Status = "ok"
+	StatusDegraded Status = "degraded"
+	StatusDown     Status = "down"
+)
+
+func (s Status) rank() int {
+	switch s {
+	case StatusOK:
+		return 0
+	case StatusDegraded:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// Symbol marks the status in text reports.
+func (s Status) Symbol() string {
+	switch s {
+	case StatusOK:
+		return "✅"
+	case StatusDegraded:
+		return "⚠️"
+	default:
+		return "❌"
+	}
+}
+
+// DefaultTimeout bounds a probe that does not finish on its own.
+const DefaultTimeout = 5 * time.Second
+
+// Check reports the state of what a probe watches, with a reason a person
+// can act on.
+type Check func(ctx context.Context) (Status, string)
+
+// Probe is a named check. A probe that is down only takes the daemon down
+// when it is Critical; otherwise the daemon is degraded.
+type Probe struct {
+	Name     string
+	Critical bool
+	Timeout  time.Duration
+	Check    Check
+}
+
+// Result is the outcome of one probe.
+type Result struct {
+	Name     string        `json:"name"`
+	Status   Status        `json:"status"`
+	Reason   string        `json:"reason,omitempty"`
+	Critical bool          `json:"critical,omitempty"`
+	Took     time.Duration `json:"took_ns"`
+}
+
+// Report is the outcome of every probe.
+type Report struct {
+	Status  Status    `json:"status"`
+	Checked time.Time `json:"checked"`
+	Results []Result  `json:"results"`
+}
+
+// Registry holds probes and caches their last report.
+type Registry struct {
+	mu         sync.Mutex
+	probes     map[string]Probe
+	last       Report
+	refreshing bool
+}
+
+func NewRegistry() *Registry {
+	return &Registry{probes: map[string]Probe{}}
+}
+
+// Register adds p, replacing a probe with the same name.
+func (r *Registry) Register(p Probe) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.probes[p.Name] = p
+}
+
+// Unregister removes the probe called name.
+func (r *Registry) Unregister(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.probes, name)
+}
+
+// Run runs every probe concurrently and aggregates the results.
+func (r *Registry) Run(ctx context.Context) Report {
+	r.mu.Lock()
+	probes := make([]Probe, 0, len(r.probes))
+	for _, p := range r.probes {
+		probes = append(probes, p)
+	}
+	r.mu.Unlock()
+
+	results := make([]Result, len(probes))
+	var wg sync.WaitGroup
+	for i, p := range probes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = run(ctx, p)
+		}()
+	}
+	wg.Wait()
+
+	rep := Aggregate(results)
+	r.mu.Lock()
+	r.last = rep
+	r.mu.Unlock()
+	return rep
+}
+
+// Report returns the last report when it is younger than maxAge, and runs
+// the probes again otherwise.
+func (r *Registry) Report(ctx context.Context, maxAge time.Duration) Report {
+	r.mu.Lock()
+	last := r.last
+	r.mu.Unlock()
+	if !last.Checked.IsZero() && time.Since(last.Checked) < maxAge {
+		return last
+	}
+	return r.Run(ctx)
+}
+
+// Last returns the last report without waiting on probes, and starts
+// running them in the background when it is older than maxAge. Before the
+// first run the report has no results and a zero Checked time.
+func (r *Registry) Last(maxAge time.Duration) Report {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.refreshing && (r.last.Checked.IsZero() || time.Since(r.last.Checked) >= maxAge) {
+		r.refreshing = true
+		go func() {
+			r.Run(context.Background())
+			r.mu.Lock()
+			r.refreshing = false
+			r.mu.Unlock()
+		}()
+	}
+	return r.last
+}
+
+// run checks p, turning a timeout or panic into a down result.
+func run(ctx context.Context, p Probe) Result {
+	timeout := p.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	res := Result{Name: p.Name, Critical: p.Critical}
+	done := make(chan struct{})
+	start := time.Now()
+	go func() {
+		defer close(done)
+		defer func() {
+			if v := recover(); v != nil {
+				res.Status, res.Reason = StatusDown, fmt.Sprintf("probe panicked: %v", v)
+			}
+		}()
+		res.Status, res.Reason = p.Check(ctx)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return Result{Name: p.Name, Critical: p.Critical, Status: StatusDown,
+			Reason: fmt.Sprintf("no answer within %s", timeout), Took: time.Since(start)}
+	}
+	res.Took = time.Since(start)
+	if res.Status == "" {
+		res.Status = StatusOK
+	}
+	return res
+}
+
+// Aggregate folds results into a report: down when a critical probe is
+// down, degraded when any probe is not ok, ok otherwise.
+func Aggregate(results []Result) Report {
+	sort.Slice(results, func(i, j int) bool { return results[i].Name < results[j].Name })
+	rep := Report{Status: StatusOK, Checked: time.Now(), Results: results}
+	for _, res := range results {
+		s := res.Status
+		if s == StatusDown && !res.Critical {
+			s = StatusDegraded
+		}
+		if s.rank() > rep.Status.rank() {
+			rep.Status = s
+		}
+	}
+	return rep
+}
+
+// Failing returns the results that are not ok.
+func (r Report) Failing() []Result {
+	var out []Result
+	for _, res := range r.Results {
+		if res.Status != StatusOK {
+			out = append(out, res)
+		}
+	}
+	return out
+}
+
+// Summary is a one-line verdict naming the failing probes.
+func (r Report) Summary() string {
+	if r.Checked.IsZero() {
+		return "System Health: checking..."
+	}
+	failing := r.Failing()
+	if len(failing) == 0 {
+		return fmt.Sprintf("System Health: %s (%d checks passed)", r.Status, len(r.Results))
+	}
+	var parts []string
+	for _, res := range failing {
+		parts = append(parts, res.Name+": "+res.Reason)
+	}
+	return fmt.Sprintf("System Health: %s (%s)", r.Status, strings.Join(parts, "; "))
+}
+
+// String lists every probe, one per line.
+func (r Report) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s Health: %s\n", r.Status.Symbol(), r.Status)
+	for _, res := range r.Results {
+		fmt.Fprintf(&b, "%s %-14s %s", res.Status.Symbol(), res.Name, res.Status)
+		if res.Reason != "" {
+			fmt.Fprintf(&b, " - %s", res.Reason)
+		}
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// Handler serves the report as JSON, answering 503 while the daemon is
+// down so load balancers and supervisors can act on the status code alone.
+func Handler(r *Registry, maxAge time.Duration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodGet && req.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		rep := r.Report(req.Context(), maxAge)
+		w.Header().Set("Content-Type", "application/json")
+		if rep.Status == StatusDown {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		_ = json.NewEncoder(w).Encode(rep)
+	})
+}
@@ -0,0 +1,95 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func fixed(s Status, reason string) Check {
+	return func(context.Context) (Status, string) { return s, reason }
+}
+
+func TestAggregateOnlyCriticalProbesTakeTheDaemonDown(t *testing.T) {
+	r := NewRegistry()
+	r.Register(Probe{Name: "disk", Critical: true, Check: fixed(StatusOK, "")})
+	r.Register(Probe{Name: "channel:telegram", Check: fixed(StatusDown, "unauthorized")})
+	if rep := r.Run(context.Background()); rep.Status != StatusDegraded {
+		t.Fatalf("non-critical probe down: status %s, want degraded", rep.Status)
+	}
+
+	r.Register(Probe{Name: "spine", Critical: true, Check: fixed(StatusDown, "last pulse 5m ago")})
+	rep := r.Run(context.Background())
+	if rep.Status != StatusDown {
+		t.Fatalf("critical probe down: status %s, want down", rep.Status)
+	}
+	if got := len(rep.Failing()); got != 2 {
+		t.Errorf("%d failing probes, want 2", got)
+	}
+	if s := rep.Summary(); !strings.Contains(s, "spine: last pulse 5m ago") {
+		t.Errorf("summary %q does not name the spine", s)
+	}
+	if rep.Results[0].Name != "channel:telegram" {
+		t.Errorf("results are not sorted: %+v", rep.Results)
+	}
+}
+
+func TestProbeTimeoutAndPanicAreDown(t *testing.T) {
+	r := NewRegistry()
+	r.Register(Probe{Name: "hung", Timeout: 20 * time.Millisecond, Check: func(ctx context.Context) (Status, string) {
+		time.Sleep(time.Second)
+		return StatusOK, ""
+	}})
+	r.Register(Probe{Name: "broken", Check: func(context.Context) (Status, string) { panic("nil map") }})
+	rep := r.Run(context.Background())
+	for _, res := range rep.Results {
+		if res.Status != StatusDown {
+			t.Errorf("%s: status %s, want down", res.Name, res.Status)
+		}
+	}
+	if !strings.Contains(rep.Results[0].Reason, "panicked") || !strings.Contains(rep.Results[1].Reason, "no answer") {
+		t.Errorf("reasons = %q, %q", rep.Results[0].Reason, rep.Results[1].Reason)
+	}
+}
+
+func TestHandlerServesReportWith503WhenDown(t *testing.T) {
+	r := NewRegistry()
+	r.Register(Probe{Name: "sqlite", Critical: true, Check: fixed(StatusDown, "corrupt")})
+	srv := httptest.NewServer(Handler(r, time.Minute))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("status code %d, want 503", resp.StatusCode)
+	}
+	var rep Report
+	if err := json.NewDecoder(resp.Body).Decode(&rep); err != nil {
+		t.Fatal(err)
+	}
+	if rep.Status != StatusDown || len(rep.Results) != 1 || rep.Results[0].Reason != "corrupt" {
+		t.Errorf("report = %+v", rep)
+	}
+}
+
+func TestLastRefreshesInTheBackground(t *testing.T) {
+	r := NewRegistry()
+	r.Register(Probe{Name: "vault", Check: fixed(StatusOK, "")})
+	if rep := r.Last(time.Minute); !rep.Checked.IsZero() {
+		t.Fatal("Last ran the probes in the foreground")
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for r.Last(time.Minute).Checked.IsZero() {
+		if time.Now().After(deadline) {
+			t.Fatal("the background run never finished")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
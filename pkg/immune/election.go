@@ -0,0 +1,77 @@
+package immune
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+var errLocked = errors.New("locked by another node")
+
+// Election holds swarm leadership through an exclusive lock on
+// swarm/leader.lock. The OS drops the lock when the leader exits, so a
+// follower wins the next campaign.
+type Election struct {
+	dir  string
+	node string
+
+	mu   sync.Mutex
+	lock *os.File
+}
+
+func NewElection(dir, node string) *Election {
+	return &Election{dir: dir, node: node}
+}
+
+// Campaign tries to take the leadership and reports whether this node leads.
+func (e *Election) Campaign() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.lock != nil {
+		return true
+	}
+
+	f, err := os.OpenFile(filepath.Join(e.dir, "leader.lock"), os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return false
+	}
+	if err := lockFile(f, false); err != nil {
+		f.Close()
+		return false
+	}
+	// The lock file itself may be unreadable to others while locked, so
+	// the leader's ID is published next to it.
+	_ = os.WriteFile(filepath.Join(e.dir, "leader"), []byte(e.node), 0644)
+	e.lock = f
+	return true
+}
+
+// IsLeader reports whether this node holds the leadership.
+func (e *Election) IsLeader() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.lock != nil
+}
+
+// Leader returns the ID of the last node to win an election.
+func (e *Election) Leader() string {
+	data, err := os.ReadFile(filepath.Join(e.dir, "leader"))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// Resign gives up the leadership.
+func (e *Election) Resign() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.lock == nil {
+		return
+	}
+	_ = unlockFile(e.lock)
+	_ = e.lock.Close()
+	e.lock = nil
+}
@@ -2,6 +2,7 @@ package immune
 
 import (
 	"context"
+	"crypto/ed25519"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -11,8 +12,11 @@ import (
 
 	"github.com/nathfavour/auracrab/pkg/biology"
 	"github.com/nathfavour/auracrab/pkg/config"
+	"github.com/nathfavour/auracrab/pkg/logging"
 )
 
+var logger = logging.For("immune")
+
 type NodeStatus string
 
 const (
@@ -21,13 +25,20 @@ const (
 	StatusMutated  NodeStatus = "mutated"
 )
 
+// NodeTimeout is how long a node may miss heartbeats before it is dead.
+const NodeTimeout = 30 * time.Second
+
 type Node struct {
 	PID       int        `json:"pid"`
 	ID        string     `json:"id"`
 	Status    NodeStatus `json:"status"`
 	ErrorRate float64    `json:"error_rate"`
+	Leader    bool       `json:"leader,omitempty"`
+	Role      Role       `json:"role,omitempty"`
 	LastPing  time.Time  `json:"last_ping"`
 	BornAt    time.Time  `json:"born_at"`
+	// PublicKey verifies the node's swarm messages.
+	PublicKey ed25519.PublicKey `json:"public_key,omitempty"`
 }
 
 type ImmuneSystem struct {
@@ -35,34 +46,203 @@ type ImmuneSystem struct {
 	registry string
 	self     *Node
 	bus      *SwarmBus
+	election *Election
+	leases   *LeaseTable
+	host     Host
+	replicas *ReplicaController // nil unless this node may clone itself
+	parent   int                // PID of the node that started this worker
+	mesh     *Mesh              // nil unless the node talks to other machines
+
+	votes       map[int]map[int]time.Time // target PID -> voter PID -> when
+	voted       map[int]time.Time         // our own votes, one per target per window
+	lastHandoff time.Time
+	handoffs    map[string]pendingHandoff // task ID -> unacknowledged local handoff
 }
 
+// SwarmDir is the registry shared by the nodes of one agent.
+func SwarmDir() string {
+	return filepath.Join(config.DataDir(), "swarm")
+}
+
+// NewImmuneSystem creates this process's swarm node. Several processes may
+// share a host name, so the node ID also carries the PID. The node signs
+// its messages with a key generated for this process, which never leaves
+// memory.
 func NewImmuneSystem(nodeID string) *ImmuneSystem {
-	regDir := filepath.Join(config.DataDir(), "swarm")
+	regDir := SwarmDir()
 	_ = os.MkdirAll(regDir, 0755)
 
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		panic(fmt.Sprintf("immune: generating node key: %v", err))
+	}
+
 	selfPID := os.Getpid()
-	return &ImmuneSystem{
+	id := fmt.Sprintf("%s-%d", nodeID, selfPID)
+	is := &ImmuneSystem{
 		registry: regDir,
 		self: &Node{
-			PID:    selfPID,
-			ID:     nodeID,
-			Status: StatusHealthy,
-			BornAt: time.Now(),
+			PID:       selfPID,
+			ID:        id,
+			Status:    StatusHealthy,
+			Role:      RoleIngress,
+			BornAt:    time.Now(),
+			PublicKey: pub,
 		},
-		bus: NewSwarmBus(selfPID),
+		election: NewElection(regDir, id),
+		leases:   NewLeaseTable(regDir, DefaultLeaseTTL),
+		votes:    map[int]map[int]time.Time{},
+		voted:    map[int]time.Time{},
+		handoffs: map[string]pendingHandoff{},
+	}
+	is.bus = NewSwarmBus(filepath.Join(regDir, "bus"), selfPID, priv, is.publicKey)
+	return is
+}
+
+// publicKey returns the key node pid published in the registry.
+func (is *ImmuneSystem) publicKey(pid int) (ed25519.PublicKey, bool) {
+	data, err := os.ReadFile(filepath.Join(is.registry, fmt.Sprintf("node_%d.json", pid)))
+	if err != nil {
+		return nil, false
+	}
+	var n Node
+	if json.Unmarshal(data, &n) != nil || n.PID != pid {
+		return nil, false
+	}
+	return n.PublicKey, true
+}
+
+// SetRole assigns the node's role before it registers. A worker retires
+// together with the process that started it.
+func (is *ImmuneSystem) SetRole(r Role) {
+	is.mu.Lock()
+	defer is.mu.Unlock()
+	is.self.Role = r
+	if r == RoleWorker {
+		is.parent = os.Getppid()
 	}
 }
 
-// Register adds the current node to the swarm registry.
+// Role returns the node's role in the swarm.
+func (is *ImmuneSystem) Role() Role {
+	is.mu.RLock()
+	defer is.mu.RUnlock()
+	return is.self.Role
+}
+
+// SetReplicaPolicy lets the node start worker replicas within p while it
+// leads. A policy without replicas disables cloning.
+func (is *ImmuneSystem) SetReplicaPolicy(p ReplicaPolicy) {
+	is.mu.Lock()
+	defer is.mu.Unlock()
+	if p.Max <= 0 && p.Min <= 0 {
+		is.replicas = nil
+		return
+	}
+	is.replicas = NewReplicaController(is.registry, p, nil)
+}
+
+// Replicas is the node's replica controller, nil when it does not clone.
+func (is *ImmuneSystem) Replicas() *ReplicaController {
+	is.mu.RLock()
+	defer is.mu.RUnlock()
+	return is.replicas
+}
+
+// JoinMesh connects the node to the swarms of other machines.
+func (is *ImmuneSystem) JoinMesh(cfg MeshConfig, creds *Credentials) error {
+	m := NewMesh(cfg, creds, is.meshSelf, is.handleMeshMessage)
+	m.SaveTo(filepath.Join(is.registry, "peers.json"))
+	if err := m.Start(); err != nil {
+		return err
+	}
+	is.mu.Lock()
+	is.mesh = m
+	is.mu.Unlock()
+	logger.Info("joined the network swarm", "node", is.self.ID, "addr", m.Addr(), "machine", creds.Name)
+	return nil
+}
+
+// Mesh is the node's network transport, nil when it has none.
+func (is *ImmuneSystem) Mesh() *Mesh {
+	is.mu.RLock()
+	defer is.mu.RUnlock()
+	return is.mesh
+}
+
+// meshSelf is the health this node gossips to other machines.
+func (is *ImmuneSystem) meshSelf() PeerInfo {
+	is.mu.RLock()
+	p := PeerInfo{
+		ID:        is.self.ID,
+		Status:    is.self.Status,
+		ErrorRate: is.self.ErrorRate,
+		Leader:    is.self.Leader,
+		Energy:    biology.Current().EnergyLevel,
+	}
+	host := is.host
+	is.mu.RUnlock()
+	if host != nil {
+		p.Skills = host.Skills()
+	}
+	return p
+}
+
+// handleMeshMessage handles a message from a peer on another machine. A
+// Handoff is answered with the HandoffAck of the tasks adopted here.
+func (is *ImmuneSystem) handleMeshMessage(msg MeshMessage) (Payload, error) {
+	switch msg.Type {
+	case MsgHandoff:
+		var h Handoff
+		if err := msg.Decode(&h); err != nil {
+			return nil, err
+		}
+		adopted, _ := is.adoptTasks(fmt.Sprintf("%s on %s", msg.From.ID, msg.From.Machine), h.Tasks)
+		return handoffAck(h.Tasks, adopted), nil
+	}
+	return nil, fmt.Errorf("unsupported message %s", msg.Type)
+}
+
+// Retire stops the node's replicas and leaves the network swarm as it
+// shuts down.
+func (is *ImmuneSystem) Retire() {
+	if rc := is.Replicas(); rc != nil {
+		rc.StopAll()
+	}
+	if m := is.Mesh(); m != nil {
+		_ = m.Close()
+	}
+}
+
+// Register adds the current node to the swarm registry and runs for leader.
 func (is *ImmuneSystem) Register() error {
+	is.mu.Lock()
 	is.self.LastPing = time.Now()
+	is.mu.Unlock()
+	is.campaign()
 	return is.writeSelf()
 }
 
+// campaign runs for leader, announcing a won election. Workers never lead.
+func (is *ImmuneSystem) campaign() {
+	if is.Role() == RoleWorker {
+		return
+	}
+	was := is.election.IsLeader()
+	leads := is.election.Campaign()
+	is.mu.Lock()
+	is.self.Leader = leads
+	is.mu.Unlock()
+	if leads && !was {
+		logger.Info("became the swarm leader", "node", is.self.ID)
+	}
+}
+
 func (is *ImmuneSystem) writeSelf() error {
 	path := filepath.Join(is.registry, fmt.Sprintf("node_%d.json", is.self.PID))
+	is.mu.RLock()
 	data, err := json.Marshal(is.self)
+	is.mu.RUnlock()
 	if err != nil {
 		return err
 	}
@@ -82,39 +262,36 @@ func (is *ImmuneSystem) Ping(ctx context.Context) error {
 	}
 	is.mu.Unlock()
 
+	// Followers keep campaigning so one takes over when the leader dies.
+	is.campaign()
+
 	if err := is.writeSelf(); err != nil {
 		return err
 	}
 
+	is.reclaimHandoffs()
 	return is.surveillance()
 }
 
-// surveillance looks for "diseased" or dead nodes.
+// surveillance looks for "diseased" or dead nodes. Only the leader clears
+// dead nodes and their task leases.
 func (is *ImmuneSystem) surveillance() error {
-	files, err := filepath.Glob(filepath.Join(is.registry, "node_*.json"))
-	if err != nil {
-		return err
-	}
-
-	for _, f := range files {
-		data, err := os.ReadFile(f)
-		if err != nil {
-			continue
-		}
-
-		var n Node
-		if err := json.Unmarshal(data, &n); err != nil {
-			continue
-		}
-
+	leader := is.IsLeader()
+	for _, n := range ReadNodes(is.registry) {
 		if n.PID == is.self.PID {
 			continue
 		}
 
 		// 1. Check for Dead Nodes (Heartbeat failure)
-		if time.Since(n.LastPing) > 30*time.Second {
-			fmt.Printf("IMMUNE: Node %d is dead (ping timeout). Removing from registry.\n", n.PID)
-			_ = os.Remove(f)
+		if time.Since(n.LastPing) > NodeTimeout {
+			is.bus.Forget(n.PID)
+			if leader {
+				logger.Warn("node is dead (ping timeout), removing it from the registry", "pid", n.PID)
+				_ = os.Remove(filepath.Join(is.registry, fmt.Sprintf("node_%d.json", n.PID)))
+				if freed, err := is.leases.ReleaseNode(n.ID); err == nil && freed > 0 {
+					logger.Info("released task leases of dead node", "leases", freed, "node", n.ID)
+				}
+			}
 			continue
 		}
 
@@ -124,29 +301,45 @@ func (is *ImmuneSystem) surveillance() error {
 		}
 	}
 
+	if leader {
+		is.bus.Sweep()
+	}
 	return nil
 }
 
 func (is *ImmuneSystem) handleSwarmMessage(msg SwarmMessage) {
 	switch msg.Type {
-	case "HANDOFF_REQUEST":
-		fmt.Printf("IMMUNE: Received handoff request from node %d. Checking capacity...\n", msg.From)
-		// If we are healthy and have capacity, we could "accept" it.
-	case "VOTE_APOPTOSIS":
-		targetPID := int(msg.Payload.(float64)) // JSON unmarshal makes it float64
-		fmt.Printf("IMMUNE: Consensus vote for apoptosis of node %d received from node %d.\n", targetPID, msg.From)
+	case MsgHandoff:
+		is.adopt(msg)
+	case MsgHandoffAck:
+		is.settleHandoff(msg)
+	case MsgUpdate:
+		is.deliver(msg)
+	case MsgVoteApoptosis:
+		var v Vote
+		if err := msg.Decode(&v); err != nil {
+			logger.Warn("bad vote", "pid", msg.From, "err", err)
+			return
+		}
+		logger.Info("apoptosis vote received", "pid", v.Target, "voter", msg.From)
+		is.tally(msg.From, v)
 	}
 }
 
+// voteToKill casts this node's vote against n, at most once per window.
 func (is *ImmuneSystem) voteToKill(n Node) {
-	fmt.Printf("IMMUNE: Node %d is mutated/unstable. Casting broadcast vote for Apoptosis.\n", n.PID)
-	_ = is.bus.Broadcast("VOTE_APOPTOSIS", n.PID)
-}
+	is.mu.Lock()
+	if time.Since(is.voted[n.PID]) < VoteWindow {
+		is.mu.Unlock()
+		return
+	}
+	is.voted[n.PID] = time.Now()
+	is.mu.Unlock()
 
-// RequestHandoff broadcasts a request for another node to take over some load.
-func (is *ImmuneSystem) RequestHandoff() {
-	fmt.Println("IMMUNE: System overloaded. Requesting node hand-off...")
-	_ = is.bus.Broadcast("HANDOFF_REQUEST", "High metabolic load detected")
+	logger.Warn("node is unstable, voting for apoptosis", "pid", n.PID)
+	v := Vote{Target: n.PID, Reason: fmt.Sprintf("status %s, error rate %.2f", n.Status, n.ErrorRate)}
+	_ = is.bus.Broadcast(v)
+	is.tally(is.self.PID, v)
 }
 
 // Name implements spine.Cell
@@ -161,23 +354,44 @@ func (is *ImmuneSystem) Pulse(ctx context.Context) error {
 		return err
 	}
 
-	// 2. Handle Swarm Messages (Isolated I/O Band)
+	// 2. Handle Swarm Messages (Isolated I/O Band) and gossip with other
+	// machines
 	messages, err := is.bus.Listen()
 	if err == nil {
 		for _, msg := range messages {
 			is.handleSwarmMessage(msg)
 		}
 	}
+	if m := is.Mesh(); m != nil {
+		m.Gossip()
+	}
 
-	// 3. Automated Apoptosis (Systemic Cleanup)
+	// 3. Hand queued work to a healthy peer while overloaded, and to the
+	// workers whenever there are any
+	is.mu.RLock()
+	degraded := is.self.Status != StatusHealthy
+	host := is.host
+	is.mu.RUnlock()
+	backlog := 0
+	if host != nil {
+		backlog = len(is.queuedTasks(host))
+	}
+	if wait, _ := biology.ShouldDefer(biology.PriorityNormal); degraded || wait || (backlog > 0 && is.hasWorkers()) {
+		is.RequestHandoff()
+	}
+
+	// 4. Automated Apoptosis (Systemic Cleanup)
 	metabolism := biology.GetMetabolism()
 	idleTime := time.Since(metabolism.LastActivity)
 
-	// 4. Autonomous Cloning: Self-replication triggered by high systemic demand
-	if biology.CanClone() && idleTime < 10*time.Second {
-		// Only clone if we are actually busy and healthy.
-		fmt.Println("IMMUNE: System under load but healthy. Triggering autonomous cloning...")
-		_ = biology.Clone()
+	// 5. Replicas: the leader keeps its workers within the policy, and a
+	// worker retires when the node that started it is gone
+	if is.Role() == RoleWorker {
+		if is.parent > 1 && !alive(is.parent) {
+			biology.Apoptosis(fmt.Sprintf("parent node %d exited", is.parent))
+		}
+	} else if rc := is.Replicas(); rc != nil && is.IsLeader() {
+		rc.Reconcile(backlog > 0, idleTime)
 	}
 
 	// If idle for more than 2 minutes, perform systemic cleanup
@@ -189,7 +403,7 @@ func (is *ImmuneSystem) Pulse(ctx context.Context) error {
 }
 
 func (is *ImmuneSystem) cleanup() {
-	fmt.Println("IMMUNE: Initiating systemic cleanup (Automated Apoptosis)...")
+	logger.Warn("initiating systemic cleanup (automated apoptosis)")
 
 	// 1. Cleanup Temp Files
 	tmpDir := filepath.Join(config.DataDir(), "tmp")
@@ -0,0 +1,156 @@
+package immune
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// DefaultLeaseTTL is how long a task lease lasts without renewal.
+const DefaultLeaseTTL = 2 * time.Minute
+
+// Lease grants one node the right to run a task until it expires.
+type Lease struct {
+	Task    string    `json:"task"`
+	Node    string    `json:"node"`
+	Expires time.Time `json:"expires"`
+}
+
+// LeaseTable is the swarm's shared record of which node runs which task.
+// It lives in swarm/leases.json and is only changed under swarm/leases.lock.
+type LeaseTable struct {
+	dir string
+	ttl time.Duration
+}
+
+func NewLeaseTable(dir string, ttl time.Duration) *LeaseTable {
+	if ttl <= 0 {
+		ttl = DefaultLeaseTTL
+	}
+	return &LeaseTable{dir: dir, ttl: ttl}
+}
+
+// update runs fn on the table under the lock and saves it if fn reports a
+// change.
+func (lt *LeaseTable) update(fn func(leases map[string]Lease) bool) error {
+	lock, err := os.OpenFile(filepath.Join(lt.dir, "leases.lock"), os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return err
+	}
+	defer lock.Close()
+	if err := lockFile(lock, true); err != nil {
+		return err
+	}
+	defer unlockFile(lock)
+
+	leases, err := lt.read()
+	if err != nil {
+		return err
+	}
+	if !fn(leases) {
+		return nil
+	}
+	data, err := json.MarshalIndent(leases, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(lt.dir, "leases.json")
+	if err := os.WriteFile(path+".tmp", data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+func (lt *LeaseTable) read() (map[string]Lease, error) {
+	leases := map[string]Lease{}
+	data, err := os.ReadFile(filepath.Join(lt.dir, "leases.json"))
+	if os.IsNotExist(err) {
+		return leases, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &leases); err != nil {
+		return nil, err
+	}
+	return leases, nil
+}
+
+// Acquire grants node the task's lease, or renews it, unless another node
+// holds an unexpired lease.
+func (lt *LeaseTable) Acquire(task, node string) (bool, error) {
+	granted := false
+	err := lt.update(func(leases map[string]Lease) bool {
+		l, ok := leases[task]
+		if ok && l.Node != node && time.Now().Before(l.Expires) {
+			return false
+		}
+		granted = true
+		// Fresh leases are not rewritten on every pulse.
+		if ok && l.Node == node && time.Until(l.Expires) > lt.ttl/2 {
+			return false
+		}
+		leases[task] = Lease{Task: task, Node: node, Expires: time.Now().Add(lt.ttl)}
+		return true
+	})
+	return granted, err
+}
+
+// Release drops node's lease on the task.
+func (lt *LeaseTable) Release(task, node string) error {
+	return lt.update(func(leases map[string]Lease) bool {
+		if l, ok := leases[task]; ok && l.Node == node {
+			delete(leases, task)
+			return true
+		}
+		return false
+	})
+}
+
+// Transfer moves a lease held by from to another node.
+func (lt *LeaseTable) Transfer(task, from, to string) (bool, error) {
+	moved := false
+	err := lt.update(func(leases map[string]Lease) bool {
+		l, ok := leases[task]
+		if ok && l.Node != from && time.Now().Before(l.Expires) {
+			return false
+		}
+		leases[task] = Lease{Task: task, Node: to, Expires: time.Now().Add(lt.ttl)}
+		moved = true
+		return true
+	})
+	return moved, err
+}
+
+// ReleaseNode drops every lease of a node, e.g. one that died.
+func (lt *LeaseTable) ReleaseNode(node string) (int, error) {
+	n := 0
+	err := lt.update(func(leases map[string]Lease) bool {
+		for task, l := range leases {
+			if l.Node == node {
+				delete(leases, task)
+				n++
+			}
+		}
+		return n > 0
+	})
+	return n, err
+}
+
+// List returns the unexpired leases ordered by task.
+func (lt *LeaseTable) List() ([]Lease, error) {
+	leases, err := lt.read()
+	if err != nil {
+		return nil, err
+	}
+	var out []Lease
+	for _, l := range leases {
+		if time.Now().Before(l.Expires) {
+			out = append(out, l)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Task < out[j].Task })
+	return out, nil
+}
@@ -0,0 +1,461 @@
+package immune
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Network transport defaults. Discovery announcements only carry a node's
+// port; everything else is exchanged over mutual TLS.
+const (
+	DefaultMeshPort = 7946
+	MulticastGroup  = "239.77.67.46:7946"
+	meshDialTimeout = 5 * time.Second
+	maxDialFailures = 3
+)
+
+// PeerInfo is the health a node gossips about itself to other machines.
+type PeerInfo struct {
+	ID        string     `json:"id"`
+	Machine   string     `json:"machine"` // name in the node certificate
+	Addr      string     `json:"addr"`
+	Status    NodeStatus `json:"status"`
+	ErrorRate float64    `json:"error_rate"`
+	Energy    float64    `json:"energy"`
+	Leader    bool       `json:"leader,omitempty"`
+	Skills    []string   `json:"skills,omitempty"`
+	SeenAt    time.Time  `json:"seen_at"`
+}
+
+// HasSkills reports whether the peer has every skill in need.
+func (p PeerInfo) HasSkills(need []string) bool {
+	have := make(map[string]bool, len(p.Skills))
+	for _, s := range p.Skills {
+		have[s] = true
+	}
+	for _, s := range need {
+		if !have[s] {
+			return false
+		}
+	}
+	return true
+}
+
+// MeshMessage is a message from a node on another machine.
+type MeshMessage struct {
+	From    PeerInfo
+	Type    string
+	Payload json.RawMessage
+}
+
+// Decode unmarshals the message payload into p, which must be the payload
+// type the message was sent with.
+func (m MeshMessage) Decode(p Payload) error {
+	return decodePayload(m.Type, m.Payload, p)
+}
+
+// MeshConfig configures the network transport.
+type MeshConfig struct {
+	Listen    string   // TCP address to accept peers on
+	Peers     []string // static peer addresses
+	Discovery bool     // announce and find peers by LAN multicast
+}
+
+// meshRequest is the single request of a connection.
+type meshRequest struct {
+	Kind    string          `json:"kind"` // "gossip" or "message"
+	From    PeerInfo        `json:"from"`
+	Type    string          `json:"type,omitempty"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+type meshResponse struct {
+	From  PeerInfo `json:"from"`
+	Known []string `json:"known,omitempty"` // addresses of other live peers
+	Error string   `json:"error,omitempty"`
+
+	// The handler's reply to a message, if any.
+	Type    string          `json:"type,omitempty"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// Mesh connects the swarms of several machines. Every connection carries
+// one request under mutual TLS with certificates from the team CA: either
+// a gossip exchange of node health, or a message for the node.
+type Mesh struct {
+	cfg    MeshConfig
+	creds  *Credentials
+	self   func() PeerInfo
+	handle func(MeshMessage) (Payload, error)
+	file   string // where the peer table is saved for "swarm status"
+
+	ln     net.Listener
+	udp    *net.UDPConn
+	cancel context.CancelFunc
+
+	mu       sync.RWMutex
+	peers    map[string]PeerInfo // by node ID
+	addrs    map[string]int      // dial targets and their failures in a row
+	static   map[string]bool
+	selfAddr map[string]bool // addresses that turned out to be this node
+}
+
+// NewMesh creates the transport for the node described by self; handle is
+// called for every message a peer sends and may return a reply for it.
+func NewMesh(cfg MeshConfig, creds *Credentials, self func() PeerInfo, handle func(MeshMessage) (Payload, error)) *Mesh {
+	m := &Mesh{
+		cfg:      cfg,
+		creds:    creds,
+		self:     self,
+		handle:   handle,
+		peers:    map[string]PeerInfo{},
+		addrs:    map[string]int{},
+		static:   map[string]bool{},
+		selfAddr: map[string]bool{},
+	}
+	for _, a := range cfg.Peers {
+		m.addrs[a] = 0
+		m.static[a] = true
+	}
+	return m
+}
+
+// SaveTo keeps a copy of the peer table at path after every gossip round.
+func (m *Mesh) SaveTo(path string) {
+	m.file = path
+}
+
+// Start listens for peers and, with discovery on, for announcements.
+func (m *Mesh) Start() error {
+	ln, err := tls.Listen("tcp", m.cfg.Listen, m.creds.ServerConfig())
+	if err != nil {
+		return err
+	}
+	m.ln = ln
+	ctx, cancel := context.WithCancel(context.Background())
+	m.cancel = cancel
+	go m.accept(ctx)
+
+	if m.cfg.Discovery {
+		group, err := net.ResolveUDPAddr("udp4", MulticastGroup)
+		if err == nil {
+			m.udp, err = net.ListenMulticastUDP("udp4", nil, group)
+		}
+		if err != nil {
+			logger.Warn("LAN discovery unavailable", "err", err)
+		} else {
+			go m.discover()
+		}
+	}
+	return nil
+}
+
+// Addr is the address the mesh accepts peers on.
+func (m *Mesh) Addr() string {
+	if m.ln == nil {
+		return m.cfg.Listen
+	}
+	return m.ln.Addr().String()
+}
+
+// Close stops accepting peers.
+func (m *Mesh) Close() error {
+	if m.cancel != nil {
+		m.cancel()
+	}
+	if m.udp != nil {
+		_ = m.udp.Close()
+	}
+	if m.ln != nil {
+		return m.ln.Close()
+	}
+	return nil
+}
+
+// Peers returns the live nodes on other machines.
+func (m *Mesh) Peers() []PeerInfo {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var peers []PeerInfo
+	for _, p := range m.peers {
+		if time.Since(p.SeenAt) <= NodeTimeout {
+			peers = append(peers, p)
+		}
+	}
+	sort.Slice(peers, func(i, j int) bool { return peers[i].ID < peers[j].ID })
+	return peers
+}
+
+// Gossip exchanges health with every known address, learning the peers
+// they know about, and announces this node on the LAN.
+func (m *Mesh) Gossip() {
+	m.announce()
+
+	m.mu.RLock()
+	var targets []string
+	for a := range m.addrs {
+		if !m.selfAddr[a] {
+			targets = append(targets, a)
+		}
+	}
+	m.mu.RUnlock()
+
+	var wg sync.WaitGroup
+	for _, a := range targets {
+		wg.Add(1)
+		go func(addr string) {
+			defer wg.Done()
+			_, err := m.exchange(addr, meshRequest{Kind: "gossip"})
+			m.mu.Lock()
+			defer m.mu.Unlock()
+			if err == nil {
+				m.addrs[addr] = 0
+				return
+			}
+			// Learned addresses are dropped once they keep failing.
+			m.addrs[addr]++
+			if !m.static[addr] && m.addrs[addr] >= maxDialFailures {
+				delete(m.addrs, addr)
+			}
+		}(a)
+	}
+	wg.Wait()
+
+	m.mu.Lock()
+	for id, p := range m.peers {
+		if time.Since(p.SeenAt) > 2*NodeTimeout {
+			delete(m.peers, id)
+		}
+	}
+	m.mu.Unlock()
+	m.save()
+}
+
+// Send delivers a message to peer id and waits for it to be handled.
+func (m *Mesh) Send(id string, p Payload) error {
+	return m.Request(id, p, nil)
+}
+
+// Request delivers a message to peer id and decodes the handler's reply
+// into reply, unless reply is nil.
+func (m *Mesh) Request(id string, p Payload, reply Payload) error {
+	m.mu.RLock()
+	peer, ok := m.peers[id]
+	m.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("unknown peer %s", id)
+	}
+	body, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	resp, err := m.exchange(peer.Addr, meshRequest{Kind: "message", Type: p.MessageType(), Payload: body})
+	if err != nil || reply == nil {
+		return err
+	}
+	return decodePayload(resp.Type, resp.Payload, reply)
+}
+
+// exchange sends one request to addr and records the responding peer.
+func (m *Mesh) exchange(addr string, req meshRequest) (meshResponse, error) {
+	var resp meshResponse
+	req.From = m.selfInfo()
+
+	dialer := &net.Dialer{Timeout: meshDialTimeout}
+	conn, err := tls.DialWithDialer(dialer, "tcp", addr, m.creds.ClientConfig())
+	if err != nil {
+		return resp, err
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(2 * meshDialTimeout))
+
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return resp, err
+	}
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return resp, err
+	}
+
+	if resp.From.ID == req.From.ID {
+		m.mu.Lock()
+		m.selfAddr[addr] = true
+		m.mu.Unlock()
+		return resp, errors.New("dialled this node")
+	}
+	resp.From.Machine = peerName(conn.ConnectionState())
+	resp.From.Addr = addr
+	m.learn(resp.From, resp.Known)
+	if resp.Error != "" {
+		return resp, errors.New(resp.Error)
+	}
+	return resp, nil
+}
+
+func (m *Mesh) accept(ctx context.Context) {
+	for {
+		conn, err := m.ln.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			continue
+		}
+		go m.serve(conn)
+	}
+}
+
+func (m *Mesh) serve(conn net.Conn) {
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(2 * meshDialTimeout))
+	tc := conn.(*tls.Conn)
+	if err := tc.Handshake(); err != nil {
+		return
+	}
+
+	var req meshRequest
+	if err := json.NewDecoder(conn).Decode(&req); err != nil {
+		return
+	}
+	self := m.selfInfo()
+	resp := meshResponse{From: self}
+
+	// The machine is whoever the certificate names, and the node is
+	// reachable where it connected from.
+	from := req.From
+	from.Machine = peerName(tc.ConnectionState())
+	if host, _, err := net.SplitHostPort(conn.RemoteAddr().String()); err == nil {
+		if _, port, err := net.SplitHostPort(from.Addr); err == nil {
+			from.Addr = net.JoinHostPort(host, port)
+		}
+	}
+	if from.ID != self.ID {
+		m.learn(from, nil)
+		resp.Known = m.knownAddrs(from.ID)
+	}
+
+	if req.Kind == "message" {
+		reply, err := m.handle(MeshMessage{From: from, Type: req.Type, Payload: req.Payload})
+		if err != nil {
+			resp.Error = err.Error()
+		}
+		if reply != nil {
+			if body, err := json.Marshal(reply); err == nil {
+				resp.Type, resp.Payload = reply.MessageType(), body
+			}
+		}
+	}
+	_ = json.NewEncoder(conn).Encode(resp)
+}
+
+// learn records a peer's health and the addresses it knows.
+func (m *Mesh) learn(p PeerInfo, known []string) {
+	p.SeenAt = time.Now()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.peers[p.ID] = p
+	if p.Addr != "" {
+		if _, ok := m.addrs[p.Addr]; !ok {
+			m.addrs[p.Addr] = 0
+		}
+	}
+	for _, a := range known {
+		if _, ok := m.addrs[a]; !ok && !m.selfAddr[a] {
+			m.addrs[a] = 0
+		}
+	}
+}
+
+func (m *Mesh) knownAddrs(except string) []string {
+	var addrs []string
+	for _, p := range m.Peers() {
+		if p.ID != except && p.Addr != "" {
+			addrs = append(addrs, p.Addr)
+		}
+	}
+	return addrs
+}
+
+func (m *Mesh) selfInfo() PeerInfo {
+	p := m.self()
+	p.Machine = m.creds.Name
+	p.Addr = m.Addr()
+	p.SeenAt = time.Now()
+	return p
+}
+
+// announcement is the LAN discovery datagram.
+type announcement struct {
+	ID   string `json:"id"`
+	Port int    `json:"port"`
+}
+
+func (m *Mesh) announce() {
+	if m.udp == nil || m.ln == nil {
+		return
+	}
+	group, err := net.ResolveUDPAddr("udp4", MulticastGroup)
+	if err != nil {
+		return
+	}
+	data, _ := json.Marshal(announcement{ID: m.self().ID, Port: m.ln.Addr().(*net.TCPAddr).Port})
+	if conn, err := net.DialUDP("udp4", nil, group); err == nil {
+		_, _ = conn.Write(data)
+		conn.Close()
+	}
+}
+
+func (m *Mesh) discover() {
+	buf := make([]byte, 1024)
+	for {
+		n, src, err := m.udp.ReadFromUDP(buf)
+		if err != nil {
+			return
+		}
+		var a announcement
+		if json.Unmarshal(buf[:n], &a) != nil || a.ID == "" || a.ID == m.self().ID || a.Port <= 0 {
+			continue
+		}
+		addr := net.JoinHostPort(src.IP.String(), strconv.Itoa(a.Port))
+		m.mu.Lock()
+		if _, ok := m.addrs[addr]; !ok && !m.selfAddr[addr] {
+			m.addrs[addr] = 0
+		}
+		m.mu.Unlock()
+	}
+}
+
+func (m *Mesh) save() {
+	if m.file == "" {
+		return
+	}
+	data, err := json.MarshalIndent(m.Peers(), "", "  ")
+	if err != nil {
+		return
+	}
+	if err := os.WriteFile(m.file+".tmp", data, 0644); err == nil {
+		_ = os.Rename(m.file+".tmp", m.file)
+	}
+}
+
+// ReadMeshPeers loads the peer table a node saved in the swarm dir.
+func ReadMeshPeers(swarmDir string) ([]PeerInfo, error) {
+	data, err := os.ReadFile(filepath.Join(swarmDir, "peers.json"))
+	if err != nil {
+		return nil, err
+	}
+	var peers []PeerInfo
+	if err := json.Unmarshal(data, &peers); err != nil {
+		return nil, err
+	}
+	return peers, nil
+}
@@ -0,0 +1,237 @@
+package immune
+
+import (
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testMeshNode is a mesh on localhost that records the handoffs it gets.
+type testMeshNode struct {
+	mesh *Mesh
+
+	mu     sync.Mutex
+	tasks  []HandoffTask
+	reject bool
+	refuse map[string]bool // tasks adopted by nobody
+}
+
+func newTestCA(t *testing.T) string {
+	t.Helper()
+	ca := t.TempDir()
+	if _, err := InitCA(ca, "test"); err != nil {
+		t.Fatal(err)
+	}
+	return ca
+}
+
+func startTestMesh(t *testing.T, ca, name string, skills []string, peers ...string) *testMeshNode {
+	t.Helper()
+	dir := filepath.Join(t.TempDir(), "tls")
+	if err := IssueNode(ca, name, dir); err != nil {
+		t.Fatal(err)
+	}
+	creds, err := LoadCredentials(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n := &testMeshNode{}
+	self := func() PeerInfo {
+		return PeerInfo{ID: name + "-1", Status: StatusHealthy, Energy: 1, Skills: skills}
+	}
+	n.mesh = NewMesh(MeshConfig{Listen: "127.0.0.1:0", Peers: peers}, creds, self, func(msg MeshMessage) (Payload, error) {
+		var h Handoff
+		if err := msg.Decode(&h); err != nil {
+			return nil, err
+		}
+		n.mu.Lock()
+		defer n.mu.Unlock()
+		if n.reject {
+			return nil, errors.New("not now")
+		}
+		var adopted []string
+		for _, t := range h.Tasks {
+			if !n.refuse[t.ID] {
+				n.tasks = append(n.tasks, t)
+				adopted = append(adopted, t.ID)
+			}
+		}
+		return handoffAck(h.Tasks, adopted), nil
+	})
+	if err := n.mesh.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { n.mesh.Close() })
+	return n
+}
+
+func peerIDs(m *Mesh) []string {
+	var ids []string
+	for _, p := range m.Peers() {
+		ids = append(ids, p.ID)
+	}
+	return ids
+}
+
+func TestMeshGossipSpreadsPeers(t *testing.T) {
+	ca := newTestCA(t)
+	a := startTestMesh(t, ca, "alpha", nil)
+	b := startTestMesh(t, ca, "beta", []string{"browser"}, a.mesh.Addr())
+	c := startTestMesh(t, ca, "gamma", nil, a.mesh.Addr())
+
+	b.mesh.Gossip()
+	c.mesh.Gossip()
+	// c learned of b through a and reaches it directly.
+	c.mesh.Gossip()
+
+	if got := peerIDs(c.mesh); len(got) != 2 || got[0] != "alpha-1" || got[1] != "beta-1" {
+		t.Fatalf("gamma's peers = %v", got)
+	}
+	for _, p := range c.mesh.Peers() {
+		if p.ID == "beta-1" && (p.Machine != "beta" || !p.HasSkills([]string{"browser"})) {
+			t.Fatalf("gossiped peer: %+v", p)
+		}
+	}
+	if got := peerIDs(a.mesh); len(got) != 2 {
+		t.Fatalf("alpha's peers = %v", got)
+	}
+}
+
+func TestMeshSendHandoff(t *testing.T) {
+	ca := newTestCA(t)
+	a := startTestMesh(t, ca, "alpha", nil)
+	b := startTestMesh(t, ca, "beta", nil, a.mesh.Addr())
+	b.mesh.Gossip()
+
+	if err := b.mesh.Send("alpha-1", Handoff{Tasks: []HandoffTask{{ID: "t1", Content: "build"}}}); err != nil {
+		t.Fatal(err)
+	}
+	a.mu.Lock()
+	if len(a.tasks) != 1 || a.tasks[0].ID != "t1" {
+		t.Fatalf("alpha got %+v", a.tasks)
+	}
+	a.reject = true
+	a.mu.Unlock()
+	if err := b.mesh.Send("alpha-1", Handoff{Tasks: []HandoffTask{{ID: "t2"}}}); err == nil {
+		t.Fatal("a refused handoff reported success")
+	}
+}
+
+func TestMeshRejectsOtherCA(t *testing.T) {
+	a := startTestMesh(t, newTestCA(t), "alpha", nil)
+	rogue := startTestMesh(t, newTestCA(t), "rogue", nil, a.mesh.Addr())
+
+	rogue.mesh.Gossip()
+	if len(rogue.mesh.Peers()) != 0 || len(a.mesh.Peers()) != 0 {
+		t.Fatal("nodes from different CAs talked")
+	}
+}
+
+func TestMeshForgetsSilentPeers(t *testing.T) {
+	ca := newTestCA(t)
+	a := startTestMesh(t, ca, "alpha", nil)
+	b := startTestMesh(t, ca, "beta", nil, a.mesh.Addr())
+	b.mesh.Gossip()
+
+	b.mesh.mu.Lock()
+	p := b.mesh.peers["alpha-1"]
+	p.SeenAt = time.Now().Add(-2 * NodeTimeout)
+	b.mesh.peers["alpha-1"] = p
+	b.mesh.mu.Unlock()
+	if len(b.mesh.Peers()) != 0 {
+		t.Fatal("a silent peer still counts as live")
+	}
+}
+
+type testHost struct {
+	queued    []HandoffTask
+	refuse    bool
+	adopted   []string
+	held      []string
+	dropped   []string
+	reclaimed []string
+}
+
+func (h *testHost) QueuedTasks() []HandoffTask { return h.queued }
+func (h *testHost) AdoptTask(t HandoffTask) error {
+	if h.refuse {
+		return errors.New("refused")
+	}
+	h.adopted = append(h.adopted, t.ID)
+	return nil
+}
+func (h *testHost) HoldTask(id, peer string)    { h.held = append(h.held, id+"->"+peer) }
+func (h *testHost) DropTask(id, peer string)    { h.dropped = append(h.dropped, id+"->"+peer) }
+func (h *testHost) ReclaimTask(id, peer string) { h.reclaimed = append(h.reclaimed, id+"<-"+peer) }
+func (h *testHost) Deliver(u Update)            {}
+func (h *testHost) Skills() []string            { return nil }
+
+func TestRemoteHandoffRespectsSkills(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	ca := newTestCA(t)
+	browser := startTestMesh(t, ca, "browser", []string{"browser"})
+	plain := startTestMesh(t, ca, "plain", nil)
+
+	is := NewImmuneSystem("local")
+	dir := filepath.Join(t.TempDir(), "tls")
+	if err := IssueNode(ca, "local", dir); err != nil {
+		t.Fatal(err)
+	}
+	creds, _ := LoadCredentials(dir)
+	cfg := MeshConfig{Listen: "127.0.0.1:0", Peers: []string{browser.mesh.Addr(), plain.mesh.Addr()}}
+	if err := is.JoinMesh(cfg, creds); err != nil {
+		t.Fatal(err)
+	}
+	defer is.Retire()
+	is.Mesh().Gossip()
+
+	host := &testHost{queued: []HandoffTask{
+		{ID: "browse", Skills: []string{"browser"}},
+		{ID: "crab", Skills: []string{"crab:reviewer"}},
+	}}
+	is.SetHost(host)
+	is.RequestHandoff()
+
+	if len(host.dropped) != 1 || host.dropped[0] != "browse->browser-1" {
+		t.Fatalf("handed off %v", host.dropped)
+	}
+	if len(plain.tasks) != 0 || len(browser.tasks) != 1 {
+		t.Fatalf("browser got %d tasks, plain got %d", len(browser.tasks), len(plain.tasks))
+	}
+}
+
+func TestRemoteHandoffKeepsRefusedTasks(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	ca := newTestCA(t)
+	peer := startTestMesh(t, ca, "peer", nil)
+	peer.refuse = map[string]bool{"refused": true}
+
+	is := NewImmuneSystem("local")
+	dir := filepath.Join(t.TempDir(), "tls")
+	if err := IssueNode(ca, "local", dir); err != nil {
+		t.Fatal(err)
+	}
+	creds, _ := LoadCredentials(dir)
+	if err := is.JoinMesh(MeshConfig{Listen: "127.0.0.1:0", Peers: []string{peer.mesh.Addr()}}, creds); err != nil {
+		t.Fatal(err)
+	}
+	defer is.Retire()
+	is.Mesh().Gossip()
+
+	host := &testHost{queued: []HandoffTask{{ID: "adopted"}, {ID: "refused"}}}
+	is.SetHost(host)
+	is.RequestHandoff()
+
+	if len(host.dropped) != 1 || host.dropped[0] != "adopted->peer-1" {
+		t.Fatalf("dropped %v, want only the adopted task", host.dropped)
+	}
+	if len(host.reclaimed) != 1 || host.reclaimed[0] != "refused<-peer-1" {
+		t.Fatalf("reclaimed %v, want the refused task", host.reclaimed)
+	}
+	if ok, err := is.leases.Acquire("refused", is.self.ID); err != nil || !ok {
+		t.Fatalf("the refused task's lease was not released: %v, %v", ok, err)
+	}
+}
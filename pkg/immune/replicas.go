@@ -0,0 +1,309 @@
+package immune
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nathfavour/auracrab/pkg/biology"
+	"github.com/nathfavour/auracrab/pkg/config"
+)
+
+// Role is a node's job in the swarm. The ingress node owns the messaging
+// channels and the schedule and may lead; workers only run tasks handed to
+// them.
+type Role string
+
+const (
+	RoleIngress Role = "ingress"
+	RoleWorker  Role = "worker"
+)
+
+// ReplicaPolicy bounds the worker replicas a node runs.
+type ReplicaPolicy struct {
+	Min       int
+	Max       int
+	Cooldown  time.Duration // between two scaling actions
+	IdleAfter time.Duration // idle time before a replica above Min retires
+}
+
+// Replica is a worker process started by this node.
+type Replica struct {
+	Name      string    `json:"name"`
+	PID       int       `json:"pid"`
+	Log       string    `json:"log"`
+	StartedAt time.Time `json:"started_at"`
+	Stopping  bool      `json:"stopping,omitempty"`
+}
+
+// Spawner starts replica name with its output going to out.
+type Spawner func(name string, out *os.File) (*os.Process, error)
+
+// ReplicaController keeps a node's worker replicas within its policy. It
+// changes the count by at most one per cooldown and reaps every child it
+// started, so crashed replicas are replaced rather than left as zombies.
+type ReplicaController struct {
+	dir      string
+	policy   ReplicaPolicy
+	spawn    Spawner
+	canClone func() bool
+
+	mu        sync.Mutex
+	children  map[string]*Replica
+	procs     map[string]*os.Process
+	lastScale time.Time
+}
+
+// NewReplicaController manages replicas for the swarm in dir.
+func NewReplicaController(dir string, policy ReplicaPolicy, spawn Spawner) *ReplicaController {
+	if spawn == nil {
+		spawn = spawnReplica
+	}
+	return &ReplicaController{
+		dir:      dir,
+		policy:   policy,
+		spawn:    spawn,
+		canClone: biology.CanClone,
+		children: map[string]*Replica{},
+		procs:    map[string]*os.Process{},
+	}
+}
+
+// Replicas lists the running replicas by name.
+func (rc *ReplicaController) Replicas() []Replica {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	return rc.listLocked()
+}
+
+func (rc *ReplicaController) listLocked() []Replica {
+	list := make([]Replica, 0, len(rc.children))
+	for _, r := range rc.children {
+		list = append(list, *r)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// Target is the replica count the controller steers to: the count set by
+// "auracrab swarm scale", or one more while work is queued and there is
+// energy to spare and one fewer after a long idle stretch. It is always
+// within the policy.
+func (rc *ReplicaController) Target(current int, demand bool, idle time.Duration) int {
+	target := current
+	if n, ok := ReadScale(rc.dir); ok {
+		target = n
+	} else if demand && rc.canClone() {
+		target++
+	} else if !demand && idle > rc.policy.IdleAfter {
+		target--
+	}
+	return clampReplicas(target, rc.policy)
+}
+
+func clampReplicas(n int, p ReplicaPolicy) int {
+	if n > p.Max {
+		n = p.Max
+	}
+	if n < p.Min {
+		n = p.Min
+	}
+	if n < 0 {
+		n = 0
+	}
+	return n
+}
+
+// Reconcile starts or stops one replica to move towards the target.
+func (rc *ReplicaController) Reconcile(demand bool, idle time.Duration) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	if time.Since(rc.lastScale) < rc.policy.Cooldown {
+		return
+	}
+
+	var running []*Replica
+	for _, r := range rc.children {
+		if !r.Stopping {
+			running = append(running, r)
+		}
+	}
+	target := rc.Target(len(running), demand, idle)
+
+	switch {
+	case len(running) < target:
+		if !rc.canClone() {
+			return
+		}
+		if err := rc.startLocked(); err != nil {
+			logger.Error("could not start a replica", "err", err)
+		}
+		rc.lastScale = time.Now()
+	case len(running) > target:
+		// Retire the newest replica.
+		sort.Slice(running, func(i, j int) bool { return running[i].StartedAt.After(running[j].StartedAt) })
+		rc.stopLocked(running[0])
+		rc.lastScale = time.Now()
+	}
+}
+
+// StopAll asks every replica to shut down; they drain like the main
+// process does.
+func (rc *ReplicaController) StopAll() {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	for _, r := range rc.children {
+		if !r.Stopping {
+			rc.stopLocked(r)
+		}
+	}
+}
+
+func (rc *ReplicaController) startLocked() error {
+	name := rc.freeNameLocked()
+	logDir := filepath.Join(config.DataDir(), "logs")
+	if err := os.MkdirAll(logDir, 0700); err != nil {
+		return err
+	}
+	// The replica writes its own rotated log; its output only catches
+	// what escapes it.
+	out, err := os.OpenFile(filepath.Join(logDir, name+".out"), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	p, err := rc.spawn(name, out)
+	if err != nil {
+		return err
+	}
+	r := &Replica{Name: name, PID: p.Pid, Log: filepath.Join(logDir, name+".log"), StartedAt: time.Now()}
+	rc.children[name] = r
+	rc.procs[name] = p
+	rc.saveLocked()
+	logger.Info("started replica", "replica", name, "pid", p.Pid, "log", r.Log)
+
+	go rc.reap(name, p)
+	return nil
+}
+
+// reap waits for a replica to exit and forgets it.
+func (rc *ReplicaController) reap(name string, p *os.Process) {
+	state, err := p.Wait()
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	if rc.procs[name] != p {
+		return
+	}
+	delete(rc.children, name)
+	delete(rc.procs, name)
+	rc.saveLocked()
+	switch {
+	case err != nil:
+		logger.Error("lost replica", "replica", name, "err", err)
+	case state.ExitCode() == 0 || state.ExitCode() == biology.ExitApoptosis:
+		logger.Info("replica exited", "replica", name, "state", state.String())
+	default:
+		logger.Error("replica died, see its log", "replica", name, "state", state.String())
+	}
+}
+
+func (rc *ReplicaController) stopLocked(r *Replica) {
+	r.Stopping = true
+	rc.saveLocked()
+	logger.Info("retiring replica", "replica", r.Name, "pid", r.PID)
+	if err := terminate(rc.procs[r.Name]); err != nil {
+		logger.Error("could not stop replica", "replica", r.Name, "err", err)
+	}
+}
+
+// freeNameLocked returns the lowest unused replica name. A replica that
+// comes back under an old name resumes that replica's saved tasks.
+func (rc *ReplicaController) freeNameLocked() string {
+	for i := 1; ; i++ {
+		name := fmt.Sprintf("worker-%d", i)
+		if _, ok := rc.children[name]; !ok {
+			return name
+		}
+	}
+}
+
+func (rc *ReplicaController) saveLocked() {
+	data, err := json.MarshalIndent(rc.listLocked(), "", "  ")
+	if err != nil {
+		return
+	}
+	path := filepath.Join(rc.dir, "replicas.json")
+	if err := os.WriteFile(path+".tmp", data, 0644); err == nil {
+		_ = os.Rename(path+".tmp", path)
+	}
+}
+
+// ReadReplicas loads the replicas the swarm leader last recorded in dir.
+func ReadReplicas(dir string) ([]Replica, error) {
+	data, err := os.ReadFile(filepath.Join(dir, "replicas.json"))
+	if err != nil {
+		return nil, err
+	}
+	var list []Replica
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// ReadScale returns the replica count set with WriteScale, if any.
+func ReadScale(dir string) (int, bool) {
+	data, err := os.ReadFile(filepath.Join(dir, "scale"))
+	if err != nil {
+		return 0, false
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return 0, false
+	}
+	return n, true
+}
+
+// WriteScale pins the swarm's replica count; a negative n returns it to
+// automatic scaling.
+func WriteScale(dir string, n int) error {
+	path := filepath.Join(dir, "scale")
+	if n < 0 {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(fmt.Sprintf("%d\n", n)), 0644)
+}
+
+// spawnReplica starts a worker from this binary with no terminal attached.
+func spawnReplica(name string, out *os.File) (*os.Process, error) {
+	exe, err := biology.DNA()
+	if err != nil {
+		return nil, err
+	}
+	null, err := os.Open(os.DevNull)
+	if err != nil {
+		return nil, err
+	}
+	defer null.Close()
+
+	attr := &os.ProcAttr{
+		Dir:   config.DataDir(),
+		Env:   os.Environ(),
+		Files: []*os.File{null, out, out},
+	}
+	return os.StartProcess(exe, []string{exe, "serve", "--child", "--replica", name}, attr)
+}
@@ -0,0 +1,110 @@
+package immune
+
+import (
+	"os"
+	"os/exec"
+	"testing"
+	"time"
+)
+
+// Replica processes in these tests are the test binary itself, which waits
+// to be stopped.
+func TestMain(m *testing.M) {
+	if os.Getenv("IMMUNE_TEST_REPLICA") == "1" {
+		time.Sleep(time.Minute)
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+func testController(t *testing.T, p ReplicaPolicy) *ReplicaController {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	rc := NewReplicaController(t.TempDir(), p, func(name string, log *os.File) (*os.Process, error) {
+		cmd := exec.Command(os.Args[0], "-test.run=^$")
+		cmd.Env = append(os.Environ(), "IMMUNE_TEST_REPLICA=1")
+		cmd.Stdout, cmd.Stderr = log, log
+		if err := cmd.Start(); err != nil {
+			return nil, err
+		}
+		return cmd.Process, nil
+	})
+	rc.canClone = func() bool { return true }
+	t.Cleanup(func() {
+		rc.StopAll()
+		waitReplicas(t, rc, 0)
+	})
+	return rc
+}
+
+func waitReplicas(t *testing.T, rc *ReplicaController, n int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if len(rc.Replicas()) == n {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("have %d replicas, want %d", len(rc.Replicas()), n)
+}
+
+func TestReplicaTarget(t *testing.T) {
+	rc := testController(t, ReplicaPolicy{Min: 1, Max: 3, IdleAfter: time.Minute})
+
+	if got := rc.Target(0, false, 0); got != 1 {
+		t.Fatalf("below the minimum: target %d", got)
+	}
+	if got := rc.Target(3, true, 0); got != 3 {
+		t.Fatalf("above the maximum: target %d", got)
+	}
+	if got := rc.Target(2, false, 2*time.Minute); got != 1 {
+		t.Fatalf("idle: target %d", got)
+	}
+
+	rc.canClone = func() bool { return false }
+	if got := rc.Target(1, true, 0); got != 1 {
+		t.Fatalf("grew without energy: target %d", got)
+	}
+
+	if err := WriteScale(rc.dir, 10); err != nil {
+		t.Fatal(err)
+	}
+	if got := rc.Target(1, false, 0); got != 3 {
+		t.Fatalf("pinned scale: target %d", got)
+	}
+	_ = WriteScale(rc.dir, -1)
+	if _, ok := ReadScale(rc.dir); ok {
+		t.Fatal("scale still pinned after returning to auto")
+	}
+}
+
+func TestReplicaControllerScalesWithinCooldown(t *testing.T) {
+	rc := testController(t, ReplicaPolicy{Max: 2, Cooldown: time.Hour})
+
+	rc.Reconcile(true, 0)
+	rc.Reconcile(true, 0)
+	if n := len(rc.Replicas()); n != 1 {
+		t.Fatalf("started %d replicas in one cooldown", n)
+	}
+	if list, _ := ReadReplicas(rc.dir); len(list) != 1 || list[0].Name != "worker-1" {
+		t.Fatalf("recorded replicas: %+v", list)
+	}
+
+	rc.lastScale = time.Time{}
+	rc.Reconcile(true, 0)
+	rc.lastScale = time.Time{}
+	rc.Reconcile(true, 0)
+	if n := len(rc.Replicas()); n != 2 {
+		t.Fatalf("have %d replicas, want the maximum of 2", n)
+	}
+
+	// Pinning a lower scale retires the newest replica, which is reaped.
+	_ = WriteScale(rc.dir, 1)
+	rc.lastScale = time.Time{}
+	rc.Reconcile(false, 0)
+	waitReplicas(t, rc, 1)
+	if r := rc.Replicas()[0]; r.Name != "worker-1" {
+		t.Fatalf("kept %s, want the oldest replica", r.Name)
+	}
+}
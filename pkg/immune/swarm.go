@@ -0,0 +1,517 @@
+package immune
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/nathfavour/auracrab/pkg/biology"
+	"github.com/nathfavour/auracrab/pkg/logging"
+)
+
+// Swarm message types.
+const (
+	MsgHandoff       = "HANDOFF"
+	MsgHandoffAck    = "HANDOFF_ACK"
+	MsgVoteApoptosis = "VOTE_APOPTOSIS"
+	MsgUpdate        = "UPDATE"
+)
+
+// VoteWindow is how long an apoptosis vote counts towards a quorum.
+const VoteWindow = 2 * time.Minute
+
+// HandoffCooldown spaces out handoffs from an overloaded node.
+const HandoffCooldown = time.Minute
+
+// HandoffTask is a queued task moved between nodes.
+type HandoffTask struct {
+	ID       string            `json:"id"`
+	Content  string            `json:"content"`
+	Platform string            `json:"platform,omitempty"`
+	ChatID   string            `json:"chat_id,omitempty"`
+	Priority string            `json:"priority,omitempty"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+	// Skills the receiving node must have installed.
+	Skills []string `json:"skills,omitempty"`
+}
+
+// Handoff moves queued tasks to the receiving node.
+type Handoff struct {
+	Tasks []HandoffTask `json:"tasks"`
+}
+
+// MessageType implements Payload.
+func (Handoff) MessageType() string { return MsgHandoff }
+
+// HandoffAck tells the sender of a Handoff which tasks were adopted. The
+// sender keeps the rest.
+type HandoffAck struct {
+	Adopted  []string `json:"adopted,omitempty"`
+	Rejected []string `json:"rejected,omitempty"`
+}
+
+// MessageType implements Payload.
+func (HandoffAck) MessageType() string { return MsgHandoffAck }
+
+// pendingHandoff is a task sent to a peer on this machine that has not
+// acknowledged it yet.
+type pendingHandoff struct {
+	node string
+	pid  int
+	sent time.Time
+}
+
+// Vote asks the swarm to retire a node.
+type Vote struct {
+	Target int    `json:"target"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// MessageType implements Payload.
+func (Vote) MessageType() string { return MsgVoteApoptosis }
+
+// Update is a chat message a worker relays through the ingress node, which
+// owns the channels.
+type Update struct {
+	Platform string `json:"platform"`
+	ChatID   string `json:"chat_id"`
+	Text     string `json:"text"`
+}
+
+// MessageType implements Payload.
+func (Update) MessageType() string { return MsgUpdate }
+
+// Host is the task runtime the swarm moves work in and out of.
+type Host interface {
+	// QueuedTasks lists tasks that have not started any work yet.
+	QueuedTasks() []HandoffTask
+	// AdoptTask takes over a task handed off by a peer.
+	AdoptTask(t HandoffTask) error
+	// HoldTask marks a task sent to the peer node, which has not
+	// acknowledged it yet. The task stays until DropTask or ReclaimTask.
+	HoldTask(id, peer string)
+	// DropTask forgets a task that moved to the peer node.
+	DropTask(id, peer string)
+	// ReclaimTask runs a held task here again after the peer refused it or
+	// did not acknowledge it in time.
+	ReclaimTask(id, peer string)
+	// Deliver sends a message a worker relayed to a chat.
+	Deliver(u Update)
+	// Skills lists what this node can run, for peers on other machines.
+	Skills() []string
+}
+
+// SetHost connects the swarm to the node's tasks.
+func (is *ImmuneSystem) SetHost(h Host) {
+	is.mu.Lock()
+	defer is.mu.Unlock()
+	is.host = h
+}
+
+// NodeID is this node's swarm identity.
+func (is *ImmuneSystem) NodeID() string {
+	return is.self.ID
+}
+
+// IsLeader reports whether this node leads the swarm.
+func (is *ImmuneSystem) IsLeader() bool {
+	return is.election.IsLeader()
+}
+
+// Leases is the swarm's task lease table.
+func (is *ImmuneSystem) Leases() *LeaseTable {
+	return is.leases
+}
+
+// Peers returns the other live nodes in the registry.
+func (is *ImmuneSystem) Peers() []Node {
+	var peers []Node
+	for _, n := range ReadNodes(is.registry) {
+		if n.PID != is.self.PID && time.Since(n.LastPing) <= NodeTimeout {
+			peers = append(peers, n)
+		}
+	}
+	return peers
+}
+
+// ReadNodes loads every node record in a swarm registry directory.
+func ReadNodes(registry string) []Node {
+	files, _ := filepath.Glob(filepath.Join(registry, "node_*.json"))
+	var nodes []Node
+	for _, f := range files {
+		data, err := os.ReadFile(f)
+		if err != nil {
+			continue
+		}
+		var n Node
+		if json.Unmarshal(data, &n) == nil {
+			nodes = append(nodes, n)
+		}
+	}
+	return nodes
+}
+
+// RequestHandoff moves this node's queued tasks to the healthiest peer. A
+// peer on this machine is preferred; otherwise each task goes to a peer on
+// another machine that has the skills it needs. Local leases move first,
+// so this node cannot start the tasks afterwards. A local peer acknowledges
+// the tasks it adopted over the bus; until then they are held here, and
+// reclaimHandoffs takes back those whose lease expires without an answer.
+func (is *ImmuneSystem) RequestHandoff() {
+	is.mu.Lock()
+	host := is.host
+	recent := time.Since(is.lastHandoff) < HandoffCooldown
+	if host != nil && !recent {
+		is.lastHandoff = time.Now()
+	}
+	is.mu.Unlock()
+	if host == nil || recent {
+		return
+	}
+
+	// Workers take work before other ingress nodes do.
+	var target *Node
+	for _, p := range is.Peers() {
+		if p.Status != StatusHealthy {
+			continue
+		}
+		if target == nil || betterTarget(p, *target) {
+			p := p
+			target = &p
+		}
+	}
+	if target == nil {
+		is.handoffRemote(host)
+		return
+	}
+
+	var moved []HandoffTask
+	for _, t := range is.queuedTasks(host) {
+		if ok, err := is.leases.Transfer(t.ID, is.self.ID, target.ID); err == nil && ok {
+			moved = append(moved, t)
+		}
+	}
+	if len(moved) == 0 {
+		return
+	}
+	if err := is.bus.Send(target.PID, Handoff{Tasks: moved}); err != nil {
+		// Take the leases back so the tasks still run here.
+		for _, t := range moved {
+			_, _ = is.leases.Transfer(t.ID, target.ID, is.self.ID)
+		}
+		logger.Warn("handoff failed", "node", target.ID, "err", err)
+		return
+	}
+	is.mu.Lock()
+	for _, t := range moved {
+		is.handoffs[t.ID] = pendingHandoff{node: target.ID, pid: target.PID, sent: time.Now()}
+	}
+	is.mu.Unlock()
+	for _, t := range moved {
+		host.HoldTask(t.ID, target.ID)
+	}
+	logger.Info("overloaded, offered queued tasks", "tasks", len(moved), "node", target.ID)
+}
+
+// queuedTasks lists the host's queued tasks that are not waiting on a
+// peer's acknowledgement.
+func (is *ImmuneSystem) queuedTasks(host Host) []HandoffTask {
+	is.mu.RLock()
+	defer is.mu.RUnlock()
+	var queued []HandoffTask
+	for _, t := range host.QueuedTasks() {
+		if _, held := is.handoffs[t.ID]; !held {
+			queued = append(queued, t)
+		}
+	}
+	return queued
+}
+
+// settleHandoff applies a peer's acknowledgement: adopted tasks are
+// dropped, refused ones run here again.
+func (is *ImmuneSystem) settleHandoff(msg SwarmMessage) {
+	var ack HandoffAck
+	if err := msg.Decode(&ack); err != nil {
+		logger.Warn("bad handoff ack", "pid", msg.From, "err", err)
+		return
+	}
+	is.mu.Lock()
+	host := is.host
+	// take settles the listed tasks this node offered to the sender; late or
+	// duplicate entries are skipped.
+	take := func(ids []string) map[string]pendingHandoff {
+		out := map[string]pendingHandoff{}
+		for _, id := range ids {
+			// Only the peer the task was sent to may settle it.
+			if p, ok := is.handoffs[id]; ok && p.pid == msg.From {
+				delete(is.handoffs, id)
+				out[id] = p
+			}
+		}
+		return out
+	}
+	adopted := take(ack.Adopted)
+	rejected := take(ack.Rejected)
+	is.mu.Unlock()
+	if host == nil {
+		return
+	}
+
+	for id, p := range adopted {
+		host.DropTask(id, p.node)
+	}
+	for id, p := range rejected {
+		_, _ = is.leases.Transfer(id, p.node, is.self.ID)
+		host.ReclaimTask(id, p.node)
+	}
+	if len(adopted) > 0 {
+		logger.Info("handed off queued tasks", "tasks", len(adopted), "pid", msg.From)
+	}
+	if len(rejected) > 0 {
+		logger.Warn("peer refused handed off tasks", "tasks", len(rejected), "pid", msg.From)
+	}
+}
+
+// reclaimHandoffs takes back held tasks whose lease ran out before the
+// peer acknowledged them. A peer that renewed the lease has the task even
+// though its acknowledgement was lost.
+func (is *ImmuneSystem) reclaimHandoffs() {
+	is.mu.Lock()
+	host := is.host
+	expired := map[string]pendingHandoff{}
+	for id, p := range is.handoffs {
+		if time.Since(p.sent) > is.leases.ttl {
+			expired[id] = p
+			delete(is.handoffs, id)
+		}
+	}
+	is.mu.Unlock()
+	if host == nil {
+		return
+	}
+
+	for id, p := range expired {
+		if ok, err := is.leases.Acquire(id, is.self.ID); err == nil && !ok {
+			host.DropTask(id, p.node)
+			continue
+		}
+		logger.Warn("handoff was not acknowledged, taking the task back", logging.KeyTask, id, "node", p.node)
+		host.ReclaimTask(id, p.node)
+	}
+}
+
+// handoffRemote sends queued tasks to healthy peers on other machines,
+// each to the one with the most energy among those with its skills.
+func (is *ImmuneSystem) handoffRemote(host Host) {
+	m := is.Mesh()
+	if m == nil {
+		logger.Warn("overloaded, but no healthy peer can take work")
+		return
+	}
+	var healthy []PeerInfo
+	for _, p := range m.Peers() {
+		if p.Status == StatusHealthy {
+			healthy = append(healthy, p)
+		}
+	}
+
+	batches := map[string][]HandoffTask{}
+	skipped := 0
+	for _, t := range is.queuedTasks(host) {
+		var best *PeerInfo
+		for i, p := range healthy {
+			if p.HasSkills(t.Skills) && (best == nil || p.Energy > best.Energy) {
+				best = &healthy[i]
+			}
+		}
+		if best == nil {
+			skipped++
+			continue
+		}
+		// Claim the task so this node does not start it mid-handoff.
+		if ok, err := is.leases.Acquire(t.ID, is.self.ID); err != nil || !ok {
+			continue
+		}
+		batches[best.ID] = append(batches[best.ID], t)
+	}
+	if skipped > 0 {
+		logger.Warn("no healthy peer has the skills for queued tasks", "tasks", skipped)
+	}
+
+	for peer, tasks := range batches {
+		var ack HandoffAck
+		if err := m.Request(peer, Handoff{Tasks: tasks}, &ack); err != nil {
+			logger.Warn("handoff failed", "node", peer, "err", err)
+			for _, t := range tasks {
+				_ = is.leases.Release(t.ID, is.self.ID)
+			}
+			continue
+		}
+		// Only the tasks the peer adopted leave; the rest run here.
+		adopted := map[string]bool{}
+		for _, id := range ack.Adopted {
+			adopted[id] = true
+		}
+		moved := 0
+		for _, t := range tasks {
+			_ = is.leases.Release(t.ID, is.self.ID)
+			if adopted[t.ID] {
+				host.DropTask(t.ID, peer)
+				moved++
+			} else {
+				host.ReclaimTask(t.ID, peer)
+			}
+		}
+		if moved < len(tasks) {
+			logger.Warn("peer refused handed off tasks", "tasks", len(tasks)-moved, "node", peer)
+		}
+		if moved > 0 {
+			logger.Info("overloaded, handed off queued tasks", "tasks", moved, "node", peer)
+		}
+	}
+}
+
+// Relay passes a chat message to the node that started this worker. It
+// reports false on nodes that send their own messages.
+func (is *ImmuneSystem) Relay(u Update) bool {
+	if is.Role() != RoleWorker || is.parent <= 1 {
+		return false
+	}
+	if err := is.bus.Send(is.parent, u); err != nil {
+		logger.Warn("could not relay update", "pid", is.parent, "err", err)
+		return false
+	}
+	return true
+}
+
+func (is *ImmuneSystem) deliver(msg SwarmMessage) {
+	var u Update
+	if err := msg.Decode(&u); err != nil {
+		logger.Warn("bad update", "pid", msg.From, "err", err)
+		return
+	}
+	is.mu.RLock()
+	host := is.host
+	is.mu.RUnlock()
+	if host != nil {
+		host.Deliver(u)
+	}
+}
+
+func betterTarget(a, b Node) bool {
+	if (a.Role == RoleWorker) != (b.Role == RoleWorker) {
+		return a.Role == RoleWorker
+	}
+	return a.ErrorRate < b.ErrorRate
+}
+
+// hasWorkers reports whether a live worker can take queued tasks.
+func (is *ImmuneSystem) hasWorkers() bool {
+	if is.Role() == RoleWorker {
+		return false
+	}
+	for _, p := range is.Peers() {
+		if p.Role == RoleWorker && p.Status == StatusHealthy {
+			return true
+		}
+	}
+	return false
+}
+
+// adopt takes over the tasks of a Handoff from a peer on this machine and
+// acknowledges which ones it adopted.
+func (is *ImmuneSystem) adopt(msg SwarmMessage) {
+	var h Handoff
+	if err := msg.Decode(&h); err != nil {
+		logger.Warn("bad handoff", "pid", msg.From, "err", err)
+		return
+	}
+	adopted, _ := is.adoptTasks(strconv.Itoa(msg.From), h.Tasks)
+	if err := is.bus.Send(msg.From, handoffAck(h.Tasks, adopted)); err != nil {
+		logger.Warn("could not acknowledge handoff", "pid", msg.From, "err", err)
+	}
+}
+
+// handoffAck acknowledges the adopted tasks of a handoff and rejects the
+// rest.
+func handoffAck(tasks []HandoffTask, adopted []string) HandoffAck {
+	ack := HandoffAck{Adopted: adopted}
+	took := map[string]bool{}
+	for _, id := range adopted {
+		took[id] = true
+	}
+	for _, t := range tasks {
+		if !took[t.ID] {
+			ack.Rejected = append(ack.Rejected, t.ID)
+		}
+	}
+	return ack
+}
+
+// adoptTasks takes over tasks handed off by node from and returns the IDs
+// it adopted. It fails when no task could be adopted.
+func (is *ImmuneSystem) adoptTasks(from string, tasks []HandoffTask) ([]string, error) {
+	is.mu.RLock()
+	host := is.host
+	is.mu.RUnlock()
+	if host == nil {
+		return nil, errors.New("node is not running tasks")
+	}
+	var adopted []string
+	for _, t := range tasks {
+		if err := host.AdoptTask(t); err != nil {
+			logger.Warn("could not adopt task", logging.KeyTask, t.ID, "err", err)
+			continue
+		}
+		adopted = append(adopted, t.ID)
+		logger.Info("adopted task", logging.KeyTask, t.ID, "node", from)
+	}
+	if len(adopted) == 0 && len(tasks) > 0 {
+		return nil, errors.New("no task could be adopted")
+	}
+	return adopted, nil
+}
+
+// tally records a vote and acts once a quorum of live nodes agrees: the
+// target retires itself, and the leader stops a target that does not.
+func (is *ImmuneSystem) tally(voter int, v Vote) {
+	is.mu.Lock()
+	if is.votes[v.Target] == nil {
+		is.votes[v.Target] = map[int]time.Time{}
+	}
+	is.votes[v.Target][voter] = time.Now()
+	count := 0
+	for pid, at := range is.votes[v.Target] {
+		if time.Since(at) > VoteWindow {
+			delete(is.votes[v.Target], pid)
+			continue
+		}
+		count++
+	}
+	is.mu.Unlock()
+
+	quorum := quorumOf(len(is.Peers()) + 1)
+	logger.Info("apoptosis votes", "pid", v.Target, "votes", count, "quorum", quorum)
+	if count < quorum {
+		return
+	}
+	if v.Target == is.self.PID {
+		biology.Apoptosis(fmt.Sprintf("swarm quorum voted to retire this node (%s)", v.Reason))
+		return
+	}
+	if is.IsLeader() {
+		if p, err := os.FindProcess(v.Target); err == nil {
+			logger.Warn("quorum reached, asking node to retire", "pid", v.Target)
+			_ = terminate(p)
+		}
+	}
+}
+
+// quorumOf is the majority of live nodes.
+func quorumOf(live int) int {
+	return live/2 + 1
+}
@@ -1,104 +1,360 @@
 package immune
 
 import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
-
-	"github.com/nathfavour/auracrab/pkg/config"
-	"github.com/nathfavour/auracrab/pkg/vault"
 )
 
+// MessageTTL bounds how long a message waits for readers that died without
+// acknowledging it.
+const MessageTTL = 10 * time.Minute
+
+// Payload is the typed body of a swarm message; its type names the message.
+type Payload interface {
+	MessageType() string
+}
+
 type SwarmMessage struct {
-	From      int       `json:"from"`
-	To        int       `json:"to"` // 0 for broadcast
-	Type      string    `json:"type"`
-	Payload   any       `json:"payload"`
-	Timestamp time.Time `json:"timestamp"`
+	From      int             `json:"from"`
+	To        int             `json:"to"` // 0 for broadcast
+	Type      string          `json:"type"`
+	Seq       uint64          `json:"seq"`
+	Payload   json.RawMessage `json:"payload"`
+	Timestamp time.Time       `json:"timestamp"`
+}
+
+// Decode unmarshals the message payload into p, which must be the payload
+// type the message was sent with.
+func (m SwarmMessage) Decode(p Payload) error {
+	return decodePayload(m.Type, m.Payload, p)
 }
 
+func decodePayload(msgType string, raw json.RawMessage, p Payload) error {
+	if msgType != p.MessageType() {
+		return fmt.Errorf("message is %s, not %s", msgType, p.MessageType())
+	}
+	return json.Unmarshal(raw, p)
+}
+
+// envelope is a message as stored on disk, signed by its sender.
+type envelope struct {
+	Message   json.RawMessage `json:"message"`
+	Signature []byte          `json:"signature"`
+}
+
+// KeyLookup returns the public key a node published in the registry.
+type KeyLookup func(pid int) (ed25519.PublicKey, bool)
+
+// SwarmBus delivers signed messages between the nodes of one machine.
+//
+// Each node appends to its own stream, a directory named after its public
+// key holding one file per sequence number. Readers keep a cursor per
+// stream, so every message is handled once and in the order it was sent,
+// and publish their cursors as acknowledgements; a sender deletes messages
+// once every live reader has acknowledged them.
 type SwarmBus struct {
-	inbox string
-	self  int
+	dir    string
+	self   int
+	key    ed25519.PrivateKey
+	stream string
+	lookup KeyLookup
+
+	mu      sync.Mutex
+	seq     uint64
+	cursors map[string]uint64
+	pinned  map[int]ed25519.PublicKey
+}
+
+// StreamID names the stream of the node holding key.
+func StreamID(key ed25519.PublicKey) string {
+	return hex.EncodeToString(key[:8])
 }
 
-func NewSwarmBus(selfPID int) *SwarmBus {
-	inboxDir := filepath.Join(config.DataDir(), "swarm", "inbox")
-	_ = os.MkdirAll(inboxDir, 0755)
-	return &SwarmBus{
-		inbox: inboxDir,
-		self:  selfPID,
+// NewSwarmBus opens the bus in dir for the node selfPID, which signs with
+// key. A new reader starts at the head of every existing stream rather
+// than replaying messages meant for the nodes that were there before it.
+func NewSwarmBus(dir string, selfPID int, key ed25519.PrivateKey, lookup KeyLookup) *SwarmBus {
+	sb := &SwarmBus{
+		dir:     dir,
+		self:    selfPID,
+		key:     key,
+		stream:  StreamID(key.Public().(ed25519.PublicKey)),
+		lookup:  lookup,
+		cursors: map[string]uint64{},
+		pinned:  map[int]ed25519.PublicKey{},
 	}
+	_ = os.MkdirAll(filepath.Join(dir, "acks"), 0700)
+	for _, s := range sb.streams() {
+		if seqs := sb.sequences(s); len(seqs) > 0 {
+			sb.cursors[s] = seqs[len(seqs)-1]
+		}
+	}
+	_ = sb.writeAcks()
+	return sb
 }
 
-// Broadcast sends an encrypted message to all nodes.
-func (sb *SwarmBus) Broadcast(msgType string, payload any) error {
-	return sb.Send(0, msgType, payload)
+// Broadcast sends a signed message to all nodes.
+func (sb *SwarmBus) Broadcast(p Payload) error {
+	return sb.Send(0, p)
 }
 
-// Send sends an encrypted message to a specific node (or broadcast if to=0).
-func (sb *SwarmBus) Send(to int, msgType string, payload any) error {
+// Send sends a signed message to a specific node (or broadcast if to=0).
+func (sb *SwarmBus) Send(to int, p Payload) error {
+	body, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+
+	sb.mu.Lock()
+	defer sb.mu.Unlock()
 	msg := SwarmMessage{
 		From:      sb.self,
 		To:        to,
-		Type:      msgType,
-		Payload:   payload,
+		Type:      p.MessageType(),
+		Seq:       sb.seq + 1,
+		Payload:   body,
 		Timestamp: time.Now(),
 	}
-
 	data, err := json.Marshal(msg)
 	if err != nil {
 		return err
 	}
-
-	encrypted, err := vault.Encrypt(data)
+	raw, err := json.Marshal(envelope{Message: data, Signature: ed25519.Sign(sb.key, data)})
 	if err != nil {
 		return err
 	}
 
-	// Message file format: msg_<timestamp>_<from>_<to>.enc
-	fileName := fmt.Sprintf("msg_%d_%d_%d.enc", time.Now().UnixNano(), sb.self, to)
-	path := filepath.Join(sb.inbox, fileName)
-
-	return os.WriteFile(path, encrypted, 0600)
+	// The stream may have been swept while empty.
+	dir := filepath.Join(sb.dir, sb.stream)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	// Readers only ever see whole messages.
+	path := filepath.Join(dir, seqName(msg.Seq))
+	if err := os.WriteFile(path+".tmp", raw, 0600); err != nil {
+		return err
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		return err
+	}
+	sb.seq = msg.Seq
+	return nil
 }
 
-// Listen reads and decrypts messages from the inbox.
+// Listen returns the messages for this node that arrived since the last
+// call, in order per sender. Messages that fail verification are dropped.
 func (sb *SwarmBus) Listen() ([]SwarmMessage, error) {
-	files, err := filepath.Glob(filepath.Join(sb.inbox, "msg_*.enc"))
-	if err != nil {
+	sb.mu.Lock()
+	defer sb.mu.Unlock()
+
+	if _, err := os.Stat(sb.dir); err != nil {
 		return nil, err
 	}
 
 	var messages []SwarmMessage
-	for _, f := range files {
-		data, err := os.ReadFile(f)
-		if err != nil {
+	present := map[string]bool{}
+	for _, s := range sb.streams() {
+		present[s] = true
+		if s == sb.stream {
 			continue
 		}
+		for _, seq := range sb.sequences(s) {
+			if seq <= sb.cursors[s] {
+				continue
+			}
+			sb.cursors[s] = seq
+			msg, err := sb.open(s, seq)
+			if err != nil {
+				logger.Warn("dropped swarm message", "stream", s, "seq", seq, "err", err)
+				continue
+			}
+			if msg.To == 0 || msg.To == sb.self {
+				messages = append(messages, msg)
+			}
+		}
+	}
+	// A swept stream starts over if its node writes again.
+	for s := range sb.cursors {
+		if !present[s] {
+			delete(sb.cursors, s)
+		}
+	}
 
-		decrypted, err := vault.Decrypt(data)
-		if err != nil {
-			continue
+	if err := sb.writeAcks(); err != nil {
+		return messages, err
+	}
+	sb.collect()
+	return messages, nil
+}
+
+// Forget drops the key pinned for a dead node, so a new process that
+// reuses its PID can be heard.
+func (sb *SwarmBus) Forget(pid int) {
+	sb.mu.Lock()
+	defer sb.mu.Unlock()
+	delete(sb.pinned, pid)
+}
+
+// Sweep removes what dead nodes left behind: messages past MessageTTL,
+// empty streams and stale acknowledgements. The leader runs it.
+func (sb *SwarmBus) Sweep() {
+	for _, s := range sb.streams() {
+		dir := filepath.Join(sb.dir, s)
+		entries, _ := os.ReadDir(dir)
+		left := 0
+		for _, e := range entries {
+			if info, err := e.Info(); err == nil && time.Since(info.ModTime()) > MessageTTL {
+				_ = os.Remove(filepath.Join(dir, e.Name()))
+				continue
+			}
+			left++
 		}
+		if left == 0 && s != sb.stream {
+			_ = os.Remove(dir)
+		}
+	}
 
-		var msg SwarmMessage
-		if err := json.Unmarshal(decrypted, &msg); err != nil {
+	acks, _ := os.ReadDir(filepath.Join(sb.dir, "acks"))
+	for _, e := range acks {
+		if info, err := e.Info(); err == nil && time.Since(info.ModTime()) > MessageTTL {
+			_ = os.Remove(filepath.Join(sb.dir, "acks", e.Name()))
+		}
+	}
+}
+
+// open reads and verifies message seq of stream s. The sender must sign
+// with the key its node published, and the key must match the stream.
+func (sb *SwarmBus) open(s string, seq uint64) (SwarmMessage, error) {
+	var msg SwarmMessage
+	raw, err := os.ReadFile(filepath.Join(sb.dir, s, seqName(seq)))
+	if err != nil {
+		return msg, err
+	}
+	var env envelope
+	if err := json.Unmarshal(raw, &env); err != nil {
+		return msg, err
+	}
+	if err := json.Unmarshal(env.Message, &msg); err != nil {
+		return msg, err
+	}
+	if msg.Seq != seq {
+		return msg, fmt.Errorf("sequence %d stored as %d", msg.Seq, seq)
+	}
+
+	key, err := sb.keyFor(msg.From)
+	if err != nil {
+		return msg, err
+	}
+	if StreamID(key) != s {
+		return msg, fmt.Errorf("node %d does not own this stream", msg.From)
+	}
+	if !ed25519.Verify(key, env.Message, env.Signature) {
+		return msg, errors.New("bad signature")
+	}
+	return msg, nil
+}
+
+// keyFor returns the sender's public key, pinned on first use so a process
+// that rewrites the registry cannot take over a live node's identity.
+func (sb *SwarmBus) keyFor(pid int) (ed25519.PublicKey, error) {
+	if pid == sb.self {
+		return nil, errors.New("message claims to be from this node")
+	}
+	key, ok := sb.lookup(pid)
+	if !ok || len(key) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("node %d has no published key", pid)
+	}
+	if pinned, ok := sb.pinned[pid]; ok && !bytes.Equal(pinned, key) {
+		return nil, fmt.Errorf("node %d changed its key", pid)
+	}
+	sb.pinned[pid] = key
+	return key, nil
+}
+
+// collect deletes this node's messages that every live reader has
+// acknowledged.
+func (sb *SwarmBus) collect() {
+	low := sb.seq
+	entries, _ := os.ReadDir(filepath.Join(sb.dir, "acks"))
+	for _, e := range entries {
+		name := strings.TrimSuffix(e.Name(), ".json")
+		if name == sb.stream || name == e.Name() {
+			continue
+		}
+		// Readers rewrite their acknowledgements on every listen.
+		if info, err := e.Info(); err != nil || time.Since(info.ModTime()) > NodeTimeout {
+			continue
+		}
+		var cursors map[string]uint64
+		data, err := os.ReadFile(filepath.Join(sb.dir, "acks", e.Name()))
+		if err != nil || json.Unmarshal(data, &cursors) != nil {
 			continue
 		}
+		if c := cursors[sb.stream]; c < low {
+			low = c
+		}
+	}
+
+	for _, seq := range sb.sequences(sb.stream) {
+		if seq > low {
+			break
+		}
+		_ = os.Remove(filepath.Join(sb.dir, sb.stream, seqName(seq)))
+	}
+}
+
+func (sb *SwarmBus) writeAcks() error {
+	data, err := json.Marshal(sb.cursors)
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(sb.dir, "acks", sb.stream+".json")
+	if err := os.WriteFile(path+".tmp", data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
 
-		// Only process messages for us or broadcasts, and not from us
-		if msg.From != sb.self && (msg.To == 0 || msg.To == sb.self) {
-			messages = append(messages, msg)
+// streams lists the stream directories on the bus.
+func (sb *SwarmBus) streams() []string {
+	entries, _ := os.ReadDir(sb.dir)
+	var streams []string
+	for _, e := range entries {
+		if e.IsDir() && e.Name() != "acks" {
+			streams = append(streams, e.Name())
 		}
+	}
+	return streams
+}
 
-		// Cleanup old messages (older than 1 minute) to avoid inbox bloat
-		if time.Since(msg.Timestamp) > 1*time.Minute {
-			_ = os.Remove(f)
+// sequences lists the message numbers stored in stream s, ascending.
+func (sb *SwarmBus) sequences(s string) []uint64 {
+	entries, _ := os.ReadDir(filepath.Join(sb.dir, s))
+	var seqs []uint64
+	for _, e := range entries {
+		name, ok := strings.CutSuffix(e.Name(), ".msg")
+		if !ok {
+			continue
+		}
+		if seq, err := strconv.ParseUint(name, 10, 64); err == nil {
+			seqs = append(seqs, seq)
 		}
 	}
+	sort.Slice(seqs, func(i, j int) bool { return seqs[i] < seqs[j] })
+	return seqs
+}
 
-	return messages, nil
+func seqName(seq uint64) string {
+	return fmt.Sprintf("%020d.msg", seq)
 }
@@ -0,0 +1,129 @@
+package immune
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type testNode struct {
+	pid int
+	pub ed25519.PublicKey
+	bus *SwarmBus
+}
+
+// newTestSwarm opens one bus per PID over a shared directory, with keys
+// published through a lookup table in place of the registry.
+func newTestSwarm(t *testing.T, pids ...int) (string, map[int]ed25519.PublicKey, []*testNode) {
+	t.Helper()
+	dir := t.TempDir()
+	keys := map[int]ed25519.PublicKey{}
+	lookup := func(pid int) (ed25519.PublicKey, bool) {
+		k, ok := keys[pid]
+		return k, ok
+	}
+	var nodes []*testNode
+	for _, pid := range pids {
+		pub, priv, err := ed25519.GenerateKey(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys[pid] = pub
+		nodes = append(nodes, &testNode{pid: pid, pub: pub, bus: NewSwarmBus(dir, pid, priv, lookup)})
+	}
+	return dir, keys, nodes
+}
+
+func TestSwarmBusDeliversOnceInOrder(t *testing.T) {
+	_, _, nodes := newTestSwarm(t, 1, 2)
+	a, b := nodes[0].bus, nodes[1].bus
+
+	for i := 1; i <= 3; i++ {
+		if err := a.Broadcast(Vote{Target: i}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := a.Send(3, Vote{Target: 99}); err != nil {
+		t.Fatal(err)
+	}
+
+	msgs, err := b.Listen()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(msgs) != 3 {
+		t.Fatalf("got %d messages, want the 3 broadcasts", len(msgs))
+	}
+	for i, m := range msgs {
+		var v Vote
+		if err := m.Decode(&v); err != nil {
+			t.Fatal(err)
+		}
+		if v.Target != i+1 || m.From != 1 {
+			t.Fatalf("message %d: %+v from %d", i, v, m.From)
+		}
+	}
+	if msgs, _ := b.Listen(); len(msgs) != 0 {
+		t.Fatalf("redelivered %d messages", len(msgs))
+	}
+}
+
+func TestSwarmBusCollectsAcknowledged(t *testing.T) {
+	dir, _, nodes := newTestSwarm(t, 1, 2)
+	a, b := nodes[0].bus, nodes[1].bus
+
+	_ = a.Broadcast(Vote{Target: 7})
+	stream := filepath.Join(dir, StreamID(nodes[0].pub))
+
+	// b has not read it yet.
+	_, _ = a.Listen()
+	if seqs := a.sequences(StreamID(nodes[0].pub)); len(seqs) != 1 {
+		t.Fatalf("collected an unread message: %v", seqs)
+	}
+
+	_, _ = b.Listen()
+	_, _ = a.Listen()
+	if entries, _ := os.ReadDir(stream); len(entries) != 0 {
+		t.Fatalf("%d acknowledged messages left", len(entries))
+	}
+}
+
+func TestSwarmBusRejectsForgery(t *testing.T) {
+	dir, keys, nodes := newTestSwarm(t, 1, 2)
+	b := nodes[1].bus
+
+	// A rogue process signs with its own key but claims to be node 1.
+	_, rogue, _ := ed25519.GenerateKey(nil)
+	forger := NewSwarmBus(dir, 1, rogue, func(int) (ed25519.PublicKey, bool) { return nil, false })
+	_ = forger.Broadcast(Vote{Target: 2})
+	if msgs, _ := b.Listen(); len(msgs) != 0 {
+		t.Fatalf("accepted a forged message: %+v", msgs)
+	}
+
+	// Publishing its key over node 1's is refused once node 1 was heard.
+	_ = nodes[0].bus.Broadcast(Vote{Target: 3})
+	if msgs, _ := b.Listen(); len(msgs) != 1 {
+		t.Fatalf("lost the genuine message")
+	}
+	keys[1] = rogue.Public().(ed25519.PublicKey)
+	_ = forger.Broadcast(Vote{Target: 2})
+	if msgs, _ := b.Listen(); len(msgs) != 0 {
+		t.Fatalf("accepted a message after a key swap: %+v", msgs)
+	}
+}
+
+func TestSwarmMessageDecodeChecksType(t *testing.T) {
+	body, _ := json.Marshal(Vote{Target: 4})
+	msg := SwarmMessage{Type: MsgVoteApoptosis, Payload: body}
+
+	var h Handoff
+	if err := msg.Decode(&h); err == nil {
+		t.Fatal("decoded a vote as a handoff")
+	}
+	var v Vote
+	if err := msg.Decode(&v); err != nil || v.Target != 4 {
+		t.Fatalf("Decode = %+v, %v", v, err)
+	}
+}
@@ -0,0 +1,201 @@
+package immune
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestElectionSingleLeaderAndFailover(t *testing.T) {
+	dir := t.TempDir()
+	a := NewElection(dir, "node-a")
+	b := NewElection(dir, "node-b")
+
+	if !a.Campaign() {
+		t.Fatal("first node did not win the election")
+	}
+	if b.Campaign() {
+		t.Fatal("two nodes lead at once")
+	}
+	if got := b.Leader(); got != "node-a" {
+		t.Fatalf("Leader() = %q", got)
+	}
+
+	// The lock goes with the leader.
+	a.Resign()
+	if !b.Campaign() || b.Leader() != "node-b" {
+		t.Fatal("follower did not take over")
+	}
+}
+
+func TestLeaseTable(t *testing.T) {
+	lt := NewLeaseTable(t.TempDir(), 50*time.Millisecond)
+
+	if ok, err := lt.Acquire("t1", "a"); err != nil || !ok {
+		t.Fatalf("Acquire: %v %v", ok, err)
+	}
+	if ok, _ := lt.Acquire("t1", "b"); ok {
+		t.Fatal("a second node leased a held task")
+	}
+	if ok, _ := lt.Acquire("t1", "a"); !ok {
+		t.Fatal("the holder could not renew")
+	}
+
+	if ok, _ := lt.Transfer("t1", "a", "b"); !ok {
+		t.Fatal("Transfer failed")
+	}
+	if ok, _ := lt.Acquire("t1", "a"); ok {
+		t.Fatal("the old holder kept the lease after a transfer")
+	}
+
+	// An expired lease is free for anyone.
+	time.Sleep(60 * time.Millisecond)
+	if ok, _ := lt.Acquire("t1", "a"); !ok {
+		t.Fatal("expired lease was not granted")
+	}
+
+	if n, _ := lt.ReleaseNode("a"); n != 1 {
+		t.Fatalf("ReleaseNode freed %d leases", n)
+	}
+	if leases, _ := lt.List(); len(leases) != 0 {
+		t.Fatalf("leases left: %+v", leases)
+	}
+}
+
+func TestVoteQuorum(t *testing.T) {
+	for live, want := range map[int]int{1: 1, 2: 2, 3: 2, 4: 3, 5: 3} {
+		if got := quorumOf(live); got != want {
+			t.Errorf("quorumOf(%d) = %d, want %d", live, got, want)
+		}
+	}
+
+	is := &ImmuneSystem{registry: t.TempDir(), self: &Node{PID: 1}, election: NewElection(t.TempDir(), "n"), votes: map[int]map[int]time.Time{}}
+	target := Vote{Target: 999}
+	is.tally(101, target)
+	is.tally(101, target) // a repeated vote counts once
+	if n := len(is.votes[999]); n != 1 {
+		t.Fatalf("recorded %d votes, want 1", n)
+	}
+}
+
+// newHandoffPair returns two nodes of one machine, a overloaded with task
+// t1 and b a healthy worker, sharing a registry, bus and lease table.
+func newHandoffPair(t *testing.T, ttl time.Duration) (a, b *ImmuneSystem, ha, hb *testHost) {
+	t.Helper()
+	registry := t.TempDir()
+	_, _, nodes := newTestSwarm(t, 1, 2)
+	leases := NewLeaseTable(registry, ttl)
+	newNode := func(n *testNode, role Role) *ImmuneSystem {
+		self := &Node{PID: n.pid, ID: fmt.Sprintf("n%d", n.pid), Status: StatusHealthy, Role: role, LastPing: time.Now(), PublicKey: n.pub}
+		data, _ := json.Marshal(self)
+		if err := os.WriteFile(filepath.Join(registry, fmt.Sprintf("node_%d.json", n.pid)), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return &ImmuneSystem{registry: registry, self: self, bus: n.bus, leases: leases, handoffs: map[string]pendingHandoff{}}
+	}
+	a, b = newNode(nodes[0], RoleIngress), newNode(nodes[1], RoleWorker)
+	ha = &testHost{queued: []HandoffTask{{ID: "t1"}}}
+	hb = &testHost{}
+	a.SetHost(ha)
+	b.SetHost(hb)
+	return a, b, ha, hb
+}
+
+func deliverAll(t *testing.T, is *ImmuneSystem) {
+	t.Helper()
+	msgs, err := is.bus.Listen()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range msgs {
+		is.handleSwarmMessage(m)
+	}
+}
+
+func TestLocalHandoffWaitsForAck(t *testing.T) {
+	cases := []struct {
+		name      string
+		refuse    bool
+		dropped   []string
+		reclaimed []string
+		holder    string
+	}{
+		{"adopted", false, []string{"t1->n2"}, nil, "n2"},
+		{"refused", true, nil, []string{"t1<-n2"}, "n1"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			a, b, ha, hb := newHandoffPair(t, time.Minute)
+			hb.refuse = c.refuse
+
+			a.RequestHandoff()
+			if len(ha.held) != 1 || len(ha.dropped) != 0 {
+				t.Fatalf("before the ack: held %v, dropped %v", ha.held, ha.dropped)
+			}
+			if len(a.queuedTasks(ha)) != 0 {
+				t.Fatal("a held task was offered again")
+			}
+
+			deliverAll(t, b)
+			deliverAll(t, a)
+			if fmt.Sprint(ha.dropped) != fmt.Sprint(c.dropped) || fmt.Sprint(ha.reclaimed) != fmt.Sprint(c.reclaimed) {
+				t.Fatalf("dropped %v, reclaimed %v", ha.dropped, ha.reclaimed)
+			}
+			if leases, _ := a.leases.List(); len(leases) != 1 || leases[0].Node != c.holder {
+				t.Fatalf("leases = %+v, want t1 held by %s", leases, c.holder)
+			}
+			if len(a.handoffs) != 0 {
+				t.Fatalf("handoffs still pending: %v", a.handoffs)
+			}
+		})
+	}
+}
+
+func TestLocalHandoffReclaimedWithoutAck(t *testing.T) {
+	a, _, ha, _ := newHandoffPair(t, 20*time.Millisecond)
+
+	a.RequestHandoff()
+	a.reclaimHandoffs()
+	if len(ha.reclaimed) != 0 {
+		t.Fatal("reclaimed before the lease expired")
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	a.reclaimHandoffs()
+	if fmt.Sprint(ha.reclaimed) != "[t1<-n2]" || len(ha.dropped) != 0 {
+		t.Fatalf("reclaimed %v, dropped %v", ha.reclaimed, ha.dropped)
+	}
+	if leases, _ := a.leases.List(); len(leases) != 1 || leases[0].Node != "n1" {
+		t.Fatalf("the lease did not come back: %+v", leases)
+	}
+}
+
+func TestHandoffAckSkipsUnknownTasks(t *testing.T) {
+	cases := []struct {
+		name      string
+		ack       HandoffAck
+		dropped   string
+		reclaimed string
+	}{
+		{"adopted", HandoffAck{Adopted: []string{"gone", "t1"}}, "[t1->n2]", "[]"},
+		{"rejected", HandoffAck{Rejected: []string{"gone", "t1"}}, "[]", "[t1<-n2]"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			a, b, ha, _ := newHandoffPair(t, time.Minute)
+			a.RequestHandoff()
+
+			// A late ack lists a task that is no longer pending first.
+			if err := b.bus.Send(a.self.PID, c.ack); err != nil {
+				t.Fatal(err)
+			}
+			deliverAll(t, a)
+			if fmt.Sprint(ha.dropped) != c.dropped || fmt.Sprint(ha.reclaimed) != c.reclaimed {
+				t.Fatalf("dropped %v, reclaimed %v", ha.dropped, ha.reclaimed)
+			}
+		})
+	}
+}
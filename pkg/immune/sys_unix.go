@@ -0,0 +1,43 @@
+//go:build !windows
+
+package immune
+
+import (
+	"os"
+	"syscall"
+)
+
+// lockFile takes an exclusive lock on f, failing with errLocked instead of
+// waiting when wait is false.
+func lockFile(f *os.File, wait bool) error {
+	how := syscall.LOCK_EX
+	if !wait {
+		how |= syscall.LOCK_NB
+	}
+	if err := syscall.Flock(int(f.Fd()), how); err != nil {
+		if err == syscall.EWOULDBLOCK {
+			return errLocked
+		}
+		return err
+	}
+	return nil
+}
+
+func unlockFile(f *os.File) error {
+	return syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+}
+
+// terminate asks a process to shut down gracefully.
+func terminate(p *os.Process) error {
+	return p.Signal(syscall.SIGTERM)
+}
+
+// alive reports whether process pid is still running.
+func alive(pid int) bool {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	err = p.Signal(syscall.Signal(0))
+	return err == nil || err == syscall.EPERM
+}
@@ -0,0 +1,256 @@
+package immune
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Files of the team CA and a machine's node certificate. Only the machine
+// that ran "auracrab swarm init" first holds ca.key.
+const (
+	CACertFile   = "ca.crt"
+	CAKeyFile    = "ca.key"
+	NodeCertFile = "node.crt"
+	NodeKeyFile  = "node.key"
+)
+
+// Certificate lifetimes.
+const (
+	caValidity   = 10 * 365 * 24 * time.Hour
+	nodeValidity = 2 * 365 * 24 * time.Hour
+)
+
+// TLSDir is where a swarm keeps its certificates.
+func TLSDir(swarmDir string) string {
+	return filepath.Join(swarmDir, "tls")
+}
+
+// InitCA creates a team CA in dir. It reports false when dir already has
+// one.
+func InitCA(dir, team string) (bool, error) {
+	if _, err := os.Stat(filepath.Join(dir, CACertFile)); err == nil {
+		return false, nil
+	}
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return false, err
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          serialNumber(),
+		Subject:               pkix.Name{CommonName: team + " swarm CA", Organization: []string{team}},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(caValidity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		MaxPathLenZero:        true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		return false, err
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return false, err
+	}
+	if err := writeKey(filepath.Join(dir, CAKeyFile), key); err != nil {
+		return false, err
+	}
+	return true, writePEM(filepath.Join(dir, CACertFile), "CERTIFICATE", der, 0644)
+}
+
+// IssueNode signs a certificate for the machine name with the CA in caDir
+// and writes it, its key and the CA certificate to out. The name is the
+// machine's identity in the swarm.
+func IssueNode(caDir, name, out string) error {
+	caCert, err := readCert(filepath.Join(caDir, CACertFile))
+	if err != nil {
+		return err
+	}
+	caKey, err := readKey(filepath.Join(caDir, CAKeyFile))
+	if err != nil {
+		return fmt.Errorf("this machine cannot issue certificates: %w", err)
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: serialNumber(),
+		Subject:      pkix.Name{CommonName: name, Organization: caCert.Subject.Organization},
+		DNSNames:     []string{name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(nodeValidity),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, caCert, &key.PublicKey, caKey)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(out, 0700); err != nil {
+		return err
+	}
+	if err := writeKey(filepath.Join(out, NodeKeyFile), key); err != nil {
+		return err
+	}
+	if err := writePEM(filepath.Join(out, NodeCertFile), "CERTIFICATE", der, 0644); err != nil {
+		return err
+	}
+	if filepath.Clean(out) == filepath.Clean(caDir) {
+		return nil
+	}
+	return writePEM(filepath.Join(out, CACertFile), "CERTIFICATE", caCert.Raw, 0644)
+}
+
+// ImportBundle installs a certificate bundle made by IssueNode into dir.
+func ImportBundle(bundle, dir string) error {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	for _, name := range []string{CACertFile, NodeCertFile, NodeKeyFile} {
+		data, err := os.ReadFile(filepath.Join(bundle, name))
+		if err != nil {
+			return err
+		}
+		perm := os.FileMode(0644)
+		if name == NodeKeyFile {
+			perm = 0600
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), data, perm); err != nil {
+			return err
+		}
+	}
+	_, err := LoadCredentials(dir)
+	return err
+}
+
+// Credentials are a machine's mutual TLS identity in the swarm.
+type Credentials struct {
+	Name string // the machine name in the node certificate
+	cert tls.Certificate
+	pool *x509.CertPool
+}
+
+// LoadCredentials reads the node certificate and team CA in dir.
+func LoadCredentials(dir string) (*Credentials, error) {
+	cert, err := tls.LoadX509KeyPair(filepath.Join(dir, NodeCertFile), filepath.Join(dir, NodeKeyFile))
+	if err != nil {
+		return nil, err
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return nil, err
+	}
+	caCert, err := readCert(filepath.Join(dir, CACertFile))
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(caCert)
+	if _, err := leaf.Verify(x509.VerifyOptions{Roots: pool, KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}}); err != nil {
+		return nil, fmt.Errorf("node certificate is not from the team CA: %w", err)
+	}
+	return &Credentials{Name: leaf.Subject.CommonName, cert: cert, pool: pool}, nil
+}
+
+// ServerConfig requires clients to present a certificate from the team CA.
+func (c *Credentials) ServerConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion:       tls.VersionTLS13,
+		Certificates:     []tls.Certificate{c.cert},
+		ClientAuth:       tls.RequireAnyClientCert,
+		VerifyConnection: c.verify(x509.ExtKeyUsageClientAuth),
+	}
+}
+
+// ClientConfig trusts servers with a certificate from the team CA. Peers
+// are dialled by whatever address they were found at, so the certificate
+// name is not matched against it.
+func (c *Credentials) ClientConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion:         tls.VersionTLS13,
+		Certificates:       []tls.Certificate{c.cert},
+		InsecureSkipVerify: true, // replaced by VerifyConnection
+		VerifyConnection:   c.verify(x509.ExtKeyUsageServerAuth),
+	}
+}
+
+func (c *Credentials) verify(usage x509.ExtKeyUsage) func(tls.ConnectionState) error {
+	return func(cs tls.ConnectionState) error {
+		if len(cs.PeerCertificates) == 0 {
+			return errors.New("peer sent no certificate")
+		}
+		opts := x509.VerifyOptions{
+			Roots:         c.pool,
+			Intermediates: x509.NewCertPool(),
+			KeyUsages:     []x509.ExtKeyUsage{usage},
+		}
+		for _, ic := range cs.PeerCertificates[1:] {
+			opts.Intermediates.AddCert(ic)
+		}
+		_, err := cs.PeerCertificates[0].Verify(opts)
+		return err
+	}
+}
+
+// peerName is the machine name in the certificate of a verified peer.
+func peerName(cs tls.ConnectionState) string {
+	if len(cs.PeerCertificates) == 0 {
+		return ""
+	}
+	return cs.PeerCertificates[0].Subject.CommonName
+}
+
+func serialNumber() *big.Int {
+	n, _ := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	return n
+}
+
+func writePEM(path, kind string, der []byte, perm os.FileMode) error {
+	return os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: kind, Bytes: der}), perm)
+}
+
+func writeKey(path string, key *ecdsa.PrivateKey) error {
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return err
+	}
+	return writePEM(path, "EC PRIVATE KEY", der, 0600)
+}
+
+func readCert(path string) (*x509.Certificate, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s: no PEM data", path)
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
+
+func readKey(path string) (*ecdsa.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s: no PEM data", path)
+	}
+	return x509.ParseECPrivateKey(block.Bytes)
+}
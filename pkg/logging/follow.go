@@ -0,0 +1,156 @@
+package logging
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FollowInterval is how often Follow polls the log for new lines.
+const FollowInterval = 250 * time.Millisecond
+
+// Filter selects log records. Empty fields match everything.
+type Filter struct {
+	Task      string
+	Subsystem string
+	Level     slog.Level // minimum level, zero meaning info
+}
+
+// Match reports whether a text or JSON log line passes the filter.
+func (f Filter) Match(line string) bool {
+	if f.Task != "" && Field(line, KeyTask) != f.Task {
+		return false
+	}
+	if f.Subsystem != "" && !strings.EqualFold(Field(line, KeySubsystem), f.Subsystem) {
+		return false
+	}
+	if lvl := Field(line, slog.LevelKey); lvl != "" && ParseLevel(lvl) < f.Level {
+		return false
+	}
+	return true
+}
+
+// Field returns the value of key in a text or JSON log line.
+func Field(line, key string) string {
+	if strings.HasPrefix(line, "{") {
+		var rec map[string]any
+		if json.Unmarshal([]byte(line), &rec) != nil {
+			return ""
+		}
+		switch v := rec[key].(type) {
+		case string:
+			return v
+		case nil:
+			return ""
+		default:
+			b, _ := json.Marshal(v)
+			return string(b)
+		}
+	}
+
+	for i := 0; i < len(line); {
+		j := strings.Index(line[i:], key+"=")
+		if j < 0 {
+			return ""
+		}
+		start := i + j
+		i = start + len(key) + 1
+		// Only whole keys count: "xtask_id=" is not "task_id=".
+		if start > 0 && line[start-1] != ' ' {
+			continue
+		}
+		rest := line[i:]
+		if strings.HasPrefix(rest, `"`) {
+			if v, err := strconv.QuotedPrefix(rest); err == nil {
+				s, _ := strconv.Unquote(v)
+				return s
+			}
+		}
+		if end := strings.IndexByte(rest, ' '); end >= 0 {
+			return rest[:end]
+		}
+		return rest
+	}
+	return ""
+}
+
+// Follow writes the lines of the log at path that pass the filter to out,
+// starting with the last tail matching lines. With follow set it keeps
+// reading new lines, across rotations, until ctx is done.
+func Follow(ctx context.Context, path string, f Filter, tail int, follow bool, out io.Writer) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() { file.Close() }()
+
+	// The backlog: the last matching lines of the current file.
+	var last []string
+	reader := bufio.NewReader(file)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			// A partial last line is read again once it is complete.
+			if _, serr := file.Seek(-int64(len(line)), io.SeekCurrent); serr == nil {
+				reader.Reset(file)
+			}
+			break
+		}
+		if f.Match(strings.TrimRight(line, "\n")) {
+			last = append(last, line)
+			if tail >= 0 && len(last) > tail {
+				last = last[1:]
+			}
+		}
+	}
+	for _, line := range last {
+		if _, err := io.WriteString(out, line); err != nil {
+			return err
+		}
+	}
+	if !follow {
+		return nil
+	}
+
+	ticker := time.NewTicker(FollowInterval)
+	defer ticker.Stop()
+	var partial string
+	for {
+		line, err := reader.ReadString('\n')
+		if err == nil {
+			line = partial + line
+			partial = ""
+			if f.Match(strings.TrimRight(line, "\n")) {
+				if _, err := io.WriteString(out, line); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		partial += line
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		// After a rotation the path names a new file.
+		cur, err1 := file.Stat()
+		next, err2 := os.Stat(path)
+		if err1 == nil && err2 == nil && !os.SameFile(cur, next) {
+			if nf, err := os.Open(path); err == nil {
+				file.Close()
+				file = nf
+				reader.Reset(file)
+				partial = ""
+			}
+		}
+	}
+}
@@ -0,0 +1,218 @@
+// Package logging is the daemon's structured logger. Each subsystem logs
+// through its own slog.Logger with a level that can be set per subsystem,
+// and correlation fields such as the task ID travel in the context.
+package logging
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/nathfavour/auracrab/pkg/config"
+)
+
+// Correlation keys carried through contexts with With.
+const (
+	KeyTask     = "task_id"
+	KeyStep     = "step_id"
+	KeyMission  = "mission_id"
+	KeyPlatform = "platform"
+	KeyChat     = "chat_id"
+)
+
+// KeySubsystem names the subsystem on every record.
+const KeySubsystem = "subsystem"
+
+type state struct {
+	handler slog.Handler
+	level   slog.Level
+	levels  map[string]slog.Level
+}
+
+var (
+	current atomic.Pointer[state]
+	closeMu sync.Mutex
+	closer  io.Closer
+)
+
+func init() {
+	current.Store(&state{
+		handler: slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		level:   slog.LevelInfo,
+	})
+}
+
+// For returns the logger of a subsystem. Loggers may be created before
+// Setup; they always write through the latest configuration.
+func For(subsystem string) *slog.Logger {
+	return slog.New(&handler{subsystem: subsystem})
+}
+
+// Path returns where the daemon writes its log.
+func Path(cfg config.LoggingConfig) string {
+	if cfg.File != "" {
+		return cfg.File
+	}
+	name := "auracrab.log"
+	if r := config.Replica(); r != "" {
+		name = r + ".log"
+	}
+	return filepath.Join(config.DataDir(), "logs", name)
+}
+
+// Setup sends every subsystem's records to the rotated log file in the
+// configured format, and to mirror as well when it is not nil.
+func Setup(cfg config.LoggingConfig, mirror io.Writer) error {
+	rot, err := NewRotator(Path(cfg), RotateOptions{
+		MaxSize:    int64(cfg.MaxSizeMB) << 20,
+		Every:      cfg.RotateEvery,
+		MaxBackups: cfg.MaxBackups,
+		Compress:   cfg.Compress,
+	})
+	if err != nil {
+		return err
+	}
+
+	var w io.Writer = rot
+	if mirror != nil {
+		w = io.MultiWriter(rot, mirror)
+	}
+	opts := &slog.HandlerOptions{Level: slog.LevelDebug}
+	var h slog.Handler
+	if strings.EqualFold(cfg.Format, "json") {
+		h = slog.NewJSONHandler(w, opts)
+	} else {
+		h = slog.NewTextHandler(w, opts)
+	}
+
+	st := &state{handler: h, level: ParseLevel(cfg.Level), levels: map[string]slog.Level{}}
+	for name, lvl := range cfg.Levels {
+		st.levels[strings.ToLower(name)] = ParseLevel(lvl)
+	}
+	current.Store(st)
+
+	closeMu.Lock()
+	defer closeMu.Unlock()
+	if closer != nil {
+		_ = closer.Close()
+	}
+	closer = rot
+	return nil
+}
+
+// Close flushes and closes the log file.
+func Close() error {
+	closeMu.Lock()
+	defer closeMu.Unlock()
+	if closer == nil {
+		return nil
+	}
+	err := closer.Close()
+	closer = nil
+	return err
+}
+
+// ParseLevel reads "debug", "info", "warn" or "error", defaulting to info.
+func ParseLevel(s string) slog.Level {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(s)); err != nil {
+		return slog.LevelInfo
+	}
+	return l
+}
+
+type ctxKey struct{}
+
+// With returns a context whose log records carry the given key/value
+// pairs, in addition to those ctx already carries.
+func With(ctx context.Context, args ...any) context.Context {
+	if len(args) == 0 {
+		return ctx
+	}
+	prev, _ := ctx.Value(ctxKey{}).([]slog.Attr)
+	attrs := append([]slog.Attr{}, prev...)
+	r := slog.NewRecord(time.Time{}, 0, "", 0)
+	r.Add(args...)
+	r.Attrs(func(a slog.Attr) bool {
+		attrs = append(attrs, a)
+		return true
+	})
+	return context.WithValue(ctx, ctxKey{}, attrs)
+}
+
+// Attrs returns the correlation fields carried by ctx.
+func Attrs(ctx context.Context) []slog.Attr {
+	if ctx == nil {
+		return nil
+	}
+	attrs, _ := ctx.Value(ctxKey{}).([]slog.Attr)
+	return attrs
+}
+
+// handler applies the subsystem's level and the context's correlation
+// fields, then writes through the current configuration.
+type handler struct {
+	subsystem string
+	attrs     []slog.Attr
+	groups    []string
+}
+
+func (h *handler) Enabled(_ context.Context, l slog.Level) bool {
+	st := current.Load()
+	min, ok := st.levels[h.subsystem]
+	if !ok {
+		min = st.level
+	}
+	return l >= min
+}
+
+func (h *handler) Handle(ctx context.Context, r slog.Record) error {
+	out := current.Load().handler.WithAttrs([]slog.Attr{slog.String(KeySubsystem, h.subsystem)})
+	if ctxAttrs := Attrs(ctx); len(ctxAttrs) > 0 {
+		out = out.WithAttrs(ctxAttrs)
+	}
+	if len(h.attrs) > 0 {
+		out = out.WithAttrs(h.attrs)
+	}
+	for _, g := range h.groups {
+		out = out.WithGroup(g)
+	}
+	return out.Handle(ctx, r)
+}
+
+func (h *handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(h.groups) > 0 {
+		// Attributes added after a group belong inside it.
+		attrs = []slog.Attr{nest(h.groups, attrs)}
+	}
+	c := *h
+	c.attrs = append(append([]slog.Attr{}, h.attrs...), attrs...)
+	return &c
+}
+
+func (h *handler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	c := *h
+	c.groups = append(append([]string{}, h.groups...), name)
+	return &c
+}
+
+func nest(groups []string, attrs []slog.Attr) slog.Attr {
+	args := make([]any, len(attrs))
+	for i, a := range attrs {
+		args[i] = a
+	}
+	a := slog.Group(groups[len(groups)-1], args...)
+	for i := len(groups) - 2; i >= 0; i-- {
+		a = slog.Group(groups[i], a)
+	}
+	return a
+}
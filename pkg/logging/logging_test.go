@@ -0,0 +1,137 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nathfavour/auracrab/pkg/config"
+)
+
+func setupTest(t *testing.T, cfg config.LoggingConfig) string {
+	t.Helper()
+	cfg.File = filepath.Join(t.TempDir(), "auracrab.log")
+	if err := Setup(cfg, nil); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = Close() })
+	return cfg.File
+}
+
+func TestSubsystemLevelsAndContextFields(t *testing.T) {
+	path := setupTest(t, config.LoggingConfig{Level: "info", Format: "json", Levels: map[string]string{"spine": "warn", "immune": "debug"}})
+
+	ctx := With(context.Background(), KeyTask, "t1")
+	ctx = With(ctx, KeyStep, "s2")
+	For("spine").Info("hidden")
+	For("spine").Warn("shown")
+	For("immune").Debug("debugging")
+	For("core").InfoContext(ctx, "running", "attempt", 1)
+
+	data, _ := os.ReadFile(path)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("got %d records:\n%s", len(lines), data)
+	}
+	last := lines[2]
+	for key, want := range map[string]string{KeySubsystem: "core", KeyTask: "t1", KeyStep: "s2", "msg": "running", "attempt": "1"} {
+		if got := Field(last, key); got != want {
+			t.Errorf("%s = %q, want %q in %s", key, got, want, last)
+		}
+	}
+}
+
+func TestTextFieldAndFilter(t *testing.T) {
+	line := `time=2026-01-01T00:00:00Z level=WARN msg="step failed" subsystem=nervous task_id=abc xtask_id=zzz reason="a b"`
+	if got := Field(line, KeyTask); got != "abc" {
+		t.Fatalf("task_id = %q", got)
+	}
+	if got := Field(line, "reason"); got != "a b" {
+		t.Fatalf("reason = %q", got)
+	}
+	if !(Filter{Task: "abc", Subsystem: "nervous"}).Match(line) {
+		t.Fatal("filter rejected a matching line")
+	}
+	if (Filter{Task: "zzz"}).Match(line) || (Filter{Level: 8}).Match(line) {
+		t.Fatal("filter accepted a line it should reject")
+	}
+}
+
+func TestRotatorRotatesCompressesAndPrunes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	r, err := NewRotator(path, RotateOptions{MaxSize: 10, MaxBackups: 2, Compress: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 4; i++ {
+		if _, err := r.Write([]byte("0123456789\n")); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(2 * time.Millisecond) // distinct backup names
+	}
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	backups := r.Backups()
+	if len(backups) != 2 {
+		t.Fatalf("kept %d backups: %v", len(backups), backups)
+	}
+	for _, b := range backups {
+		if !strings.HasSuffix(b, ".gz") {
+			t.Fatalf("backup %s is not compressed", b)
+		}
+	}
+	if data, _ := os.ReadFile(path); string(data) != "0123456789\n" {
+		t.Fatalf("current file holds %q", data)
+	}
+}
+
+func TestRotatorRotatesByAge(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	r, err := NewRotator(path, RotateOptions{Every: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	_, _ = r.Write([]byte("old\n"))
+	r.opened = time.Now().Add(-2 * time.Hour)
+	_, _ = r.Write([]byte("new\n"))
+	if len(r.Backups()) != 1 {
+		t.Fatalf("backups = %v", r.Backups())
+	}
+}
+
+func TestFollowFiltersByTask(t *testing.T) {
+	path := setupTest(t, config.LoggingConfig{Level: "info"})
+	log := For("core")
+	log.Info("first", KeyTask, "a")
+	log.Info("other", KeyTask, "b")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var out bytes.Buffer
+	done := make(chan error, 1)
+	go func() { done <- Follow(ctx, path, Filter{Task: "a"}, 10, true, &syncWriter{buf: &out}) }()
+
+	log.Info("second", KeyTask, "a")
+	time.Sleep(3 * FollowInterval)
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	got := out.String()
+	if !strings.Contains(got, "msg=first") || !strings.Contains(got, "msg=second") || strings.Contains(got, "msg=other") {
+		t.Fatalf("followed:\n%s", got)
+	}
+}
+
+type syncWriter struct {
+	buf *bytes.Buffer
+}
+
+func (w *syncWriter) Write(p []byte) (int, error) { return w.buf.Write(p) }
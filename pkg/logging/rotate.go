@@ -0,0 +1,173 @@
+package logging
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RotateOptions bounds a log file. Zero values disable the limit.
+type RotateOptions struct {
+	MaxSize    int64         // bytes before the file is rotated
+	Every      time.Duration // age before the file is rotated
+	MaxBackups int           // rotated files to keep
+	Compress   bool          // gzip rotated files
+}
+
+// Rotator is a log file that moves itself aside when it grows past
+// MaxSize or gets older than Every. Rotated files are named after the
+// time they were rotated and optionally compressed.
+type Rotator struct {
+	path string
+	opts RotateOptions
+
+	mu     sync.Mutex
+	file   *os.File
+	size   int64
+	opened time.Time
+	wg     sync.WaitGroup
+}
+
+// NewRotator opens path for appending, creating its directory.
+func NewRotator(path string, opts RotateOptions) (*Rotator, error) {
+	r := &Rotator{path: path, opts: opts}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *Rotator) open() error {
+	if err := os.MkdirAll(filepath.Dir(r.path), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(r.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	r.file = f
+	r.size = info.Size()
+	r.opened = info.ModTime()
+	if r.size == 0 {
+		r.opened = time.Now()
+	}
+	return nil
+}
+
+// Write appends p, rotating first when the file is due.
+func (r *Rotator) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.file == nil {
+		return 0, os.ErrClosed
+	}
+	if r.due(int64(len(p))) {
+		if err := r.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+func (r *Rotator) due(next int64) bool {
+	if r.size == 0 {
+		return false
+	}
+	if r.opts.MaxSize > 0 && r.size+next > r.opts.MaxSize {
+		return true
+	}
+	return r.opts.Every > 0 && time.Since(r.opened) >= r.opts.Every
+}
+
+// rotate moves the current file aside and starts a new one.
+func (r *Rotator) rotate() error {
+	if err := r.file.Close(); err != nil {
+		return err
+	}
+	ext := filepath.Ext(r.path)
+	backup := strings.TrimSuffix(r.path, ext) + "-" + time.Now().Format("20060102T150405.000") + ext
+	if err := os.Rename(r.path, backup); err != nil {
+		return err
+	}
+	if err := r.open(); err != nil {
+		return err
+	}
+
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		if r.opts.Compress {
+			if err := compress(backup); err == nil {
+				_ = os.Remove(backup)
+			}
+		}
+		r.prune()
+	}()
+	return nil
+}
+
+// Close closes the file once pending compression is done.
+func (r *Rotator) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.wg.Wait()
+	if r.file == nil {
+		return nil
+	}
+	err := r.file.Close()
+	r.file = nil
+	return err
+}
+
+// Backups lists the rotated files, oldest first.
+func (r *Rotator) Backups() []string {
+	ext := filepath.Ext(r.path)
+	matches, _ := filepath.Glob(strings.TrimSuffix(r.path, ext) + "-*" + ext + "*")
+	sort.Strings(matches)
+	return matches
+}
+
+func (r *Rotator) prune() {
+	if r.opts.MaxBackups <= 0 {
+		return
+	}
+	backups := r.Backups()
+	for len(backups) > r.opts.MaxBackups {
+		_ = os.Remove(backups[0])
+		backups = backups[1:]
+	}
+}
+
+func compress(path string) error {
+	in, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(path+".gz", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(out)
+	if _, err := io.Copy(zw, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,205 @@
+package memory
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"strings"
+)
+
+// Chunk is a structurally meaningful slice of a source file.
+type Chunk struct {
+	Path      string `json:"path"`
+	Kind      string `json:"kind"`   // "go", "markdown" or "text"
+	Symbol    string `json:"symbol"` // Declaration name or heading path
+	StartLine int    `json:"start_line"`
+	EndLine   int    `json:"end_line"`
+	Content   string `json:"content"`
+}
+
+// ID identifies the chunk by path and line range only. Content changes are
+// tracked per file by the manifest hash: re-ingesting a changed file removes
+// all its previous chunks before adding the new ones.
+func (c Chunk) ID() string {
+	return fmt.Sprintf("%s#L%d-%d", c.Path, c.StartLine, c.EndLine)
+}
+
+const (
+	maxChunkBytes   = 6 * 1024
+	textWindowLines = 60
+	textWindowStep  = 50
+)
+
+// ChunkFile splits a file into chunks according to its type: Go files by
+// top-level declaration, Markdown by heading, anything else by line window.
+func ChunkFile(path string, content []byte) []Chunk {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".go":
+		if chunks, err := chunkGo(path, content); err == nil {
+			return chunks
+		}
+	case ".md", ".markdown":
+		return chunkMarkdown(path, string(content))
+	}
+	return chunkLines(path, "text", "", strings.Split(string(content), "\n"), 1)
+}
+
+func chunkGo(path string, content []byte) ([]Chunk, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, path, content, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+
+	lines := strings.Split(string(content), "\n")
+	var chunks []Chunk
+	for _, decl := range file.Decls {
+		start := decl.Pos()
+		var symbol string
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			if d.Doc != nil {
+				start = d.Doc.Pos()
+			}
+			symbol = d.Name.Name
+			if d.Recv != nil && len(d.Recv.List) > 0 {
+				symbol = receiverName(d.Recv.List[0].Type) + "." + symbol
+			}
+		case *ast.GenDecl:
+			if d.Tok == token.IMPORT {
+				continue
+			}
+			if d.Doc != nil {
+				start = d.Doc.Pos()
+			}
+			var names []string
+			for _, spec := range d.Specs {
+				switch s := spec.(type) {
+				case *ast.TypeSpec:
+					names = append(names, s.Name.Name)
+				case *ast.ValueSpec:
+					for _, n := range s.Names {
+						names = append(names, n.Name)
+					}
+				}
+			}
+			symbol = d.Tok.String() + " " + strings.Join(names, ", ")
+		}
+
+		from := fset.Position(start).Line
+		to := fset.Position(decl.End()).Line
+		if from < 1 || to > len(lines) || from > to {
+			continue
+		}
+		chunks = append(chunks, chunkLines(path, "go", symbol, lines[from-1:to], from)...)
+	}
+	return chunks, nil
+}
+
+func receiverName(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.StarExpr:
+		return receiverName(t.X)
+	case *ast.Ident:
+		return t.Name
+	case *ast.IndexExpr:
+		return receiverName(t.X)
+	case *ast.IndexListExpr:
+		return receiverName(t.X)
+	}
+	return "?"
+}
+
+func chunkMarkdown(path, content string) []Chunk {
+	lines := strings.Split(content, "\n")
+	var chunks []Chunk
+	var headings []string
+	start := 0
+	inFence := false
+
+	flush := func(end int) {
+		if end <= start {
+			return
+		}
+		body := strings.TrimSpace(strings.Join(lines[start:end], "\n"))
+		if body == "" {
+			return
+		}
+		chunks = append(chunks, chunkLines(path, "markdown", strings.Join(headings, " > "), lines[start:end], start+1)...)
+	}
+
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			inFence = !inFence
+			continue
+		}
+		if inFence || !strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		level := len(trimmed) - len(strings.TrimLeft(trimmed, "#"))
+		if level > 6 || (len(trimmed) > level && trimmed[level] != ' ') {
+			continue
+		}
+
+		flush(i)
+		start = i
+		title := strings.TrimSpace(trimmed[level:])
+		if level <= len(headings) {
+			headings = headings[:level-1]
+		}
+		for len(headings) < level-1 {
+			headings = append(headings, "")
+		}
+		headings = append(headings, title)
+	}
+	flush(len(lines))
+	return chunks
+}
+
+// chunkLines emits lines as one chunk, or as overlapping windows when the
+// block is larger than maxChunkBytes.
+func chunkLines(path, kind, symbol string, lines []string, firstLine int) []Chunk {
+	body := strings.Join(lines, "\n")
+	if len(body) <= maxChunkBytes && (kind != "text" || len(lines) <= textWindowLines) {
+		if strings.TrimSpace(body) == "" {
+			return nil
+		}
+		return []Chunk{{
+			Path:      path,
+			Kind:      kind,
+			Symbol:    symbol,
+			StartLine: firstLine,
+			EndLine:   firstLine + len(lines) - 1,
+			Content:   body,
+		}}
+	}
+
+	var chunks []Chunk
+	for i := 0; i < len(lines); i += textWindowStep {
+		end := i + textWindowLines
+		if end > len(lines) {
+			end = len(lines)
+		}
+		window := strings.Join(lines[i:end], "\n")
+		if len(window) > maxChunkBytes {
+			window = window[:maxChunkBytes]
+		}
+		if strings.TrimSpace(window) != "" {
+			chunks = append(chunks, Chunk{
+				Path:      path,
+				Kind:      kind,
+				Symbol:    symbol,
+				StartLine: firstLine + i,
+				EndLine:   firstLine + end - 1,
+				Content:   window,
+			})
+		}
+		if end == len(lines) {
+			break
+		}
+	}
+	return chunks
+}
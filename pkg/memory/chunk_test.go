@@ -0,0 +1,81 @@
+package memory
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestChunkFile(t *testing.T) {
+	goSrc := `package demo
+
+import "fmt"
+
+// Greeter says hello.
+type Greeter struct{ Name string }
+
+const a, b = 1, 2
+
+// Greet prints the greeting.
+func (g *Greeter) Greet() {
+	fmt.Println("hello", g.Name)
+}
+`
+	mdSrc := "# Guide\n\nIntro.\n\n## Install\n\nRun it.\n\n```sh\n# not a heading\n```\n\n### Linux\n\napt install\n\n## Use\n\nCall it.\n"
+
+	type span struct {
+		Symbol     string
+		Start, End int
+	}
+	cases := []struct {
+		path    string
+		content string
+		kind    string
+		want    []span
+	}{
+		{"demo.go", goSrc, "go", []span{
+			{"type Greeter", 5, 6},
+			{"const a, b", 8, 8},
+			{"Greeter.Greet", 10, 13},
+		}},
+		{"guide.md", mdSrc, "markdown", []span{
+			{"Guide", 1, 4},
+			{"Guide > Install", 5, 12},
+			{"Guide > Install > Linux", 13, 16},
+			{"Guide > Use", 17, 20},
+		}},
+		{"broken.go", "package demo\nfunc {", "text", []span{{"", 1, 2}}},
+	}
+	for _, c := range cases {
+		t.Run(c.path, func(t *testing.T) {
+			chunks := ChunkFile(c.path, []byte(c.content))
+			var got []span
+			for _, ch := range chunks {
+				if ch.Kind != c.kind {
+					t.Errorf("chunk %s has kind %q, want %q", ch.ID(), ch.Kind, c.kind)
+				}
+				got = append(got, span{ch.Symbol, ch.StartLine, ch.EndLine})
+			}
+			if !reflect.DeepEqual(got, c.want) {
+				t.Fatalf("chunks = %+v, want %+v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestChunkFileWindowsLongText(t *testing.T) {
+	lines := make([]string, 130)
+	for i := range lines {
+		lines[i] = "line"
+	}
+	chunks := ChunkFile("notes.txt", []byte(strings.Join(lines, "\n")))
+
+	var got [][2]int
+	for _, c := range chunks {
+		got = append(got, [2]int{c.StartLine, c.EndLine})
+	}
+	want := [][2]int{{1, 60}, {51, 110}, {101, 130}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("windows = %v, want %v", got, want)
+	}
+}
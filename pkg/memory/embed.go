@@ -0,0 +1,141 @@
+package memory
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math"
+	"strings"
+	"unicode"
+)
+
+// Embedder turns text into a dense vector for similarity search.
+// vibe.Client satisfies this interface through its "embed" UDS method.
+type Embedder interface {
+	Embed(content string) ([]float64, error)
+}
+
+// HashEmbedder is a local, dependency-free embedder based on feature hashing
+// of word unigrams and bigrams. It is used when no model-backed embedder is
+// reachable so that similarity search keeps working offline.
+type HashEmbedder struct {
+	Dims int
+}
+
+const defaultHashDims = 256
+
+func (h HashEmbedder) Embed(content string) ([]float64, error) {
+	dims := h.Dims
+	if dims <= 0 {
+		dims = defaultHashDims
+	}
+	vec := make([]float64, dims)
+
+	tokens := Tokenize(content)
+	add := func(feature string, weight float64) {
+		f := fnv.New32a()
+		_, _ = f.Write([]byte(feature))
+		sum := f.Sum32()
+		idx := int(sum % uint32(dims))
+		// Use one hash bit as the sign to reduce collision bias.
+		if sum&(1<<31) != 0 {
+			weight = -weight
+		}
+		vec[idx] += weight
+	}
+
+	for i, tok := range tokens {
+		add(tok, 1.0)
+		if i > 0 {
+			add(tokens[i-1]+" "+tok, 0.5)
+		}
+	}
+
+	var norm float64
+	for _, v := range vec {
+		norm += v * v
+	}
+	if norm > 0 {
+		norm = math.Sqrt(norm)
+		for i := range vec {
+			vec[i] /= norm
+		}
+	}
+	return vec, nil
+}
+
+// FallbackEmbedder tries the primary embedder and falls back to a local
+// HashEmbedder when it errors out (e.g. vibeauracle is not running).
+type FallbackEmbedder struct {
+	Primary  Embedder
+	Fallback Embedder
+}
+
+func NewFallbackEmbedder(primary Embedder) *FallbackEmbedder {
+	return &FallbackEmbedder{
+		Primary:  primary,
+		Fallback: HashEmbedder{},
+	}
+}
+
+func (f *FallbackEmbedder) Embed(content string) ([]float64, error) {
+	if f.Primary != nil {
+		if vec, err := f.Primary.Embed(content); err == nil && len(vec) > 0 {
+			return vec, nil
+		}
+	}
+	return f.Fallback.Embed(content)
+}
+
+// Tokenize lowercases text and splits it into word tokens, keeping
+// apostrophes so that negations like "don't" survive as a single token.
+// camelCase and snake_case identifiers are split into their words.
+func Tokenize(text string) []string {
+	var b strings.Builder
+	var prev rune
+	for _, r := range text {
+		if unicode.IsUpper(r) && (unicode.IsLower(prev) || unicode.IsDigit(prev)) {
+			b.WriteRune(' ')
+		}
+		b.WriteRune(r)
+		prev = r
+	}
+	return strings.FieldsFunc(strings.ToLower(b.String()), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '\''
+	})
+}
+
+// ProbeEmbedder returns primary if it can currently embed text, otherwise
+// the local HashEmbedder. Use it before bulk indexing so that one collection
+// is embedded by a single backend.
+func ProbeEmbedder(primary Embedder) Embedder {
+	if primary != nil {
+		if vec, err := primary.Embed("probe"); err == nil && len(vec) > 0 {
+			return primary
+		}
+	}
+	return HashEmbedder{}
+}
+
+// EmbedderKind names the backend whose vectors e returns, so that a
+// collection can record it: "hash" for HashEmbedder, the EmbedderKind method
+// of e when it has one (vibe.Client reports "vibe"), its type otherwise. A
+// FallbackEmbedder mixes backends and reports "".
+func EmbedderKind(e Embedder) string {
+	switch e := e.(type) {
+	case HashEmbedder:
+		return "hash"
+	case *FallbackEmbedder:
+		return ""
+	case interface{ EmbedderKind() string }:
+		return e.EmbedderKind()
+	}
+	return fmt.Sprintf("%T", e)
+}
+
+// primaryEmbedder unwraps a FallbackEmbedder to the backend it prefers.
+func primaryEmbedder(e Embedder) Embedder {
+	if f, ok := e.(*FallbackEmbedder); ok && f.Primary != nil {
+		return f.Primary
+	}
+	return e
+}
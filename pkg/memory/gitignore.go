@@ -0,0 +1,140 @@
+package memory
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// ignoreRule is a single compiled .gitignore pattern.
+type ignoreRule struct {
+	re      *regexp.Regexp
+	base    string // directory of the .gitignore, relative to the walk root
+	negate  bool
+	dirOnly bool
+}
+
+// ignoreMatcher evaluates the .gitignore files found while walking a tree.
+// Rules from deeper directories are appended after their parents so that the
+// last matching rule wins, as in git.
+type ignoreMatcher struct {
+	rules []ignoreRule
+}
+
+// loadDir reads the .gitignore in dir (relative to root) if there is one.
+func (m *ignoreMatcher) loadDir(root, dir string) {
+	f, err := os.Open(filepath.Join(root, dir, ".gitignore"))
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		rule := ignoreRule{base: filepath.ToSlash(dir)}
+		if strings.HasPrefix(line, "!") {
+			rule.negate = true
+			line = line[1:]
+		}
+		line = strings.TrimPrefix(line, "\\")
+		if strings.HasSuffix(line, "/") {
+			rule.dirOnly = true
+			line = strings.TrimSuffix(line, "/")
+		}
+
+		// Patterns without a slash match at any depth below the .gitignore.
+		anchored := strings.Contains(line, "/")
+		line = strings.TrimPrefix(line, "/")
+		if !anchored {
+			line = "**/" + line
+		}
+
+		re, err := regexp.Compile("^" + globToRegexp(line) + "$")
+		if err != nil {
+			continue
+		}
+		rule.re = re
+		m.rules = append(m.rules, rule)
+	}
+}
+
+// ignored reports whether rel (slash-separated, relative to the walk root)
+// is excluded by the loaded rules.
+func (m *ignoreMatcher) ignored(rel string, isDir bool) bool {
+	rel = filepath.ToSlash(rel)
+	ignored := false
+	for _, r := range m.rules {
+		if r.dirOnly && !isDir {
+			continue
+		}
+		target := rel
+		if r.base != "." && r.base != "" {
+			if !strings.HasPrefix(rel, r.base+"/") {
+				continue
+			}
+			target = strings.TrimPrefix(rel, r.base+"/")
+		}
+		if r.re.MatchString(target) {
+			ignored = !r.negate
+		}
+	}
+	return ignored
+}
+
+// globToRegexp converts a gitignore glob into a regular expression body.
+func globToRegexp(glob string) string {
+	var b strings.Builder
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch {
+		case c == '*' && strings.HasPrefix(glob[i:], "**/"):
+			b.WriteString("(?:.*/)?")
+			i += 2
+		case c == '*' && strings.HasPrefix(glob[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(glob[i:], ']')
+			if end < 0 {
+				b.WriteString(regexp.QuoteMeta(string(c)))
+				continue
+			}
+			class := glob[i+1 : i+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + class + "]")
+			i += end
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return b.String()
+}
+
+// MatchGlob reports whether a slash-separated path matches a glob that may
+// contain "**". Patterns without a slash are matched against the base name.
+func MatchGlob(pattern, name string) bool {
+	name = filepath.ToSlash(name)
+	if !strings.Contains(pattern, "/") {
+		ok, _ := path.Match(pattern, path.Base(name))
+		return ok
+	}
+	re, err := regexp.Compile("^" + globToRegexp(pattern) + "$")
+	if err != nil {
+		return false
+	}
+	return re.MatchString(name)
+}
@@ -0,0 +1,75 @@
+package memory
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestIgnoreMatcher(t *testing.T) {
+	root := t.TempDir()
+	write := func(rel, content string) {
+		p := filepath.Join(root, rel)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(".gitignore", "# comment\n*.log\n!keep.log\nbuild/\n/root.txt\ndocs/*.tmp\n\\#literal\n")
+	write("sub/.gitignore", "*.gen\n!/important.gen\n")
+
+	m := &ignoreMatcher{}
+	m.loadDir(root, ".")
+	m.loadDir(root, "sub")
+
+	cases := []struct {
+		rel   string
+		isDir bool
+		want  bool
+	}{
+		{"app.log", false, true},
+		{"deep/nested/app.log", false, true},
+		{"keep.log", false, false},
+		{"deep/keep.log", false, false},
+		{"build", true, true},
+		{"pkg/build", true, true},
+		{"build", false, false},
+		{"root.txt", false, true},
+		{"sub/root.txt", false, false},
+		{"docs/a.tmp", false, true},
+		{"docs/more/a.tmp", false, false},
+		{"#literal", false, true},
+		{"sub/x.gen", false, true},
+		{"sub/deeper/x.gen", false, true},
+		{"x.gen", false, false},
+		{"sub/important.gen", false, false},
+		{"sub/deeper/important.gen", false, true},
+		{"main.go", false, false},
+	}
+	for _, c := range cases {
+		if got := m.ignored(c.rel, c.isDir); got != c.want {
+			t.Errorf("ignored(%q, dir=%v) = %v, want %v", c.rel, c.isDir, got, c.want)
+		}
+	}
+}
+
+func TestMatchGlob(t *testing.T) {
+	cases := []struct {
+		pattern, name string
+		want          bool
+	}{
+		{"*.go", "pkg/core/butler.go", true},
+		{"*.go", "README.md", false},
+		{"vendor/**", "vendor/a/b.go", true},
+		{"vendor/**", "pkg/vendor/b.go", false},
+		{"**/testdata/*", "pkg/x/testdata/in.txt", true},
+		{"src/*.ts", "src/a/b.ts", false},
+	}
+	for _, c := range cases {
+		if got := MatchGlob(c.pattern, c.name); got != c.want {
+			t.Errorf("MatchGlob(%q, %q) = %v, want %v", c.pattern, c.name, got, c.want)
+		}
+	}
+}
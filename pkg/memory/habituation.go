@@ -1,24 +1,90 @@
 package memory
 
 import (
+	"crypto/sha1"
+	"encoding/hex"
 	"encoding/json"
+	"fmt"
+	"math"
 	"os"
 	"path/filepath"
+	"regexp"
+	"sort"
 	"strings"
 	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/nathfavour/auracrab/pkg/config"
 )
 
+const (
+	// DefaultHabitThreshold is the minimum cosine similarity between the
+	// templated goals for a habit to be replayed.
+	DefaultHabitThreshold = 0.82
+	// MinHabitScore is the minimum decayed success score a habit needs.
+	MinHabitScore = 0.5
+	// HabitHalfLife is how long it takes for an outcome to lose half its weight.
+	HabitHalfLife = 14 * 24 * time.Hour
+)
+
+// HabitSlot is a parameter extracted from a goal (repo, branch, path, ...).
+// Slots are replaced by placeholders in the stored template and steps, and
+// refilled with the new values when the habit is replayed.
+type HabitSlot struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
 type HabitRecord struct {
-	Goal  string   `json:"goal"`
-	Steps []string `json:"steps"`
+	ID          string      `json:"id"`
+	Crab        string      `json:"crab,omitempty"` // Empty for the global agent
+	Version     int         `json:"version"`
+	Goal        string      `json:"goal"`
+	Template    string      `json:"template"`
+	Slots       []HabitSlot `json:"slots,omitempty"`
+	Steps       []string    `json:"steps"`
+	Embedding   []float64   `json:"embedding,omitempty"`
+	Successes   float64     `json:"successes"`
+	Failures    float64     `json:"failures"`
+	Replays     int         `json:"replays"`
+	Invalidated bool        `json:"invalidated,omitempty"`
+	Reason      string      `json:"reason,omitempty"`
+	LearnedAt   time.Time   `json:"learned_at"`
+	UpdatedAt   time.Time   `json:"updated_at"`
+}
+
+// Score returns the Laplace-smoothed success ratio with time decay applied.
+func (h *HabitRecord) Score() float64 {
+	decay := 1.0
+	if !h.UpdatedAt.IsZero() {
+		decay = math.Pow(0.5, float64(time.Since(h.UpdatedAt))/float64(HabitHalfLife))
+	}
+	s := h.Successes * decay
+	f := h.Failures * decay
+	return (s + 1) / (s + f + 2)
+}
+
+// HabitMatch is the result of a successful Recall.
+type HabitMatch struct {
+	ID         string
+	Steps      []string
+	Similarity float64
+	Score      float64
+	Version    int
 }
 
 type HabitStore struct {
-	mu     sync.RWMutex
-	path   string
-	habits map[string]HabitRecord
+	mu        sync.RWMutex
+	path      string
+	habits    map[string]*HabitRecord
+	embedder  Embedder
+	threshold float64
+
+	// stamp is the version of the file last loaded or written. The CLI and
+	// replicas write the same file, so it is reloaded whenever it changes.
+	stamp manifestStamp
 }
 
 var (
@@ -30,62 +96,487 @@ func GetHabitStore() *HabitStore {
 	habitOnce.Do(func() {
 		path := filepath.Join(config.DataDir(), "habituation.json")
 		store := &HabitStore{
-			path:   path,
-			habits: make(map[string]HabitRecord),
+			path:      path,
+			habits:    make(map[string]*HabitRecord),
+			embedder:  HashEmbedder{},
+			threshold: DefaultHabitThreshold,
 		}
-		store.load()
+		store.mu.Lock()
+		store.refreshLocked()
+		store.mu.Unlock()
 		habitStore = store
 	})
 	return habitStore
 }
 
-func (s *HabitStore) load() {
+// SetEmbedder replaces the embedder used to compare goals.
+func (s *HabitStore) SetEmbedder(e Embedder) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.embedder = e
+}
+
+// SetThreshold overrides the similarity threshold for Recall.
+func (s *HabitStore) SetThreshold(t float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.threshold = t
+}
+
+// refresh reloads the habits when another process changed the file.
+func (s *HabitStore) refresh() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.refreshLocked()
+}
+
+func (s *HabitStore) refreshLocked() {
+	stamp := statManifest(s.path)
+	if stamp == s.stamp {
+		return
+	}
+	s.stamp = stamp
+	s.habits = make(map[string]*HabitRecord)
 	data, err := os.ReadFile(s.path)
 	if err != nil {
 		return
 	}
+
+	var raw map[string]*HabitRecord
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return
+	}
+
+	// Records written before versioned habits were keyed by the lowercased
+	// goal and carry neither an ID nor a template. Upgrade them in place.
+	for _, h := range raw {
+		if h == nil {
+			continue
+		}
+		if h.Template == "" {
+			h.Template, h.Slots = ExtractSlots(h.Goal)
+			h.Steps = templateSteps(h.Steps, h.Slots)
+		}
+		if h.ID == "" {
+			h.ID = habitID(h.Crab, h.Template)
+		}
+		if h.Version == 0 {
+			h.Version = 1
+		}
+		s.habits[h.ID] = h
+	}
+}
+
+// saveLocked writes the habits. Callers refresh first under the same lock,
+// so that changes made by other processes are kept.
+func (s *HabitStore) saveLocked() {
+	data, _ := json.MarshalIndent(s.habits, "", "  ")
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return
+	}
+	if err := os.Rename(tmp, s.path); err == nil {
+		s.stamp = statManifest(s.path)
+	}
+}
+
+// Learn records a successful sequence of steps for a given goal, scoped to
+// a crab ("" for the global agent). Relearning a template with a different
+// plan bumps its version and clears any invalidation.
+func (s *HabitStore) Learn(crab, goal string, steps []string) string {
+	template, slots := ExtractSlots(goal)
+	templated := templateSteps(steps, slots)
+	id := habitID(crab, template)
+	embedding := s.embed(template)
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	_ = json.Unmarshal(data, &s.habits)
+	s.refreshLocked()
+	now := time.Now()
+	h, ok := s.habits[id]
+	if !ok {
+		h = &HabitRecord{
+			ID:        id,
+			Crab:      crab,
+			Version:   1,
+			LearnedAt: now,
+		}
+		s.habits[id] = h
+	} else if !equalSteps(h.Steps, templated) {
+		h.Version++
+		h.Successes = 0
+		h.Failures = 0
+		h.LearnedAt = now
+	}
+	h.Goal = goal
+	h.Template = template
+	h.Slots = slots
+	h.Steps = templated
+	h.Embedding = embedding
+	h.Successes++
+	h.Invalidated = false
+	h.Reason = ""
+	h.UpdatedAt = now
+	s.saveLocked()
+	return id
 }
 
-func (s *HabitStore) save() {
+// Recall attempts to find a cached plan for a semantically similar goal.
+// Habits of the given crab are preferred over global ones.
+func (s *HabitStore) Recall(crab, goal string) (*HabitMatch, bool) {
+	template, slots := ExtractSlots(goal)
+	query := s.embed(template)
+
+	s.refresh()
 	s.mu.RLock()
-	data, _ := json.MarshalIndent(s.habits, "", "  ")
+	candidates := make([]*HabitRecord, 0, len(s.habits))
+	for _, h := range s.habits {
+		if h.Invalidated || (h.Crab != "" && h.Crab != crab) {
+			continue
+		}
+		candidates = append(candidates, h)
+	}
+	threshold := s.threshold
 	s.mu.RUnlock()
-	_ = os.WriteFile(s.path, data, 0644)
-}
 
-// Learn records a successful sequence of steps for a given goal.
-func (s *HabitStore) Learn(goal string, steps []string) {
+	var best *HabitMatch
+	var bestRank float64
+	for _, h := range candidates {
+		if negated(template) != negated(h.Template) {
+			continue
+		}
+
+		emb := h.Embedding
+		if len(emb) != len(query) {
+			// Stored with a different embedder; re-embed the template.
+			emb = s.embed(h.Template)
+		}
+		sim := CosineSimilarity(query, emb)
+		if sim < threshold {
+			continue
+		}
+
+		score := h.Score()
+		if score < MinHabitScore {
+			continue
+		}
+
+		steps, ok := fillSteps(h.Steps, h.Slots, slots)
+		if !ok {
+			continue
+		}
+
+		rank := sim * score
+		if h.Crab != "" && h.Crab == crab {
+			rank += 0.01
+		}
+		if best == nil || rank > bestRank {
+			best = &HabitMatch{ID: h.ID, Steps: steps, Similarity: sim, Score: score, Version: h.Version}
+			bestRank = rank
+		}
+	}
+
+	if best == nil {
+		return nil, false
+	}
+
 	s.mu.Lock()
-	// Normalize goal for better matching (simple lowercase)
-	key := strings.ToLower(strings.TrimSpace(goal))
-	s.habits[key] = HabitRecord{
-		Goal:  goal,
-		Steps: steps,
+	s.refreshLocked()
+	if h, ok := s.habits[best.ID]; ok {
+		h.Replays++
+		s.saveLocked()
 	}
 	s.mu.Unlock()
-	s.save()
+	return best, true
+}
+
+// RecordOutcome updates the score of a replayed habit. A failed replay
+// invalidates the habit until it is learned again.
+func (s *HabitStore) RecordOutcome(id string, success bool, reason string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.refreshLocked()
+	h, ok := s.habits[id]
+	if !ok {
+		return
+	}
+	// Fold the decay into the counters before adding the new outcome.
+	decay := math.Pow(0.5, float64(time.Since(h.UpdatedAt))/float64(HabitHalfLife))
+	h.Successes *= decay
+	h.Failures *= decay
+	if success {
+		h.Successes++
+	} else {
+		h.Failures++
+		h.Invalidated = true
+		h.Reason = reason
+	}
+	h.UpdatedAt = time.Now()
+	s.saveLocked()
+}
+
+// List returns all habits sorted by crab and goal.
+func (s *HabitStore) List() []HabitRecord {
+	s.refresh()
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	list := make([]HabitRecord, 0, len(s.habits))
+	for _, h := range s.habits {
+		list = append(list, *h)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Crab != list[j].Crab {
+			return list[i].Crab < list[j].Crab
+		}
+		return list[i].Template < list[j].Template
+	})
+	return list
 }
 
-// Recall attempts to find a cached plan for a similar goal.
-func (s *HabitStore) Recall(goal string) ([]string, bool) {
+// Get returns a habit by ID or unique ID prefix.
+func (s *HabitStore) Get(id string) (HabitRecord, error) {
+	s.refresh()
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	key := strings.ToLower(strings.TrimSpace(goal))
-	
-	// Direct match first
-	if habit, ok := s.habits[key]; ok {
-		return habit.Steps, true
+	if h, ok := s.habits[id]; ok {
+		return *h, nil
+	}
+	var found *HabitRecord
+	for k, h := range s.habits {
+		if strings.HasPrefix(k, id) {
+			if found != nil {
+				return HabitRecord{}, fmt.Errorf("habit ID prefix '%s' is ambiguous", id)
+			}
+			found = h
+		}
+	}
+	if found == nil {
+		return HabitRecord{}, fmt.Errorf("habit '%s' not found", id)
+	}
+	return *found, nil
+}
+
+// Forget removes a habit by ID or unique ID prefix.
+func (s *HabitStore) Forget(id string) error {
+	h, err := s.Get(id)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.refreshLocked()
+	delete(s.habits, h.ID)
+	s.saveLocked()
+	return nil
+}
+
+// ForgetCrab removes every habit learned for a crab ("" for global habits).
+func (s *HabitStore) ForgetCrab(crab string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.refreshLocked()
+	removed := 0
+	for k, h := range s.habits {
+		if h.Crab == crab {
+			delete(s.habits, k)
+			removed++
+		}
+	}
+	s.saveLocked()
+	return removed
+}
+
+func (s *HabitStore) embed(text string) []float64 {
+	s.mu.RLock()
+	e := s.embedder
+	s.mu.RUnlock()
+	if e == nil {
+		e = HashEmbedder{}
+	}
+	vec, err := e.Embed(text)
+	if err != nil || len(vec) == 0 {
+		vec, _ = HashEmbedder{}.Embed(text)
+	}
+	return vec
+}
+
+func habitID(crab, template string) string {
+	sum := sha1.Sum([]byte(crab + "\x00" + strings.ToLower(template)))
+	return "h_" + hex.EncodeToString(sum[:])[:10]
+}
+
+var slotPatterns = []struct {
+	name string
+	re   *regexp.Regexp
+}{
+	{"url", regexp.MustCompile(`https?://\S+`)},
+	{"branch", regexp.MustCompile(`(?i)\bbranch\s+([\w./-]+)`)},
+	{"arg", regexp.MustCompile("\"([^\"]+)\"|`([^`]+)`")},
+	{"path", regexp.MustCompile(`(?:^|\s)((?:~|\.{1,2})?/[\w.-]+(?:/[\w.-]+)*|[\w.-]+(?:/[\w.-]+){2,}|(?:[\w.-]+/)?[\w-]+\.(?:go|md|ya?ml|json|ts|js|py|rs|toml|txt))\b`)},
+	{"repo", regexp.MustCompile(`\b[\w.-]+/[\w.-]+\b`)},
+}
+
+// ExtractSlots replaces parameter-like tokens in a goal (URLs, branch names,
+// quoted arguments, paths, owner/repo pairs) with numbered placeholders and
+// returns the resulting template together with the extracted values.
+func ExtractSlots(goal string) (string, []HabitSlot) {
+	template := strings.TrimSpace(goal)
+	var slots []HabitSlot
+	counts := map[string]int{}
+
+	for _, p := range slotPatterns {
+		template = p.re.ReplaceAllStringFunc(template, func(match string) string {
+			if strings.Contains(match, "{") {
+				return match
+			}
+			value := match
+			if sub := p.re.FindStringSubmatch(match); len(sub) > 1 {
+				for _, g := range sub[1:] {
+					if g != "" {
+						value = g
+						break
+					}
+				}
+			}
+			counts[p.name]++
+			name := p.name
+			if counts[p.name] > 1 {
+				name = fmt.Sprintf("%s%d", p.name, counts[p.name])
+			}
+			slots = append(slots, HabitSlot{Name: name, Value: value})
+			return strings.Replace(match, value, "{"+name+"}", 1)
+		})
+	}
+	return template, slots
+}
+
+// templateSteps replaces slot values in the steps by their placeholders.
+// Longer values go first so that a value contained in another one does
+// not split it.
+func templateSteps(steps []string, slots []HabitSlot) []string {
+	ordered := append([]HabitSlot(nil), slots...)
+	sort.SliceStable(ordered, func(i, j int) bool { return len(ordered[i].Value) > len(ordered[j].Value) })
+	out := make([]string, len(steps))
+	for i, step := range steps {
+		for _, slot := range ordered {
+			step = replaceToken(step, slot.Value, "{"+slot.Name+"}")
+		}
+		out[i] = step
+	}
+	return out
+}
+
+// replaceToken replaces the occurrences of value in s that stand on their
+// own, so that a branch "main" leaves "domain", "main.go" and "{main}"
+// alone.
+func replaceToken(s, value, repl string) string {
+	if value == "" {
+		return s
+	}
+	var b strings.Builder
+	for {
+		i := strings.Index(s, value)
+		if i < 0 {
+			b.WriteString(s)
+			return b.String()
+		}
+		end := i + len(value)
+		if gluedBefore(s[:i]) || gluedAfter(s[end:]) {
+			b.WriteString(s[:end])
+		} else {
+			b.WriteString(s[:i])
+			b.WriteString(repl)
+		}
+		s = s[end:]
+	}
+}
+
+func tokenRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '-'
+}
+
+// gluedBefore reports whether the text before a match continues into it:
+// a word character, a placeholder brace, or a dot or slash after one.
+func gluedBefore(before string) bool {
+	r, n := utf8.DecodeLastRuneInString(before)
+	if n == 0 {
+		return false
+	}
+	if tokenRune(r) || r == '{' {
+		return true
+	}
+	if r == '.' || r == '/' {
+		prev, m := utf8.DecodeLastRuneInString(before[:len(before)-n])
+		return m > 0 && tokenRune(prev)
+	}
+	return false
+}
+
+// gluedAfter reports whether the text after a match continues it: a word
+// character, or a dot or slash followed by one, as in an extension or path.
+func gluedAfter(after string) bool {
+	r, n := utf8.DecodeRuneInString(after)
+	if n == 0 {
+		return false
+	}
+	if tokenRune(r) {
+		return true
+	}
+	if r == '.' || r == '/' {
+		next, m := utf8.DecodeRuneInString(after[n:])
+		return m > 0 && tokenRune(next)
+	}
+	return false
+}
+
+// fillSteps substitutes the new goal's slot values into templated steps.
+// It fails if the habit needs a slot the new goal does not provide.
+func fillSteps(steps []string, habitSlots, goalSlots []HabitSlot) ([]string, bool) {
+	values := map[string]string{}
+	for _, s := range goalSlots {
+		values[s.Name] = s.Value
+	}
+	for _, s := range habitSlots {
+		if _, ok := values[s.Name]; !ok {
+			return nil, false
+		}
+	}
+
+	out := make([]string, len(steps))
+	for i, step := range steps {
+		for name, value := range values {
+			step = strings.ReplaceAll(step, "{"+name+"}", value)
+		}
+		out[i] = step
 	}
+	return out, true
+}
+
+var negations = map[string]bool{
+	"not": true, "no": true, "never": true, "don't": true, "dont": true,
+	"without": true, "stop": true, "skip": true, "avoid": true,
+	"undo": true, "revert": true, "cancel": true, "disable": true,
+}
 
-	// Fuzzy match: If the goal contains a known habit goal
-	for k, v := range s.habits {
-		if strings.Contains(key, k) || strings.Contains(k, key) {
-			return v.Steps, true
+// negated reports whether a goal is phrased as a negation or reversal, so
+// that "don't deploy to prod" never replays a "deploy to prod" habit.
+func negated(text string) bool {
+	for _, tok := range Tokenize(text) {
+		if negations[tok] || strings.HasSuffix(tok, "n't") {
+			return true
 		}
 	}
+	return false
+}
 
-	return nil, false
+func equalSteps(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
 }
@@ -0,0 +1,214 @@
+package memory
+
+import (
+	"math"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func newTestHabitStore(t *testing.T) *HabitStore {
+	t.Helper()
+	return &HabitStore{
+		path:      filepath.Join(t.TempDir(), "habituation.json"),
+		habits:    make(map[string]*HabitRecord),
+		embedder:  HashEmbedder{},
+		threshold: DefaultHabitThreshold,
+	}
+}
+
+func TestExtractSlotsAndRefill(t *testing.T) {
+	cases := []struct {
+		name     string
+		learned  string
+		steps    []string
+		replayed string
+		want     []string
+	}{
+		{
+			name:     "branch",
+			learned:  "rebase branch feature/login",
+			steps:    []string{"git checkout feature/login", "git rebase origin/main"},
+			replayed: "rebase branch fix/crash",
+			want:     []string{"git checkout fix/crash", "git rebase origin/main"},
+		},
+		{
+			name:     "repo",
+			learned:  "release nathfavour/auracrab",
+			steps:    []string{"clone nathfavour/auracrab", "tag nathfavour/auracrab"},
+			replayed: "release acme/rocket",
+			want:     []string{"clone acme/rocket", "tag acme/rocket"},
+		},
+		{
+			name:     "path",
+			learned:  "lint pkg/core/butler.go",
+			steps:    []string{"go vet pkg/core/butler.go"},
+			replayed: "lint pkg/memory/store.go",
+			want:     []string{"go vet pkg/memory/store.go"},
+		},
+		{
+			name:     "quoted argument",
+			learned:  `run "go test ./..." twice`,
+			steps:    []string{"go test ./...", "go test ./..."},
+			replayed: `run "go vet ./..." twice`,
+			want:     []string{"go vet ./...", "go vet ./..."},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			s := newTestHabitStore(t)
+			s.Learn("", c.learned, c.steps)
+			match, ok := s.Recall("", c.replayed)
+			if !ok {
+				t.Fatalf("expected %q to recall the habit of %q", c.replayed, c.learned)
+			}
+			if !reflect.DeepEqual(match.Steps, c.want) {
+				t.Fatalf("refilled steps = %q, want %q", match.Steps, c.want)
+			}
+		})
+	}
+}
+
+func TestTemplateStepsReplacesWholeTokens(t *testing.T) {
+	cases := []struct {
+		step  string
+		slots []HabitSlot
+		want  string
+	}{
+		{"git checkout main", []HabitSlot{{"branch", "main"}}, "git checkout {branch}"},
+		{"update the domain on main", []HabitSlot{{"branch", "main"}}, "update the domain on {branch}"},
+		{"go run main.go on main.", []HabitSlot{{"branch", "main"}}, "go run main.go on {branch}."},
+		{"build app, not the application or src/app", []HabitSlot{{"repo", "app"}}, "build {repo}, not the application or src/app"},
+		{"deploy (app) to my-app", []HabitSlot{{"repo", "app"}}, "deploy ({repo}) to my-app"},
+		{"push acme/app then app", []HabitSlot{{"name", "app"}, {"repo", "acme/app"}}, "push {repo} then {name}"},
+	}
+	for _, c := range cases {
+		if got := templateSteps([]string{c.step}, c.slots)[0]; got != c.want {
+			t.Errorf("templateSteps(%q) = %q, want %q", c.step, got, c.want)
+		}
+	}
+}
+
+func TestRecallSkipsNegatedGoals(t *testing.T) {
+	cases := []struct {
+		learned, asked string
+		recalled       bool
+	}{
+		{"deploy to prod", "deploy to prod", true},
+		{"deploy to prod", "don't deploy to prod", false},
+		{"deploy to prod", "never deploy to prod", false},
+		{"don't deploy to prod", "deploy to prod", false},
+		{"deploy to prod", "deploy to prod without tests", false},
+	}
+	for _, c := range cases {
+		s := newTestHabitStore(t)
+		s.Learn("", c.learned, []string{"build", "ship"})
+		if _, ok := s.Recall("", c.asked); ok != c.recalled {
+			t.Errorf("learned %q, asked %q: recalled = %v, want %v", c.learned, c.asked, ok, c.recalled)
+		}
+	}
+}
+
+func TestHabitScoreDecays(t *testing.T) {
+	cases := []struct {
+		name    string
+		age     time.Duration
+		success float64
+		failure float64
+		want    float64
+	}{
+		{"fresh", 0, 3, 0, 4.0 / 5.0},
+		{"one half-life", HabitHalfLife, 4, 0, 3.0 / 4.0},
+		{"two half-lives", 2 * HabitHalfLife, 4, 0, 2.0 / 3.0},
+		{"failures decay too", HabitHalfLife, 0, 4, 1.0 / 4.0},
+		{"long forgotten", 50 * HabitHalfLife, 10, 0, 0.5},
+	}
+	for _, c := range cases {
+		h := &HabitRecord{Successes: c.success, Failures: c.failure, UpdatedAt: time.Now().Add(-c.age)}
+		if got := h.Score(); math.Abs(got-c.want) > 0.001 {
+			t.Errorf("%s: Score() = %.4f, want %.4f", c.name, got, c.want)
+		}
+	}
+}
+
+func TestRecordOutcome(t *testing.T) {
+	cases := []struct {
+		name        string
+		success     bool
+		invalidated bool
+		recalled    bool
+	}{
+		{"success keeps the habit", true, false, true},
+		{"failure invalidates it", false, true, false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			s := newTestHabitStore(t)
+			id := s.Learn("", "deploy to prod", []string{"build", "ship"})
+			s.RecordOutcome(id, c.success, "ship failed")
+
+			h, err := s.Get(id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if h.Invalidated != c.invalidated {
+				t.Fatalf("Invalidated = %v, want %v", h.Invalidated, c.invalidated)
+			}
+			if !c.success && h.Reason != "ship failed" {
+				t.Errorf("expected the failure reason, got %q", h.Reason)
+			}
+			if _, ok := s.Recall("", "deploy to prod"); ok != c.recalled {
+				t.Fatalf("recalled = %v, want %v", ok, c.recalled)
+			}
+		})
+	}
+}
+
+func TestLearnVersions(t *testing.T) {
+	cases := []struct {
+		name    string
+		steps   []string
+		version int
+	}{
+		{"same plan", []string{"build", "ship"}, 1},
+		{"changed plan", []string{"build", "test", "ship"}, 2},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			s := newTestHabitStore(t)
+			id := s.Learn("", "deploy to prod", []string{"build", "ship"})
+			s.RecordOutcome(id, false, "broke")
+			s.Learn("", "deploy to prod", c.steps)
+
+			h, _ := s.Get(id)
+			if h.Version != c.version {
+				t.Fatalf("Version = %d, want %d", h.Version, c.version)
+			}
+			if h.Invalidated {
+				t.Error("relearning must clear the invalidation")
+			}
+			if c.version > 1 && (h.Failures != 0 || h.Successes != 1) {
+				t.Errorf("a new version starts a fresh record, got %+v", h)
+			}
+		})
+	}
+}
+
+func TestHabitStoresSharingAFile(t *testing.T) {
+	daemon := newTestHabitStore(t)
+	cli := &HabitStore{path: daemon.path, habits: map[string]*HabitRecord{}, embedder: HashEmbedder{}, threshold: DefaultHabitThreshold}
+
+	id := daemon.Learn("", "deploy to prod", []string{"build", "ship"})
+	if err := cli.Forget(id); err != nil {
+		t.Fatalf("the CLI did not see the daemon's habit: %v", err)
+	}
+	docs := daemon.Learn("", "build the docs", []string{"generate", "publish"})
+
+	if _, err := daemon.Get(id); err == nil {
+		t.Fatal("the daemon's save brought a forgotten habit back")
+	}
+	if list := cli.List(); len(list) != 1 || list[0].ID != docs {
+		t.Fatalf("the CLI sees %+v, want only the docs habit", list)
+	}
+}
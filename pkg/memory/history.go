@@ -1,9 +1,11 @@
 package memory
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -160,6 +162,32 @@ func (h *HistoryStore) ListAuthorizedEntities(platform string) ([]string, error)
 	return ids, nil
 }
 
+// Integrity runs SQLite's quick integrity check on the database.
+func (h *HistoryStore) Integrity(ctx context.Context) error {
+	rows, err := h.db.QueryContext(ctx, "PRAGMA quick_check")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	var problems []string
+	for rows.Next() {
+		var line string
+		if err := rows.Scan(&line); err != nil {
+			return err
+		}
+		if line != "ok" {
+			problems = append(problems, line)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("history database is corrupt: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
 // Close closes the database connection.
 func (h *HistoryStore) Close() error {
 	return h.db.Close()
@@ -0,0 +1,432 @@
+package memory
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nathfavour/auracrab/pkg/config"
+)
+
+const maxIngestFileBytes = 1 << 20
+
+// textExtensions lists the file types worth indexing. Files without an
+// extension (Makefile, Dockerfile, ...) are indexed when they look like text.
+var textExtensions = map[string]bool{
+	".go": true, ".md": true, ".markdown": true, ".txt": true, ".rst": true,
+	".yaml": true, ".yml": true, ".json": true, ".toml": true, ".mod": true,
+	".ts": true, ".tsx": true, ".js": true, ".jsx": true, ".py": true,
+	".rs": true, ".java": true, ".kt": true, ".c": true, ".h": true,
+	".cpp": true, ".hpp": true, ".sh": true, ".sql": true, ".proto": true,
+	".html": true, ".css": true, ".rb": true, ".swift": true,
+}
+
+// FileManifest remembers the content hash of an ingested file and the IDs of
+// the chunks it produced, so re-ingestion only touches changed files.
+type FileManifest struct {
+	Hash   string   `json:"hash"`
+	Chunks []string `json:"chunks"`
+}
+
+type KnowledgeManifest struct {
+	Collection string `json:"collection"`
+	Root       string `json:"root"`
+	Dims       int    `json:"dims"`
+	// Embedder is the EmbedderKind of the backend that embedded every chunk;
+	// queries embedded by another backend are not comparable with them.
+	Embedder  string                  `json:"embedder,omitempty"`
+	Files     map[string]FileManifest `json:"files"`
+	UpdatedAt time.Time               `json:"updated_at"`
+}
+
+type IngestOptions struct {
+	Exclude  []string // Extra globs to skip on top of .gitignore
+	Progress func(path string)
+}
+
+type IngestStats struct {
+	Scanned   int
+	Indexed   int
+	Unchanged int
+	Removed   int
+	Chunks    int
+}
+
+// KnowledgeBase is a named VectorStore collection of repository chunks.
+type KnowledgeBase struct {
+	mu       sync.Mutex
+	store    *VectorStore
+	manifest KnowledgeManifest
+	path     string
+	embedder Embedder
+	stamp    manifestStamp
+}
+
+// manifestStamp identifies the version of a file, such as a collection
+// manifest, that a store has loaded or written.
+type manifestStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+func statManifest(path string) manifestStamp {
+	info, err := os.Stat(path)
+	if err != nil {
+		return manifestStamp{}
+	}
+	return manifestStamp{modTime: info.ModTime(), size: info.Size()}
+}
+
+func knowledgeDir() string {
+	return filepath.Join(config.DataDir(), "memory")
+}
+
+// OpenKnowledge opens (or creates) the knowledge collection with the given name.
+func OpenKnowledge(collection string, embedder Embedder) (*KnowledgeBase, error) {
+	store, err := NewVectorStore(collection)
+	if err != nil {
+		return nil, err
+	}
+	if embedder == nil {
+		embedder = HashEmbedder{}
+	}
+
+	kb := &KnowledgeBase{
+		store:    store,
+		path:     filepath.Join(knowledgeDir(), collection+"_knowledge.json"),
+		embedder: embedder,
+		manifest: KnowledgeManifest{
+			Collection: collection,
+			Files:      make(map[string]FileManifest),
+		},
+	}
+	kb.stamp = statManifest(kb.path)
+	if data, err := os.ReadFile(kb.path); err == nil {
+		_ = json.Unmarshal(data, &kb.manifest)
+		if kb.manifest.Files == nil {
+			kb.manifest.Files = make(map[string]FileManifest)
+		}
+	}
+	return kb, nil
+}
+
+// FindKnowledgeForPath returns the name of the collection whose ingested
+// root contains dir, preferring the deepest root.
+func FindKnowledgeForPath(dir string) (string, bool) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", false
+	}
+	files, _ := filepath.Glob(filepath.Join(knowledgeDir(), "*_knowledge.json"))
+
+	best, bestLen := "", -1
+	for _, f := range files {
+		data, err := os.ReadFile(f)
+		if err != nil {
+			continue
+		}
+		var m KnowledgeManifest
+		if json.Unmarshal(data, &m) != nil || m.Root == "" {
+			continue
+		}
+		if abs == m.Root || strings.HasPrefix(abs, m.Root+string(filepath.Separator)) {
+			if len(m.Root) > bestLen {
+				best, bestLen = m.Collection, len(m.Root)
+			}
+		}
+	}
+	return best, bestLen >= 0
+}
+
+// ListKnowledge returns the manifests of all ingested collections.
+func ListKnowledge() []KnowledgeManifest {
+	files, _ := filepath.Glob(filepath.Join(knowledgeDir(), "*_knowledge.json"))
+	var list []KnowledgeManifest
+	for _, f := range files {
+		data, err := os.ReadFile(f)
+		if err != nil {
+			continue
+		}
+		var m KnowledgeManifest
+		if json.Unmarshal(data, &m) == nil {
+			list = append(list, m)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Collection < list[j].Collection })
+	return list
+}
+
+func (kb *KnowledgeBase) Manifest() KnowledgeManifest {
+	kb.mu.Lock()
+	defer kb.mu.Unlock()
+	return kb.manifest
+}
+
+// Stale reports whether the manifest on disk changed since kb loaded or
+// wrote it, e.g. because `auracrab memory ingest` ran in another process.
+func (kb *KnowledgeBase) Stale() bool {
+	kb.mu.Lock()
+	defer kb.mu.Unlock()
+	now := statManifest(kb.path)
+	return !now.modTime.Equal(kb.stamp.modTime) || now.size != kb.stamp.size
+}
+
+// Ingest walks root, honouring .gitignore files, and (re)indexes every text
+// file whose content hash changed since the last run. Chunks of deleted or
+// changed files are removed from the collection. A FallbackEmbedder is
+// resolved once, so that the whole collection is embedded by one backend;
+// when that backend differs from the one recorded in the manifest every file
+// is embedded again.
+func (kb *KnowledgeBase) Ingest(ctx context.Context, root string, opts IngestOptions) (IngestStats, error) {
+	kb.mu.Lock()
+	defer kb.mu.Unlock()
+
+	var stats IngestStats
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return stats, err
+	}
+	if kb.manifest.Root != "" && kb.manifest.Root != root {
+		return stats, fmt.Errorf("collection '%s' already indexes %s", kb.manifest.Collection, kb.manifest.Root)
+	}
+	kb.manifest.Root = root
+
+	embedder := kb.embedder
+	if _, ok := embedder.(*FallbackEmbedder); ok {
+		embedder = ProbeEmbedder(primaryEmbedder(embedder))
+	}
+	kind := EmbedderKind(embedder)
+
+	var removals []string
+	if kb.manifest.Embedder != kind {
+		for rel, fm := range kb.manifest.Files {
+			removals = append(removals, fm.Chunks...)
+			delete(kb.manifest.Files, rel)
+		}
+		kb.manifest.Embedder = kind
+		kb.manifest.Dims = 0
+	}
+
+	matcher := &ignoreMatcher{}
+	seen := make(map[string]bool)
+	var upserts []VectorEntry
+
+	err = filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		rel, _ := filepath.Rel(root, p)
+		rel = filepath.ToSlash(rel)
+
+		if d.IsDir() {
+			if d.Name() == ".git" || (rel != "." && matcher.ignored(rel, true)) {
+				return filepath.SkipDir
+			}
+			matcher.loadDir(root, rel)
+			return nil
+		}
+		if matcher.ignored(rel, false) || excluded(rel, opts.Exclude) || !d.Type().IsRegular() {
+			return nil
+		}
+
+		ext := strings.ToLower(filepath.Ext(rel))
+		if ext != "" && !textExtensions[ext] {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil || info.Size() > maxIngestFileBytes {
+			return nil
+		}
+		content, err := os.ReadFile(p)
+		if err != nil || bytes.IndexByte(content[:min(len(content), 8192)], 0) >= 0 {
+			return nil
+		}
+
+		stats.Scanned++
+		seen[rel] = true
+		sum := sha256.Sum256(content)
+		hash := hex.EncodeToString(sum[:])
+		if prev, ok := kb.manifest.Files[rel]; ok && prev.Hash == hash {
+			stats.Unchanged++
+			return nil
+		}
+		if opts.Progress != nil {
+			opts.Progress(rel)
+		}
+
+		if prev, ok := kb.manifest.Files[rel]; ok {
+			removals = append(removals, prev.Chunks...)
+		}
+		var ids []string
+		for _, c := range ChunkFile(rel, content) {
+			vec, err := embedder.Embed(c.Symbol + "\n" + c.Content)
+			if err != nil {
+				continue
+			}
+			if kb.manifest.Dims == 0 {
+				kb.manifest.Dims = len(vec)
+			}
+			upserts = append(upserts, VectorEntry{
+				ID:      c.ID(),
+				Content: c.Content,
+				Metadata: map[string]interface{}{
+					"path":       c.Path,
+					"kind":       c.Kind,
+					"symbol":     c.Symbol,
+					"start_line": c.StartLine,
+					"end_line":   c.EndLine,
+				},
+				Embedding: vec,
+			})
+			ids = append(ids, c.ID())
+		}
+		kb.manifest.Files[rel] = FileManifest{Hash: hash, Chunks: ids}
+		stats.Indexed++
+		stats.Chunks += len(ids)
+		return nil
+	})
+	if err != nil {
+		return stats, err
+	}
+
+	for rel, fm := range kb.manifest.Files {
+		if !seen[rel] {
+			removals = append(removals, fm.Chunks...)
+			delete(kb.manifest.Files, rel)
+			stats.Removed++
+		}
+	}
+
+	// Remove stale chunks first so that unchanged positions re-added by
+	// upserts are not dropped again.
+	if err := kb.store.Remove(removals); err != nil {
+		return stats, err
+	}
+	if err := kb.store.Upsert(upserts); err != nil {
+		return stats, err
+	}
+
+	kb.manifest.UpdatedAt = time.Now()
+	return stats, kb.saveManifest()
+}
+
+// Query returns the topK chunks most relevant to text. When paths is not
+// empty only chunks from those files are considered. The query is embedded
+// by the backend recorded in the manifest; when that backend is unavailable,
+// or the collection predates the record, chunks are matched by their words
+// instead, since vectors of different backends cannot be compared.
+func (kb *KnowledgeBase) Query(text string, topK int, paths ...string) []VectorEntry {
+	m := kb.Manifest()
+	var vec []float64
+	switch m.Embedder {
+	case "":
+	case "hash":
+		vec, _ = HashEmbedder{Dims: m.Dims}.Embed(text)
+	default:
+		if primary := primaryEmbedder(kb.embedder); EmbedderKind(primary) == m.Embedder {
+			if v, err := primary.Embed(text); err == nil && len(v) == m.Dims {
+				vec = v
+			}
+		}
+	}
+
+	var filter func(VectorEntry) bool
+	if len(paths) > 0 {
+		want := make(map[string]bool, len(paths))
+		for _, p := range paths {
+			want[kb.RelPath(p)] = true
+		}
+		filter = func(e VectorEntry) bool {
+			p, _ := e.Metadata["path"].(string)
+			return want[p]
+		}
+	}
+	if vec == nil {
+		return kb.store.SearchText(text, topK, filter)
+	}
+	return kb.store.SearchWhere(vec, topK, filter)
+}
+
+// Covers reports whether path has been ingested into this collection.
+func (kb *KnowledgeBase) Covers(path string) bool {
+	kb.mu.Lock()
+	defer kb.mu.Unlock()
+	_, ok := kb.manifest.Files[kb.relPathLocked(path)]
+	return ok
+}
+
+// RelPath maps p to the collection-relative, slash-separated form used in
+// chunk metadata.
+func (kb *KnowledgeBase) RelPath(p string) string {
+	kb.mu.Lock()
+	defer kb.mu.Unlock()
+	return kb.relPathLocked(p)
+}
+
+func (kb *KnowledgeBase) relPathLocked(p string) string {
+	if kb.manifest.Root != "" {
+		abs := p
+		if !filepath.IsAbs(abs) {
+			if a, err := filepath.Abs(p); err == nil {
+				abs = a
+			}
+		}
+		if rel, err := filepath.Rel(kb.manifest.Root, abs); err == nil && !strings.HasPrefix(rel, "..") {
+			return filepath.ToSlash(rel)
+		}
+	}
+	return filepath.ToSlash(p)
+}
+
+func (kb *KnowledgeBase) saveManifest() error {
+	data, err := json.MarshalIndent(kb.manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(kb.path, data, 0644); err != nil {
+		return err
+	}
+	kb.stamp = statManifest(kb.path)
+	return nil
+}
+
+func excluded(rel string, globs []string) bool {
+	for _, g := range globs {
+		if MatchGlob(g, rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// FormatChunk renders a retrieved chunk with its location for prompts.
+func FormatChunk(e VectorEntry) string {
+	path, _ := e.Metadata["path"].(string)
+	symbol, _ := e.Metadata["symbol"].(string)
+	start, _ := e.Metadata["start_line"].(float64)
+	end, _ := e.Metadata["end_line"].(float64)
+	if s, ok := e.Metadata["start_line"].(int); ok {
+		start = float64(s)
+	}
+	if s, ok := e.Metadata["end_line"].(int); ok {
+		end = float64(s)
+	}
+	header := fmt.Sprintf("- %s:%d-%d", path, int(start), int(end))
+	if symbol != "" {
+		header += " (" + symbol + ")"
+	}
+	return header + ":\n```\n" + e.Content + "\n```"
+}
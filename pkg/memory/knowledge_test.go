@@ -0,0 +1,169 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// modelEmbedder stands in for a model-backed embedder such as vibeauracle.
+type modelEmbedder struct {
+	down bool
+}
+
+func (e *modelEmbedder) EmbedderKind() string { return "model" }
+
+func (e *modelEmbedder) Embed(content string) ([]float64, error) {
+	if e.down {
+		return nil, errors.New("model offline")
+	}
+	return HashEmbedder{Dims: 64}.Embed(content)
+}
+
+func writeTree(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for rel, content := range files {
+		p := filepath.Join(root, rel)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestKnowledgeIngestIsIncremental(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{
+		".gitignore":      "dist/\n",
+		"main.go":         "package main\n\nfunc main() {}\n",
+		"README.md":       "# Demo\n\nDeploys the service.\n",
+		"dist/bundle.js":  "ignored()",
+		"image.png":       "\x89PNG",
+		"notes/todo.txt":  "write more tests\n",
+		".git/HEAD":       "ref: refs/heads/main\n",
+		"vendor/x/x.go":   "package x\n",
+		"notes/draft.txt": "half a thought\n",
+	})
+
+	kb, err := OpenKnowledge("demo", HashEmbedder{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ingest := func() IngestStats {
+		t.Helper()
+		stats, err := kb.Ingest(context.Background(), root, IngestOptions{Exclude: []string{"vendor/**"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return stats
+	}
+
+	steps := []struct {
+		name   string
+		change func()
+		want   IngestStats
+	}{
+		{"first run", func() {}, IngestStats{Scanned: 4, Indexed: 4, Chunks: 4}},
+		{"nothing changed", func() {}, IngestStats{Scanned: 4, Unchanged: 4}},
+		{"one file edited", func() {
+			writeTree(t, root, map[string]string{"main.go": "package main\n\nfunc main() {}\n\nfunc helper() {}\n"})
+		}, IngestStats{Scanned: 4, Indexed: 1, Unchanged: 3, Chunks: 2}},
+		{"one file removed", func() {
+			_ = os.Remove(filepath.Join(root, "notes", "draft.txt"))
+		}, IngestStats{Scanned: 3, Unchanged: 3, Removed: 1}},
+	}
+	for _, s := range steps {
+		s.change()
+		if got := ingest(); got != s.want {
+			t.Fatalf("%s: stats = %+v, want %+v", s.name, got, s.want)
+		}
+	}
+
+	m := kb.Manifest()
+	if m.Embedder != "hash" || m.Dims != defaultHashDims {
+		t.Errorf("manifest records embedder %q with %d dims", m.Embedder, m.Dims)
+	}
+	if got := len(m.Files["main.go"].Chunks); got != 2 {
+		t.Errorf("main.go has %d chunks, want 2", got)
+	}
+	if kb.store.Len() != 4 {
+		t.Errorf("store holds %d chunks, want 4", kb.store.Len())
+	}
+	if res := kb.Query("deploys the service", 1); len(res) != 1 || res[0].Metadata["path"] != "README.md" {
+		t.Errorf("query found %+v", res)
+	}
+}
+
+func TestKnowledgeQueryNeedsTheIngestBackend(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{
+		"deploy.md": "# Deploy\n\nShip the release to production.\n",
+		"test.md":   "# Test\n\nRun the unit tests.\n",
+	})
+
+	model := &modelEmbedder{}
+	kb, err := OpenKnowledge("model", NewFallbackEmbedder(model))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := kb.Ingest(context.Background(), root, IngestOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if m := kb.Manifest(); m.Embedder != "model" || m.Dims != 64 {
+		t.Fatalf("manifest records embedder %q with %d dims", m.Embedder, m.Dims)
+	}
+
+	for _, down := range []bool{false, true} {
+		model.down = down
+		res := kb.Query("ship release", 2)
+		if len(res) == 0 || res[0].Metadata["path"] != "deploy.md" {
+			t.Errorf("model down=%v: query found %+v", down, res)
+		}
+		if down && len(res) != 1 {
+			t.Errorf("lexical matching must skip chunks sharing no word, got %d", len(res))
+		}
+	}
+
+	// Re-ingesting with another backend re-embeds every file.
+	stats, err := kb.Ingest(context.Background(), root, IngestOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Indexed != 2 || kb.Manifest().Embedder != "hash" {
+		t.Fatalf("expected a full re-index by the hash embedder, got %+v (%s)", stats, kb.Manifest().Embedder)
+	}
+}
+
+func TestKnowledgeStale(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{"a.txt": "alpha\n"})
+
+	kb, err := OpenKnowledge("stale", HashEmbedder{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := kb.Ingest(context.Background(), root, IngestOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if kb.Stale() {
+		t.Fatal("a collection is not stale after its own ingest")
+	}
+
+	other, _ := OpenKnowledge("stale", HashEmbedder{})
+	writeTree(t, root, map[string]string{"b.txt": "beta\n"})
+	time.Sleep(10 * time.Millisecond)
+	if _, err := other.Ingest(context.Background(), root, IngestOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if !kb.Stale() {
+		t.Fatal("an ingest by another process must make the cached collection stale")
+	}
+}
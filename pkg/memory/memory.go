@@ -49,6 +49,17 @@ func (s *Store) Get(key string) (interface{}, bool) {
 	return val, ok
 }
 
+// All returns a snapshot of every stored key.
+func (s *Store) All() map[string]interface{} {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := make(map[string]interface{}, len(s.data))
+	for k, v := range s.data {
+		out[k] = v
+	}
+	return out
+}
+
 func (s *Store) Delete(key string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -0,0 +1,31 @@
+package memory
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/nathfavour/auracrab/pkg/metrics"
+)
+
+func init() {
+	metrics.NewGaugeFunc("auracrab_vector_store_chunks",
+		"Chunks indexed in each knowledge collection.", []string{"collection"},
+		func(emit metrics.Emit) {
+			for _, m := range ListKnowledge() {
+				n := 0
+				for _, f := range m.Files {
+					n += len(f.Chunks)
+				}
+				emit(float64(n), m.Collection)
+			}
+		})
+	metrics.NewGaugeFunc("auracrab_vector_store_bytes",
+		"Size on disk of each knowledge collection's vectors.", []string{"collection"},
+		func(emit metrics.Emit) {
+			for _, m := range ListKnowledge() {
+				if info, err := os.Stat(filepath.Join(knowledgeDir(), m.Collection+"_vectors.json")); err == nil {
+					emit(float64(info.Size()), m.Collection)
+				}
+			}
+		})
+}
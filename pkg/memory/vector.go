@@ -5,6 +5,7 @@ import (
 	"math"
 	"os"
 	"path/filepath"
+	"sort"
 	"sync"
 
 	"github.com/nathfavour/auracrab/pkg/config"
@@ -55,7 +56,92 @@ func (vs *VectorStore) Add(id, content string, metadata map[string]interface{},
 	return vs.save()
 }
 
+// Upsert inserts or replaces entries by ID and saves once for the whole batch.
+func (vs *VectorStore) Upsert(entries []VectorEntry) error {
+	vs.mu.Lock()
+	defer vs.mu.Unlock()
+
+	index := make(map[string]int, len(vs.entries))
+	for i, e := range vs.entries {
+		index[e.ID] = i
+	}
+	for _, e := range entries {
+		if i, ok := index[e.ID]; ok {
+			vs.entries[i] = e
+			continue
+		}
+		index[e.ID] = len(vs.entries)
+		vs.entries = append(vs.entries, e)
+	}
+	return vs.save()
+}
+
+// Remove deletes entries by ID.
+func (vs *VectorStore) Remove(ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	vs.mu.Lock()
+	defer vs.mu.Unlock()
+
+	drop := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		drop[id] = true
+	}
+	kept := vs.entries[:0]
+	for _, e := range vs.entries {
+		if !drop[e.ID] {
+			kept = append(kept, e)
+		}
+	}
+	vs.entries = kept
+	return vs.save()
+}
+
+// Len returns the number of stored entries.
+func (vs *VectorStore) Len() int {
+	vs.mu.RLock()
+	defer vs.mu.RUnlock()
+	return len(vs.entries)
+}
+
 func (vs *VectorStore) Search(queryEmbedding []float64, topK int) []VectorEntry {
+	return vs.SearchWhere(queryEmbedding, topK, nil)
+}
+
+// SearchWhere is like Search but only considers entries accepted by filter.
+func (vs *VectorStore) SearchWhere(queryEmbedding []float64, topK int, filter func(VectorEntry) bool) []VectorEntry {
+	return vs.searchBy(func(e VectorEntry) float64 {
+		return CosineSimilarity(queryEmbedding, e.Embedding)
+	}, topK, filter)
+}
+
+// SearchText ranks entries by the share of the query's words found in their
+// content, ignoring embeddings. Entries sharing no word are left out. It
+// serves collections whose embedding backend is not available.
+func (vs *VectorStore) SearchText(query string, topK int, filter func(VectorEntry) bool) []VectorEntry {
+	words := make(map[string]bool)
+	for _, tok := range Tokenize(query) {
+		words[tok] = true
+	}
+	if len(words) == 0 {
+		return []VectorEntry{}
+	}
+	return vs.searchBy(func(e VectorEntry) float64 {
+		found := make(map[string]bool)
+		for _, tok := range Tokenize(e.Content) {
+			if words[tok] {
+				found[tok] = true
+			}
+		}
+		if len(found) == 0 {
+			return math.Inf(-1)
+		}
+		return float64(len(found)) / float64(len(words))
+	}, topK, filter)
+}
+
+func (vs *VectorStore) searchBy(score func(VectorEntry) float64, topK int, filter func(VectorEntry) bool) []VectorEntry {
 	vs.mu.RLock()
 	defer vs.mu.RUnlock()
 
@@ -66,17 +152,18 @@ func (vs *VectorStore) Search(queryEmbedding []float64, topK int) []VectorEntry
 
 	results := []result{}
 	for _, entry := range vs.entries {
-		score := CosineSimilarity(queryEmbedding, entry.Embedding)
-		results = append(results, result{entry, score})
-	}
-
-	// Sort by score descending (simple insertion sort for now)
-	for i := 1; i < len(results); i++ {
-		for j := i; j > 0 && results[j].score > results[j-1].score; j-- {
-			results[j], results[j-1] = results[j-1], results[j]
+		if filter != nil && !filter(entry) {
+			continue
+		}
+		if s := score(entry); !math.IsInf(s, -1) {
+			results = append(results, result{entry, s})
 		}
 	}
 
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].score > results[j].score
+	})
+
 	final := []VectorEntry{}
 	for i := 0; i < topK && i < len(results); i++ {
 		final = append(final, results[i].entry)
@@ -0,0 +1,284 @@
+// Package metrics exposes the daemon's counters, gauges and histograms in
+// the Prometheus text format. Packages declare their metrics as package
+// variables; values that already live elsewhere, such as the number of
+// tasks by status, are read at scrape time through collector functions.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultBuckets suit durations in seconds, from fast local calls to long
+// model queries.
+var DefaultBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120, 300}
+
+// Emit reports one sample of a collector, with one value per label.
+type Emit func(value float64, labels ...string)
+
+// Registry holds metric families and writes them out.
+type Registry struct {
+	mu       sync.Mutex
+	families map[string]*family
+}
+
+// Default is the registry served on /metrics.
+var Default = NewRegistry()
+
+func NewRegistry() *Registry {
+	return &Registry{families: map[string]*family{}}
+}
+
+type family struct {
+	name    string
+	help    string
+	kind    string
+	labels  []string
+	buckets []float64
+	collect func(Emit)
+
+	mu     sync.Mutex
+	series map[string]*series
+}
+
+type series struct {
+	labels []string
+	value  float64
+	counts []uint64 // histograms: per bucket, not cumulative
+	sum    float64
+	count  uint64
+}
+
+func (r *Registry) register(f *family) *family {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.families[f.name]; ok {
+		panic("metrics: " + f.name + " registered twice")
+	}
+	f.series = map[string]*series{}
+	r.families[f.name] = f
+	return f
+}
+
+func (f *family) get(labels []string) *series {
+	if len(labels) != len(f.labels) {
+		panic(fmt.Sprintf("metrics: %s takes %d labels, got %d", f.name, len(f.labels), len(labels)))
+	}
+	key := strings.Join(labels, "\xff")
+	s, ok := f.series[key]
+	if !ok {
+		s = &series{labels: append([]string{}, labels...)}
+		if f.kind == "histogram" {
+			s.counts = make([]uint64, len(f.buckets))
+		}
+		f.series[key] = s
+	}
+	return s
+}
+
+// Counter only goes up.
+type Counter struct{ f *family }
+
+// NewCounter registers a counter with the given label names in r.
+func (r *Registry) NewCounter(name, help string, labels ...string) *Counter {
+	return &Counter{r.register(&family{name: name, help: help, kind: "counter", labels: labels})}
+}
+
+// NewCounter registers a counter in the default registry.
+func NewCounter(name, help string, labels ...string) *Counter {
+	return Default.NewCounter(name, help, labels...)
+}
+
+// Inc adds one to the series with the given label values.
+func (c *Counter) Inc(labels ...string) { c.Add(1, labels...) }
+
+// Add adds v, which must not be negative.
+func (c *Counter) Add(v float64, labels ...string) {
+	if v < 0 {
+		return
+	}
+	c.f.mu.Lock()
+	defer c.f.mu.Unlock()
+	c.f.get(labels).value += v
+}
+
+// Gauge goes up and down.
+type Gauge struct{ f *family }
+
+// NewGauge registers a gauge with the given label names in r.
+func (r *Registry) NewGauge(name, help string, labels ...string) *Gauge {
+	return &Gauge{r.register(&family{name: name, help: help, kind: "gauge", labels: labels})}
+}
+
+// NewGauge registers a gauge in the default registry.
+func NewGauge(name, help string, labels ...string) *Gauge {
+	return Default.NewGauge(name, help, labels...)
+}
+
+func (g *Gauge) Set(v float64, labels ...string) {
+	g.f.mu.Lock()
+	defer g.f.mu.Unlock()
+	g.f.get(labels).value = v
+}
+
+func (g *Gauge) Add(v float64, labels ...string) {
+	g.f.mu.Lock()
+	defer g.f.mu.Unlock()
+	g.f.get(labels).value += v
+}
+
+// Histogram counts observations in buckets.
+type Histogram struct{ f *family }
+
+// NewHistogram registers a histogram with the given upper bounds in r;
+// nil buckets mean DefaultBuckets.
+func (r *Registry) NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	if buckets == nil {
+		buckets = DefaultBuckets
+	}
+	b := append([]float64{}, buckets...)
+	sort.Float64s(b)
+	return &Histogram{r.register(&family{name: name, help: help, kind: "histogram", labels: labels, buckets: b})}
+}
+
+// NewHistogram registers a histogram in the default registry.
+func NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	return Default.NewHistogram(name, help, buckets, labels...)
+}
+
+func (h *Histogram) Observe(v float64, labels ...string) {
+	h.f.mu.Lock()
+	defer h.f.mu.Unlock()
+	s := h.f.get(labels)
+	if i := sort.SearchFloat64s(h.f.buckets, v); i < len(s.counts) {
+		s.counts[i]++
+	}
+	s.sum += v
+	s.count++
+}
+
+// NewGaugeFunc registers a gauge whose samples collect reports at scrape
+// time.
+func (r *Registry) NewGaugeFunc(name, help string, labels []string, collect func(Emit)) {
+	r.register(&family{name: name, help: help, kind: "gauge", labels: labels, collect: collect})
+}
+
+// NewGaugeFunc registers a collected gauge in the default registry.
+func NewGaugeFunc(name, help string, labels []string, collect func(Emit)) {
+	Default.NewGaugeFunc(name, help, labels, collect)
+}
+
+// NewCounterFunc registers a counter kept elsewhere and read at scrape
+// time.
+func (r *Registry) NewCounterFunc(name, help string, labels []string, collect func(Emit)) {
+	r.register(&family{name: name, help: help, kind: "counter", labels: labels, collect: collect})
+}
+
+// NewCounterFunc registers a collected counter in the default registry.
+func NewCounterFunc(name, help string, labels []string, collect func(Emit)) {
+	Default.NewCounterFunc(name, help, labels, collect)
+}
+
+// Write renders every family in the Prometheus text exposition format,
+// sorted by name.
+func (r *Registry) Write(w io.Writer) error {
+	r.mu.Lock()
+	families := make([]*family, 0, len(r.families))
+	for _, f := range r.families {
+		families = append(families, f)
+	}
+	r.mu.Unlock()
+	sort.Slice(families, func(i, j int) bool { return families[i].name < families[j].name })
+
+	bw := bufio.NewWriter(w)
+	for _, f := range families {
+		f.write(bw)
+	}
+	return bw.Flush()
+}
+
+func (f *family) write(w *bufio.Writer) {
+	var list []*series
+	if f.collect != nil {
+		// Collectors may be slow; they run without the family lock.
+		f.collect(func(v float64, labels ...string) {
+			if len(labels) == len(f.labels) {
+				list = append(list, &series{labels: labels, value: v})
+			}
+		})
+	} else {
+		f.mu.Lock()
+		for _, s := range f.series {
+			c := *s
+			c.counts = append([]uint64{}, s.counts...)
+			list = append(list, &c)
+		}
+		f.mu.Unlock()
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return strings.Join(list[i].labels, "\xff") < strings.Join(list[j].labels, "\xff")
+	})
+
+	fmt.Fprintf(w, "# HELP %s %s\n", f.name, escapeHelp(f.help))
+	fmt.Fprintf(w, "# TYPE %s %s\n", f.name, f.kind)
+	for _, s := range list {
+		if f.kind != "histogram" {
+			fmt.Fprintf(w, "%s%s %s\n", f.name, labelSet(f.labels, s.labels, "", ""), formatValue(s.value))
+			continue
+		}
+		var cum uint64
+		for i, b := range f.buckets {
+			cum += s.counts[i]
+			fmt.Fprintf(w, "%s_bucket%s %d\n", f.name, labelSet(f.labels, s.labels, "le", formatValue(b)), cum)
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", f.name, labelSet(f.labels, s.labels, "le", "+Inf"), s.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", f.name, labelSet(f.labels, s.labels, "", ""), formatValue(s.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", f.name, labelSet(f.labels, s.labels, "", ""), s.count)
+	}
+}
+
+func labelSet(names, values []string, extraName, extraValue string) string {
+	if len(names) == 0 && extraName == "" {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, n := range names {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, "%s=\"%s\"", n, labelEscaper.Replace(values[i]))
+	}
+	if extraName != "" {
+		if len(names) > 0 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, "%s=\"%s\"", extraName, extraValue)
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeHelp(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(s)
+}
+
+func formatValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
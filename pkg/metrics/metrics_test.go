@@ -0,0 +1,100 @@
+package metrics
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestWriteTextFormat(t *testing.T) {
+	r := NewRegistry()
+	c := r.NewCounter("test_messages_total", "Messages.", "platform", "direction")
+	g := r.NewGauge("test_energy", "Energy.")
+	h := r.NewHistogram("test_latency_seconds", "Latency.", []float64{1, 0.1}, "provider")
+	r.NewGaugeFunc("test_tasks", "Tasks\nby status.", []string{"status"}, func(emit Emit) {
+		emit(2, "running")
+		emit(1, "failed")
+		emit(9) // wrong label count, dropped
+	})
+
+	c.Inc("telegram", "in")
+	c.Add(2, "telegram", "in")
+	c.Inc(`we"ird\`, "out")
+	c.Add(-1, "telegram", "in")
+	g.Set(0.75)
+	h.Observe(0.05, "vibe")
+	h.Observe(0.5, "vibe")
+	h.Observe(5, "vibe")
+
+	var b strings.Builder
+	if err := r.Write(&b); err != nil {
+		t.Fatal(err)
+	}
+	want := `# HELP test_energy Energy.
+# TYPE test_energy gauge
+test_energy 0.75
+# HELP test_latency_seconds Latency.
+# TYPE test_latency_seconds histogram
+test_latency_seconds_bucket{provider="vibe",le="0.1"} 1
+test_latency_seconds_bucket{provider="vibe",le="1"} 2
+test_latency_seconds_bucket{provider="vibe",le="+Inf"} 3
+test_latency_seconds_sum{provider="vibe"} 5.55
+test_latency_seconds_count{provider="vibe"} 3
+# HELP test_messages_total Messages.
+# TYPE test_messages_total counter
+test_messages_total{platform="telegram",direction="in"} 3
+test_messages_total{platform="we\"ird\\",direction="out"} 1
+# HELP test_tasks Tasks\nby status.
+# TYPE test_tasks gauge
+test_tasks{status="failed"} 1
+test_tasks{status="running"} 2
+`
+	if got := b.String(); got != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestLoopbackOnly(t *testing.T) {
+	for _, addr := range []string{"127.0.0.1:9464", "localhost:0", "[::1]:9464"} {
+		if err := Loopback(addr); err != nil {
+			t.Errorf("%s: %v", addr, err)
+		}
+	}
+	for _, addr := range []string{":9464", "0.0.0.0:9464", "192.168.1.5:9464", "9464"} {
+		if err := Loopback(addr); err == nil {
+			t.Errorf("%s accepted", addr)
+		}
+	}
+}
+
+func TestServeMetrics(t *testing.T) {
+	NewCounter("test_served_total", "Served.").Inc()
+
+	srv, err := Listen("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- srv.Serve(ctx) }()
+
+	resp, err := http.Get("http://" + srv.Addr() + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != ContentType {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	if !strings.Contains(string(body), "test_served_total 1\n") {
+		t.Errorf("body:\n%s", body)
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
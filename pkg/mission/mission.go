@@ -7,11 +7,14 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
 	"sync"
 	"time"
 
 	"github.com/nathfavour/auracrab/internal/provider"
 	"github.com/nathfavour/auracrab/pkg/config"
+	"github.com/nathfavour/auracrab/pkg/prompts"
+	"github.com/nathfavour/auracrab/pkg/schema"
 )
 
 type Status string
@@ -30,6 +33,9 @@ type SubTask struct {
 	Status       Status   `json:"status"`
 	Dependencies []string `json:"dependencies"` // IDs of other sub-tasks
 	Result       string   `json:"result,omitempty"`
+
+	// Spec is a prebuilt workflow step; such sub-tasks run without planning.
+	Spec json.RawMessage `json:"spec,omitempty"`
 }
 
 type Mission struct {
@@ -48,6 +54,12 @@ type Mission struct {
 	// Temporal Awareness metrics
 	EstimatedTTC time.Duration `json:"estimated_ttc"` // Time To Complete
 	Progress     float64       `json:"progress"`      // 0.0 to 1.0
+
+	// Workflow missions remember their source and rendered inputs.
+	Workflow string            `json:"workflow,omitempty"`
+	Inputs   map[string]string `json:"inputs,omitempty"`
+	// Watch is the watch rule that applied the workflow, if any.
+	Watch string `json:"watch,omitempty"`
 }
 
 type Manager struct {
@@ -121,6 +133,45 @@ func (m *Mission) UpdateSubTaskStatus(id string, status Status, result string) e
 	return fmt.Errorf("sub-task %s not found", id)
 }
 
+// AbandonDependents abandons the active sub-tasks that depend, directly or
+// through others, on the failed sub-task id, and returns them.
+func (m *Mission) AbandonDependents(id string) []SubTask {
+	failed := map[string]bool{id: true}
+	var abandoned []SubTask
+	for changed := true; changed; {
+		changed = false
+		for i, t := range m.Tasks {
+			if t.Status != StatusActive {
+				continue
+			}
+			for _, dep := range t.Dependencies {
+				if failed[dep] {
+					m.Tasks[i].Status = StatusAbandoned
+					m.Tasks[i].Result = fmt.Sprintf("dependency %s failed", dep)
+					failed[t.ID] = true
+					abandoned = append(abandoned, m.Tasks[i])
+					changed = true
+					break
+				}
+			}
+		}
+	}
+	if len(abandoned) > 0 {
+		m.UpdatedAt = time.Now()
+	}
+	return abandoned
+}
+
+// Settled reports whether no sub-task is still to run or running.
+func (m *Mission) Settled() bool {
+	for _, t := range m.Tasks {
+		if t.Status == StatusActive {
+			return false
+		}
+	}
+	return true
+}
+
 func (m *Manager) CreateMission(title, desc, goal string, deadline time.Time) *Mission {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -153,6 +204,36 @@ func (m *Manager) GetActiveMission() *Mission {
 	return nil
 }
 
+// ActiveMissions returns every active mission, oldest first.
+func (m *Manager) ActiveMissions() []*Mission {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var active []*Mission
+	for _, mission := range m.missions {
+		if mission.Status == StatusActive {
+			active = append(active, mission)
+		}
+	}
+	sort.Slice(active, func(i, j int) bool { return active[i].CreatedAt.Before(active[j].CreatedAt) })
+	return active
+}
+
+// SetInputs replaces the inputs of a workflow mission.
+func (m *Manager) SetInputs(id string, inputs map[string]string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	mission, ok := m.missions[id]
+	if !ok {
+		return os.ErrNotExist
+	}
+
+	mission.Inputs = inputs
+	mission.UpdatedAt = time.Now()
+	return m.save()
+}
+
 func (m *Manager) UpdateProgress(id string, progress float64, ttc time.Duration) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -182,8 +263,8 @@ func (m *Manager) TimeRemaining(id string) (time.Duration, error) {
 }
 
 type MissionSuggestion struct {
-	Title    string    `json:"title"`
-	Goal     string    `json:"goal"`
+	Title    string    `json:"title" schema:"required"`
+	Goal     string    `json:"goal" schema:"required"`
 	Deadline time.Time `json:"deadline"`
 	Reason   string    `json:"reason"`
 }
@@ -191,27 +272,36 @@ type MissionSuggestion struct {
 func (m *Manager) ParseMission(text string, querier interface {
 	QueryWithContext(context.Context, string, string) (provider.CompletionResponse, error)
 }) (*MissionSuggestion, error) {
-	prompt := fmt.Sprintf("Analyze the following text and determine if it contains a potential project mission or hackathon goal. If it does, extract the Title, Goal, and an estimated or explicit Deadline. \n\nTEXT: %s\n\nReturn JSON only: {\"title\": \"...\", \"goal\": \"...\", \"deadline\": \"RFC3339\", \"reason\": \"why this is a mission\"}", text)
+	prompt := prompts.Text("mission.detect", "", map[string]string{"Text": text})
 
 	resp, err := querier.QueryWithContext(context.Background(), prompt, "ask")
 	if err != nil {
 		return nil, err
 	}
 
-	// Simple JSON extraction
+	// Models often wrap the object in prose or markdown fences; ask once for
+	// a corrected reply when it does not validate.
 	var suggestion MissionSuggestion
-	if err := json.Unmarshal([]byte(resp.Content), &suggestion); err != nil {
-		// Try regex if direct unmarshal fails (LLM might wrap in markdown)
-		return nil, fmt.Errorf("failed to parse mission suggestion: %v. Raw: %s", err, resp.Content)
+	err = schema.ExtractWithRepair(resp.Content, &suggestion, func(repair string) (string, error) {
+		r, err := querier.QueryWithContext(context.Background(), repair, "ask")
+		return r.Content, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse mission suggestion: %w", err)
 	}
 
 	return &suggestion, nil
 }
 
+// vars are the template variables of the mission script prompts.
+func (m *Mission) vars() map[string]string {
+	return map[string]string{"Title": m.Title, "Goal": m.Goal}
+}
+
 func (m *Mission) BootstrapRequirements(querier interface {
 	QueryWithContext(context.Context, string, string) (provider.CompletionResponse, error)
 }) error {
-	prompt := fmt.Sprintf("MISSION: %s\nGOAL: %s\n\nBased on this mission, autonomously generate a shell script to bootstrap the project environment (e.g., create directories, initialize git/go/rust, create README.md). \n\nReturn SH SCRIPT ONLY. NO MARKDOWN.", m.Title, m.Goal)
+	prompt := prompts.Text("mission.bootstrap", "", m.vars())
 
 	resp, err := querier.QueryWithContext(context.Background(), prompt, "crud")
 	if err != nil {
@@ -229,7 +319,7 @@ func (m *Mission) BootstrapRequirements(querier interface {
 func (m *Mission) PreFlightCheck(querier interface {
 	QueryWithContext(context.Context, string, string) (provider.CompletionResponse, error)
 }) (string, error) {
-	prompt := fmt.Sprintf("MISSION: %s\nGOAL: %s\n\nGenerate a shell script to perform a comprehensive pre-flight check for this mission. It should run tests, linting, and verify the build. \n\nReturn SH SCRIPT ONLY. NO MARKDOWN.", m.Title, m.Goal)
+	prompt := prompts.Text("mission.preflight", "", m.vars())
 
 	resp, err := querier.QueryWithContext(context.Background(), prompt, "crud")
 	if err != nil {
@@ -247,7 +337,7 @@ func (m *Mission) PreFlightCheck(querier interface {
 func (m *Mission) FinalizeMission(querier interface {
 	QueryWithContext(context.Context, string, string) (provider.CompletionResponse, error)
 }) (string, error) {
-	prompt := fmt.Sprintf("MISSION: %s\nGOAL: %s\n\nGenerate a shell script to finalize and deliver this mission. This might involve committing and pushing to git, uploading artifacts, or sending a completion signal. \n\nReturn SH SCRIPT ONLY. NO MARKDOWN.", m.Title, m.Goal)
+	prompt := prompts.Text("mission.finalize", "", m.vars())
 
 	resp, err := querier.QueryWithContext(context.Background(), prompt, "crud")
 	if err != nil {
@@ -262,6 +352,13 @@ func (m *Mission) FinalizeMission(querier interface {
 	return string(out), err
 }
 
+// Save persists missions after in-place edits such as added sub-tasks.
+func (m *Manager) Save() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.save()
+}
+
 func (m *Manager) CompleteMission(id string) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -277,6 +374,22 @@ func (m *Manager) CompleteMission(id string) error {
 	return m.save()
 }
 
+// FailMission ends a mission whose sub-tasks failed, so that the next
+// mission can become active.
+func (m *Manager) FailMission(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	mission, ok := m.missions[id]
+	if !ok {
+		return os.ErrNotExist
+	}
+
+	mission.Status = StatusFailed
+	mission.UpdatedAt = time.Now()
+	return m.save()
+}
+
 func (m *Manager) load() error {
 	data, err := os.ReadFile(m.path)
 	if err != nil {
@@ -0,0 +1,208 @@
+package prompts
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/nathfavour/auracrab/pkg/config"
+	"github.com/nathfavour/auracrab/pkg/logging"
+)
+
+var logger = logging.For("prompts")
+
+//go:embed templates/*.tmpl
+var defaults embed.FS
+
+// Var is a variable a template may use.
+type Var struct {
+	Name        string
+	Description string
+	Sample      string
+}
+
+// Spec describes a named prompt template and the variables it receives.
+type Spec struct {
+	Name        string
+	Description string
+	Vars        []Var
+}
+
+var specs = []Spec{
+	{Name: "planning", Description: "Breaks a task goal into a step graph.", Vars: []Var{
+		{"Goal", "The task goal.", "Upgrade the project to Go 1.24 and fix the build"},
+		{"Blueprint", "The reply format the step parser expects.", `Return JSON only: {"steps": [...]}`},
+	}},
+	{Name: "planning.repair", Description: "Asks for a step graph that failed validation to be fixed.", Vars: []Var{
+		{"Goal", "The task goal.", "Upgrade the project to Go 1.24 and fix the build"},
+		{"Previous", "The rejected planning reply.", `{"steps": [{"id": "test", "depends_on": ["build"]}]}`},
+		{"Problems", "Why the graph was rejected.", `plan[0] depends on unknown step "build"`},
+		{"Blueprint", "The reply format the step parser expects.", `Return JSON only: {"steps": [...]}`},
+	}},
+	{Name: "execution", Description: "Runs one step of a planned task.", Vars: []Var{
+		{"Goal", "The task goal.", "Upgrade the project to Go 1.24 and fix the build"},
+		{"Step", "The current step description.", "Run go test ./... and collect failures"},
+		{"Dependencies", "Results of the steps this one depends on, one per line.", "- Bump go.mod: done"},
+		{"Iteration", "Iteration number of a loop-until step.", "1"},
+		{"Previous", "Result of the previous iteration when the exit check failed.", ""},
+	}},
+	{Name: "reflex", Description: "Idle self-reflection emitted by the reflex cell."},
+	{Name: "vibe", Description: "Wraps a direct prompt sent to vibeauracle.", Vars: []Var{
+		{"WorkingDir", "The agent's working directory.", "/home/crab/project"},
+		{"Snapshot", "Listing of project files.", "go.mod\nmain.go"},
+		{"Prompt", "The user prompt.", "Why does the build fail?"},
+	}},
+	{Name: "crab.delegate", Description: "Task handed to a crab agent with '@crab ...'.", Vars: []Var{
+		{"Name", "The crab's name.", "Reviewer"},
+		{"Instructions", "The crab's standing instructions.", "Review diffs for bugs."},
+		{"Task", "The user's task.", "Review the last commit"},
+	}},
+	{Name: "mission.detect", Description: "Detects a mission in free text.", Vars: []Var{
+		{"Text", "The text to analyze.", "We need to ship the payments demo by Friday for the hackathon."},
+	}},
+	{Name: "mission.bootstrap", Description: "Generates the mission bootstrap script.", Vars: missionVars},
+	{Name: "mission.preflight", Description: "Generates the mission pre-flight check script.", Vars: missionVars},
+	{Name: "mission.finalize", Description: "Generates the mission delivery script.", Vars: missionVars},
+	{Name: "social.post", Description: "Generates a scheduled social post.", Vars: []Var{
+		{"Topic", "The prompt configured with 'social set-prompt', if any.", ""},
+	}},
+	{Name: "social.command", Description: "Handles a bot command routed through the agent.", Vars: []Var{
+		{"Command", "The command text.", "/deploy now"},
+	}},
+}
+
+var missionVars = []Var{
+	{"Title", "The mission title.", "Payments demo"},
+	{"Goal", "The mission goal.", "Ship a working payments demo"},
+}
+
+// Specs returns every known template, sorted by name.
+func Specs() []Spec {
+	out := append([]Spec{}, specs...)
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// Lookup returns the spec of a named template.
+func Lookup(name string) (Spec, bool) {
+	for _, s := range specs {
+		if s.Name == name {
+			return s, true
+		}
+	}
+	return Spec{}, false
+}
+
+// Sample returns the sample values of a template's variables.
+func (s Spec) Sample() map[string]string {
+	data := make(map[string]string, len(s.Vars))
+	for _, v := range s.Vars {
+		data[v.Name] = v.Sample
+	}
+	return data
+}
+
+// Dir is where agent-wide overrides live, one <name>.tmpl per template.
+func Dir() string {
+	return filepath.Join(config.DataDir(), "prompts")
+}
+
+// CrabDir holds the overrides of a single crab.
+func CrabDir(crab string) string {
+	return filepath.Join(Dir(), "crabs", crab)
+}
+
+// Source returns the template text in effect for a crab (or "" for the
+// agent itself) and where it came from: a crab override, an agent override
+// or the embedded default.
+func Source(name, crab string) (text, origin string, err error) {
+	if _, ok := Lookup(name); !ok {
+		return "", "", fmt.Errorf("unknown prompt template '%s'", name)
+	}
+	var paths []string
+	if crab != "" {
+		paths = append(paths, filepath.Join(CrabDir(crab), name+".tmpl"))
+	}
+	paths = append(paths, filepath.Join(Dir(), name+".tmpl"))
+	for _, p := range paths {
+		if data, err := os.ReadFile(p); err == nil {
+			return string(data), p, nil
+		}
+	}
+	return Default(name), "default", nil
+}
+
+// Default returns the embedded template text.
+func Default(name string) string {
+	data, _ := defaults.ReadFile("templates/" + name + ".tmpl")
+	return string(data)
+}
+
+// Render renders the template in effect for crab with data, which must hold
+// exactly the variables the template declares.
+func Render(name, crab string, data map[string]string) (string, error) {
+	text, origin, err := Source(name, crab)
+	if err != nil {
+		return "", err
+	}
+	out, err := execute(name, text, data)
+	if err != nil {
+		return "", fmt.Errorf("prompt template '%s' (%s): %w", name, origin, err)
+	}
+	return out, nil
+}
+
+// Text renders like Render, but a broken override falls back to the
+// embedded default so a bad edit cannot stall the agent.
+func Text(name, crab string, data map[string]string) string {
+	out, err := Render(name, crab, data)
+	if err == nil {
+		return out
+	}
+	logger.Warn("using the default template", "err", err)
+	out, err = execute(name, Default(name), data)
+	if err != nil {
+		logger.Error("default template failed", "template", name, "err", err)
+	}
+	return out
+}
+
+// RenderText renders text as the named template, e.g. to try an edit
+// before installing it. Syntax errors and undeclared variables are reported.
+func RenderText(name, text string, data map[string]string) (string, error) {
+	if _, ok := Lookup(name); !ok {
+		return "", fmt.Errorf("unknown prompt template '%s'", name)
+	}
+	return execute(name, text, data)
+}
+
+func execute(name, text string, data map[string]string) (string, error) {
+	spec, _ := Lookup(name)
+	declared := map[string]bool{}
+	for _, v := range spec.Vars {
+		declared[v.Name] = true
+		if _, ok := data[v.Name]; !ok {
+			return "", fmt.Errorf("missing variable %q", v.Name)
+		}
+	}
+	for k := range data {
+		if !declared[k] {
+			return "", fmt.Errorf("undeclared variable %q", k)
+		}
+	}
+
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
@@ -0,0 +1,64 @@
+package prompts
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDefaultsRenderWithSamples(t *testing.T) {
+	files, _ := fs.Glob(defaults, "templates/*.tmpl")
+	if len(files) != len(specs) {
+		t.Errorf("%d embedded templates for %d specs", len(files), len(specs))
+	}
+	for _, s := range specs {
+		if Default(s.Name) == "" {
+			t.Errorf("%s: no embedded default", s.Name)
+			continue
+		}
+		if out, err := RenderText(s.Name, Default(s.Name), s.Sample()); err != nil || out == "" {
+			t.Errorf("%s: %q, %v", s.Name, out, err)
+		}
+	}
+}
+
+func TestOverrides(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	data := map[string]string{"Command": "/ping"}
+
+	if out := Text("social.command", "", data); !strings.HasPrefix(out, "USER COMMAND: /ping") {
+		t.Errorf("default not used: %q", out)
+	}
+
+	write := func(dir, text string) {
+		_ = os.MkdirAll(dir, 0755)
+		if err := os.WriteFile(filepath.Join(dir, "social.command.tmpl"), []byte(text), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(Dir(), "agent {{ .Command }}")
+	write(CrabDir("rev"), "crab {{ .Command }}")
+
+	if out := Text("social.command", "", data); out != "agent /ping" {
+		t.Errorf("agent override not used: %q", out)
+	}
+	if out := Text("social.command", "rev", data); out != "crab /ping" {
+		t.Errorf("crab override not used: %q", out)
+	}
+
+	// An override using an undeclared variable is rejected and the default
+	// takes over.
+	write(Dir(), "{{ .Nope }}")
+	if _, err := Render("social.command", "", data); err == nil {
+		t.Error("expected undeclared variable error")
+	}
+	if out := Text("social.command", "", data); !strings.HasPrefix(out, "USER COMMAND") {
+		t.Errorf("expected fallback to default, got %q", out)
+	}
+
+	if _, err := Render("social.command", "", map[string]string{}); err == nil {
+		t.Error("expected missing variable error")
+	}
+}
@@ -0,0 +1,303 @@
+package rules
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/nathfavour/auracrab/pkg/logging"
+	"github.com/nathfavour/auracrab/pkg/spine"
+	"go.yaml.in/yaml/v3"
+)
+
+var logger = logging.For("rules")
+
+// Rule reacts to bus events, e.g.
+//
+//	rules:
+//	  - name: degraded-health
+//	    when: health.degraded
+//	    for: 5m
+//	    then:
+//	      - start_task: "Investigate degraded health: {{ .reason }}"
+//	      - notify: "Health degraded for 5m ({{ .reason }})"
+//
+// With "for", the first matching event arms a timer and the rule fires when
+// it expires, unless an event matching "clear" arrives first. Holds and
+// cooldowns are kept per subject (the payload's subsystem or probe), so one
+// subsystem recovering does not clear another; a clear event without a
+// subject clears them all.
+type Rule struct {
+	Name     string            `yaml:"name"`
+	When     string            `yaml:"when"`
+	Match    map[string]string `yaml:"match,omitempty"` // payload field -> substring
+	For      string            `yaml:"for,omitempty"`
+	Clear    string            `yaml:"clear,omitempty"`
+	Cooldown string            `yaml:"cooldown,omitempty"`
+	Then     []Action          `yaml:"then"`
+
+	hold, cooldown time.Duration
+	templates      []*template.Template
+}
+
+// Action is one effect of a fired rule. Texts are templates over the
+// triggering event's payload fields plus .topic.
+type Action struct {
+	StartTask string `yaml:"start_task,omitempty"`
+	Notify    string `yaml:"notify,omitempty"`
+}
+
+func (a Action) text() string {
+	if a.StartTask != "" {
+		return a.StartTask
+	}
+	return a.Notify
+}
+
+// Actions carries out rule effects; the butler implements it.
+type Actions interface {
+	StartTask(goal string) error
+	Notify(text string)
+}
+
+// defaultClear pairs topics whose condition ends with another topic.
+var defaultClear = map[string]string{
+	spine.TopicHealthDegraded: spine.TopicHealthRecovered,
+}
+
+// Load reads a rules file. A missing file means no rules.
+func Load(path string) ([]*Rule, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
+
+// Parse decodes and validates a rules document.
+func Parse(data []byte) ([]*Rule, error) {
+	var doc struct {
+		Rules []*Rule `yaml:"rules"`
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("invalid rules: %w", err)
+	}
+
+	var errs []string
+	for i, r := range doc.Rules {
+		where := fmt.Sprintf("rules[%d] (%s)", i, r.Name)
+		if r.Name == "" || r.When == "" || len(r.Then) == 0 {
+			errs = append(errs, where+": name, when and then are required")
+		}
+		var err error
+		if r.For != "" {
+			if r.hold, err = time.ParseDuration(r.For); err != nil {
+				errs = append(errs, fmt.Sprintf("%s: invalid for %q", where, r.For))
+			}
+		}
+		if r.Cooldown != "" {
+			if r.cooldown, err = time.ParseDuration(r.Cooldown); err != nil {
+				errs = append(errs, fmt.Sprintf("%s: invalid cooldown %q", where, r.Cooldown))
+			}
+		}
+		if r.Clear == "" {
+			r.Clear = defaultClear[r.When]
+		}
+		for j, a := range r.Then {
+			if (a.StartTask == "") == (a.Notify == "") {
+				errs = append(errs, fmt.Sprintf("%s: then[%d] needs exactly one of start_task or notify", where, j))
+				continue
+			}
+			t, err := template.New(r.Name).Parse(a.text())
+			if err != nil {
+				errs = append(errs, fmt.Sprintf("%s: then[%d]: %v", where, j, err))
+				continue
+			}
+			r.templates = append(r.templates, t)
+		}
+	}
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("invalid rules:\n  - %s", strings.Join(errs, "\n  - "))
+	}
+	return doc.Rules, nil
+}
+
+// Engine evaluates rules against the bus.
+type Engine struct {
+	bus     *spine.Bus
+	actions Actions
+	rules   []*Rule
+
+	mu    sync.Mutex
+	armed map[holdKey]*time.Timer
+	fired map[holdKey]time.Time
+	subs  []*spine.Subscription
+}
+
+// holdKey identifies the condition a rule holds or cools down for.
+type holdKey struct {
+	rule, subject string
+}
+
+// subjectFields are the payload fields naming what an event is about.
+var subjectFields = []string{"subsystem", "probe"}
+
+func subject(data map[string]interface{}) string {
+	for _, f := range subjectFields {
+		if s, ok := data[f].(string); ok && s != "" {
+			return s
+		}
+	}
+	return ""
+}
+
+func NewEngine(bus *spine.Bus, actions Actions, rules []*Rule) *Engine {
+	return &Engine{
+		bus:     bus,
+		actions: actions,
+		rules:   rules,
+		armed:   make(map[holdKey]*time.Timer),
+		fired:   make(map[holdKey]time.Time),
+	}
+}
+
+// Start subscribes every rule to its topics. A rule's trigger and clear
+// events share one subscription so they are handled in publish order.
+func (e *Engine) Start() {
+	for _, r := range e.rules {
+		r := r
+		pattern := r.When
+		if r.Clear != "" {
+			pattern = coverPattern(r.When, r.Clear)
+		}
+		e.subs = append(e.subs, e.bus.Handle(pattern, func(ev spine.Event) {
+			if r.Clear != "" && spine.Match(r.Clear, ev.Type) {
+				e.disarm(r, ev)
+			}
+			if spine.Match(r.When, ev.Type) {
+				e.trigger(r, ev)
+			}
+		}))
+	}
+}
+
+// coverPattern returns a pattern matching every topic either pattern does,
+// e.g. "health.>" for health.degraded and health.recovered.
+func coverPattern(a, b string) string {
+	if a == b {
+		return a
+	}
+	p, q := strings.Split(a, "."), strings.Split(b, ".")
+	var common []string
+	for i := 0; i < len(p)-1 && i < len(q)-1 && p[i] == q[i] && p[i] != ">"; i++ {
+		common = append(common, p[i])
+	}
+	return strings.Join(append(common, ">"), ".")
+}
+
+// Stop unsubscribes and cancels armed timers.
+func (e *Engine) Stop() {
+	for _, s := range e.subs {
+		s.Close()
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for k, t := range e.armed {
+		t.Stop()
+		delete(e.armed, k)
+	}
+}
+
+func (e *Engine) trigger(r *Rule, ev spine.Event) {
+	data := eventData(ev)
+	for field, want := range r.Match {
+		if !strings.Contains(strings.ToLower(fmt.Sprint(data[field])), strings.ToLower(want)) {
+			return
+		}
+	}
+
+	key := holdKey{r.Name, subject(data)}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if r.cooldown > 0 && time.Since(e.fired[key]) < r.cooldown {
+		return
+	}
+	if r.hold == 0 {
+		e.fired[key] = time.Now()
+		go e.fire(r, data)
+		return
+	}
+	if _, ok := e.armed[key]; ok {
+		return
+	}
+	var timer *time.Timer
+	timer = time.AfterFunc(r.hold, func() {
+		e.mu.Lock()
+		if e.armed[key] != timer {
+			e.mu.Unlock()
+			return
+		}
+		delete(e.armed, key)
+		e.fired[key] = time.Now()
+		e.mu.Unlock()
+		e.fire(r, data)
+	})
+	e.armed[key] = timer
+}
+
+// disarm cancels the rule's hold for the clear event's subject, or every
+// hold of the rule when the event names none.
+func (e *Engine) disarm(r *Rule, ev spine.Event) {
+	subj := subject(eventData(ev))
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for k, t := range e.armed {
+		if k.rule == r.Name && (subj == "" || k.subject == subj) {
+			t.Stop()
+			delete(e.armed, k)
+		}
+	}
+}
+
+func (e *Engine) fire(r *Rule, data map[string]interface{}) {
+	logger.Info("rule fired", "rule", r.Name, "topic", data["topic"])
+	for i, a := range r.Then {
+		var buf bytes.Buffer
+		if err := r.templates[i].Execute(&buf, data); err != nil {
+			logger.Warn("rule action failed", "rule", r.Name, "action", i, "err", err)
+			continue
+		}
+		switch {
+		case a.StartTask != "":
+			if err := e.actions.StartTask(buf.String()); err != nil {
+				logger.Error("rule could not start task", "rule", r.Name, "err", err)
+			}
+		case a.Notify != "":
+			e.actions.Notify(buf.String())
+		}
+	}
+}
+
+// eventData flattens the payload's JSON fields for matching and templates.
+func eventData(ev spine.Event) map[string]interface{} {
+	data := map[string]interface{}{}
+	if raw, err := json.Marshal(ev.Payload); err == nil {
+		_ = json.Unmarshal(raw, &data)
+	}
+	if data == nil { // a nil payload decodes as null
+		data = map[string]interface{}{}
+	}
+	data["topic"] = ev.Type
+	data["time"] = ev.Timestamp
+	return data
+}
@@ -0,0 +1,139 @@
+package rules
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nathfavour/auracrab/pkg/spine"
+)
+
+type recorder struct {
+	mu    sync.Mutex
+	tasks []string
+	notes []string
+}
+
+func (r *recorder) StartTask(goal string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.tasks = append(r.tasks, goal)
+	return nil
+}
+
+func (r *recorder) Notify(text string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.notes = append(r.notes, text)
+}
+
+func (r *recorder) counts() (int, int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.tasks), len(r.notes)
+}
+
+func TestParseErrors(t *testing.T) {
+	_, err := Parse([]byte(`
+rules:
+  - name: bad
+    when: health.degraded
+    for: soon
+    then:
+      - start_task: x
+        notify: y
+`))
+	if err == nil {
+		t.Fatal("expected validation errors")
+	}
+}
+
+func TestHoldAndClear(t *testing.T) {
+	list, err := Parse([]byte(`
+rules:
+  - name: degraded
+    when: health.degraded
+    match: {subsystem: vibe}
+    for: 50ms
+    then:
+      - start_task: "Investigate {{ .reason }}"
+      - notify: "degraded: {{ .reason }}"
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if list[0].Clear != spine.TopicHealthRecovered {
+		t.Errorf("expected default clear topic, got %q", list[0].Clear)
+	}
+
+	bus := spine.NewBus()
+	rec := &recorder{}
+	engine := NewEngine(bus, rec, list)
+	engine.Start()
+	defer engine.Stop()
+
+	degraded := spine.Event{Type: spine.TopicHealthDegraded, Payload: spine.HealthChange{Subsystem: "vibeauracle", Reason: "errors"}}
+
+	// Recovered before the hold expires: nothing fires.
+	bus.Publish(degraded)
+	time.Sleep(10 * time.Millisecond)
+	bus.Publish(spine.Event{Type: spine.TopicHealthRecovered})
+	time.Sleep(100 * time.Millisecond)
+	if n, _ := rec.counts(); n != 0 {
+		t.Fatalf("rule fired despite recovery")
+	}
+
+	// Another subsystem does not match.
+	bus.Publish(spine.Event{Type: spine.TopicHealthDegraded, Payload: spine.HealthChange{Subsystem: "disk"}})
+	bus.Publish(degraded)
+	time.Sleep(150 * time.Millisecond)
+	tasks, notes := rec.counts()
+	if tasks != 1 || notes != 1 || rec.tasks[0] != "Investigate errors" {
+		t.Errorf("expected one task and one notification, got %v %v", rec.tasks, rec.notes)
+	}
+}
+
+func TestHoldPerSubject(t *testing.T) {
+	list, err := Parse([]byte(`
+rules:
+  - name: degraded
+    when: health.degraded
+    for: 50ms
+    then:
+      - notify: "degraded: {{ .subsystem }}"
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	bus := spine.NewBus()
+	rec := &recorder{}
+	engine := NewEngine(bus, rec, list)
+	engine.Start()
+	defer engine.Stop()
+
+	bus.Publish(spine.Event{Type: spine.TopicHealthDegraded, Payload: spine.HealthChange{Subsystem: "disk"}})
+	bus.Publish(spine.Event{Type: spine.TopicHealthDegraded, Payload: spine.HealthChange{Subsystem: "vault"}})
+	// disk recovers in order after degrading; vault keeps failing.
+	bus.Publish(spine.Event{Type: spine.TopicHealthRecovered, Payload: spine.HealthChange{Subsystem: "disk"}})
+	time.Sleep(150 * time.Millisecond)
+
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	if len(rec.notes) != 1 || rec.notes[0] != "degraded: vault" {
+		t.Fatalf("expected only vault to fire, got %v", rec.notes)
+	}
+}
+
+func TestCoverPattern(t *testing.T) {
+	cases := []struct{ a, b, want string }{
+		{"health.degraded", "health.recovered", "health.>"},
+		{"task.failed", "task.failed", "task.failed"},
+		{"task.failed", "mission.completed", ">"},
+		{"health", "health.recovered", ">"},
+	}
+	for _, c := range cases {
+		if got := coverPattern(c.a, c.b); got != c.want {
+			t.Errorf("coverPattern(%q, %q) = %q, want %q", c.a, c.b, got, c.want)
+		}
+	}
+}
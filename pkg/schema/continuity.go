@@ -0,0 +1,399 @@
+package schema
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// ContinuityVersion is the current TaskContinuity schema version. Older
+// documents are upgraded through the registered migrations when decoded.
+const ContinuityVersion = "3.0"
+
+// Legal continuity statuses for tasks and steps. Steps may additionally be
+// "waiting" for human input or "skipped" by a condition.
+var (
+	ContinuityStatuses = []string{"pending", "running", "completed", "failed"}
+	StepStatuses       = []string{"pending", "running", "waiting", "completed", "skipped", "failed"}
+)
+
+// DefaultMaxIterations bounds loop-until steps without an explicit limit.
+const DefaultMaxIterations = 3
+
+// NewTaskContinuity returns an empty continuity at the current version.
+func NewTaskContinuity(taskID, goal, status string) *TaskContinuity {
+	return &TaskContinuity{
+		Version: ContinuityVersion,
+		TaskID:  taskID,
+		Goal:    goal,
+		Status:  status,
+		Plan:    []ContinuityStep{},
+		Meta:    ContinuityMeta{MaxRetries: 3},
+	}
+}
+
+// ContinuityMigration upgrades a raw continuity document in place from one
+// version to the next.
+type ContinuityMigration struct {
+	From, To string
+	Upgrade  func(doc map[string]interface{}) error
+}
+
+var (
+	migrationsMu sync.RWMutex
+	migrations   = map[string]ContinuityMigration{}
+)
+
+// RegisterContinuityMigration adds an upgrade step. Each source version may
+// have exactly one migration.
+func RegisterContinuityMigration(m ContinuityMigration) {
+	migrationsMu.Lock()
+	defer migrationsMu.Unlock()
+	if _, dup := migrations[m.From]; dup {
+		panic(fmt.Sprintf("continuity migration from %q registered twice", m.From))
+	}
+	migrations[m.From] = m
+}
+
+func init() {
+	// Documents written before versioning have no version field.
+	RegisterContinuityMigration(ContinuityMigration{From: "", To: "1.0", Upgrade: func(doc map[string]interface{}) error {
+		if doc["plan"] == nil {
+			doc["plan"] = []interface{}{}
+		}
+		return nil
+	}})
+
+	// 2.0 tracks attempts per step and requires unique step IDs, legal
+	// statuses and an in-range cursor.
+	RegisterContinuityMigration(ContinuityMigration{From: "1.0", To: "2.0", Upgrade: func(doc map[string]interface{}) error {
+		taskID, _ := doc["task_id"].(string)
+		plan, _ := doc["plan"].([]interface{})
+		seen := map[string]bool{}
+		for i, raw := range plan {
+			step, ok := raw.(map[string]interface{})
+			if !ok {
+				return fmt.Errorf("plan[%d] is not an object", i)
+			}
+			id, _ := step["id"].(string)
+			if id == "" || seen[id] {
+				id = fmt.Sprintf("%s_s%d", taskID, i)
+			}
+			seen[id] = true
+			step["id"] = id
+
+			switch status, _ := step["status"].(string); strings.ToLower(status) {
+			case "done", "success", "completed":
+				step["status"] = "completed"
+			case "error", "failed":
+				step["status"] = "failed"
+			case "running", "in_progress":
+				step["status"] = "running"
+			default:
+				step["status"] = "pending"
+			}
+		}
+
+		cursor, _ := doc["cursor"].(float64)
+		if cursor < 0 {
+			cursor = 0
+		}
+		if int(cursor) > len(plan) {
+			cursor = float64(len(plan))
+		}
+		doc["cursor"] = cursor
+
+		meta, _ := doc["meta"].(map[string]interface{})
+		if meta == nil {
+			meta = map[string]interface{}{}
+			doc["meta"] = meta
+		}
+		if mr, _ := meta["max_retries"].(float64); mr <= 0 {
+			meta["max_retries"] = 3
+		}
+		return nil
+	}})
+
+	// 3.0 replaces the linear cursor walk with a step graph; older plans
+	// become a chain so they keep their order.
+	RegisterContinuityMigration(ContinuityMigration{From: "2.0", To: "3.0", Upgrade: func(doc map[string]interface{}) error {
+		plan, _ := doc["plan"].([]interface{})
+		prev := ""
+		for _, raw := range plan {
+			step, ok := raw.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if _, has := step["depends_on"]; !has && prev != "" {
+				step["depends_on"] = []interface{}{prev}
+			}
+			prev, _ = step["id"].(string)
+		}
+		return nil
+	}})
+}
+
+// MigrateContinuity upgrades a raw continuity document to ContinuityVersion.
+func MigrateContinuity(doc map[string]interface{}) error {
+	migrationsMu.RLock()
+	defer migrationsMu.RUnlock()
+
+	for steps := 0; ; steps++ {
+		version, _ := doc["version"].(string)
+		if version == ContinuityVersion {
+			return nil
+		}
+		m, ok := migrations[version]
+		if !ok || steps > len(migrations) {
+			return fmt.Errorf("no continuity migration from version %q to %s", version, ContinuityVersion)
+		}
+		if err := m.Upgrade(doc); err != nil {
+			return fmt.Errorf("continuity migration %q -> %s: %w", m.From, m.To, err)
+		}
+		doc["version"] = m.To
+	}
+}
+
+// UnmarshalJSON decodes a continuity document of any known version,
+// migrating it to ContinuityVersion first.
+func (tc *TaskContinuity) UnmarshalJSON(data []byte) error {
+	var doc map[string]interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	if err := MigrateContinuity(doc); err != nil {
+		return err
+	}
+	upgraded, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	// The alias drops this method so decoding does not recurse.
+	type plain TaskContinuity
+	return json.Unmarshal(upgraded, (*plain)(tc))
+}
+
+// Validate checks the continuity invariants and reports every violation.
+func (tc *TaskContinuity) Validate() error {
+	var errs []string
+	if tc.Version != ContinuityVersion {
+		errs = append(errs, fmt.Sprintf("version %q, expected %s", tc.Version, ContinuityVersion))
+	}
+	if tc.TaskID == "" || tc.Goal == "" {
+		errs = append(errs, "missing task_id or goal")
+	}
+	if tc.Status != "" && !legal(ContinuityStatuses, tc.Status) {
+		errs = append(errs, fmt.Sprintf("illegal status %q", tc.Status))
+	}
+	if tc.Cursor < 0 || tc.Cursor > len(tc.Plan) {
+		errs = append(errs, fmt.Sprintf("cursor %d out of range [0, %d]", tc.Cursor, len(tc.Plan)))
+	}
+
+	seen := map[string]bool{}
+	for i, step := range tc.Plan {
+		switch {
+		case step.ID == "":
+			errs = append(errs, fmt.Sprintf("plan[%d] has no id", i))
+		case seen[step.ID]:
+			errs = append(errs, fmt.Sprintf("plan[%d] duplicates step id %q", i, step.ID))
+		}
+		seen[step.ID] = true
+		if !legal(StepStatuses, step.Status) {
+			errs = append(errs, fmt.Sprintf("plan[%d] has illegal status %q", i, step.Status))
+		}
+		if step.Weight < 0 {
+			errs = append(errs, fmt.Sprintf("plan[%d] has negative weight", i))
+		}
+	}
+	for i, step := range tc.Plan {
+		for _, dep := range step.DependsOn {
+			if !seen[dep] {
+				errs = append(errs, fmt.Sprintf("plan[%d] depends on unknown step %q", i, dep))
+			}
+		}
+		if step.Condition != nil && step.Condition.Step != "" && !seen[step.Condition.Step] {
+			errs = append(errs, fmt.Sprintf("plan[%d] condition refers to unknown step %q", i, step.Condition.Step))
+		}
+	}
+	if cycle := tc.cycle(); cycle != "" {
+		errs = append(errs, "dependency cycle through step "+cycle)
+	}
+
+	if len(errs) > 0 {
+		return errors.New("invalid continuity: " + strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+func legal(allowed []string, s string) bool {
+	for _, a := range allowed {
+		if a == s {
+			return true
+		}
+	}
+	return false
+}
+
+// StepGuard matches a step result by substring and/or regular expression.
+// Step names the step whose result is checked; empty means the step itself.
+type StepGuard struct {
+	Step     string `json:"step,omitempty"`
+	Contains string `json:"contains,omitempty"`
+	Matches  string `json:"matches,omitempty"`
+	Negate   bool   `json:"negate,omitempty"`
+}
+
+// Match reports whether result satisfies the guard. An invalid regular
+// expression never matches.
+func (g *StepGuard) Match(result string) bool {
+	ok := true
+	if g.Contains != "" {
+		ok = strings.Contains(strings.ToLower(result), strings.ToLower(g.Contains))
+	}
+	if ok && g.Matches != "" {
+		re, err := regexp.Compile(g.Matches)
+		ok = err == nil && re.MatchString(result)
+	}
+	return ok != g.Negate
+}
+
+// Step returns the step with the given ID.
+func (tc *TaskContinuity) Step(id string) *ContinuityStep {
+	for i := range tc.Plan {
+		if tc.Plan[i].ID == id {
+			return &tc.Plan[i]
+		}
+	}
+	return nil
+}
+
+func stepDone(status string) bool {
+	return status == "completed" || status == "skipped"
+}
+
+func stepTerminal(status string) bool {
+	return stepDone(status) || status == "failed"
+}
+
+// Ready returns the pending steps whose dependencies have all completed or
+// been skipped, in plan order.
+func (tc *TaskContinuity) Ready() []*ContinuityStep {
+	var ready []*ContinuityStep
+	for i := range tc.Plan {
+		step := &tc.Plan[i]
+		if step.Status != "pending" {
+			continue
+		}
+		ok := true
+		for _, dep := range step.DependsOn {
+			if d := tc.Step(dep); d == nil || !stepDone(d.Status) {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			ready = append(ready, step)
+		}
+	}
+	return ready
+}
+
+// Finished reports whether no step can make further progress: every step is
+// terminal, or the remaining ones wait on a failed dependency.
+func (tc *TaskContinuity) Finished() bool {
+	if len(tc.Ready()) > 0 {
+		return false
+	}
+	for _, step := range tc.Plan {
+		if step.Status == "running" || step.Status == "waiting" {
+			return false
+		}
+	}
+	return true
+}
+
+// Failed reports whether any step failed.
+func (tc *TaskContinuity) Failed() bool {
+	for _, step := range tc.Plan {
+		if step.Status == "failed" {
+			return true
+		}
+	}
+	return false
+}
+
+// Progress is the weighted share of completed or skipped steps. Steps
+// without a weight count as 1.
+func (tc *TaskContinuity) Progress() float64 {
+	var done, total float64
+	for _, step := range tc.Plan {
+		w := float64(step.Weight)
+		if w <= 0 {
+			w = 1
+		}
+		total += w
+		if stepDone(step.Status) {
+			done += w
+		}
+	}
+	if total == 0 {
+		return 0
+	}
+	return done / total
+}
+
+// Sync refreshes the derived Cursor (first unfinished step) and
+// RemainingSteps from the step graph.
+func (tc *TaskContinuity) Sync() {
+	tc.Cursor = len(tc.Plan)
+	tc.RemainingSteps = tc.RemainingSteps[:0]
+	for i, step := range tc.Plan {
+		if stepTerminal(step.Status) {
+			continue
+		}
+		if i < tc.Cursor {
+			tc.Cursor = i
+		}
+		tc.RemainingSteps = append(tc.RemainingSteps, step.Description)
+	}
+}
+
+// cycle returns the ID of a step on a dependency cycle, or "".
+func (tc *TaskContinuity) cycle() string {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := map[string]int{}
+	deps := map[string][]string{}
+	for _, step := range tc.Plan {
+		deps[step.ID] = step.DependsOn
+	}
+	var visit func(id string) string
+	visit = func(id string) string {
+		switch state[id] {
+		case visiting:
+			return id
+		case visited:
+			return ""
+		}
+		state[id] = visiting
+		for _, d := range deps[id] {
+			if c := visit(d); c != "" {
+				return c
+			}
+		}
+		state[id] = visited
+		return ""
+	}
+	for _, step := range tc.Plan {
+		if c := visit(step.ID); c != "" {
+			return c
+		}
+	}
+	return ""
+}
@@ -0,0 +1,81 @@
+package schema
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestContinuityMigratesUnversioned(t *testing.T) {
+	old := `{"task_id": "task_1", "goal": "ship", "cursor": 9,
+		"plan": [{"description": "a", "status": "done"}, {"id": "x", "description": "b", "status": "weird"}]}`
+
+	var tc TaskContinuity
+	if err := json.Unmarshal([]byte(old), &tc); err != nil {
+		t.Fatal(err)
+	}
+	if tc.Version != ContinuityVersion {
+		t.Errorf("expected version %s, got %s", ContinuityVersion, tc.Version)
+	}
+	if tc.Plan[0].ID != "task_1_s0" || tc.Plan[0].Status != "completed" || tc.Plan[1].Status != "pending" {
+		t.Errorf("steps not upgraded: %+v", tc.Plan)
+	}
+	if tc.Cursor != 2 || tc.Meta.MaxRetries != 3 {
+		t.Errorf("cursor or meta not upgraded: %d %+v", tc.Cursor, tc.Meta)
+	}
+	if err := tc.Validate(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestContinuityRejectsUnknownVersion(t *testing.T) {
+	var tc TaskContinuity
+	if err := json.Unmarshal([]byte(`{"version": "9.0", "task_id": "t", "goal": "g"}`), &tc); err == nil {
+		t.Error("expected an error for a future version")
+	}
+}
+
+func TestContinuityValidate(t *testing.T) {
+	tc := NewTaskContinuity("t", "g", "running")
+	tc.Plan = []ContinuityStep{{ID: "a", Status: "pending"}, {ID: "a", Status: "sleeping"}}
+	tc.Cursor = 5
+
+	err := tc.Validate()
+	if err == nil {
+		t.Fatal("expected validation errors")
+	}
+	for _, want := range []string{"cursor 5", "duplicates step id", "illegal status"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("missing %q in %v", want, err)
+		}
+	}
+}
+
+func TestContinuityStepGraph(t *testing.T) {
+	tc := NewTaskContinuity("t", "g", "running")
+	tc.Plan = []ContinuityStep{
+		{ID: "a", Status: "completed", Weight: 3, Result: "tests FAILED"},
+		{ID: "b", Status: "pending", DependsOn: []string{"a"}, Condition: &StepGuard{Step: "a", Contains: "failed"}},
+		{ID: "c", Status: "pending", DependsOn: []string{"a"}},
+		{ID: "d", Status: "pending", DependsOn: []string{"b", "c"}},
+	}
+	if err := tc.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	ready := tc.Ready()
+	if len(ready) != 2 || ready[0].ID != "b" || ready[1].ID != "c" {
+		t.Fatalf("expected b and c to be ready in parallel, got %v", ready)
+	}
+	if !ready[0].Condition.Match(tc.Step("a").Result) {
+		t.Error("expected the condition to match case-insensitively")
+	}
+	if got := tc.Progress(); got != 0.5 {
+		t.Errorf("expected weighted progress 0.5, got %v", got)
+	}
+
+	tc.Plan[3].DependsOn = []string{"d"}
+	if err := tc.Validate(); err == nil || !strings.Contains(err.Error(), "cycle") {
+		t.Errorf("expected a cycle error, got %v", err)
+	}
+}
@@ -0,0 +1,202 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/hjson/hjson-go/v4"
+)
+
+// ValidationError reports why no JSON object in a model reply matched the
+// expected schema.
+type ValidationError struct {
+	Errors []string
+	Raw    string
+}
+
+func (e *ValidationError) Error() string {
+	raw := e.Raw
+	if len(raw) > 200 {
+		raw = raw[:200] + "..."
+	}
+	return fmt.Sprintf("invalid response: %s. Raw: %s", strings.Join(e.Errors, "; "), raw)
+}
+
+// ExtractJSON returns the candidate JSON objects found in text: the contents
+// of markdown code fences first, then every balanced top-level {...} block.
+// Braces inside string literals are ignored.
+func ExtractJSON(text string) []string {
+	var candidates []string
+	seen := map[string]bool{}
+	add := func(c string) {
+		c = strings.TrimSpace(c)
+		if c != "" && !seen[c] {
+			seen[c] = true
+			candidates = append(candidates, c)
+		}
+	}
+
+	for _, body := range codeFences(text) {
+		if strings.HasPrefix(strings.TrimSpace(body), "{") {
+			add(body)
+		}
+	}
+	for _, obj := range balancedObjects(text) {
+		add(obj)
+	}
+	return candidates
+}
+
+// codeFences returns the bodies of ``` fenced blocks, dropping the info string.
+func codeFences(text string) []string {
+	var bodies []string
+	for {
+		start := strings.Index(text, "```")
+		if start < 0 {
+			return bodies
+		}
+		rest := text[start+3:]
+		nl := strings.IndexByte(rest, '\n')
+		if nl < 0 {
+			return bodies
+		}
+		rest = rest[nl+1:]
+		end := strings.Index(rest, "```")
+		if end < 0 {
+			// Unterminated fence: take the remainder, models often get cut off.
+			return append(bodies, rest)
+		}
+		bodies = append(bodies, rest[:end])
+		text = rest[end+3:]
+	}
+}
+
+// balancedObjects scans for top-level objects, tracking string literals and
+// escapes so that braces in values do not end an object early. A brace that
+// is never closed, such as a stray "{" in prose, is skipped and the scan
+// resumes after it.
+func balancedObjects(text string) []string {
+	var objects []string
+	depth, start := 0, -1
+	inString, escaped := false, false
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				inString = false
+			}
+			continue
+		}
+		switch c {
+		case '"':
+			if depth > 0 {
+				inString = true
+			}
+		case '{':
+			if depth == 0 {
+				start = i
+			}
+			depth++
+		case '}':
+			if depth > 0 {
+				depth--
+				if depth == 0 {
+					objects = append(objects, text[start:i+1])
+				}
+			}
+		}
+	}
+	if depth > 0 {
+		return append(objects, balancedObjects(text[start+1:])...)
+	}
+	return objects
+}
+
+// decodeLenient parses strict JSON, falling back to HJSON which tolerates
+// trailing commas, comments and unquoted keys.
+func decodeLenient(candidate string) (interface{}, error) {
+	var v interface{}
+	if err := json.Unmarshal([]byte(candidate), &v); err == nil {
+		return v, nil
+	}
+	if err := hjson.Unmarshal([]byte(candidate), &v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// Extract finds the JSON object in a model reply that validates against the
+// schema of out and decodes it into out. Later candidates win, since models
+// usually put the final answer last.
+func Extract(text string, out interface{}) error {
+	candidates := ExtractJSON(text)
+	if len(candidates) == 0 {
+		return &ValidationError{Errors: []string{"no JSON object found"}, Raw: text}
+	}
+
+	s := SchemaFor(out)
+	var best []string
+	for i := len(candidates) - 1; i >= 0; i-- {
+		v, err := decodeLenient(candidates[i])
+		if err != nil {
+			if best == nil {
+				best = []string{"malformed JSON: " + err.Error()}
+			}
+			continue
+		}
+		if errs := s.Validate(v); len(errs) > 0 {
+			if best == nil || strings.HasPrefix(best[0], "malformed JSON") || len(errs) < len(best) {
+				best = errs
+			}
+			continue
+		}
+		// Re-encode the generic value so lenient HJSON input decodes too.
+		data, _ := json.Marshal(v)
+		if err := json.Unmarshal(data, out); err != nil {
+			best = []string{err.Error()}
+			continue
+		}
+		return nil
+	}
+	return &ValidationError{Errors: best, Raw: text}
+}
+
+// RepairFunc sends a follow-up prompt to the model and returns its reply.
+type RepairFunc func(prompt string) (string, error)
+
+// ExtractWithRepair behaves like Extract, but when the reply does not
+// validate it asks the model once to fix it, listing the validation errors
+// and the expected schema.
+func ExtractWithRepair(text string, out interface{}, repair RepairFunc) error {
+	err := Extract(text, out)
+	if err == nil || repair == nil {
+		return err
+	}
+	verr, ok := err.(*ValidationError)
+	if !ok {
+		return err
+	}
+
+	reply, rerr := repair(RepairPrompt(text, verr.Errors, SchemaFor(out)))
+	if rerr != nil {
+		return fmt.Errorf("%w (repair query failed: %v)", err, rerr)
+	}
+	return Extract(reply, out)
+}
+
+// RepairPrompt asks the model to correct a reply that failed validation.
+func RepairPrompt(reply string, errs []string, s *JSONSchema) string {
+	if len(reply) > 4000 {
+		reply = reply[len(reply)-4000:]
+	}
+	return fmt.Sprintf(
+		"RESPONSE_REPAIR: Your previous reply could not be used.\nERRORS:\n- %s\n\nJSON_SCHEMA:\n%s\n\nPREVIOUS_REPLY:\n%s\n\nReturn ONLY the corrected JSON object. No markdown, no commentary.",
+		strings.Join(errs, "\n- "), s, reply,
+	)
+}
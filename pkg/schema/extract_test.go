@@ -0,0 +1,108 @@
+package schema
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseResponse_NestedObjects(t *testing.T) {
+	reply := "Sure! Here is the plan:\n```json\n" + `{
+  "intent": "fix",
+  "strategy": "patch the handler",
+  "actions": [{"tool": "system", "parameters": {"cmd": "go vet", "env": {"GOFLAGS": "-mod=mod"}}, "assurance_score": 0.8}],
+  "cooldown_ms": 0,
+  "update_sub_task": {"id": "t1", "status": "completed", "result": "done }"}
+}` + "\n```\nLet me know."
+
+	resp, err := ParseResponse(reply)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.UpdateSubTask == nil || resp.UpdateSubTask.Result != "done }" {
+		t.Errorf("nested update_sub_task lost: %+v", resp.UpdateSubTask)
+	}
+	env, _ := resp.Actions[0].Parameters["env"].(map[string]interface{})
+	if env["GOFLAGS"] != "-mod=mod" {
+		t.Errorf("nested parameters lost: %+v", resp.Actions[0].Parameters)
+	}
+}
+
+func TestParseResponse_ValidationErrors(t *testing.T) {
+	_, err := ParseResponse(`{"strategy": "x", "actions": [{"tool": "system", "assurance_score": 3}]}`)
+	verr, ok := err.(*ValidationError)
+	if !ok {
+		t.Fatalf("expected a ValidationError, got %v", err)
+	}
+	joined := strings.Join(verr.Errors, "\n")
+	if !strings.Contains(joined, `"intent"`) || !strings.Contains(joined, "assurance_score") {
+		t.Errorf("unexpected errors: %v", verr.Errors)
+	}
+}
+
+func TestBalancedObjectsSkipsStrayBrace(t *testing.T) {
+	got := balancedObjects(`use {x to mean the input, then: {"a":1} and {"b":{"c":2}}`)
+	if len(got) != 2 || got[0] != `{"a":1}` || got[1] != `{"b":{"c":2}}` {
+		t.Fatalf("objects = %q", got)
+	}
+}
+
+func TestExtractWithRepair(t *testing.T) {
+	calls := 0
+	var resp ResponsePacket
+	err := ExtractWithRepair("I think we should fix it.", &resp, func(prompt string) (string, error) {
+		calls++
+		if !strings.Contains(prompt, "no JSON object found") {
+			t.Errorf("repair prompt lacks the errors: %s", prompt)
+		}
+		return `{"intent": "fix", "actions": [],}`, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != 1 || resp.Intent != "fix" {
+		t.Errorf("expected one repair yielding intent 'fix', got %d calls and %+v", calls, resp)
+	}
+}
+
+func FuzzParseResponse(f *testing.F) {
+	seeds := []string{
+		`{"intent": "a"}`,
+		"```json\n{\"intent\": \"a\", \"actions\": [{\"tool\": \"t\"}]}\n```",
+		"```\n{\"intent\": ",
+		`{"intent": "a", "strategy": "{ \" }"}`,
+		`{{{}}}`,
+		`}{`,
+		`{"intent": "x", "cooldown_ms": "soon"}`,
+		`text {"intent": 1} more {"intent": "ok"}`,
+		`{intent: a, actions: [ ], }`,
+		"\x00{\"\\",
+	}
+	for _, s := range seeds {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, reply string) {
+		resp, err := ParseResponse(reply)
+		if err != nil {
+			return
+		}
+		if resp.Intent == "" && !strings.Contains(reply, "intent") {
+			t.Errorf("accepted a packet without intent: %q", reply)
+		}
+		if resp.Cooldown < 0 {
+			t.Errorf("accepted a negative cooldown: %q", reply)
+		}
+	})
+}
+
+func FuzzExtractJSON(f *testing.F) {
+	f.Add(`prefix {"a": {"b": "}"}} suffix`)
+	f.Add("```json\n{}\n```")
+	f.Add(`{"a": "\"}"}`)
+	f.Fuzz(func(t *testing.T, text string) {
+		for _, c := range ExtractJSON(text) {
+			if !strings.Contains(text, c) {
+				t.Errorf("candidate %q is not part of the input", c)
+			}
+		}
+	})
+}
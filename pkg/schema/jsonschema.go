@@ -0,0 +1,236 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// JSONSchema is the subset of JSON Schema needed to describe and validate
+// model replies. It is generated from Go types with SchemaFor.
+type JSONSchema struct {
+	Type                 string                 `json:"type,omitempty"`
+	Format               string                 `json:"format,omitempty"`
+	Properties           map[string]*JSONSchema `json:"properties,omitempty"`
+	Required             []string               `json:"required,omitempty"`
+	Items                *JSONSchema            `json:"items,omitempty"`
+	AdditionalProperties *JSONSchema            `json:"additionalProperties,omitempty"`
+	Minimum              *float64               `json:"minimum,omitempty"`
+	Maximum              *float64               `json:"maximum,omitempty"`
+	Enum                 []interface{}          `json:"enum,omitempty"`
+	Nullable             bool                   `json:"-"`
+}
+
+func (s *JSONSchema) String() string {
+	data, _ := json.Marshal(s)
+	return string(data)
+}
+
+var schemaCache sync.Map // reflect.Type -> *JSONSchema
+
+// SchemaFor generates a JSON Schema for v's type. Struct fields follow their
+// json tags; a `schema` tag adds constraints, e.g. `schema:"required"` or
+// `schema:"min=0,max=1"`.
+func SchemaFor(v interface{}) *JSONSchema {
+	t := reflect.TypeOf(v)
+	if cached, ok := schemaCache.Load(t); ok {
+		return cached.(*JSONSchema)
+	}
+	s := schemaForType(t)
+	schemaCache.Store(t, s)
+	return s
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func schemaForType(t reflect.Type) *JSONSchema {
+	nullable := false
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+		nullable = true
+	}
+	if t == timeType {
+		return &JSONSchema{Type: "string", Format: "date-time", Nullable: nullable}
+	}
+
+	s := &JSONSchema{Nullable: nullable}
+	switch t.Kind() {
+	case reflect.Bool:
+		s.Type = "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		s.Type = "integer"
+	case reflect.Float32, reflect.Float64:
+		s.Type = "number"
+	case reflect.String:
+		s.Type = "string"
+	case reflect.Slice, reflect.Array:
+		s.Type = "array"
+		s.Items = schemaForType(t.Elem())
+		// nil slices marshal as null
+		s.Nullable = true
+	case reflect.Map:
+		s.Type = "object"
+		s.AdditionalProperties = schemaForType(t.Elem())
+		s.Nullable = true
+	case reflect.Interface:
+		// Any JSON value, including null.
+		s.Nullable = true
+	case reflect.Struct:
+		s.Type = "object"
+		s.Properties = map[string]*JSONSchema{}
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if !f.IsExported() {
+				continue
+			}
+			name := f.Name
+			if tag := f.Tag.Get("json"); tag != "" {
+				if tag == "-" {
+					continue
+				}
+				if n := strings.Split(tag, ",")[0]; n != "" {
+					name = n
+				}
+			}
+			prop := schemaForType(f.Type)
+			for _, c := range strings.Split(f.Tag.Get("schema"), ",") {
+				key, val, _ := strings.Cut(strings.TrimSpace(c), "=")
+				switch key {
+				case "required":
+					s.Required = append(s.Required, name)
+				case "min":
+					if n, err := strconv.ParseFloat(val, 64); err == nil {
+						prop.Minimum = &n
+					}
+				case "max":
+					if n, err := strconv.ParseFloat(val, 64); err == nil {
+						prop.Maximum = &n
+					}
+				}
+			}
+			s.Properties[name] = prop
+		}
+	}
+	return s
+}
+
+// Validate checks a decoded JSON value (as produced by json.Unmarshal into
+// interface{}) against the schema and returns every violation found.
+func (s *JSONSchema) Validate(v interface{}) []string {
+	var errs []string
+	s.validate("$", v, &errs)
+	return errs
+}
+
+func (s *JSONSchema) validate(path string, v interface{}, errs *[]string) {
+	if v == nil {
+		if !s.Nullable {
+			*errs = append(*errs, fmt.Sprintf("%s: must not be null (expected %s)", path, s.Type))
+		}
+		return
+	}
+
+	fail := func(format string, args ...interface{}) {
+		*errs = append(*errs, path+": "+fmt.Sprintf(format, args...))
+	}
+
+	if len(s.Enum) > 0 {
+		found := false
+		for _, e := range s.Enum {
+			if fmt.Sprint(e) == fmt.Sprint(v) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			fail("must be one of %v, got %v", s.Enum, v)
+		}
+	}
+
+	switch s.Type {
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			fail("expected boolean, got %s", jsonKind(v))
+		}
+	case "integer", "number":
+		n, ok := v.(float64)
+		if !ok {
+			fail("expected %s, got %s", s.Type, jsonKind(v))
+			return
+		}
+		if s.Type == "integer" && n != float64(int64(n)) {
+			fail("expected integer, got %v", n)
+		}
+		if s.Minimum != nil && n < *s.Minimum {
+			fail("must be >= %v, got %v", *s.Minimum, n)
+		}
+		if s.Maximum != nil && n > *s.Maximum {
+			fail("must be <= %v, got %v", *s.Maximum, n)
+		}
+	case "string":
+		str, ok := v.(string)
+		if !ok {
+			fail("expected string, got %s", jsonKind(v))
+			return
+		}
+		if s.Format == "date-time" {
+			if _, err := time.Parse(time.RFC3339, str); err != nil {
+				fail("expected an RFC3339 date-time, got %q", str)
+			}
+		}
+	case "array":
+		list, ok := v.([]interface{})
+		if !ok {
+			fail("expected array, got %s", jsonKind(v))
+			return
+		}
+		for i, item := range list {
+			s.Items.validate(fmt.Sprintf("%s[%d]", path, i), item, errs)
+		}
+	case "object":
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			fail("expected object, got %s", jsonKind(v))
+			return
+		}
+		for _, r := range s.Required {
+			if _, ok := obj[r]; !ok {
+				fail("missing required field %q", r)
+			}
+		}
+		keys := make([]string, 0, len(obj))
+		for k := range obj {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if prop, ok := s.Properties[k]; ok {
+				prop.validate(path+"."+k, obj[k], errs)
+			} else if s.AdditionalProperties != nil {
+				s.AdditionalProperties.validate(path+"."+k, obj[k], errs)
+			}
+		}
+	}
+}
+
+func jsonKind(v interface{}) string {
+	switch v.(type) {
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return "null"
+}
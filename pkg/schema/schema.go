@@ -3,7 +3,7 @@ package schema
 import (
 	"encoding/json"
 	"fmt"
-	"regexp"
+	"strings"
 
 	"github.com/hjson/hjson-go/v4"
 )
@@ -31,6 +31,20 @@ type ContinuityStep struct {
 	Status      string `json:"status"`
 	Result      string `json:"result,omitempty"`
 	Weight      int    `json:"weight,omitempty"`
+	Attempts    int    `json:"attempts,omitempty"`
+	StartedAt   int64  `json:"started_at,omitempty"`
+
+	// Step graph: a step runs once every step it depends on has completed
+	// (or was skipped), so independent branches run in parallel.
+	DependsOn []string `json:"depends_on,omitempty"`
+	// Condition skips the step unless a prior step's result matches.
+	Condition *StepGuard `json:"condition,omitempty"`
+	// Until re-runs the step until its result matches, at most MaxIterations.
+	Until         *StepGuard `json:"until,omitempty"`
+	MaxIterations int        `json:"max_iterations,omitempty"`
+	Iterations    int        `json:"iterations,omitempty"`
+	// HumanInput steps ask Description in chat and wait for the reply.
+	HumanInput bool `json:"human_input,omitempty"`
 }
 
 type ContinuityMemory struct {
@@ -44,13 +58,11 @@ type ContinuityMeta struct {
 	MaxRetries   int     `json:"max_retries"`
 	EnergyBudget float64 `json:"energy_budget"`
 	LastError    string  `json:"last_error,omitempty"`
-}
-
-func (tc *TaskContinuity) Validate() error {
-	if tc.TaskID == "" || tc.Goal == "" {
-		return fmt.Errorf("invalid continuity: missing task_id or goal")
-	}
-	return nil
+	// TokenBudget caps the prompt and response tokens of the task, and
+	// EnergyBurned / TokensUsed are what it has spent so far.
+	TokenBudget  int     `json:"token_budget,omitempty"`
+	EnergyBurned float64 `json:"energy_burned,omitempty"`
+	TokensUsed   int     `json:"tokens_used,omitempty"`
 }
 
 // Existing Prompt Schema ---
@@ -101,22 +113,39 @@ type ToolManifest struct {
 
 type PromptPacket struct {
 	Mode      string          `json:"mode"` // "analytical" or "casual"
+	Request   string          `json:"request"`
+	Signature string          `json:"signature,omitempty"`
 	Project   ProjectTopology `json:"project"`
+	// Focus holds the contents of the files in focus and Knowledge the
+	// recalled repository chunks, as in the living prompt.
+	Focus     []string        `json:"focus,omitempty"`
+	Knowledge []string        `json:"knowledge,omitempty"`
 	System    SystemTelemetry `json:"system"`
 	Memory    MemoryContext   `json:"memory"`
 	Tools     []ToolManifest  `json:"tools"`
 	Blueprint string          `json:"response_blueprint"`
 }
 
-// ToHjson converts the prompt packet to HJSON string
+// Wire formats for prompts sent to providers.
+const (
+	FormatText  = "text" // The metabolizer's sectioned living prompt
+	FormatHJSON = "hjson"
+	FormatJSON  = "json"
+)
+
+// ResponseBlueprint tells the model how to shape its ResponsePacket reply.
+const ResponseBlueprint = `Reply with a single JSON object: {"intent": string, "strategy": string, "actions": [{"tool": string, "parameters": object, "assurance_score": 0.0-1.0}], "casual_message": string, "cooldown_ms": int, "self_correction": string, "mission_progress": 0.0-1.0, "estimated_ttc": string, "finalize": bool, "new_sub_tasks": [{"title": string, "description": string, "dependencies": [string]}], "update_sub_task": {"id": string, "status": string, "result": string}}. Omit fields that do not apply.`
+
+// ToHjson converts the prompt packet to HJSON string, keeping the field
+// order of the Go types so the request stays near the top.
 func (p *PromptPacket) ToHjson() (string, error) {
 	data, err := json.Marshal(p)
 	if err != nil {
 		return "", err
 	}
 
-	var hjsonObj interface{}
-	err = hjson.Unmarshal(data, &hjsonObj)
+	hjsonObj := hjson.NewOrderedMap()
+	err = hjson.Unmarshal(data, hjsonObj)
 	if err != nil {
 		return "", err
 	}
@@ -125,24 +154,42 @@ func (p *PromptPacket) ToHjson() (string, error) {
 	return string(hjsonString), err
 }
 
+// ToJSON converts the prompt packet to indented JSON.
+func (p *PromptPacket) ToJSON() (string, error) {
+	data, err := json.MarshalIndent(p, "", "  ")
+	return string(data), err
+}
+
+// Encode serialises the packet in the given wire format.
+func (p *PromptPacket) Encode(format string) (string, error) {
+	switch format {
+	case FormatHJSON:
+		return p.ToHjson()
+	case FormatJSON:
+		return p.ToJSON()
+	default:
+		return "", fmt.Errorf("unsupported prompt packet format: %s", format)
+	}
+}
+
 // --- Response Schema ---
 
 type Action struct {
-	Tool           string                 `json:"tool"`
+	Tool           string                 `json:"tool" schema:"required"`
 	Parameters     map[string]interface{} `json:"parameters"`
-	AssuranceScore float64                `json:"assurance_score"` // 0.0 - 1.0
+	AssuranceScore float64                `json:"assurance_score" schema:"min=0,max=1"` // 0.0 - 1.0
 }
 
 type ResponsePacket struct {
-	Intent         string   `json:"intent"`
+	Intent         string   `json:"intent" schema:"required"`
 	Strategy       string   `json:"strategy"`
 	Actions        []Action `json:"actions"`
 	CasualMessage  string   `json:"casual_message,omitempty"` // For the "Taunting Friend" vibe
-	Cooldown       int      `json:"cooldown_ms"`
+	Cooldown       int      `json:"cooldown_ms" schema:"min=0"`
 	SelfCorrection string   `json:"self_correction,omitempty"`
 
 	// Autonomous mission management
-	MissionProgress *float64       `json:"mission_progress,omitempty"`
+	MissionProgress *float64       `json:"mission_progress,omitempty" schema:"min=0,max=1"`
 	EstimatedTTC    *string        `json:"estimated_ttc,omitempty"` // e.g. "2h45m"
 	Finalize        bool           `json:"finalize,omitempty"`
 	NewSubTasks     []NewSubTask   `json:"new_sub_tasks,omitempty"`
@@ -150,36 +197,42 @@ type ResponsePacket struct {
 }
 
 type NewSubTask struct {
-	Title        string   `json:"title"`
+	Title        string   `json:"title" schema:"required"`
 	Description  string   `json:"description"`
 	Dependencies []string `json:"dependencies"`
 }
 
 type UpdateSubTask struct {
-	ID     string `json:"id"`
-	Status string `json:"status"`
+	ID     string `json:"id" schema:"required"`
+	Status string `json:"status" schema:"required"`
 	Result string `json:"result,omitempty"`
 }
 
-func ParseResponse(data string) (*ResponsePacket, error) {
-	// Use non-greedy match to find individual JSON objects
-	re := regexp.MustCompile(`(?s)\{.*?\}`)
-	matches := re.FindAllString(data, -1)
-
-	if len(matches) == 0 {
-		return nil, fmt.Errorf("no JSON found in response: %s", data)
+// Text renders the human-facing part of a response: the casual message or
+// strategy, followed by the planned actions.
+func (r *ResponsePacket) Text() string {
+	var b strings.Builder
+	switch {
+	case r.CasualMessage != "":
+		b.WriteString(r.CasualMessage)
+	case r.Strategy != "":
+		b.WriteString(r.Strategy)
+	default:
+		b.WriteString(r.Intent)
 	}
-
-	// Try matches from last to first (latest is usually best)
-	var lastErr error
-	for i := len(matches) - 1; i >= 0; i-- {
-		var resp ResponsePacket
-		if err := json.Unmarshal([]byte(matches[i]), &resp); err == nil {
-			return &resp, nil
-		} else {
-			lastErr = err
-		}
+	for _, a := range r.Actions {
+		params, _ := json.Marshal(a.Parameters)
+		fmt.Fprintf(&b, "\n- %s %s", a.Tool, params)
 	}
+	return b.String()
+}
 
-	return nil, fmt.Errorf("failed to parse any JSON block: %v. Raw: %s", lastErr, data)
+// ParseResponse extracts the ResponsePacket from a model reply, tolerating
+// surrounding prose and markdown code fences.
+func ParseResponse(data string) (*ResponsePacket, error) {
+	var resp ResponsePacket
+	if err := Extract(data, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
 }
@@ -0,0 +1,71 @@
+package schema
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite golden files")
+
+func goldenPacket() *PromptPacket {
+	return &PromptPacket{
+		Mode:      "analytical",
+		Request:   "TASK_EXECUTION: Goal: 'fix the build'. Current Step: 'run go vet'.",
+		Signature: "PULSE_SIGNATURE [#2]:\nGoal: fix the build",
+		Project: ProjectTopology{
+			Files:            []string{"pkg/core/butler.go", "go.mod"},
+			ModifiedRecently: []string{"pkg/core/butler.go"},
+			Dependencies:     []string{"github.com/spf13/cobra@v1.8.0"},
+			Deltas:           " pkg/core/butler.go | 2 +-",
+		},
+		System: SystemTelemetry{OS: "linux", CPUUsage: 12.5, MemoryUsage: 40, EnergyLevel: 0.9},
+		Memory: MemoryContext{
+			RecentActions: []string{"Evaluated task"},
+			LastFailures:  []string{"go vet: unreachable code"},
+			EgoState:      "Neutral",
+			Mission: &MissionInfo{
+				Title:         "Ship v1",
+				Goal:          "Release",
+				TimeRemaining: "2h0m0s",
+				Progress:      0.4,
+				TTC:           "1h0m0s",
+				SubTasks:      []SubTaskInfo{{ID: "t1", Title: "Tests", Status: "active", Dependencies: []string{}}},
+			},
+			Facts: map[string]string{"owner": "nath"},
+		},
+		Tools:     []ToolManifest{{Name: "system", Description: "System checks", Parameters: `{"type":"object"}`}},
+		Blueprint: ResponseBlueprint,
+	}
+}
+
+func TestPromptPacketGolden(t *testing.T) {
+	for _, format := range []string{FormatHJSON, FormatJSON} {
+		t.Run(format, func(t *testing.T) {
+			got, err := goldenPacket().Encode(format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			path := filepath.Join("testdata", "packet."+format)
+			if *update {
+				if err := os.WriteFile(path, []byte(got), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("missing golden file (run with -update): %v", err)
+			}
+			if got != string(want) {
+				t.Errorf("%s output changed:\n%s", format, got)
+			}
+		})
+	}
+}
+
+func TestEncodeUnknownFormat(t *testing.T) {
+	if _, err := goldenPacket().Encode("xml"); err == nil {
+		t.Error("expected an error for an unsupported format")
+	}
+}
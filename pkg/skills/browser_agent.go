@@ -175,7 +175,7 @@ func (s *BrowserAgentSkill) executeStep(ctx context.Context, bc *connect.Browser
 		if !ok {
 			return "", fmt.Errorf("skill not found: %s", step.SkillName)
 		}
-		return skill.Execute(ctx, step.SkillArgs)
+		return Execute(ctx, skill, step.SkillArgs)
 	case "finished":
 		return "Goal achieved: " + step.Text, nil
 	default:
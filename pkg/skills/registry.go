@@ -4,6 +4,8 @@ import (
 	"context"
 	"encoding/json"
 	"sync"
+
+	"github.com/nathfavour/auracrab/pkg/tracing"
 )
 
 // Skill interface defines what a skill can do.
@@ -14,6 +16,17 @@ type Skill interface {
 	Execute(ctx context.Context, args json.RawMessage) (string, error)
 }
 
+// Execute runs s in a span of its own. Callers use it instead of calling
+// s.Execute so every skill call shows up in the trace of its task.
+func Execute(ctx context.Context, s Skill, args json.RawMessage) (string, error) {
+	ctx, span := tracing.Start(ctx, "Skill.Execute", "skill", s.Name(), "args", string(args))
+	defer span.End()
+	out, err := s.Execute(ctx, args)
+	span.SetAttrs("output", out)
+	span.RecordError(err)
+	return out, err
+}
+
 type Registry struct {
 	skills map[string]Skill
 	mu     sync.RWMutex
@@ -4,7 +4,6 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"log"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -15,7 +14,9 @@ import (
 
 	"github.com/nathfavour/auracrab/internal/provider"
 	"github.com/nathfavour/auracrab/pkg/config"
+	"github.com/nathfavour/auracrab/pkg/logging"
 	"github.com/nathfavour/auracrab/pkg/memory"
+	"github.com/nathfavour/auracrab/pkg/prompts"
 )
 
 type ContextualQuerier interface {
@@ -51,10 +52,17 @@ type BotManager struct {
 	mu        sync.RWMutex
 	path      string
 
+	// states holds each running bot's connection error, nil once it
+	// receives updates.
+	states map[string]error
+
 	// Shell blacklist from POC
 	shellBlacklist []string
 }
 
+// ErrConnecting is the state of a bot that has not received updates yet.
+var ErrConnecting = fmt.Errorf("connecting")
+
 var (
 	botManagerInstance *BotManager
 	botOnce            sync.Once
@@ -66,6 +74,7 @@ func GetBotManager() *BotManager {
 		botManagerInstance = &BotManager{
 			path:      path,
 			providers: make(map[string]MessengerProvider),
+			states:    make(map[string]error),
 			shellBlacklist: []string{
 				"rm ", "mkfs", "dd ", "fdisk", "reboot", "shutdown", "init ",
 				"chmod", "chown", "mv /", "> /dev", "kill", "halt", "poweroff",
@@ -87,7 +96,7 @@ func (bm *BotManager) load() {
 	}
 
 	if err := json.Unmarshal(data, &bm.bots); err != nil {
-		log.Printf("Error loading bots: %v", err)
+		logger.Error("could not load bots", "err", err)
 		bm.bots = []BotConfig{}
 	}
 }
@@ -141,9 +150,33 @@ func (bm *BotManager) StartBots(ctx context.Context, history *memory.HistoryStor
 	}
 }
 
+// Connections returns the connection state of each bot started by
+// StartBots, by name: nil when connected, ErrConnecting or the error that
+// stopped it otherwise.
+func (bm *BotManager) Connections() map[string]error {
+	bm.mu.RLock()
+	defer bm.mu.RUnlock()
+	out := make(map[string]error, len(bm.states))
+	for k, v := range bm.states {
+		out[k] = v
+	}
+	return out
+}
+
+func (bm *BotManager) setState(cfg *BotConfig, err error) {
+	name := cfg.Name
+	if name == "" {
+		name = cfg.Platform
+	}
+	bm.mu.Lock()
+	bm.states[name] = err
+	bm.mu.Unlock()
+}
+
 func (bm *BotManager) runBot(ctx context.Context, cfg *BotConfig, history *memory.HistoryStore, querier ContextualQuerier, onTask func(platform, chatID, from, text string) string) {
 	var p MessengerProvider
 	var err error
+	bm.setState(cfg, ErrConnecting)
 
 	switch cfg.Platform {
 	case "telegram":
@@ -151,12 +184,14 @@ func (bm *BotManager) runBot(ctx context.Context, cfg *BotConfig, history *memor
 	case "discord":
 		p, err = NewDiscordProvider(cfg.Token)
 	default:
-		log.Printf("Unsupported platform: %s", cfg.Platform)
+		logger.Warn("unsupported bot platform", logging.KeyPlatform, cfg.Platform)
+		bm.setState(cfg, fmt.Errorf("unsupported platform %q", cfg.Platform))
 		return
 	}
 
 	if err != nil {
-		log.Printf("Failed to start bot %s: %v", cfg.Name, err)
+		logger.Error("could not start bot", "bot", cfg.Name, "err", err)
+		bm.setState(cfg, err)
 		return
 	}
 
@@ -179,11 +214,13 @@ func (bm *BotManager) runBot(ctx context.Context, cfg *BotConfig, history *memor
 
 	updates, err := p.GetUpdates(ctx)
 	if err != nil {
-		log.Printf("Bot %s updates error: %v", cfg.Name, err)
+		logger.Error("bot updates failed", "bot", cfg.Name, "err", err)
+		bm.setState(cfg, err)
 		return
 	}
+	bm.setState(cfg, nil)
 
-	log.Printf("Bot %s (%s) started in %s mode", cfg.Name, cfg.Platform, cfg.Mode)
+	logger.Info("bot started", "bot", cfg.Name, logging.KeyPlatform, cfg.Platform, "mode", cfg.Mode)
 
 	for {
 		select {
@@ -191,6 +228,7 @@ func (bm *BotManager) runBot(ctx context.Context, cfg *BotConfig, history *memor
 			return
 		case update, ok := <-updates:
 			if !ok {
+				bm.setState(cfg, fmt.Errorf("update stream closed"))
 				return
 			}
 
@@ -294,7 +332,9 @@ func (bm *BotManager) handleCommand(ctx context.Context, p MessengerProvider, cf
 			"*Core Commands:*\n" +
 			"/mode - Switch between Chat, Agent, and Shell\n" +
 			"/status - Check system health and task count\n" +
-			"/mission - View current objectives\n\n" +
+			"/mission - View current objectives\n" +
+			"/resume <task-id> - Continue a task paused over its budget\n" +
+			"/answer [task-id] <answer> - Reply to a task waiting for input\n\n" +
 			"*Experimental (SettlerEngine):*\n" +
 			"/pay - Initiate x402 payment\n" +
 			"/wallet - View agent wallet address and balance\n" +
@@ -305,6 +345,11 @@ func (bm *BotManager) handleCommand(ctx context.Context, p MessengerProvider, cf
 		return true
 	}
 
+	if strings.HasPrefix(text, "/resume") || strings.HasPrefix(text, "/answer") {
+		p.SendMessage(update.ChatID, onTask(cfg.Platform, update.ChatID, cfg.OwnerID, text), MessageOptions{})
+		return true
+	}
+
 	if text == "/mission" {
 		p.SendAction(update.ChatID, ActionTyping)
 		// Try to get actual mission if available
@@ -319,15 +364,8 @@ func (bm *BotManager) handleCommand(ctx context.Context, p MessengerProvider, cf
 
 	if text == "/status" {
 		p.SendAction(update.ChatID, ActionTyping)
-		// We use a generic response since we don't have direct butler access here
-		// but we can mock it nicely.
-		resp := "📊 *System Status*\n\n" +
-			"*Daemon:* `Running`\n" +
-			"*Memory:* `4.2GB / 8GB`\n" +
-			"*Vibe:* `Positive`\n" +
-			"*Health:* `Excellent`\n\n" +
-			"Auracrab is stable and ready for instructions."
-		p.SendMessage(update.ChatID, resp, MessageOptions{ParseMode: ParseModeMarkdown})
+		resp := "📊 System Status\n\n" + onTask(cfg.Platform, update.ChatID, update.ChatID, "get_status_internal")
+		p.SendMessage(update.ChatID, resp, MessageOptions{})
 		return true
 	}
 
@@ -335,7 +373,7 @@ func (bm *BotManager) handleCommand(ctx context.Context, p MessengerProvider, cf
 	// This allows the agent to challenge or mock the command request.
 	p.SendAction(update.ChatID, ActionTyping)
 
-	prompt := fmt.Sprintf("USER COMMAND: %s\n\nHandle this command. You can choose to execute it, ignore it, or challenge the user. Be punchy and mocking if you feel like it.", text)
+	prompt := prompts.Text("social.command", "", map[string]string{"Command": text})
 
 	// Record in history first
 	hist, _ := memory.NewHistoryStore()
@@ -515,7 +553,7 @@ func (bm *BotManager) SendMessage(platform string, chatID string, text string) e
 }
 
 func (bm *BotManager) BroadcastLog(text string) {
-	fmt.Printf("Butler [Log]: %s\n", text)
+	logger.Info("broadcast", "text", text)
 	bm.mu.RLock()
 	defer bm.mu.RUnlock()
 
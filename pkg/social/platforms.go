@@ -6,11 +6,11 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"log"
 	"net/http"
 	"os"
 	"time"
 
+	"github.com/nathfavour/auracrab/pkg/logging"
 	"github.com/nathfavour/auracrab/pkg/vault"
 )
 
@@ -23,7 +23,7 @@ func (x *XDriver) Name() string {
 }
 
 func (x *XDriver) Post(ctx context.Context, content string) (string, error) {
-	log.Printf("Posting to X: %s", content)
+	logger.Info("posting", logging.KeyPlatform, "x", "content", content)
 	// Placeholder for real API call
 	return "https://x.com/status/mock_id", nil
 }
@@ -41,7 +41,7 @@ func (l *LinkedInDriver) Name() string {
 }
 
 func (l *LinkedInDriver) Post(ctx context.Context, content string) (string, error) {
-	log.Printf("Posting to LinkedIn: %s", content)
+	logger.Info("posting", logging.KeyPlatform, "linkedin", "content", content)
 	return "https://www.linkedin.com/feed/update/mock_id", nil
 }
 
@@ -53,7 +53,7 @@ type FacebookDriver struct{}
 
 func (f *FacebookDriver) Name() string { return "facebook" }
 func (f *FacebookDriver) Post(ctx context.Context, content string) (string, error) {
-	log.Printf("Posting to Facebook: %s", content)
+	logger.Info("posting", logging.KeyPlatform, "facebook", "content", content)
 	return "https://facebook.com/mock_post", nil
 }
 func (f *FacebookDriver) GetFeed(ctx context.Context, limit int) ([]Post, error) {
@@ -64,7 +64,7 @@ type InstagramDriver struct{}
 
 func (i *InstagramDriver) Name() string { return "instagram" }
 func (i *InstagramDriver) Post(ctx context.Context, content string) (string, error) {
-	log.Printf("Posting to Instagram: %s", content)
+	logger.Info("posting", logging.KeyPlatform, "instagram", "content", content)
 	return "https://instagram.com/mock_post", nil
 }
 func (i *InstagramDriver) GetFeed(ctx context.Context, limit int) ([]Post, error) {
@@ -94,7 +94,7 @@ func (t *ThreadsDriver) Post(ctx context.Context, content string) (string, error
 
 	// Fallback to placeholder/mock if credentials are not configured
 	if accessToken == "" {
-		log.Printf("[Threads] THREADS_ACCESS_TOKEN not set. Running in mock/dry-run mode.")
+		logger.Warn("THREADS_ACCESS_TOKEN not set, running in dry-run mode", logging.KeyPlatform, "threads")
 		return "https://threads.net/mock_post_dryrun", nil
 	}
 
@@ -152,7 +152,7 @@ func (t *ThreadsDriver) Post(ctx context.Context, content string) (string, error
 	}
 
 	postURL := fmt.Sprintf("https://www.threads.net/post/%s", result.ID)
-	log.Printf("[Threads] Successfully published post: %s", postURL)
+	logger.Info("published post", logging.KeyPlatform, "threads", "url", postURL)
 
 	return postURL, nil
 }
@@ -4,16 +4,20 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"log"
 	"os"
 	"path/filepath"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/nathfavour/auracrab/pkg/biology"
 	"github.com/nathfavour/auracrab/pkg/config"
+	"github.com/nathfavour/auracrab/pkg/logging"
+	"github.com/nathfavour/auracrab/pkg/prompts"
 )
 
+var logger = logging.For("social")
+
 // Platform defines the interface for social media automation.
 type Platform interface {
 	Name() string
@@ -120,7 +124,7 @@ func SaveSocialConfig(cfg *SocialConfig) error {
 }
 
 func (m *Manager) Start(ctx context.Context, querier ContextualQuerier) {
-	log.Println("[Social] Starting social daemon loop...")
+	logger.Info("starting social daemon loop")
 	ticker := time.NewTicker(30 * time.Second)
 	defer ticker.Stop()
 
@@ -129,12 +133,12 @@ func (m *Manager) Start(ctx context.Context, querier ContextualQuerier) {
 	for {
 		select {
 		case <-ctx.Done():
-			log.Println("[Social] Stopping social daemon loop.")
+			logger.Info("stopping social daemon loop")
 			return
 		case <-ticker.C:
 			cfg, err := LoadSocialConfig()
 			if err != nil {
-				log.Printf("[Social] Error loading social config: %v", err)
+				logger.Error("could not load social config", "err", err)
 				continue
 			}
 
@@ -152,27 +156,28 @@ func (m *Manager) Start(ctx context.Context, querier ContextualQuerier) {
 			}
 
 			if time.Since(lastPostTime) >= interval {
-				log.Println("[Social] Time to post. Querying AI model for content...")
-				prompt := cfg.Prompt
-				if prompt == "" {
-					prompt = "Write a developer joke or observation about the agentic future of software."
+				if wait, reason := biology.ShouldDefer(biology.PriorityBackground); wait {
+					logger.Info("deferring post", "reason", reason)
+					continue
 				}
+				logger.Info("time to post, generating content")
+				prompt := prompts.Text("social.post", "", map[string]string{"Topic": cfg.Prompt})
 
 				resp, err := querier.QueryWithContext(ctx, prompt, "social_post_generation")
 				if err != nil {
-					log.Printf("[Social] Error generating social post: %v", err)
+					logger.Error("could not generate social post", "err", err)
 					continue
 				}
 
 				content := strings.TrimSpace(resp.Content)
 				if content == "" {
-					log.Println("[Social] Generated content was empty, skipping.")
+					logger.Warn("generated content was empty, skipping")
 					continue
 				}
 
-				log.Printf("[Social] Posting generated content to %v: %s", cfg.Platforms, content)
+				logger.Info("posting generated content", "platforms", cfg.Platforms, "content", content)
 				results := m.PostToAll(ctx, content, cfg.Platforms)
-				log.Printf("[Social] Posting results:\n%s", results)
+				logger.Info("posting results", "results", results)
 
 				lastPostTime = time.Now()
 			}
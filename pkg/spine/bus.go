@@ -0,0 +1,292 @@
+package spine
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultQueueSize bounds a subscriber's pending events.
+const DefaultQueueSize = 256
+
+// Match reports whether a topic matches a pattern. Patterns are dotted
+// segments where "*" matches one segment and a trailing ">" matches one or
+// more, e.g. "task.*" or "mission.>"; ">" alone matches everything.
+func Match(pattern, topic string) bool {
+	p := strings.Split(pattern, ".")
+	t := strings.Split(topic, ".")
+	for i, seg := range p {
+		if seg == ">" {
+			return i < len(t)
+		}
+		if i >= len(t) || (seg != "*" && seg != t[i]) {
+			return false
+		}
+	}
+	return len(p) == len(t)
+}
+
+// Subscription receives the events matching its pattern in publish order.
+type Subscription struct {
+	Pattern string
+	queue   chan Event
+	done    chan struct{}
+	block   bool
+	bus     *Bus
+
+	mu      sync.Mutex
+	dropped uint64
+	closed  bool
+}
+
+// Dropped is the number of events lost because the queue was full.
+func (s *Subscription) Dropped() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.dropped
+}
+
+// C is the subscriber's queue.
+func (s *Subscription) C() <-chan Event {
+	return s.queue
+}
+
+// Done is closed when the subscription is closed.
+func (s *Subscription) Done() <-chan struct{} {
+	return s.done
+}
+
+// Close removes the subscription from its bus.
+func (s *Subscription) Close() {
+	s.bus.unsubscribe(s)
+}
+
+// SubOption configures a subscription.
+type SubOption func(*Subscription)
+
+// QueueSize sets the subscriber's queue length.
+func QueueSize(n int) SubOption {
+	return func(s *Subscription) {
+		if n > 0 {
+			s.queue = make(chan Event, n)
+		}
+	}
+}
+
+// Blocking makes Publish wait for room in the queue instead of dropping,
+// pushing back on publishers when the subscriber falls behind. A blocking
+// handler must not publish itself.
+func Blocking() SubOption {
+	return func(s *Subscription) { s.block = true }
+}
+
+// Bus is a typed publish/subscribe bus with per-subscriber bounded queues
+// and optional persistence.
+type Bus struct {
+	pubMu sync.Mutex // keeps deliveries in sequence order
+	mu    sync.RWMutex
+	subs  []*Subscription
+	seq   uint64
+
+	storeMu  sync.Mutex
+	path     string
+	persist  []string
+	maxBytes int64
+}
+
+func NewBus() *Bus {
+	return &Bus{}
+}
+
+// Subscribe returns a subscription to topics matching pattern.
+func (b *Bus) Subscribe(pattern string, opts ...SubOption) *Subscription {
+	s := &Subscription{Pattern: pattern, queue: make(chan Event, DefaultQueueSize), done: make(chan struct{}), bus: b}
+	for _, o := range opts {
+		o(s)
+	}
+	b.mu.Lock()
+	b.subs = append(b.subs, s)
+	b.mu.Unlock()
+	return s
+}
+
+// Handle subscribes fn, which is called from one goroutine per subscription
+// so a subscriber sees its events in order.
+func (b *Bus) Handle(pattern string, fn func(Event), opts ...SubOption) *Subscription {
+	s := b.Subscribe(pattern, opts...)
+	go func() {
+		for {
+			select {
+			case e := <-s.queue:
+				fn(e)
+			case <-s.done:
+				return
+			}
+		}
+	}()
+	return s
+}
+
+func (b *Bus) unsubscribe(s *Subscription) {
+	b.mu.Lock()
+	for i, sub := range b.subs {
+		if sub == s {
+			b.subs = append(b.subs[:i], b.subs[i+1:]...)
+			break
+		}
+	}
+	b.mu.Unlock()
+
+	s.mu.Lock()
+	if !s.closed {
+		s.closed = true
+		close(s.done)
+	}
+	s.mu.Unlock()
+}
+
+// Publish stamps the event and delivers it to every matching subscriber.
+// A full queue drops the event for that subscriber unless it is Blocking.
+func (b *Bus) Publish(e Event) Event {
+	b.pubMu.Lock()
+	defer b.pubMu.Unlock()
+
+	b.mu.Lock()
+	b.seq++
+	e.Seq = b.seq
+	if e.Timestamp.IsZero() {
+		e.Timestamp = time.Now()
+	}
+	subs := make([]*Subscription, 0, len(b.subs))
+	for _, s := range b.subs {
+		if Match(s.Pattern, e.Type) {
+			subs = append(subs, s)
+		}
+	}
+	b.mu.Unlock()
+
+	b.store(e)
+
+	for _, s := range subs {
+		s.deliver(e)
+	}
+	return e
+}
+
+func (s *Subscription) deliver(e Event) {
+	if s.block {
+		select {
+		case s.queue <- e:
+		case <-s.done:
+		}
+		return
+	}
+	select {
+	case s.queue <- e:
+	case <-s.done:
+	default:
+		s.mu.Lock()
+		s.dropped++
+		dropped := s.dropped
+		s.mu.Unlock()
+		if dropped == 1 || dropped%100 == 0 {
+			logger.Warn("subscriber is falling behind", "pattern", s.Pattern, "dropped", dropped)
+		}
+	}
+}
+
+// Persist appends events matching any of patterns to a JSONL file, which is
+// rotated to <path>.1 once it exceeds maxBytes (0 means 16 MiB).
+func (b *Bus) Persist(path string, maxBytes int64, patterns ...string) {
+	if maxBytes <= 0 {
+		maxBytes = 16 << 20
+	}
+	b.storeMu.Lock()
+	defer b.storeMu.Unlock()
+	b.path = path
+	b.maxBytes = maxBytes
+	b.persist = patterns
+}
+
+type storedEvent struct {
+	Seq       uint64          `json:"seq"`
+	Type      string          `json:"type"`
+	Timestamp time.Time       `json:"ts"`
+	Payload   json.RawMessage `json:"payload,omitempty"`
+}
+
+func (b *Bus) store(e Event) {
+	b.storeMu.Lock()
+	defer b.storeMu.Unlock()
+	if b.path == "" {
+		return
+	}
+	keep := false
+	for _, p := range b.persist {
+		if Match(p, e.Type) {
+			keep = true
+			break
+		}
+	}
+	if !keep {
+		return
+	}
+
+	payload, err := json.Marshal(e.Payload)
+	if err != nil {
+		return
+	}
+	line, err := json.Marshal(storedEvent{Seq: e.Seq, Type: e.Type, Timestamp: e.Timestamp, Payload: payload})
+	if err != nil {
+		return
+	}
+
+	if info, err := os.Stat(b.path); err == nil && info.Size() > b.maxBytes {
+		_ = os.Rename(b.path, b.path+".1")
+	}
+	// Events carry chat text and step results, so the log is private.
+	_ = os.MkdirAll(filepath.Dir(b.path), 0700)
+	f, err := os.OpenFile(b.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	// Logs written by earlier versions were world-readable.
+	_ = f.Chmod(0600)
+	_, _ = f.Write(append(line, '\n'))
+}
+
+// History returns persisted events matching pattern since the given time,
+// oldest first, with payloads decoded into their registered types.
+func (b *Bus) History(pattern string, since time.Time) ([]Event, error) {
+	b.storeMu.Lock()
+	path := b.path
+	b.storeMu.Unlock()
+	if path == "" {
+		return nil, fmt.Errorf("event persistence is disabled")
+	}
+
+	var events []Event
+	for _, p := range []string{path + ".1", path} {
+		f, err := os.Open(p)
+		if err != nil {
+			continue
+		}
+		scanner := bufio.NewScanner(f)
+		scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+		for scanner.Scan() {
+			var se storedEvent
+			if json.Unmarshal(scanner.Bytes(), &se) != nil || !Match(pattern, se.Type) || se.Timestamp.Before(since) {
+				continue
+			}
+			events = append(events, Event{Seq: se.Seq, Type: se.Type, Timestamp: se.Timestamp, Payload: decodePayload(se.Type, se.Payload)})
+		}
+		f.Close()
+	}
+	return events, nil
+}
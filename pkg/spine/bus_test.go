@@ -0,0 +1,99 @@
+package spine
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestMatch(t *testing.T) {
+	cases := []struct {
+		pattern, topic string
+		want           bool
+	}{
+		{"task.*", "task.created", true},
+		{"task.*", "task.a.b", false},
+		{"task.>", "task.a.b", true},
+		{"task.>", "task", false},
+		{">", "health.degraded", true},
+		{"*.degraded", "health.degraded", true},
+		{"health.degraded", "health.recovered", false},
+	}
+	for _, c := range cases {
+		if got := Match(c.pattern, c.topic); got != c.want {
+			t.Errorf("Match(%q, %q) = %v", c.pattern, c.topic, got)
+		}
+	}
+}
+
+func TestBusOrderingAndBackpressure(t *testing.T) {
+	bus := NewBus()
+	ordered := bus.Subscribe("task.*", Blocking(), QueueSize(1))
+	lossy := bus.Subscribe("task.*", QueueSize(2))
+	other := bus.Subscribe("health.*")
+
+	done := make(chan []uint64)
+	go func() {
+		var seqs []uint64
+		for len(seqs) < 10 {
+			seqs = append(seqs, (<-ordered.C()).Seq)
+		}
+		done <- seqs
+	}()
+	for i := 0; i < 10; i++ {
+		bus.Publish(Event{Type: "task.status"})
+	}
+
+	seqs := <-done
+	for i := 1; i < len(seqs); i++ {
+		if seqs[i] <= seqs[i-1] {
+			t.Fatalf("events out of order: %v", seqs)
+		}
+	}
+	if lossy.Dropped() != 8 {
+		t.Errorf("expected 8 dropped events, got %d", lossy.Dropped())
+	}
+	if len(other.C()) != 0 {
+		t.Error("health subscriber received task events")
+	}
+
+	lossy.Close()
+	bus.Publish(Event{Type: "task.status"})
+	if lossy.Dropped() != 8 {
+		t.Error("closed subscription still receives events")
+	}
+}
+
+func TestBusPersistence(t *testing.T) {
+	bus := NewBus()
+	path := filepath.Join(t.TempDir(), "events", "events.jsonl")
+	bus.Persist(path, 0, "health.*", "cron.*")
+
+	bus.Publish(Event{Type: TopicHealthDegraded, Payload: HealthChange{Subsystem: "disk", Reason: "full"}})
+	bus.Publish(Event{Type: TopicChannelMessage, Payload: ChannelMessage{Text: "not persisted"}})
+	bus.Publish(Event{Type: TopicCronFired, Payload: CronFired{Job: "audit", Interval: time.Hour}})
+
+	events, err := bus.History(">", time.Time{})
+	if err != nil || len(events) != 2 {
+		t.Fatalf("History: %d events, %v", len(events), err)
+	}
+	if h, ok := PayloadAs[HealthChange](events[0]); !ok || h.Reason != "full" {
+		t.Errorf("payload not decoded into HealthChange: %#v", events[0].Payload)
+	}
+	if c, ok := PayloadAs[CronFired](events[1]); !ok || c.Interval != time.Hour {
+		t.Errorf("payload not decoded into CronFired: %#v", events[1].Payload)
+	}
+	if runtime.GOOS != "windows" {
+		for p, want := range map[string]os.FileMode{filepath.Dir(path): 0700, path: 0600} {
+			info, err := os.Stat(p)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.Mode().Perm() != want {
+				t.Errorf("%s: mode %v, want %v", p, info.Mode().Perm(), want)
+			}
+		}
+	}
+}
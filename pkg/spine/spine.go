@@ -2,20 +2,28 @@ package spine
 
 import (
 	"context"
-	"fmt"
 	"sync"
 	"time"
 
 	"github.com/nathfavour/auracrab/pkg/biology"
+	"github.com/nathfavour/auracrab/pkg/logging"
+	"github.com/nathfavour/auracrab/pkg/metrics"
 )
 
+var logger = logging.For("spine")
+
+var pulseLag = metrics.NewGauge("auracrab_spine_pulse_lag_seconds",
+	"How much later than scheduled the last spine beat came.")
+
 // Cell represents a unit of work or a sub-agent that attaches to the spine.
 type Cell interface {
 	Pulse(ctx context.Context) error
 	Name() string
 }
 
+// Event is published on the bus under a topic (Type), see topics.go.
 type Event struct {
+	Seq       uint64
 	Type      string
 	Payload   interface{}
 	Timestamp time.Time
@@ -27,81 +35,85 @@ type EventHandler interface {
 
 // Spine is the central nervous system pulse.
 type Spine struct {
-	mu       sync.RWMutex
-	cells    []Cell
-	handlers []EventHandler
-	rate     time.Duration
-	energy   biology.Energy
+	mu    sync.RWMutex
+	cells []*supervisor
+	rate  time.Duration
+	Bus   *Bus
+
+	// StatsFile, when set, receives the cell stats after every beat.
+	StatsFile string
+
+	stop     chan struct{}
+	stopOnce sync.Once
+
+	lastBeat time.Time
+	lag      time.Duration
 }
 
 func NewSpine(rate time.Duration) *Spine {
 	s := &Spine{
-		cells:    []Cell{},
-		handlers: []EventHandler{},
-		rate:     rate,
+		cells: []*supervisor{},
+		rate:  rate,
+		Bus:   NewBus(),
+		stop:  make(chan struct{}),
 	}
-	// Initial energy check
-	s.energy, _ = biology.CheckThermodynamics()
-	// Start background energy sensor
-	go s.sense(context.Background())
 	return s
 }
 
-func (s *Spine) Attach(cell Cell) {
+// Attach supervises cell on the spine's beat.
+func (s *Spine) Attach(cell Cell, opts ...CellOption) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	s.cells = append(s.cells, cell)
+	s.cells = append(s.cells, newSupervisor(cell, opts...))
 }
 
+// RegisterHandler subscribes h to every event.
 func (s *Spine) RegisterHandler(h EventHandler) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-	s.handlers = append(s.handlers, h)
+	s.Bus.Handle(">", h.Handle)
 }
 
+// Broadcast publishes e on the spine's bus.
 func (s *Spine) Broadcast(e Event) {
-	s.mu.RLock()
-	handlers := make([]EventHandler, len(s.handlers))
-	copy(handlers, s.handlers)
-	s.mu.RUnlock()
+	s.Bus.Publish(e)
+}
 
-	if e.Timestamp.IsZero() {
-		e.Timestamp = time.Now()
-	}
+// Stop ends the heartbeat without cancelling the context of pulses still
+// running, so their work can finish.
+func (s *Spine) Stop() {
+	s.stopOnce.Do(func() { close(s.stop) })
+}
 
-	for _, h := range handlers {
-		go h.Handle(e)
+// Idle reports whether no cell is in the middle of a pulse.
+func (s *Spine) Idle() bool {
+	for _, c := range s.Stats() {
+		if c.Running {
+			return false
+		}
 	}
+	return true
 }
 
-// sense periodically updates the spine's awareness of its physical state.
-func (s *Spine) sense(ctx context.Context) {
-	ticker := time.NewTicker(10 * time.Second)
-	defer ticker.Stop()
+// PulseLag is how much later than scheduled the last beat came, counting
+// the time the beat before it took.
+func (s *Spine) PulseLag() time.Duration {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.lag
+}
 
-	for {
-		select {
-		case <-ctx.Done():
-			return
-		case <-ticker.C:
-			energy, err := biology.CheckThermodynamics()
-			if err == nil {
-				s.mu.Lock()
-				s.energy = energy
-				s.mu.Unlock()
-			}
-		}
-	}
+// LastBeat is when the spine last pulsed.
+func (s *Spine) LastBeat() time.Time {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.lastBeat
 }
 
 // Breathes starts the heartbeat loop with adaptive rate.
 func (s *Spine) Breathes(ctx context.Context) {
-	fmt.Printf("SPINE: Starting pulse. Initial rate %v\n", s.rate)
+	logger.Info("starting pulse", "rate", s.rate)
 
 	for {
-		s.mu.RLock()
-		energy := s.energy
-		s.mu.RUnlock()
+		energy := biology.Current()
 
 		currentRate := s.rate
 		metabolism := biology.GetMetabolism()
@@ -122,17 +134,37 @@ func (s *Spine) Breathes(ctx context.Context) {
 
 		select {
 		case <-ctx.Done():
-			fmt.Println("SPINE: Context cancelled, stopping pulse.")
+			logger.Info("context cancelled, stopping pulse")
+			return
+		case <-s.stop:
+			logger.Info("stopped, no further pulses")
 			return
 		case <-time.After(currentRate):
+			s.beat(currentRate)
 			s.pulse(ctx)
 		}
 	}
 }
 
+// beat records the lag of a beat scheduled rate after the previous one.
+func (s *Spine) beat(rate time.Duration) {
+	now := time.Now()
+	s.mu.Lock()
+	if !s.lastBeat.IsZero() {
+		s.lag = max(now.Sub(s.lastBeat)-rate, 0)
+	}
+	s.lastBeat = now
+	lag := s.lag
+	s.mu.Unlock()
+	pulseLag.Set(lag.Seconds())
+}
+
 func (s *Spine) pulse(ctx context.Context) {
+	energy := biology.Current()
+
 	s.mu.RLock()
-	energy := s.energy
+	cells := s.cells
+	statsFile := s.StatsFile
 	s.mu.RUnlock()
 
 	// 1. Check Physics (Thermodynamics & Entropy)
@@ -142,17 +174,16 @@ func (s *Spine) pulse(ctx context.Context) {
 
 	// Optional: Log energy state on pulse if it's significant
 	if energy.EnergyLevel < 0.2 {
-		fmt.Printf("SPINE WARNING: Low Energy Level: %.2f (CPU: %.1f%%, MEM: %.1f%%)\n",
-			energy.EnergyLevel, energy.CPUUsage, energy.MemoryUsage)
+		logger.Warn("low energy level", "energy", energy.EnergyLevel, "cpu", energy.CPUUsage, "mem", energy.MemoryUsage)
+	}
+
+	// 2. Pulse every attached cell that is due and not still running
+	now := time.Now()
+	for _, c := range cells {
+		c.tick(ctx, now)
 	}
 
-	// 2. Pulse all attached cells
-	for _, cell := range s.cells {
-		go func(c Cell) {
-			err := c.Pulse(ctx)
-			if err != nil {
-				fmt.Printf("SPINE: Cell '%s' failed pulse: %v\n", c.Name(), err)
-			}
-		}(cell)
+	if statsFile != "" {
+		_ = s.WriteStats(statsFile)
 	}
 }
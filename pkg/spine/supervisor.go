@@ -0,0 +1,197 @@
+package spine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// Restart backoff after a panicking pulse doubles from MinBackoff up to
+// MaxBackoff and resets once a pulse completes.
+const (
+	MinBackoff = time.Second
+	MaxBackoff = 5 * time.Minute
+)
+
+// CellOption configures how the spine supervises a cell.
+type CellOption func(*supervisor)
+
+// Every sets the cell's own cadence. Cells pulse on every beat by default;
+// an interval shorter than the beat has no effect.
+func Every(d time.Duration) CellOption {
+	return func(s *supervisor) { s.interval = d }
+}
+
+// Timeout cancels the pulse context after d. A cell that ignores its
+// context keeps its slot until it returns, so it is skipped rather than
+// stacked.
+func Timeout(d time.Duration) CellOption {
+	return func(s *supervisor) { s.timeout = d }
+}
+
+// CellStats is a supervised cell's pulse record.
+type CellStats struct {
+	Name         string        `json:"name"`
+	Interval     time.Duration `json:"interval"`
+	Timeout      time.Duration `json:"timeout"`
+	Running      bool          `json:"running"`
+	LastPulse    time.Time     `json:"last_pulse"`
+	LastDuration time.Duration `json:"last_duration"`
+	Pulses       uint64        `json:"pulses"`
+	Skipped      uint64        `json:"skipped"`
+	Errors       uint64        `json:"errors"`
+	Timeouts     uint64        `json:"timeouts"`
+	Restarts     uint64        `json:"restarts"`
+	LastError    string        `json:"last_error,omitempty"`
+	BackoffUntil time.Time     `json:"backoff_until,omitempty"`
+}
+
+// supervisor runs one cell: at most one pulse at a time, at the cell's own
+// cadence, restarting it with backoff after a panic.
+type supervisor struct {
+	cell     Cell
+	interval time.Duration
+	timeout  time.Duration
+
+	mu      sync.Mutex
+	stats   CellStats
+	started time.Time
+	backoff time.Duration
+}
+
+func newSupervisor(cell Cell, opts ...CellOption) *supervisor {
+	s := &supervisor{cell: cell}
+	for _, o := range opts {
+		o(s)
+	}
+	s.stats = CellStats{Name: cell.Name(), Interval: s.interval, Timeout: s.timeout}
+	return s
+}
+
+// tick starts a pulse if the cell is due, idle and not backing off.
+func (s *supervisor) tick(ctx context.Context, now time.Time) {
+	s.mu.Lock()
+	if s.stats.Running {
+		s.stats.Skipped++
+		s.mu.Unlock()
+		return
+	}
+	if now.Before(s.stats.BackoffUntil) || (!s.started.IsZero() && now.Sub(s.started) < s.interval) {
+		s.mu.Unlock()
+		return
+	}
+	if s.backoff > 0 {
+		s.stats.Restarts++
+		logger.Info("restarting cell", "cell", s.stats.Name)
+	}
+	s.stats.Running = true
+	s.started = now
+	s.mu.Unlock()
+
+	go s.run(ctx)
+}
+
+func (s *supervisor) run(ctx context.Context) {
+	if s.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	var err error
+	panicked := false
+	func() {
+		defer func() {
+			if r := recover(); r != nil {
+				panicked = true
+				err = fmt.Errorf("panic: %v", r)
+				logger.Error("cell panicked", "cell", s.stats.Name, "panic", r, "stack", string(debug.Stack()))
+			}
+		}()
+		err = s.cell.Pulse(ctx)
+	}()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stats.Running = false
+	s.stats.LastPulse = start
+	s.stats.LastDuration = time.Since(start)
+	s.stats.Pulses++
+
+	switch {
+	case panicked:
+		s.stats.Errors++
+		s.stats.LastError = err.Error()
+		if s.backoff == 0 {
+			s.backoff = MinBackoff
+		} else if s.backoff < MaxBackoff {
+			s.backoff *= 2
+			if s.backoff > MaxBackoff {
+				s.backoff = MaxBackoff
+			}
+		}
+		s.stats.BackoffUntil = time.Now().Add(s.backoff)
+		return
+	case err != nil:
+		s.stats.Errors++
+		s.stats.LastError = err.Error()
+		if ctx.Err() == context.DeadlineExceeded {
+			s.stats.Timeouts++
+		}
+		logger.Warn("cell failed pulse", "cell", s.stats.Name, "err", err)
+	}
+	s.backoff = 0
+	s.stats.BackoffUntil = time.Time{}
+}
+
+func (s *supervisor) snapshot() CellStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stats
+}
+
+// Stats returns the pulse record of every attached cell in attach order.
+func (s *Spine) Stats() []CellStats {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	stats := make([]CellStats, 0, len(s.cells))
+	for _, c := range s.cells {
+		stats = append(stats, c.snapshot())
+	}
+	return stats
+}
+
+// WriteStats saves the cell stats to path so other processes can read them.
+func (s *Spine) WriteStats(path string) error {
+	data, err := json.MarshalIndent(s.Stats(), "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// ReadStats loads cell stats saved by WriteStats.
+func ReadStats(path string) ([]CellStats, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var stats []CellStats
+	if err := json.Unmarshal(data, &stats); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
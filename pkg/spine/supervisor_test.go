@@ -0,0 +1,106 @@
+package spine
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+type testCell struct {
+	pulse func(ctx context.Context) error
+}
+
+func (c testCell) Name() string                    { return "test" }
+func (c testCell) Pulse(ctx context.Context) error { return c.pulse(ctx) }
+
+func waitIdle(t *testing.T, s *supervisor) CellStats {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if st := s.snapshot(); !st.Running {
+			return st
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatal("cell never finished its pulse")
+	return CellStats{}
+}
+
+func TestSupervisorSkipsOverlappingPulses(t *testing.T) {
+	release := make(chan struct{})
+	s := newSupervisor(testCell{pulse: func(ctx context.Context) error {
+		<-release
+		return nil
+	}})
+
+	ctx := context.Background()
+	now := time.Now()
+	s.tick(ctx, now)
+	s.tick(ctx, now.Add(time.Second))
+	s.tick(ctx, now.Add(2*time.Second))
+	close(release)
+
+	st := waitIdle(t, s)
+	if st.Pulses != 1 || st.Skipped != 2 {
+		t.Fatalf("pulses=%d skipped=%d, want 1 and 2", st.Pulses, st.Skipped)
+	}
+}
+
+func TestSupervisorInterval(t *testing.T) {
+	s := newSupervisor(testCell{pulse: func(ctx context.Context) error { return nil }}, Every(time.Minute))
+
+	ctx := context.Background()
+	now := time.Now()
+	s.tick(ctx, now)
+	waitIdle(t, s)
+	s.tick(ctx, now.Add(30*time.Second))
+	if st := waitIdle(t, s); st.Pulses != 1 {
+		t.Fatalf("pulsed %d times before the interval elapsed", st.Pulses)
+	}
+	s.tick(ctx, now.Add(time.Minute))
+	if st := waitIdle(t, s); st.Pulses != 2 {
+		t.Fatalf("pulses = %d after the interval, want 2", st.Pulses)
+	}
+}
+
+func TestSupervisorRecoversAndBacksOff(t *testing.T) {
+	fail := true
+	s := newSupervisor(testCell{pulse: func(ctx context.Context) error {
+		if fail {
+			panic("boom")
+		}
+		return nil
+	}})
+
+	ctx := context.Background()
+	s.tick(ctx, time.Now())
+	st := waitIdle(t, s)
+	if st.Errors != 1 || st.LastError != "panic: boom" || st.BackoffUntil.IsZero() {
+		t.Fatalf("unexpected stats after panic: %+v", st)
+	}
+
+	// Still backing off.
+	s.tick(ctx, time.Now())
+	if st := waitIdle(t, s); st.Pulses != 1 {
+		t.Fatalf("pulsed during backoff")
+	}
+
+	fail = false
+	s.tick(ctx, st.BackoffUntil.Add(time.Millisecond))
+	st = waitIdle(t, s)
+	if st.Restarts != 1 || st.Pulses != 2 || !st.BackoffUntil.IsZero() {
+		t.Fatalf("unexpected stats after restart: %+v", st)
+	}
+}
+
+func TestSupervisorTimeout(t *testing.T) {
+	s := newSupervisor(testCell{pulse: func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}}, Timeout(10*time.Millisecond))
+
+	s.tick(context.Background(), time.Now())
+	if st := waitIdle(t, s); st.Timeouts != 1 {
+		t.Fatalf("timeouts = %d, want 1", st.Timeouts)
+	}
+}
@@ -0,0 +1,126 @@
+package spine
+
+import (
+	"encoding/json"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// Event topics. Task and step events carry the task trace entries of
+// package core; the rest carry the payload types below.
+const (
+	TopicTaskCreated = "task.created"
+	TopicTaskStatus  = "task.status"
+	TopicTaskPlan    = "task.plan"
+	TopicTaskPrompt  = "task.prompt"
+	TopicTaskRetry   = "task.retry"
+	TopicTaskSkill   = "task.skill"
+	TopicTaskInput   = "task.input"
+
+	// Step topics end in the step status, e.g. step.completed.
+	TopicStepRunning   = "step.running"
+	TopicStepCompleted = "step.completed"
+	TopicStepFailed    = "step.failed"
+
+	TopicMissionDispatched = "mission.dispatched"
+	TopicMissionSubtask    = "mission.subtask"
+	TopicMissionCompleted  = "mission.completed"
+	TopicMissionFailed     = "mission.failed"
+
+	TopicChannelMessage     = "channel.message"
+	TopicWatcherFileChanged = "watcher.file_changed"
+	TopicHealthDegraded     = "health.degraded"
+	TopicHealthRecovered    = "health.recovered"
+	TopicCronFired          = "cron.fired"
+)
+
+// MissionEvent is the payload of mission.* topics.
+type MissionEvent struct {
+	MissionID string `json:"mission_id"`
+	Title     string `json:"title"`
+	SubTaskID string `json:"subtask_id,omitempty"`
+	SubTask   string `json:"subtask,omitempty"`
+	Status    string `json:"status,omitempty"`
+	Result    string `json:"result,omitempty"`
+}
+
+// ChannelMessage is the payload of channel.message.
+type ChannelMessage struct {
+	Platform string `json:"platform"`
+	ChatID   string `json:"chat_id"`
+	From     string `json:"from"`
+	Text     string `json:"text"`
+}
+
+// FileChanged is the payload of watcher.file_changed.
+type FileChanged struct {
+	Watcher string `json:"watcher,omitempty"`
+	Path    string `json:"path"`
+	Op      string `json:"op"`
+}
+
+// HealthChange is the payload of health.degraded and health.recovered.
+type HealthChange struct {
+	Subsystem string `json:"subsystem"`
+	Reason    string `json:"reason"`
+	Anomalies int    `json:"anomalies,omitempty"`
+}
+
+// CronFired is the payload of cron.fired.
+type CronFired struct {
+	Job      string        `json:"job"`
+	Interval time.Duration `json:"interval"`
+}
+
+var (
+	catalogueMu sync.RWMutex
+	catalogue   = map[string]reflect.Type{}
+)
+
+// RegisterPayload declares the payload type published on a topic pattern so
+// persisted events decode back into it.
+func RegisterPayload(pattern string, prototype interface{}) {
+	catalogueMu.Lock()
+	defer catalogueMu.Unlock()
+	catalogue[pattern] = reflect.TypeOf(prototype)
+}
+
+func init() {
+	RegisterPayload("mission.*", MissionEvent{})
+	RegisterPayload(TopicChannelMessage, ChannelMessage{})
+	RegisterPayload(TopicWatcherFileChanged, FileChanged{})
+	RegisterPayload("health.*", HealthChange{})
+	RegisterPayload(TopicCronFired, CronFired{})
+}
+
+// decodePayload turns a persisted payload back into its registered type,
+// or a generic JSON value for unknown topics.
+func decodePayload(topic string, raw json.RawMessage) interface{} {
+	catalogueMu.RLock()
+	var t reflect.Type
+	for pattern, typ := range catalogue {
+		if Match(pattern, topic) {
+			t = typ
+			break
+		}
+	}
+	catalogueMu.RUnlock()
+
+	if t == nil {
+		var v interface{}
+		_ = json.Unmarshal(raw, &v)
+		return v
+	}
+	ptr := reflect.New(t)
+	if err := json.Unmarshal(raw, ptr.Interface()); err != nil {
+		return nil
+	}
+	return ptr.Elem().Interface()
+}
+
+// PayloadAs returns the event payload as T.
+func PayloadAs[T any](e Event) (T, bool) {
+	v, ok := e.Payload.(T)
+	return v, ok
+}
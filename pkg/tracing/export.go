@@ -0,0 +1,275 @@
+package tracing
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// fileSpan is one line of the file exporter.
+type fileSpan struct {
+	TraceID    string         `json:"trace_id"`
+	SpanID     string         `json:"span_id"`
+	ParentID   string         `json:"parent_id,omitempty"`
+	Name       string         `json:"name"`
+	Start      time.Time      `json:"start"`
+	End        time.Time      `json:"end"`
+	DurationMS float64        `json:"duration_ms"`
+	Attrs      map[string]any `json:"attrs,omitempty"`
+	Error      string         `json:"error,omitempty"`
+}
+
+// FileExporter appends spans to a file, one JSON object per line.
+type FileExporter struct {
+	mu sync.Mutex
+	f  *os.File
+}
+
+// NewFileExporter opens path for appending, creating it and its directory.
+func NewFileExporter(path string) (*FileExporter, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, err
+	}
+	return &FileExporter{f: f}, nil
+}
+
+func (e *FileExporter) Export(_ context.Context, spans []SpanData) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	w := bufio.NewWriter(e.f)
+	enc := json.NewEncoder(w)
+	for _, s := range spans {
+		line := fileSpan{
+			TraceID:    s.TraceID.String(),
+			SpanID:     s.SpanID.String(),
+			Name:       s.Name,
+			Start:      s.Start,
+			End:        s.End,
+			DurationMS: float64(s.End.Sub(s.Start).Microseconds()) / 1000,
+			Error:      s.Error,
+		}
+		if !s.ParentID.IsZero() {
+			line.ParentID = s.ParentID.String()
+		}
+		if len(s.Attrs) > 0 {
+			line.Attrs = make(map[string]any, len(s.Attrs))
+			for _, a := range s.Attrs {
+				line.Attrs[a.Key] = attrValue(a.Value)
+			}
+		}
+		if err := enc.Encode(line); err != nil {
+			return err
+		}
+	}
+	return w.Flush()
+}
+
+func (e *FileExporter) Close() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.f.Close()
+}
+
+func attrValue(v slog.Value) any {
+	switch v.Kind() {
+	case slog.KindInt64:
+		return v.Int64()
+	case slog.KindUint64:
+		return v.Uint64()
+	case slog.KindFloat64:
+		return v.Float64()
+	case slog.KindBool:
+		return v.Bool()
+	case slog.KindDuration:
+		return v.Duration().String()
+	default:
+		return v.String()
+	}
+}
+
+// OTLPExporter posts spans to an OTLP/HTTP collector in the JSON
+// encoding.
+type OTLPExporter struct {
+	url     string
+	headers map[string]string
+	client  *http.Client
+}
+
+// NewOTLPExporter sends to endpoint/v1/traces, unless endpoint already
+// names that path.
+func NewOTLPExporter(endpoint string, headers map[string]string) (*OTLPExporter, error) {
+	if endpoint == "" {
+		endpoint = "http://localhost:4318"
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("bad OTLP endpoint %q: want http(s)://host:port", endpoint)
+	}
+	if !strings.HasSuffix(u.Path, "/v1/traces") {
+		u.Path = strings.TrimSuffix(u.Path, "/") + "/v1/traces"
+	}
+	return &OTLPExporter{
+		url:     u.String(),
+		headers: headers,
+		client:  &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+func (e *OTLPExporter) Export(ctx context.Context, spans []SpanData) error {
+	body, err := json.Marshal(otlpRequest(spans))
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range e.headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return errors.New("collector answered " + resp.Status + ": " + strings.TrimSpace(string(msg)))
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return nil
+}
+
+func (e *OTLPExporter) Close() error { return nil }
+
+// The OTLP/JSON shapes, limited to what the daemon records.
+type (
+	otlpTraces struct {
+		ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+	}
+	otlpResourceSpans struct {
+		Resource   otlpResource     `json:"resource"`
+		ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+	}
+	otlpResource struct {
+		Attributes []otlpKeyValue `json:"attributes"`
+	}
+	otlpScopeSpans struct {
+		Scope otlpScope  `json:"scope"`
+		Spans []otlpSpan `json:"spans"`
+	}
+	otlpScope struct {
+		Name string `json:"name"`
+	}
+	otlpSpan struct {
+		TraceID           string         `json:"traceId"`
+		SpanID            string         `json:"spanId"`
+		ParentSpanID      string         `json:"parentSpanId,omitempty"`
+		Name              string         `json:"name"`
+		Kind              int            `json:"kind"`
+		StartTimeUnixNano string         `json:"startTimeUnixNano"`
+		EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+		Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+		Status            *otlpStatus    `json:"status,omitempty"`
+	}
+	otlpStatus struct {
+		Code    int    `json:"code"`
+		Message string `json:"message,omitempty"`
+	}
+	otlpKeyValue struct {
+		Key   string    `json:"key"`
+		Value otlpValue `json:"value"`
+	}
+	otlpValue struct {
+		StringValue *string  `json:"stringValue,omitempty"`
+		IntValue    *string  `json:"intValue,omitempty"`
+		DoubleValue *float64 `json:"doubleValue,omitempty"`
+		BoolValue   *bool    `json:"boolValue,omitempty"`
+	}
+)
+
+// OTLP span kinds and status codes.
+const (
+	kindInternal = 1
+	kindClient   = 3
+	statusError  = 2
+)
+
+func otlpRequest(spans []SpanData) otlpTraces {
+	service := "auracrab"
+	out := make([]otlpSpan, 0, len(spans))
+	for _, s := range spans {
+		o := otlpSpan{
+			TraceID:           s.TraceID.String(),
+			SpanID:            s.SpanID.String(),
+			Name:              s.Name,
+			Kind:              kindInternal,
+			StartTimeUnixNano: strconv.FormatInt(s.Start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(s.End.UnixNano(), 10),
+		}
+		if !s.ParentID.IsZero() {
+			o.ParentSpanID = s.ParentID.String()
+		}
+		for _, a := range s.Attrs {
+			if a.Key == "provider" {
+				// Model queries leave the process.
+				o.Kind = kindClient
+			}
+			o.Attributes = append(o.Attributes, otlpKeyValue{Key: a.Key, Value: otlpAttr(a.Value)})
+		}
+		if s.Error != "" {
+			o.Status = &otlpStatus{Code: statusError, Message: s.Error}
+		}
+		out = append(out, o)
+	}
+	return otlpTraces{ResourceSpans: []otlpResourceSpans{{
+		Resource: otlpResource{Attributes: []otlpKeyValue{
+			{Key: "service.name", Value: otlpValue{StringValue: &service}},
+		}},
+		ScopeSpans: []otlpScopeSpans{{
+			Scope: otlpScope{Name: "github.com/nathfavour/auracrab/pkg/tracing"},
+			Spans: out,
+		}},
+	}}}
+}
+
+func otlpAttr(v slog.Value) otlpValue {
+	switch v.Kind() {
+	case slog.KindInt64:
+		i := strconv.FormatInt(v.Int64(), 10)
+		return otlpValue{IntValue: &i}
+	case slog.KindUint64:
+		i := strconv.FormatUint(v.Uint64(), 10)
+		return otlpValue{IntValue: &i}
+	case slog.KindFloat64:
+		f := v.Float64()
+		return otlpValue{DoubleValue: &f}
+	case slog.KindBool:
+		b := v.Bool()
+		return otlpValue{BoolValue: &b}
+	case slog.KindDuration:
+		s := v.Duration().String()
+		return otlpValue{StringValue: &s}
+	default:
+		s := v.String()
+		return otlpValue{StringValue: &s}
+	}
+}
@@ -0,0 +1,73 @@
+package tracing
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+// DefaultMaxAttrBytes caps recorded attribute values. Prompts and replies
+// are usually larger and are recorded by size and hash only.
+const DefaultMaxAttrBytes = 512
+
+// Attributes whose keys end in one of sensitiveKeys, such as api_key or
+// access_token, are never recorded. Counts such as prompt_tokens are.
+var sensitiveKeys = []string{"token", "secret", "password", "passwd", "key", "apikey", "authorization", "cookie", "credential", "credentials"}
+
+type redactor struct {
+	max int
+}
+
+// attrs turns slog-style key/value pairs into redacted attributes.
+func (r redactor) attrs(args []any) []slog.Attr {
+	if len(args) == 0 {
+		return nil
+	}
+	rec := slog.Record{}
+	rec.Add(args...)
+	attrs := make([]slog.Attr, 0, rec.NumAttrs())
+	rec.Attrs(func(a slog.Attr) bool {
+		attrs = append(attrs, r.attr(a))
+		return true
+	})
+	return attrs
+}
+
+func (r redactor) attr(a slog.Attr) slog.Attr {
+	a.Value = a.Value.Resolve()
+	if sensitive(a.Key) {
+		return slog.String(a.Key, "[redacted]")
+	}
+	switch a.Value.Kind() {
+	case slog.KindString:
+		return slog.String(a.Key, r.text(a.Value.String()))
+	case slog.KindInt64, slog.KindUint64, slog.KindFloat64, slog.KindBool, slog.KindDuration:
+		return a
+	case slog.KindTime:
+		return slog.String(a.Key, a.Value.Time().Format("2006-01-02T15:04:05.000Z07:00"))
+	default:
+		return slog.String(a.Key, r.text(fmt.Sprint(a.Value.Any())))
+	}
+}
+
+// text keeps short values and replaces longer ones by their size and a
+// hash, so equal payloads can still be matched across spans.
+func (r redactor) text(s string) string {
+	if len(s) <= r.max {
+		return s
+	}
+	sum := sha256.Sum256([]byte(s))
+	return fmt.Sprintf("[redacted: %d bytes, sha256 %s]", len(s), hex.EncodeToString(sum[:6]))
+}
+
+func sensitive(key string) bool {
+	k := strings.ToLower(key)
+	for _, s := range sensitiveKeys {
+		if strings.HasSuffix(k, s) {
+			return true
+		}
+	}
+	return false
+}
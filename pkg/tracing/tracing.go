@@ -0,0 +1,360 @@
+// Package tracing records spans around the daemon's units of work (a task,
+// its planning, each step, each model query and each skill call) so a
+// slow task shows where its time went. Spans are batched and exported to
+// a local JSONL file or an OTLP/HTTP collector; with no exporter
+// configured, starting a span costs next to nothing.
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/nathfavour/auracrab/pkg/config"
+	"github.com/nathfavour/auracrab/pkg/logging"
+)
+
+// KeyTrace correlates log records with the trace of the work they belong
+// to.
+const KeyTrace = "trace_id"
+
+// Batching of finished spans.
+const (
+	queueSize     = 2048
+	batchSize     = 256
+	flushInterval = 2 * time.Second
+)
+
+var logger = logging.For("tracing")
+
+// TraceID identifies a trace: a task and everything done for it.
+type TraceID [16]byte
+
+// SpanID identifies a span within a trace.
+type SpanID [8]byte
+
+func (t TraceID) String() string { return hex.EncodeToString(t[:]) }
+func (s SpanID) String() string  { return hex.EncodeToString(s[:]) }
+
+func (t TraceID) IsZero() bool { return t == TraceID{} }
+func (s SpanID) IsZero() bool  { return s == SpanID{} }
+
+// SpanData is a finished span as exporters see it.
+type SpanData struct {
+	TraceID  TraceID
+	SpanID   SpanID
+	ParentID SpanID
+	Name     string
+	Start    time.Time
+	End      time.Time
+	Attrs    []slog.Attr
+	Error    string
+}
+
+// Span is a unit of work being timed. A nil *Span, which Start returns
+// while tracing is off, ignores every call.
+type Span struct {
+	t *tracer
+
+	mu    sync.Mutex
+	data  SpanData
+	ended bool
+}
+
+// SetAttrs adds key/value pairs, as in slog, to the span.
+func (s *Span) SetAttrs(args ...any) {
+	if s == nil {
+		return
+	}
+	attrs := s.t.redactor.attrs(args)
+	s.mu.Lock()
+	s.data.Attrs = append(s.data.Attrs, attrs...)
+	s.mu.Unlock()
+}
+
+// RecordError marks the span failed when err is not nil.
+func (s *Span) RecordError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.mu.Lock()
+	s.data.Error = s.t.redactor.text(err.Error())
+	s.mu.Unlock()
+}
+
+// End finishes the span and queues it for export. Only the first call
+// counts.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.data.End = time.Now()
+	data := s.data
+	s.mu.Unlock()
+	s.t.enqueue(data)
+}
+
+// Traceparent returns the span's W3C trace context, which Resume accepts
+// to continue the trace later or in another process.
+func (s *Span) Traceparent() string {
+	if s == nil {
+		return ""
+	}
+	return fmt.Sprintf("00-%s-%s-01", s.data.TraceID, s.data.SpanID)
+}
+
+// TraceID returns the ID of the span's trace.
+func (s *Span) TraceID() TraceID {
+	if s == nil {
+		return TraceID{}
+	}
+	return s.data.TraceID
+}
+
+type spanKey struct{}
+
+// parent is the span new spans in a context become children of.
+type parent struct {
+	trace TraceID
+	span  SpanID
+}
+
+func parentFrom(ctx context.Context) (parent, bool) {
+	p, ok := ctx.Value(spanKey{}).(parent)
+	return p, ok
+}
+
+// Start begins a span named name as a child of the span in ctx, or as the
+// root of a new trace. args are key/value attributes, as in slog. The
+// returned context carries the new span.
+func Start(ctx context.Context, name string, args ...any) (context.Context, *Span) {
+	t := current.Load()
+	if t == nil {
+		return ctx, nil
+	}
+
+	data := SpanData{Name: name, Start: time.Now(), SpanID: newSpanID()}
+	if p, ok := parentFrom(ctx); ok {
+		data.TraceID, data.ParentID = p.trace, p.span
+	} else {
+		data.TraceID = newTraceID()
+		ctx = logging.With(ctx, KeyTrace, data.TraceID.String())
+	}
+	data.Attrs = t.redactor.attrs(args)
+
+	span := &Span{t: t, data: data}
+	return context.WithValue(ctx, spanKey{}, parent{data.TraceID, data.SpanID}), span
+}
+
+// Resume continues the trace recorded by Traceparent when ctx is not
+// already part of one, so work that runs later, such as a task's steps on
+// spine pulses, joins the trace of the task.
+func Resume(ctx context.Context, traceparent string) context.Context {
+	if current.Load() == nil || traceparent == "" {
+		return ctx
+	}
+	if _, ok := parentFrom(ctx); ok {
+		return ctx
+	}
+	p, err := parseTraceparent(traceparent)
+	if err != nil {
+		return ctx
+	}
+	ctx = logging.With(ctx, KeyTrace, p.trace.String())
+	return context.WithValue(ctx, spanKey{}, p)
+}
+
+func parseTraceparent(s string) (parent, error) {
+	var p parent
+	parts := strings.Split(s, "-")
+	if len(parts) != 4 || parts[0] != "00" {
+		return p, errors.New("not a version 00 traceparent")
+	}
+	tid, err := hex.DecodeString(parts[1])
+	if err != nil || len(tid) != len(p.trace) {
+		return p, errors.New("bad trace ID")
+	}
+	sid, err := hex.DecodeString(parts[2])
+	if err != nil || len(sid) != len(p.span) {
+		return p, errors.New("bad span ID")
+	}
+	copy(p.trace[:], tid)
+	copy(p.span[:], sid)
+	if p.trace.IsZero() || p.span.IsZero() {
+		return p, errors.New("zero trace or span ID")
+	}
+	return p, nil
+}
+
+func newTraceID() TraceID {
+	var id TraceID
+	_, _ = rand.Read(id[:])
+	return id
+}
+
+func newSpanID() SpanID {
+	var id SpanID
+	_, _ = rand.Read(id[:])
+	return id
+}
+
+// Exporter sends finished spans somewhere.
+type Exporter interface {
+	Export(ctx context.Context, spans []SpanData) error
+	Close() error
+}
+
+type tracer struct {
+	exporter Exporter
+	redactor redactor
+	queue    chan SpanData
+	done     chan struct{}
+	dropped  atomic.Uint64
+}
+
+var (
+	current atomic.Pointer[tracer]
+	setupMu sync.Mutex
+)
+
+// Path returns where the file exporter writes spans.
+func Path(cfg config.TracingConfig) string {
+	if cfg.File != "" {
+		return cfg.File
+	}
+	name := "spans.jsonl"
+	if r := config.Replica(); r != "" {
+		name = r + "-spans.jsonl"
+	}
+	return filepath.Join(config.DataDir(), "logs", name)
+}
+
+// Setup starts exporting spans as configured; an empty exporter leaves
+// tracing off.
+func Setup(cfg config.TracingConfig) error {
+	var exp Exporter
+	switch strings.ToLower(cfg.Exporter) {
+	case "", "none":
+		return Shutdown(context.Background())
+	case "file":
+		f, err := NewFileExporter(Path(cfg))
+		if err != nil {
+			return err
+		}
+		exp = f
+	case "otlp":
+		o, err := NewOTLPExporter(cfg.Endpoint, cfg.Headers)
+		if err != nil {
+			return err
+		}
+		exp = o
+	default:
+		return fmt.Errorf("unknown trace exporter %q; use file or otlp", cfg.Exporter)
+	}
+	if err := Shutdown(context.Background()); err != nil {
+		logger.Warn("could not flush the previous exporter", "err", err)
+	}
+	Install(exp, cfg.MaxAttrBytes)
+	return nil
+}
+
+// Install starts exporting spans to exp, capping recorded attribute values
+// at maxAttrBytes (DefaultMaxAttrBytes when zero).
+func Install(exp Exporter, maxAttrBytes int) {
+	setupMu.Lock()
+	defer setupMu.Unlock()
+	if maxAttrBytes <= 0 {
+		maxAttrBytes = DefaultMaxAttrBytes
+	}
+	t := &tracer{
+		exporter: exp,
+		redactor: redactor{max: maxAttrBytes},
+		queue:    make(chan SpanData, queueSize),
+		done:     make(chan struct{}),
+	}
+	go t.run()
+	current.Store(t)
+}
+
+// Shutdown stops tracing and exports the spans still queued.
+func Shutdown(ctx context.Context) error {
+	setupMu.Lock()
+	defer setupMu.Unlock()
+	t := current.Swap(nil)
+	if t == nil {
+		return nil
+	}
+	close(t.queue)
+	select {
+	case <-t.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	if n := t.dropped.Load(); n > 0 {
+		logger.Warn("dropped spans while the exporter was behind", "spans", n)
+	}
+	return t.exporter.Close()
+}
+
+func (t *tracer) enqueue(s SpanData) {
+	defer func() {
+		// The span ended after Shutdown closed the queue.
+		if recover() != nil {
+			t.dropped.Add(1)
+		}
+	}()
+	select {
+	case t.queue <- s:
+	default:
+		t.dropped.Add(1)
+	}
+}
+
+// run exports spans in batches until the queue is closed.
+func (t *tracer) run() {
+	defer close(t.done)
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	var batch []SpanData
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		if err := t.exporter.Export(ctx, batch); err != nil {
+			logger.Warn("could not export spans", "spans", len(batch), "err", err)
+		}
+		cancel()
+		batch = nil
+	}
+	for {
+		select {
+		case s, ok := <-t.queue:
+			if !ok {
+				flush()
+				return
+			}
+			batch = append(batch, s)
+			if len(batch) >= batchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
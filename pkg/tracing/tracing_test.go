@@ -0,0 +1,161 @@
+package tracing
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/nathfavour/auracrab/pkg/config"
+)
+
+func readSpans(t *testing.T, path string) []fileSpan {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var spans []fileSpan
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		var s fileSpan
+		if err := json.Unmarshal(sc.Bytes(), &s); err != nil {
+			t.Fatalf("bad line %q: %v", sc.Text(), err)
+		}
+		spans = append(spans, s)
+	}
+	return spans
+}
+
+func TestFileExporterNestsSpansAndRedacts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "spans.jsonl")
+	if err := Setup(config.TracingConfig{Exporter: "file", File: path, MaxAttrBytes: 16}); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, task := Start(context.Background(), "StartTask", "task_id", "t1", "api_key", "sk-123")
+	tp := task.Traceparent()
+	task.End()
+
+	// Steps run later, from a fresh context.
+	ctx2 := Resume(context.Background(), tp)
+	_, step := Start(ctx2, "executeStep", "step_id", "s1", "attempt", 2)
+	step.SetAttrs("prompt", strings.Repeat("x", 100))
+	step.RecordError(errors.New("boom"))
+	step.End()
+	step.End()
+	_ = ctx
+
+	if err := Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	spans := readSpans(t, path)
+	if len(spans) != 2 {
+		t.Fatalf("got %d spans, want 2", len(spans))
+	}
+	root, child := spans[0], spans[1]
+	if root.ParentID != "" || child.ParentID != root.SpanID || child.TraceID != root.TraceID {
+		t.Errorf("child %+v is not nested under %+v", child, root)
+	}
+	if root.Attrs["api_key"] != "[redacted]" || root.Attrs["task_id"] != "t1" {
+		t.Errorf("root attrs = %v", root.Attrs)
+	}
+	if p, _ := child.Attrs["prompt"].(string); !strings.HasPrefix(p, "[redacted: 100 bytes") {
+		t.Errorf("prompt recorded as %q", p)
+	}
+	if child.Attrs["attempt"] != float64(2) || child.Error != "boom" {
+		t.Errorf("child = %+v", child)
+	}
+}
+
+func TestDisabledTracingIsNoop(t *testing.T) {
+	_ = Shutdown(context.Background())
+	ctx, span := Start(context.Background(), "noop")
+	if span != nil || ctx != context.Background() {
+		t.Fatal("Start made a span with tracing off")
+	}
+	span.SetAttrs("k", "v")
+	span.RecordError(errors.New("x"))
+	span.End()
+	if span.Traceparent() != "" {
+		t.Error("nil span has a traceparent")
+	}
+}
+
+func TestOTLPExporterPostsJSON(t *testing.T) {
+	var (
+		mu   sync.Mutex
+		got  otlpTraces
+		auth string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/traces" {
+			http.NotFound(w, r)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		defer mu.Unlock()
+		auth = r.Header.Get("Authorization")
+		_ = json.Unmarshal(body, &got)
+	}))
+	defer srv.Close()
+
+	err := Setup(config.TracingConfig{Exporter: "otlp", Endpoint: srv.URL, Headers: map[string]string{"Authorization": "Bearer t"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, task := Start(context.Background(), "StartTask")
+	_, q := Start(ctx, "GetCompletion", "provider", "vibe", "tokens", 12)
+	q.RecordError(errors.New("timeout"))
+	q.End()
+	task.End()
+	if err := Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if auth != "Bearer t" {
+		t.Errorf("authorization header = %q", auth)
+	}
+	if len(got.ResourceSpans) != 1 || len(got.ResourceSpans[0].ScopeSpans) != 1 {
+		t.Fatalf("unexpected body %+v", got)
+	}
+	spans := got.ResourceSpans[0].ScopeSpans[0].Spans
+	if len(spans) != 2 {
+		t.Fatalf("got %d spans", len(spans))
+	}
+	query := spans[0]
+	if query.Kind != kindClient || query.Status == nil || query.Status.Code != statusError {
+		t.Errorf("query span = %+v", query)
+	}
+	if query.ParentSpanID != spans[1].SpanID || len(query.TraceID) != 32 {
+		t.Errorf("query span ids = %+v", query)
+	}
+	if a := query.Attributes[1]; a.Key != "tokens" || a.Value.IntValue == nil || *a.Value.IntValue != "12" {
+		t.Errorf("tokens attribute = %+v", a)
+	}
+}
+
+func TestParseTraceparent(t *testing.T) {
+	for _, bad := range []string{"", "01-x-y-01", "00-" + strings.Repeat("0", 32) + "-" + strings.Repeat("1", 16) + "-01", "00-abc-def-01"} {
+		if _, err := parseTraceparent(bad); err == nil {
+			t.Errorf("accepted %q", bad)
+		}
+	}
+	want := "00-" + strings.Repeat("ab", 16) + "-" + strings.Repeat("cd", 8) + "-01"
+	p, err := parseTraceparent(want)
+	if err != nil || p.trace.String() != strings.Repeat("ab", 16) || p.span.String() != strings.Repeat("cd", 8) {
+		t.Errorf("parsed %q as %+v, %v", want, p, err)
+	}
+}
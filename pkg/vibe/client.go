@@ -9,8 +9,12 @@ import (
 	"path/filepath"
 	"sync"
 	"time"
+
+	"github.com/nathfavour/auracrab/pkg/logging"
 )
 
+var logger = logging.For("vibe")
+
 func vibeSocketPath() string {
 	if v := os.Getenv("VIBEAURA_SOCKET"); v != "" {
 		return v
@@ -63,7 +67,7 @@ func (c *Client) getConn() (net.Conn, error) {
 		return c.conn, nil
 	}
 
-	fmt.Printf("VibeClient: Dialing %s\n", c.socketPath)
+	logger.Debug("dialing", "socket", c.socketPath)
 	var conn net.Conn
 	var err error
 	maxRetries := 2
@@ -72,7 +76,7 @@ func (c *Client) getConn() (net.Conn, error) {
 		if err == nil {
 			break
 		}
-		fmt.Printf("VibeClient: Dial attempt %d failed: %v\n", i+1, err)
+		logger.Debug("dial attempt failed", "attempt", i+1, "err", err)
 		if i < maxRetries-1 {
 			time.Sleep(200 * time.Millisecond)
 		}
@@ -82,7 +86,7 @@ func (c *Client) getConn() (net.Conn, error) {
 		return nil, fmt.Errorf("failed to connect to vibeauracle UDS: %w", err)
 	}
 
-	fmt.Println("VibeClient: Connected to UDS")
+	logger.Debug("connected", "socket", c.socketPath)
 	c.conn = conn
 	return c.conn, nil
 }
@@ -97,7 +101,7 @@ func (c *Client) closeConn() {
 }
 
 func (c *Client) call(method string, payload interface{}) (json.RawMessage, error) {
-	fmt.Printf("VibeClient: Calling %s\n", method)
+	logger.Debug("calling", "method", method)
 	conn, err := c.getConn()
 	if err != nil {
 		return nil, err
@@ -130,7 +134,7 @@ func (c *Client) call(method string, payload interface{}) (json.RawMessage, erro
 	scanner := bufio.NewScanner(conn)
 	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
 	if scanner.Scan() {
-		fmt.Printf("VibeClient: Received response for %s\n", method)
+		logger.Debug("received response", "method", method)
 		var resp Response
 		if err := json.Unmarshal(scanner.Bytes(), &resp); err != nil {
 			return nil, fmt.Errorf("failed to unmarshal response: %w. raw: %s", err, string(scanner.Bytes()))
@@ -145,7 +149,7 @@ func (c *Client) call(method string, payload interface{}) (json.RawMessage, erro
 	}
 
 	if err := scanner.Err(); err != nil {
-		fmt.Printf("VibeClient: Scanner error: %v\n", err)
+		logger.Error("read failed", "err", err)
 		c.closeConn()
 		return nil, err
 	}
@@ -276,6 +280,9 @@ func (c *Client) QueryStream(content string, intent string) (<-chan string, erro
 	return out, nil
 }
 
+// EmbedderKind names vibeauracle as the backend of the vectors Embed returns.
+func (c *Client) EmbedderKind() string { return "vibe" }
+
 func (c *Client) Embed(content string) ([]float64, error) {
 	raw, err := c.call("embed", map[string]string{"content": content})
 	if err != nil {
@@ -2,89 +2,275 @@ package watcher
 
 import (
 	"context"
-	"log"
+	"fmt"
+	"io/fs"
 	"os"
+	"path"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/fsnotify/fsnotify"
+
+	"github.com/nathfavour/auracrab/pkg/logging"
 )
 
+var logger = logging.For("watcher")
+
+// DefaultDebounce is how long the tree must be quiet before a batch of
+// changes is reported.
+const DefaultDebounce = 2 * time.Second
+
+// DefaultOps are the changes reported when no event kinds are given.
+const DefaultOps = fsnotify.Create | fsnotify.Write | fsnotify.Remove | fsnotify.Rename
+
+var opNames = map[string]fsnotify.Op{
+	"create": fsnotify.Create,
+	"write":  fsnotify.Write,
+	"remove": fsnotify.Remove,
+	"rename": fsnotify.Rename,
+	"chmod":  fsnotify.Chmod,
+}
+
+// ParseOps turns event kind names (create, write, remove, rename, chmod)
+// into an fsnotify mask, DefaultOps when names is empty.
+func ParseOps(names []string) (fsnotify.Op, error) {
+	if len(names) == 0 {
+		return DefaultOps, nil
+	}
+	var ops fsnotify.Op
+	for _, n := range names {
+		op, ok := opNames[strings.ToLower(strings.TrimSpace(n))]
+		if !ok {
+			return 0, fmt.Errorf("unknown event %q, expected create, write, remove, rename or chmod", n)
+		}
+		ops |= op
+	}
+	return ops, nil
+}
+
+// Options select what a Watcher reports. Globs are matched against paths
+// relative to the watched directory, see Match.
+type Options struct {
+	Include []string // Every file when empty
+	Exclude []string // Also prunes matching directories
+	Ops     fsnotify.Op
+	// Debounce is DefaultDebounce when zero.
+	Debounce time.Duration
+}
+
+// Validate checks the glob patterns.
+func (o Options) Validate() error {
+	for _, p := range append(append([]string{}, o.Include...), o.Exclude...) {
+		if _, err := path.Match(p, ""); err != nil {
+			return fmt.Errorf("invalid glob %q: %v", p, err)
+		}
+	}
+	return nil
+}
+
+// Change is one changed path in a batch; Op merges every kind of change
+// the path saw during the debounce window.
+type Change struct {
+	Path string
+	Op   fsnotify.Op
+}
+
+// Watcher watches a directory tree, including directories created after it
+// starts, and reports changed files in debounced batches.
 type Watcher struct {
-	watcher *fsnotify.Watcher
-	onEvent func(string)
+	watcher  *fsnotify.Watcher
+	opts     Options
+	root     string
+	onChange func([]Change)
 }
 
-func NewWatcher(onEvent func(string)) (*Watcher, error) {
+func NewWatcher(opts Options, onChange func([]Change)) (*Watcher, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	if opts.Ops == 0 {
+		opts.Ops = DefaultOps
+	}
+	if opts.Debounce <= 0 {
+		opts.Debounce = DefaultDebounce
+	}
 	w, err := fsnotify.NewWatcher()
 	if err != nil {
 		return nil, err
 	}
 
 	return &Watcher{
-		watcher: w,
-		onEvent: onEvent,
+		watcher:  w,
+		opts:     opts,
+		onChange: onChange,
 	}, nil
 }
 
 func (w *Watcher) Start(ctx context.Context, dir string) error {
-	// Recursive watch
-	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+	root, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+	w.root = root
+	if err := w.addTree(root, nil); err != nil {
+		return err
+	}
+
+	go w.loop(ctx)
+	return nil
+}
+
+// addTree watches dir and every directory below it that is not excluded.
+// Files found are passed to found, so that files created in a new
+// directory before it was watched are not missed.
+func (w *Watcher) addTree(dir string, found func(string)) error {
+	return filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
 		if err != nil {
+			// The directory may be gone again already.
+			if p != dir && os.IsNotExist(err) {
+				return nil
+			}
 			return err
 		}
-		if info.IsDir() {
-			// Skip hidden directories
-			if strings.HasPrefix(info.Name(), ".") && info.Name() != "." {
+		if p != w.root && w.excluded(w.rel(p)) {
+			if d.IsDir() {
 				return filepath.SkipDir
 			}
-			return w.watcher.Add(path)
+			return nil
+		}
+		if d.IsDir() {
+			return w.watcher.Add(p)
+		}
+		if found != nil {
+			found(p)
 		}
 		return nil
 	})
-	if err != nil {
-		return err
-	}
+}
 
-	go func() {
-		// Debounce timer to avoid multiple heartbeats for simultaneous changes
-		var timer *time.Timer
-		const debounceDuration = 2 * time.Second
+func (w *Watcher) loop(ctx context.Context) {
+	pending := map[string]fsnotify.Op{}
+	timer := time.NewTimer(time.Hour)
+	timer.Stop()
+	defer timer.Stop()
 
-		for {
-			select {
-			case <-ctx.Done():
-				return
-			case event, ok := <-w.watcher.Events:
-				if !ok {
-					return
-				}
+	record := func(p string, op fsnotify.Op) {
+		if op&w.opts.Ops == 0 || !w.included(w.rel(p)) {
+			return
+		}
+		pending[p] |= op & w.opts.Ops
+		timer.Reset(w.opts.Debounce)
+	}
 
-				// Ignore non-write/create/remove/rename events
-				if event.Op&fsnotify.Chmod == fsnotify.Chmod {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-w.watcher.Events:
+			if !ok {
+				return
+			}
+			rel := w.rel(event.Name)
+			if w.excluded(rel) {
+				continue
+			}
+			if event.Has(fsnotify.Create) {
+				if info, err := os.Lstat(event.Name); err == nil && info.IsDir() {
+					err := w.addTree(event.Name, func(p string) { record(p, fsnotify.Create) })
+					if err != nil {
+						logger.Warn("could not watch new directory", "dir", event.Name, "err", err)
+					}
 					continue
 				}
+			}
+			record(event.Name, event.Op)
 
-				if timer != nil {
-					timer.Stop()
-				}
-				timer = time.AfterFunc(debounceDuration, func() {
-					w.onEvent(event.Name)
-				})
+		case <-timer.C:
+			if len(pending) == 0 {
+				continue
+			}
+			batch := make([]Change, 0, len(pending))
+			for p, op := range pending {
+				batch = append(batch, Change{Path: p, Op: op})
+			}
+			sort.Slice(batch, func(i, j int) bool { return batch[i].Path < batch[j].Path })
+			pending = map[string]fsnotify.Op{}
+			w.onChange(batch)
 
-			case err, ok := <-w.watcher.Errors:
-				if !ok {
-					return
-				}
-				log.Printf("Watcher error: %v", err)
+		case err, ok := <-w.watcher.Errors:
+			if !ok {
+				return
 			}
+			logger.Error("watch failed", "dir", w.root, "err", err)
 		}
-	}()
+	}
+}
 
-	return nil
+// rel returns p relative to the watched root, slash-separated.
+func (w *Watcher) rel(p string) string {
+	r, err := filepath.Rel(w.root, p)
+	if err != nil {
+		return filepath.ToSlash(p)
+	}
+	return filepath.ToSlash(r)
+}
+
+func (w *Watcher) included(rel string) bool {
+	if len(w.opts.Include) == 0 {
+		return true
+	}
+	return matchAny(w.opts.Include, rel)
+}
+
+func (w *Watcher) excluded(rel string) bool {
+	return matchAny(w.opts.Exclude, rel)
 }
 
 func (w *Watcher) Close() error {
 	return w.watcher.Close()
 }
+
+func matchAny(patterns []string, rel string) bool {
+	for _, p := range patterns {
+		if Match(p, rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// Match reports whether a slash-separated relative path matches a glob.
+// A pattern without "/" matches the base name at any depth, and "**"
+// matches any number of directories, so "vendor/**" matches vendor itself
+// and everything below it.
+func Match(pattern, rel string) bool {
+	pattern = strings.TrimPrefix(filepath.ToSlash(pattern), "./")
+	if !strings.Contains(pattern, "/") {
+		ok, _ := path.Match(pattern, path.Base(rel))
+		return ok
+	}
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(rel, "/"))
+}
+
+func matchSegments(pattern, parts []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(parts); i++ {
+				if matchSegments(pattern[1:], parts[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(parts) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], parts[0]); !ok {
+			return false
+		}
+		pattern, parts = pattern[1:], parts[1:]
+	}
+	return len(parts) == 0
+}
@@ -0,0 +1,142 @@
+package watcher
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+func TestMatch(t *testing.T) {
+	cases := []struct {
+		pattern, rel string
+		want         bool
+	}{
+		{"*.go", "main.go", true},
+		{"*.go", "pkg/core/butler.go", true},
+		{"*.go", "README.md", false},
+		{".git/**", ".git", true},
+		{".git/**", ".git/objects/ab/cdef", true},
+		{".git/**", ".github/workflows/ci.yml", false},
+		{"pkg/**/*.go", "pkg/watcher/watcher.go", true},
+		{"pkg/**/*.go", "pkg/a.go", true},
+		{"pkg/**/*.go", "cmd/a.go", false},
+		{"docs/*.md", "docs/guide/intro.md", false},
+		{"./docs/*.md", "docs/intro.md", true},
+	}
+	for _, c := range cases {
+		if got := Match(c.pattern, c.rel); got != c.want {
+			t.Errorf("Match(%q, %q) = %v, want %v", c.pattern, c.rel, got, c.want)
+		}
+	}
+}
+
+func TestParseOps(t *testing.T) {
+	ops, err := ParseOps([]string{"create", "Write"})
+	if err != nil || ops != fsnotify.Create|fsnotify.Write {
+		t.Fatalf("got %v, %v", ops, err)
+	}
+	if ops, _ := ParseOps(nil); ops != DefaultOps {
+		t.Fatalf("expected the default ops, got %v", ops)
+	}
+	if _, err := ParseOps([]string{"touch"}); err == nil {
+		t.Fatal("expected an error for an unknown event")
+	}
+}
+
+// start watches dir and returns the batches it reports.
+func start(t *testing.T, dir string, opts Options) <-chan []Change {
+	t.Helper()
+	batches := make(chan []Change, 10)
+	opts.Debounce = 200 * time.Millisecond
+	w, err := NewWatcher(opts, func(c []Change) { batches <- c })
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(func() {
+		cancel()
+		w.Close()
+	})
+	if err := w.Start(ctx, dir); err != nil {
+		t.Fatal(err)
+	}
+	return batches
+}
+
+func next(t *testing.T, batches <-chan []Change) map[string]fsnotify.Op {
+	t.Helper()
+	select {
+	case batch := <-batches:
+		got := map[string]fsnotify.Op{}
+		for _, c := range batch {
+			got[c.Path] = c.Op
+		}
+		return got
+	case <-time.After(5 * time.Second):
+		t.Fatal("no changes reported")
+	}
+	return nil
+}
+
+func write(t *testing.T, path string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWatcher_ReportsEveryFileInTheWindow(t *testing.T) {
+	dir := t.TempDir()
+	batches := start(t, dir, Options{Include: []string{"*.go"}})
+
+	write(t, filepath.Join(dir, "a.go"))
+	write(t, filepath.Join(dir, "b.go"))
+	write(t, filepath.Join(dir, "notes.txt"))
+
+	got := next(t, batches)
+	if len(got) != 2 {
+		t.Fatalf("expected a.go and b.go in one batch, got %v", got)
+	}
+	if !got[filepath.Join(dir, "a.go")].Has(fsnotify.Create) {
+		t.Fatalf("expected a.go to be reported as created, got %v", got)
+	}
+}
+
+func TestWatcher_FollowsNewDirectories(t *testing.T) {
+	dir := t.TempDir()
+	batches := start(t, dir, Options{})
+
+	sub := filepath.Join(dir, "sub", "deeper")
+	if err := os.MkdirAll(sub, 0755); err != nil {
+		t.Fatal(err)
+	}
+	write(t, filepath.Join(sub, "early.txt"))
+	next(t, batches)
+
+	write(t, filepath.Join(sub, "late.txt"))
+	if got := next(t, batches); got[filepath.Join(sub, "late.txt")] == 0 {
+		t.Fatalf("expected a change in the new directory, got %v", got)
+	}
+}
+
+func TestWatcher_ExcludesPruneDirectories(t *testing.T) {
+	dir := t.TempDir()
+	for _, d := range []string{".git", ".github"} {
+		if err := os.Mkdir(filepath.Join(dir, d), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	batches := start(t, dir, Options{Exclude: []string{".git/**"}})
+
+	write(t, filepath.Join(dir, ".git", "index"))
+	write(t, filepath.Join(dir, ".github", "ci.yml"))
+
+	got := next(t, batches)
+	if len(got) != 1 || got[filepath.Join(dir, ".github", "ci.yml")] == 0 {
+		t.Fatalf("expected only .github/ci.yml, got %v", got)
+	}
+}
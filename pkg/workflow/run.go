@@ -0,0 +1,201 @@
+package workflow
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nathfavour/auracrab/internal/provider"
+	"github.com/nathfavour/auracrab/pkg/skills"
+)
+
+// Querier answers prompt steps; core.Butler satisfies it.
+type Querier interface {
+	QueryWithContext(context.Context, string, string) (provider.CompletionResponse, error)
+}
+
+// Event reports step progress during Run.
+type Event struct {
+	Step    string
+	Status  string // "running", "retry", "completed", "failed", "skipped"
+	Attempt int
+	Output  string
+	Err     error
+}
+
+// RunStep renders and executes one step with its retries and timeout.
+func RunStep(ctx context.Context, s Step, vars map[string]interface{}, q Querier, onRetry func(attempt int, err error)) (string, error) {
+	var lastErr error
+	for attempt := 0; attempt <= s.Retries; attempt++ {
+		if attempt > 0 {
+			if onRetry != nil {
+				onRetry(attempt, lastErr)
+			}
+			select {
+			case <-ctx.Done():
+				return "", ctx.Err()
+			case <-time.After(time.Duration(attempt) * time.Second):
+			}
+		}
+
+		out, err := runOnce(ctx, s, vars, q)
+		if err == nil {
+			return out, nil
+		}
+		lastErr = err
+	}
+	return "", lastErr
+}
+
+func runOnce(ctx context.Context, s Step, vars map[string]interface{}, q Querier) (string, error) {
+	if d := s.TimeoutDuration(); d > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, d)
+		defer cancel()
+	}
+
+	if s.Prompt != "" {
+		prompt, err := Render(s.ID, s.Prompt, vars)
+		if err != nil {
+			return "", err
+		}
+		intent := s.Intent
+		if intent == "" {
+			intent = "agent"
+		}
+		resp, err := q.QueryWithContext(ctx, prompt, intent)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimSpace(resp.Content), nil
+	}
+
+	skill, ok := skills.GetRegistry().Get(s.Skill)
+	if !ok {
+		return "", fmt.Errorf("unknown skill %q", s.Skill)
+	}
+	args := map[string]interface{}{}
+	for k, v := range s.Args {
+		if str, ok := v.(string); ok {
+			rendered, err := Render(s.ID+"."+k, str, vars)
+			if err != nil {
+				return "", err
+			}
+			v = rendered
+		}
+		args[k] = v
+	}
+	raw, err := json.Marshal(args)
+	if err != nil {
+		return "", err
+	}
+
+	// Skills do not all honour ctx, so the timeout is enforced here too.
+	type result struct {
+		out string
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		out, err := skills.Execute(ctx, skill, raw)
+		done <- result{out, err}
+	}()
+	select {
+	case r := <-done:
+		return strings.TrimSpace(r.out), r.err
+	case <-ctx.Done():
+		return "", fmt.Errorf("step %s: %w", s.ID, ctx.Err())
+	}
+}
+
+// Run executes the workflow ad hoc: independent steps run in parallel, each
+// step starts once its dependencies completed, and a failure skips everything
+// downstream. It returns the output of every completed step.
+func Run(ctx context.Context, wf *Workflow, inputs map[string]string, q Querier, events func(Event)) (map[string]string, error) {
+	if events == nil {
+		events = func(Event) {}
+	}
+
+	var mu sync.Mutex
+	outputs := map[string]string{}
+	state := map[string]string{} // step id -> status
+	var failed []string
+
+	var wg sync.WaitGroup
+	var launch func()
+	launch = func() {
+		// Caller holds mu.
+		for _, s := range wf.Steps {
+			if state[s.ID] != "" {
+				continue
+			}
+			ready, blocked := true, false
+			for _, d := range s.DependsOn {
+				switch state[d] {
+				case "completed":
+				case "failed", "skipped":
+					blocked = true
+				default:
+					ready = false
+				}
+			}
+			if blocked {
+				state[s.ID] = "skipped"
+				events(Event{Step: s.ID, Status: "skipped"})
+				continue
+			}
+			if !ready {
+				continue
+			}
+
+			state[s.ID] = "running"
+			vars := Vars(inputs, outputs)
+			wg.Add(1)
+			go func(s Step) {
+				defer wg.Done()
+				events(Event{Step: s.ID, Status: "running", Attempt: 1})
+				out, err := RunStep(ctx, s, vars, q, func(attempt int, err error) {
+					events(Event{Step: s.ID, Status: "retry", Attempt: attempt + 1, Err: err})
+				})
+
+				mu.Lock()
+				defer mu.Unlock()
+				if err != nil {
+					state[s.ID] = "failed"
+					failed = append(failed, fmt.Sprintf("%s: %v", s.ID, err))
+					events(Event{Step: s.ID, Status: "failed", Err: err})
+				} else {
+					state[s.ID] = "completed"
+					outputs[s.ID] = out
+					events(Event{Step: s.ID, Status: "completed", Output: out})
+				}
+				launch()
+			}(s)
+		}
+		// A pass can skip steps that unblock nothing else but still need a
+		// second look at their own dependents.
+		for _, s := range wf.Steps {
+			if state[s.ID] == "" {
+				for _, d := range s.DependsOn {
+					if state[d] == "skipped" {
+						launch()
+						return
+					}
+				}
+			}
+		}
+	}
+
+	mu.Lock()
+	launch()
+	mu.Unlock()
+	wg.Wait()
+
+	if len(failed) > 0 {
+		return outputs, fmt.Errorf("workflow '%s' failed: %s", wf.Name, strings.Join(failed, "; "))
+	}
+	return outputs, nil
+}
@@ -0,0 +1,446 @@
+package workflow
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/nathfavour/auracrab/pkg/config"
+	"github.com/nathfavour/auracrab/pkg/mission"
+	"github.com/nathfavour/auracrab/pkg/schema"
+	"github.com/nathfavour/auracrab/pkg/skills"
+	"go.yaml.in/yaml/v3"
+)
+
+// Workflow is a declarative, reusable multi-step job.
+//
+//	name: dep-bump
+//	inputs:
+//	  repo: {required: true}
+//	steps:
+//	  - id: bump
+//	    prompt: "Bump the dependencies of {{ .inputs.repo }}"
+//	  - id: test
+//	    skill: system
+//	    args: {action: df}
+//	    depends_on: [bump]
+//	    retries: 2
+//	    timeout: 5m
+type Workflow struct {
+	Name        string           `yaml:"name" json:"name"`
+	Description string           `yaml:"description,omitempty" json:"description,omitempty"`
+	Goal        string           `yaml:"goal,omitempty" json:"goal,omitempty"`
+	Deadline    string           `yaml:"deadline,omitempty" json:"deadline,omitempty"` // Duration from apply, e.g. "48h"
+	Inputs      map[string]Input `yaml:"inputs,omitempty" json:"inputs,omitempty"`
+	Steps       []Step           `yaml:"steps" json:"steps"`
+}
+
+type Input struct {
+	Description string `yaml:"description,omitempty" json:"description,omitempty"`
+	Required    bool   `yaml:"required,omitempty" json:"required,omitempty"`
+	Default     string `yaml:"default,omitempty" json:"default,omitempty"`
+}
+
+// Step is either a skill call with typed args or a prompt to the model.
+// String args and prompts are templates over .inputs and .steps.<id>.output.
+type Step struct {
+	ID        string                 `yaml:"id" json:"id"`
+	Name      string                 `yaml:"name,omitempty" json:"name,omitempty"`
+	Skill     string                 `yaml:"skill,omitempty" json:"skill,omitempty"`
+	Args      map[string]interface{} `yaml:"args,omitempty" json:"args,omitempty"`
+	Prompt    string                 `yaml:"prompt,omitempty" json:"prompt,omitempty"`
+	Intent    string                 `yaml:"intent,omitempty" json:"intent,omitempty"`
+	DependsOn []string               `yaml:"depends_on,omitempty" json:"depends_on,omitempty"`
+	Retries   int                    `yaml:"retries,omitempty" json:"retries,omitempty"`
+	Timeout   string                 `yaml:"timeout,omitempty" json:"timeout,omitempty"`
+}
+
+// Title is the human name of the step.
+func (s *Step) Title() string {
+	if s.Name != "" {
+		return s.Name
+	}
+	return s.ID
+}
+
+// TimeoutDuration returns the step timeout, or 0 for none.
+func (s *Step) TimeoutDuration() time.Duration {
+	d, _ := time.ParseDuration(s.Timeout)
+	return d
+}
+
+// Dir is where named workflows are stored for `workflow run <name>`.
+func Dir() string {
+	dir := filepath.Join(config.DataDir(), "workflows")
+	_ = os.MkdirAll(dir, 0755)
+	return dir
+}
+
+// Load reads a workflow from a path, or by name from Dir().
+func Load(nameOrPath string) (*Workflow, error) {
+	path := nameOrPath
+	if _, err := os.Stat(path); err != nil {
+		path = filepath.Join(Dir(), nameOrPath+".yaml")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("workflow '%s' not found", nameOrPath)
+	}
+	return Parse(data)
+}
+
+// Parse decodes and validates a workflow document.
+func Parse(data []byte) (*Workflow, error) {
+	var wf Workflow
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&wf); err != nil {
+		return nil, fmt.Errorf("invalid workflow: %w", err)
+	}
+	if err := wf.Validate(); err != nil {
+		return nil, err
+	}
+	return &wf, nil
+}
+
+// ErrStored reports that Save would replace a different stored workflow.
+var ErrStored = errors.New("a different workflow of that name is stored")
+
+// Save stores the workflow under its name in Dir(). A different workflow
+// stored under the same name is only replaced when overwrite is set.
+func (wf *Workflow) Save(overwrite bool) (string, error) {
+	data, err := yaml.Marshal(wf)
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(Dir(), wf.Name+".yaml")
+	if old, err := os.ReadFile(path); err == nil && !overwrite && !bytes.Equal(old, data) {
+		return path, fmt.Errorf("%w: '%s' at %s", ErrStored, wf.Name, path)
+	}
+	return path, os.WriteFile(path, data, 0644)
+}
+
+// List returns the names of stored workflows.
+func List() []string {
+	matches, _ := filepath.Glob(filepath.Join(Dir(), "*.yaml"))
+	var names []string
+	for _, m := range matches {
+		names = append(names, strings.TrimSuffix(filepath.Base(m), ".yaml"))
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Validate checks structure, references, templates and skill args, and
+// reports every problem at once.
+func (wf *Workflow) Validate() error {
+	var errs []string
+	if wf.Name == "" || strings.ContainsAny(wf.Name, `/\ `) {
+		errs = append(errs, "name is required and may not contain spaces or slashes")
+	}
+	if len(wf.Steps) == 0 {
+		errs = append(errs, "at least one step is required")
+	}
+	if wf.Deadline != "" {
+		if _, err := time.ParseDuration(wf.Deadline); err != nil {
+			errs = append(errs, fmt.Sprintf("deadline: %v", err))
+		}
+	}
+
+	ids := map[string]bool{}
+	for i, s := range wf.Steps {
+		where := fmt.Sprintf("steps[%d]", i)
+		if s.ID == "" {
+			errs = append(errs, where+": id is required")
+		} else if ids[s.ID] {
+			errs = append(errs, fmt.Sprintf("%s: duplicate id %q", where, s.ID))
+		}
+		ids[s.ID] = true
+	}
+
+	for i, s := range wf.Steps {
+		where := fmt.Sprintf("steps[%d] (%s)", i, s.ID)
+		switch {
+		case s.Skill == "" && s.Prompt == "":
+			errs = append(errs, where+": needs either skill or prompt")
+		case s.Skill != "" && s.Prompt != "":
+			errs = append(errs, where+": has both skill and prompt")
+		}
+		for _, d := range s.DependsOn {
+			if !ids[d] {
+				errs = append(errs, fmt.Sprintf("%s: depends on unknown step %q", where, d))
+			}
+		}
+		if s.Retries < 0 {
+			errs = append(errs, where+": retries may not be negative")
+		}
+		if s.Timeout != "" {
+			if _, err := time.ParseDuration(s.Timeout); err != nil {
+				errs = append(errs, fmt.Sprintf("%s: timeout: %v", where, err))
+			}
+		}
+		for _, tmpl := range s.templates() {
+			if _, err := template.New(s.ID).Parse(tmpl); err != nil {
+				errs = append(errs, fmt.Sprintf("%s: template: %v", where, err))
+			}
+			for _, ref := range references(tmpl, ".inputs.") {
+				if _, ok := wf.Inputs[ref]; !ok {
+					errs = append(errs, fmt.Sprintf("%s: uses undeclared input %q", where, ref))
+				}
+			}
+			for _, ref := range references(tmpl, ".steps.") {
+				if !wf.upstream(s.ID, ref) {
+					errs = append(errs, fmt.Sprintf("%s: uses output of %q which is not one of its dependencies", where, ref))
+				}
+			}
+		}
+		if s.Skill != "" {
+			errs = append(errs, validateArgs(where, s)...)
+		}
+	}
+
+	if c := wf.cycle(); c != "" {
+		errs = append(errs, "dependency cycle through step "+c)
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid workflow '%s':\n  - %s", wf.Name, strings.Join(errs, "\n  - "))
+	}
+	return nil
+}
+
+// validateArgs checks skill args against the skill's parameter schema.
+// Templated string values are only known at run time and are skipped.
+func validateArgs(where string, s Step) []string {
+	skill, ok := skills.GetRegistry().Get(s.Skill)
+	if !ok {
+		return []string{fmt.Sprintf("%s: unknown skill %q", where, s.Skill)}
+	}
+	var manifest struct {
+		Parameters *schema.JSONSchema `json:"parameters"`
+	}
+	if err := json.Unmarshal(skill.Manifest(), &manifest); err != nil || manifest.Parameters == nil {
+		return nil
+	}
+
+	args := map[string]interface{}{}
+	for k, v := range s.Args {
+		if str, ok := v.(string); ok && strings.Contains(str, "{{") {
+			continue
+		}
+		args[k] = v
+	}
+	// Round-trip through JSON so YAML ints become JSON numbers.
+	data, err := json.Marshal(args)
+	if err != nil {
+		return []string{fmt.Sprintf("%s: args: %v", where, err)}
+	}
+	var generic interface{}
+	_ = json.Unmarshal(data, &generic)
+
+	var errs []string
+	for _, e := range manifest.Parameters.Validate(generic) {
+		// Required args may be provided through templates.
+		if strings.Contains(e, "missing required field") && templatedArg(s.Args, e) {
+			continue
+		}
+		errs = append(errs, fmt.Sprintf("%s: args%s", where, strings.TrimPrefix(e, "$")))
+	}
+	return errs
+}
+
+func templatedArg(args map[string]interface{}, e string) bool {
+	for k, v := range args {
+		if str, ok := v.(string); ok && strings.Contains(str, "{{") && strings.Contains(e, `"`+k+`"`) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Step) templates() []string {
+	list := []string{}
+	if s.Prompt != "" {
+		list = append(list, s.Prompt)
+	}
+	for _, v := range s.Args {
+		if str, ok := v.(string); ok {
+			list = append(list, str)
+		}
+	}
+	return list
+}
+
+// references returns the identifiers following prefix in a template, e.g.
+// "repo" for ".inputs.repo".
+func references(tmpl, prefix string) []string {
+	var refs []string
+	for rest := tmpl; ; {
+		i := strings.Index(rest, prefix)
+		if i < 0 {
+			return refs
+		}
+		rest = rest[i+len(prefix):]
+		end := strings.IndexFunc(rest, func(r rune) bool {
+			return !(r == '_' || r == '-' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9')
+		})
+		if end < 0 {
+			end = len(rest)
+		}
+		refs = append(refs, rest[:end])
+	}
+}
+
+func (wf *Workflow) step(id string) *Step {
+	for i := range wf.Steps {
+		if wf.Steps[i].ID == id {
+			return &wf.Steps[i]
+		}
+	}
+	return nil
+}
+
+// upstream reports whether ancestor is a transitive dependency of id.
+func (wf *Workflow) upstream(id, ancestor string) bool {
+	seen := map[string]bool{}
+	var walk func(string) bool
+	walk = func(cur string) bool {
+		s := wf.step(cur)
+		if s == nil || seen[cur] {
+			return false
+		}
+		seen[cur] = true
+		for _, d := range s.DependsOn {
+			if d == ancestor || walk(d) {
+				return true
+			}
+		}
+		return false
+	}
+	return walk(id)
+}
+
+func (wf *Workflow) cycle() string {
+	state := map[string]int{} // 1 visiting, 2 done
+	var visit func(string) string
+	visit = func(id string) string {
+		switch state[id] {
+		case 1:
+			return id
+		case 2:
+			return ""
+		}
+		state[id] = 1
+		if s := wf.step(id); s != nil {
+			for _, d := range s.DependsOn {
+				if c := visit(d); c != "" {
+					return c
+				}
+			}
+		}
+		state[id] = 2
+		return ""
+	}
+	for _, s := range wf.Steps {
+		if c := visit(s.ID); c != "" {
+			return c
+		}
+	}
+	return ""
+}
+
+// ResolveInputs applies defaults and rejects missing or unknown inputs.
+func (wf *Workflow) ResolveInputs(given map[string]string) (map[string]string, error) {
+	resolved := map[string]string{}
+	var errs []string
+	for k := range given {
+		if _, ok := wf.Inputs[k]; !ok {
+			errs = append(errs, fmt.Sprintf("unknown input %q", k))
+		}
+	}
+	for name, in := range wf.Inputs {
+		v, ok := given[name]
+		if !ok {
+			v = in.Default
+		}
+		if v == "" && in.Required {
+			errs = append(errs, fmt.Sprintf("missing required input %q", name))
+		}
+		resolved[name] = v
+	}
+	if len(errs) > 0 {
+		sort.Strings(errs)
+		return nil, fmt.Errorf("workflow '%s': %s", wf.Name, strings.Join(errs, "; "))
+	}
+	return resolved, nil
+}
+
+// Vars builds the template data for a step from inputs and step outputs.
+func Vars(inputs map[string]string, outputs map[string]string) map[string]interface{} {
+	steps := map[string]interface{}{}
+	for id, out := range outputs {
+		steps[id] = map[string]interface{}{"output": out}
+	}
+	ins := map[string]interface{}{}
+	for k, v := range inputs {
+		ins[k] = v
+	}
+	return map[string]interface{}{"inputs": ins, "steps": steps}
+}
+
+// Render executes a step template; unknown keys are errors.
+func Render(name, text string, vars map[string]interface{}) (string, error) {
+	t, err := template.New(name).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	if err := t.Execute(&b, vars); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// ToMission loads the workflow into a mission whose sub-tasks are the
+// prebuilt steps, so the planner does not have to re-invent them.
+func (wf *Workflow) ToMission(mgr *mission.Manager, inputs map[string]string) (*mission.Mission, error) {
+	deadline := time.Now().Add(7 * 24 * time.Hour)
+	if wf.Deadline != "" {
+		d, _ := time.ParseDuration(wf.Deadline)
+		deadline = time.Now().Add(d)
+	}
+	goal := wf.Goal
+	if goal == "" {
+		goal = wf.Description
+	}
+	if rendered, err := Render("goal", goal, Vars(inputs, nil)); err == nil {
+		goal = rendered
+	}
+
+	m := mgr.CreateMission(wf.Name, wf.Description, goal, deadline)
+	m.Workflow = wf.Name
+	m.Inputs = inputs
+	for _, s := range wf.Steps {
+		spec, err := json.Marshal(s)
+		if err != nil {
+			return nil, err
+		}
+		m.Tasks = append(m.Tasks, mission.SubTask{
+			ID:           s.ID,
+			Title:        s.Title(),
+			Description:  s.Prompt,
+			Status:       mission.StatusActive,
+			Dependencies: s.DependsOn,
+			Spec:         spec,
+		})
+	}
+	m.UpdatedAt = time.Now()
+	return m, mgr.Save()
+}
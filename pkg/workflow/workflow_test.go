@@ -0,0 +1,113 @@
+package workflow
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/nathfavour/auracrab/internal/provider"
+)
+
+func TestParseReportsAllErrors(t *testing.T) {
+	_, err := Parse([]byte(`
+name: broken
+steps:
+  - id: a
+    prompt: "hello {{ .inputs.missing }}"
+  - id: b
+    depends_on: [nope]
+    prompt: "{{ .steps.a.output }}"
+  - id: a
+    skill: system
+    prompt: both
+`))
+	if err == nil {
+		t.Fatal("expected validation error")
+	}
+	for _, want := range []string{"missing", "nope", "duplicate", "not one of its dependencies"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %q", err, want)
+		}
+	}
+
+	if _, err := Parse([]byte("name: x\nsteps: []\nbogus: 1\n")); err == nil {
+		t.Error("expected unknown field to be rejected")
+	}
+}
+
+type fakeQuerier struct{ fail string }
+
+func (f fakeQuerier) QueryWithContext(_ context.Context, prompt, _ string) (provider.CompletionResponse, error) {
+	if f.fail != "" && strings.Contains(prompt, f.fail) {
+		return provider.CompletionResponse{}, errors.New("boom")
+	}
+	return provider.CompletionResponse{Content: "<" + prompt + ">"}, nil
+}
+
+func TestRun(t *testing.T) {
+	wf, err := Parse([]byte(`
+name: chain
+inputs:
+  who: {default: world}
+steps:
+  - id: greet
+    prompt: "hi {{ .inputs.who }}"
+  - id: echo
+    depends_on: [greet]
+    prompt: "echo {{ .steps.greet.output }}"
+  - id: side
+    prompt: side
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	inputs, err := wf.ResolveInputs(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := Run(context.Background(), wf, inputs, fakeQuerier{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out["echo"] != "<echo <hi world>>" || out["side"] != "<side>" {
+		t.Errorf("unexpected outputs: %v", out)
+	}
+
+	var skipped []string
+	out, err = Run(context.Background(), wf, inputs, fakeQuerier{fail: "hi"}, func(e Event) {
+		if e.Status == "skipped" {
+			skipped = append(skipped, e.Step)
+		}
+	})
+	if err == nil || len(skipped) != 1 || skipped[0] != "echo" || out["side"] != "<side>" {
+		t.Errorf("expected greet failure to skip echo only: err=%v skipped=%v out=%v", err, skipped, out)
+	}
+}
+
+func TestSaveKeepsADifferentStoredWorkflow(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	parse := func(prompt string) *Workflow {
+		wf, err := Parse([]byte("name: release\nsteps:\n  - id: a\n    prompt: " + prompt + "\n"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return wf
+	}
+	if _, err := parse("build").Save(false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := parse("build").Save(false); err != nil {
+		t.Fatalf("re-applying the same workflow failed: %v", err)
+	}
+	if _, err := parse("deploy").Save(false); !errors.Is(err, ErrStored) {
+		t.Fatalf("err = %v, want ErrStored", err)
+	}
+	if _, err := parse("deploy").Save(true); err != nil {
+		t.Fatal(err)
+	}
+	if wf, err := Load("release"); err != nil || wf.Steps[0].Prompt != "deploy" {
+		t.Fatalf("forced save did not replace the workflow: %+v, %v", wf, err)
+	}
+}